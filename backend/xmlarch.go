@@ -6,10 +6,10 @@ import (
 )
 
 type XmlArch struct {
-	XMLName   xml.Name     `xml:"arch"`
-	Name      string       `xml:"name,attr"`
-	IOType    []XmlIOType  `xml:"io-type"`
-	Processes []XmlProcess `xml:"process"`
+	XMLName   xml.Name     `xml:"arch" json:"-"`
+	Name      string       `xml:"name,attr" json:"name"`
+	IOType    []XmlIOType  `xml:"io-type" json:"io-type,omitempty"`
+	Processes []XmlProcess `xml:"process" json:"process,omitempty"`
 }
 
 func XmlArchNew(name string) *XmlArch {
@@ -1,8 +1,11 @@
 package backend
 
 import (
+	"fmt"
+	"github.com/axel-freesp/sge/tool"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,14 +18,23 @@ const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
 
 var xmlRoot string
 var xmlSearchPaths []string
+var envSearchPaths []string
 
+var project *XmlProject
+var projectDir string
+
+// Init takes the root directory and search path from the environment.
+// Both are only defaults: an opened project (see SetProject) overrides
+// them, the environment search path is then searched last.
 func Init() {
 	xmlRoot = os.Getenv(envPathKey)
 	if len(xmlRoot) == 0 {
-		log.Fatal("XmlBackendInit Error: missing environment variable FREESP_PATH")
+		xmlRoot, _ = os.Getwd()
+		log.Printf("backend.Init: %s not set, using %s\n", envPathKey, xmlRoot)
 	}
 	searchPath := os.Getenv(envSearchPathKey)
-	xmlSearchPaths = strings.SplitN(searchPath, envSearchPathSep, -1)
+	envSearchPaths = strings.SplitN(searchPath, envSearchPathSep, -1)
+	xmlSearchPaths = envSearchPaths
 	log.Println("backend.Init: search path:", XmlSearchPaths())
 }
 
@@ -33,3 +45,68 @@ func XmlRoot() string {
 func XmlSearchPaths() []string {
 	return xmlSearchPaths
 }
+
+// SetProject makes the project read from projectfile the current one:
+// its directory becomes the root directory, its search paths precede
+// the ones from the environment.
+func SetProject(p *XmlProject, projectfile string) {
+	dir, err := filepath.Abs(filepath.Dir(projectfile))
+	if err != nil {
+		dir = filepath.Dir(projectfile)
+	}
+	project, projectDir = p, dir
+	canonical = p.Canonical
+	xmlRoot = dir
+	xmlSearchPaths = []string{dir}
+	for _, sp := range p.SearchPaths {
+		xmlSearchPaths = appendPath(xmlSearchPaths, ProjectPath(sp.Path))
+	}
+	for _, sp := range envSearchPaths {
+		xmlSearchPaths = appendPath(xmlSearchPaths, sp)
+	}
+	log.Println("backend.SetProject: search path:", XmlSearchPaths())
+}
+
+func appendPath(list []string, path string) []string {
+	for _, p := range list {
+		if p == path {
+			return list
+		}
+	}
+	return append(list, path)
+}
+
+// Current project, nil if none is open.
+func Project() *XmlProject {
+	return project
+}
+
+// ProjectPath resolves a path relative to the directory of the
+// current project.
+func ProjectPath(path string) string {
+	if project == nil || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectDir, path)
+}
+
+// Directory where new files with the given suffix are stored.
+func OutputDir(suffix string) string {
+	if project != nil {
+		dir := project.OutputDir(suffix)
+		if len(dir) > 0 {
+			return ProjectPath(dir)
+		}
+	}
+	return xmlRoot
+}
+
+// HintFilename returns the name of the hint file belonging to the
+// document filename: "dir/graph.sml" has hints in "dir/graph-sml.hints.xml",
+// "dir/graph.sml.json" in "dir/graph-sml.hints.json".
+func HintFilename(filename string) string {
+	if tool.IsJson(filename) {
+		return fmt.Sprintf("%s-%s.hints.json", tool.DocPrefix(filename), tool.DocSuffix(filename))
+	}
+	return fmt.Sprintf("%s-%s.hints.xml", tool.Prefix(filename), tool.Suffix(filename))
+}
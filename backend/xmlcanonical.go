@@ -0,0 +1,170 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/axel-freesp/sge/tool"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// In canonical mode, documents are written independent of the order
+// of the model in memory: named elements (nodes, connections, types,
+// archs, processes, mappings and their hints) are sorted by name,
+// attributes come in schema order, lines carry no trailing whitespace
+// and the document ends with a single line break. Ports, channels,
+// parameters, signal fields and implementations keep their order, it
+// is significant.
+// Saving the same model twice then yields the same files.
+
+var canonical bool
+
+// Canonical mode is on for sgefmt and projects that ask for it.
+func SetCanonical(on bool) {
+	canonical = on
+}
+
+func IsCanonical() bool {
+	return canonical
+}
+
+// DocumentNew returns an empty document of the kind stored as path,
+// hint files included.
+func DocumentNew(path string) (doc Document, ok bool) {
+	ok = true
+	hintSuffix := fmt.Sprintf(".hints.%s", tool.Suffix(path))
+	if strings.HasSuffix(path, hintSuffix) {
+		p := strings.TrimSuffix(path, hintSuffix)
+		switch p[strings.LastIndex(p, "-")+1:] {
+		case "sml":
+			doc = XmlGraphHintNew("")
+		case "spml":
+			doc = XmlPlatformHintNew("")
+		case "mml":
+			doc = XmlMappingHintNew("")
+		default:
+			ok = false
+		}
+		return
+	}
+	switch tool.DocSuffix(path) {
+	case "sml":
+		doc = XmlSignalGraphNew()
+	case "alml":
+		doc = XmlLibraryNew()
+	case "spml":
+		doc = XmlPlatformNew()
+	case "mml":
+		doc = XmlMappingNew("", "")
+	case ProjectSuffix:
+		doc = XmlProjectNew("")
+	default:
+		ok = false
+	}
+	return
+}
+
+// Canonicalize sorts the named elements of doc, in place.
+func Canonicalize(doc Document) {
+	switch d := doc.(type) {
+	case *XmlSignalGraph:
+		canonicalSignalGraph(d)
+	case *XmlLibrary:
+		sort.SliceStable(d.SignalTypes, func(i, j int) bool { return d.SignalTypes[i].Name < d.SignalTypes[j].Name })
+		sort.SliceStable(d.NodeTypes, func(i, j int) bool { return d.NodeTypes[i].TypeName < d.NodeTypes[j].TypeName })
+		sort.SliceStable(d.Compatible, func(i, j int) bool {
+			a, b := d.Compatible[i], d.Compatible[j]
+			return a.From < b.From || a.From == b.From && a.To < b.To
+		})
+		for _, nt := range d.NodeTypes {
+			for _, impl := range nt.Implementation {
+				for k := range impl.SignalGraph {
+					canonicalSignalGraph(&impl.SignalGraph[k])
+				}
+			}
+		}
+	case *XmlPlatform:
+		sort.SliceStable(d.Arch, func(i, j int) bool { return d.Arch[i].Name < d.Arch[j].Name })
+		for _, a := range d.Arch {
+			sort.SliceStable(a.IOType, func(i, j int) bool { return a.IOType[i].Name < a.IOType[j].Name })
+			sort.SliceStable(a.Processes, func(i, j int) bool { return a.Processes[i].Name < a.Processes[j].Name })
+		}
+	case *XmlMapping:
+		sort.SliceStable(d.IOMappings, func(i, j int) bool { return d.IOMappings[i].Name < d.IOMappings[j].Name })
+		sort.SliceStable(d.Mappings, func(i, j int) bool { return d.Mappings[i].Name < d.Mappings[j].Name })
+	case *XmlGraphHint:
+		canonicalNodeHints(d.InputNode)
+		canonicalNodeHints(d.OutputNode)
+		canonicalNodeHints(d.ProcessingNode)
+	case *XmlPlatformHint:
+		canonicalArchHints(d.Arch)
+	case *XmlMappingHint:
+		canonicalNodeHints(d.MappedNodes)
+		canonicalArchHints(d.Arch)
+	}
+}
+
+// A sorted copy of doc, for writing without reordering the model in
+// memory. The copy goes through XML, the Xml* structs hold nothing
+// else.
+func canonicalCopy(doc Document) (ret Document, err error) {
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		err = fmt.Errorf("canonicalCopy error: %s", err)
+		return
+	}
+	ret = reflect.New(reflect.TypeOf(doc).Elem()).Interface().(Document)
+	err = xml.Unmarshal(data, ret)
+	if err != nil {
+		err = fmt.Errorf("canonicalCopy error: %s", err)
+		return
+	}
+	Canonicalize(ret)
+	return
+}
+
+func canonicalSignalGraph(g *XmlSignalGraph) {
+	sort.SliceStable(g.InputNodes, func(i, j int) bool { return g.InputNodes[i].NName < g.InputNodes[j].NName })
+	sort.SliceStable(g.OutputNodes, func(i, j int) bool { return g.OutputNodes[i].NName < g.OutputNodes[j].NName })
+	sort.SliceStable(g.ProcessingNodes, func(i, j int) bool {
+		return g.ProcessingNodes[i].NName < g.ProcessingNodes[j].NName
+	})
+	key := func(c XmlConnect) string {
+		return fmt.Sprintf("%s\x00%s\x00%s\x00%s", c.From, c.FromPort, c.To, c.ToPort)
+	}
+	sort.SliceStable(g.Connections, func(i, j int) bool { return key(g.Connections[i]) < key(g.Connections[j]) })
+}
+
+func canonicalNodeHints(list []XmlNodePosHint) {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+}
+
+// Arch ports are sorted only if all of them name their channel.
+func canonicalArchHints(list []XmlArchPosHint) {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	for _, a := range list {
+		sort.SliceStable(a.Processes, func(i, j int) bool { return a.Processes[i].Name < a.Processes[j].Name })
+		named := true
+		for _, p := range a.ArchPorts {
+			named = named && len(p.Channel) > 0
+		}
+		if named {
+			sort.SliceStable(a.ArchPorts, func(i, j int) bool { return a.ArchPorts[i].Channel < a.ArchPorts[j].Channel })
+		}
+	}
+}
+
+// Whitespace of a written document: line breaks as \n, no trailing
+// blanks, a single line break at the end.
+func canonicalText(data []byte) []byte {
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	lines := bytes.Split(bytes.TrimRight(data, " \t\r\n"), []byte("\n"))
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.Write(bytes.TrimRight(l, " \t\r"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
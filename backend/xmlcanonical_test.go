@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"testing"
+)
+
+const canonicalGraphIn = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <processing-node name="g" type="Gain"></processing-node>
+      <input port="o" name="in" type="src"></input>
+      <processing-node name="f" type="Filter">
+         <outtype port="y" type="s"></outtype>
+         <outtype port="x" type="s"></outtype>
+      </processing-node>
+   </nodes>
+   <connections>
+      <connect from="f" to="g" from-port="y" to-port="i"></connect>
+      <connect from="f" to="g" from-port="x" to-port="i"></connect>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>
+
+`
+
+// Nodes and connections sorted, ports not.
+const canonicalGraphOut = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="o" name="in" type="src"></input>
+      <processing-node name="f" type="Filter">
+         <outtype port="y" type="s"></outtype>
+         <outtype port="x" type="s"></outtype>
+      </processing-node>
+      <processing-node name="g" type="Gain"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="f" to="g" from-port="x" to-port="i"></connect>
+      <connect from="f" to="g" from-port="y" to-port="i"></connect>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>
+`
+
+const canonicalMappingIn = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p.spml">
+   <map-node name="b" process="a1/p1"></map-node>
+   <map-ionode name="out" process="a1/p2"></map-ionode>
+   <map-node name="a" process="a1/p2"></map-node>
+   <map-ionode name="in" process="a1/p1"></map-ionode>
+</mapping>`
+
+const canonicalMappingOut = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p.spml">
+   <map-ionode name="in" process="a1/p1"></map-ionode>
+   <map-ionode name="out" process="a1/p2"></map-ionode>
+   <map-node name="a" process="a1/p2"></map-node>
+   <map-node name="b" process="a1/p1"></map-node>
+</mapping>
+`
+
+const canonicalLibraryIn = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s2" scope="local" mode="sync" c-type="int" message-id=""></signal-type>
+   <signal-type name="s1" scope="local" mode="sync" c-type="int" message-id=""></signal-type>
+   <node-type name="Gain">
+      <intype port="i" type="s1"></intype>
+   </node-type>
+   <node-type name="Filter">
+      <outtype port="y" type="s1"></outtype>
+      <outtype port="x" type="s1"></outtype>
+      <implementation name="graph">
+         <signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+            <nodes>
+               <processing-node name="g" type="Gain"></processing-node>
+               <processing-node name="f" type="Gain"></processing-node>
+            </nodes>
+            <connections></connections>
+         </signal-graph>
+      </implementation>
+   </node-type>
+</library>`
+
+// Types and the nodes of implementations sorted, ports not.
+const canonicalLibraryOut = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s1" scope="local" mode="sync" c-type="int" message-id=""></signal-type>
+   <signal-type name="s2" scope="local" mode="sync" c-type="int" message-id=""></signal-type>
+   <node-type name="Filter">
+      <outtype port="y" type="s1"></outtype>
+      <outtype port="x" type="s1"></outtype>
+      <implementation name="graph">
+         <signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+            <nodes>
+               <processing-node name="f" type="Gain"></processing-node>
+               <processing-node name="g" type="Gain"></processing-node>
+            </nodes>
+            <connections></connections>
+         </signal-graph>
+      </implementation>
+   </node-type>
+   <node-type name="Gain">
+      <intype port="i" type="s1"></intype>
+   </node-type>
+</library>
+`
+
+const canonicalPlatformIn = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="b">
+      <io-type name="t2" mode="sync"></io-type>
+      <io-type name="t1" mode="sync"></io-type>
+      <process name="p2">
+         <output-channel io-type="t2" dest="p1"></output-channel>
+         <output-channel io-type="t1" dest="p1"></output-channel>
+      </process>
+      <process name="p1"></process>
+   </arch>
+   <arch name="a"></arch>
+</platform>`
+
+// Archs, io-types and processes sorted, channels not.
+const canonicalPlatformOut = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a"></arch>
+   <arch name="b">
+      <io-type name="t1" mode="sync"></io-type>
+      <io-type name="t2" mode="sync"></io-type>
+      <process name="p1"></process>
+      <process name="p2">
+         <output-channel io-type="t2" dest="p1"></output-channel>
+         <output-channel io-type="t1" dest="p1"></output-channel>
+      </process>
+   </arch>
+</platform>
+`
+
+const canonicalGraphHintIn = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="g" expanded="false"></processing-node>
+   <processing-node name="f" expanded="false"></processing-node>
+</hints>`
+
+const canonicalGraphHintOut = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="f" expanded="false"></processing-node>
+   <processing-node name="g" expanded="false"></processing-node>
+</hints>
+`
+
+// Arch ports of b name their channels and are sorted, those of a are
+// not.
+const canonicalPlatformHintIn = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="p.spml">
+   <arch name="b">
+      <arch-port channel="p1/t2">
+         <hint mode="normal" x="20" y="0"></hint>
+      </arch-port>
+      <arch-port channel="p1/t1">
+         <hint mode="normal" x="10" y="0"></hint>
+      </arch-port>
+      <process name="p2"></process>
+      <process name="p1"></process>
+   </arch>
+   <arch name="a">
+      <arch-port>
+         <hint mode="normal" x="20" y="0"></hint>
+      </arch-port>
+      <arch-port channel="p1/t1">
+         <hint mode="normal" x="10" y="0"></hint>
+      </arch-port>
+   </arch>
+</hints>`
+
+const canonicalPlatformHintOut = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="p.spml">
+   <arch name="a">
+      <arch-port>
+         <hint mode="normal" x="20" y="0"></hint>
+      </arch-port>
+      <arch-port channel="p1/t1">
+         <hint mode="normal" x="10" y="0"></hint>
+      </arch-port>
+   </arch>
+   <arch name="b">
+      <arch-port channel="p1/t1">
+         <hint mode="normal" x="10" y="0"></hint>
+      </arch-port>
+      <arch-port channel="p1/t2">
+         <hint mode="normal" x="20" y="0"></hint>
+      </arch-port>
+      <process name="p1"></process>
+      <process name="p2"></process>
+   </arch>
+</hints>
+`
+
+func TestCanonical(t *testing.T) {
+	case1 := []struct {
+		filename, in, out string
+	}{
+		{"c.sml", canonicalGraphIn, canonicalGraphOut},
+		{"c.sml", canonicalGraphOut, canonicalGraphOut},
+		{"c.mml", canonicalMappingIn, canonicalMappingOut},
+		{"c.alml", canonicalLibraryIn, canonicalLibraryOut},
+		{"c.spml", canonicalPlatformIn, canonicalPlatformOut},
+		{"c-sml.hints.xml", canonicalGraphHintIn, canonicalGraphHintOut},
+		{"c-spml.hints.xml", canonicalPlatformHintIn, canonicalPlatformHintOut},
+	}
+	SetCanonical(true)
+	defer SetCanonical(false)
+	for i, c := range case1 {
+		doc, ok := DocumentNew(c.filename)
+		if !ok {
+			t.Fatalf("testcase %d failed: no document for %s\n", i, c.filename)
+		}
+		_, err := DocumentRead(doc, []byte(c.in), c.filename)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		before, _ := doc.Write()
+		data, err := DocumentWrite(doc, c.filename)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		if string(data) != c.out {
+			t.Errorf("testcase %d failed: got\n%s\nexpected\n%s\n", i, data, c.out)
+		}
+		after, _ := doc.Write()
+		if string(after) != string(before) {
+			t.Errorf("testcase %d failed: document changed by writing\n%s\n", i, after)
+		}
+	}
+}
@@ -6,19 +6,19 @@ import (
 )
 
 type XmlChannel struct {
-	IOType string `xml:"io-type,attr"`
+	IOType string `xml:"io-type,attr" json:"io-type"`
 }
 
 type XmlInChannel struct {
 	XmlChannel
-	XMLName xml.Name `xml:"input-channel"`
-	Source  string   `xml:"source,attr"`
+	XMLName xml.Name `xml:"input-channel" json:"-"`
+	Source  string   `xml:"source,attr" json:"source"`
 }
 
 type XmlOutChannel struct {
 	XmlChannel
-	XMLName xml.Name `xml:"output-channel"`
-	Dest    string   `xml:"dest,attr"`
+	XMLName xml.Name `xml:"output-channel" json:"-"`
+	Dest    string   `xml:"dest,attr" json:"dest"`
 }
 
 func XmlInChannelNew(name, ioType, source string) *XmlInChannel {
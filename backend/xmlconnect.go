@@ -6,11 +6,11 @@ import (
 )
 
 type XmlConnect struct {
-	XMLName  xml.Name `xml:"connect"`
-	From     string   `xml:"from,attr"`
-	To       string   `xml:"to,attr"`
-	FromPort string   `xml:"from-port,attr"`
-	ToPort   string   `xml:"to-port,attr"`
+	XMLName  xml.Name `xml:"connect" json:"-"`
+	From     string   `xml:"from,attr" json:"from"`
+	To       string   `xml:"to,attr" json:"to"`
+	FromPort string   `xml:"from-port,attr" json:"from-port"`
+	ToPort   string   `xml:"to-port,attr" json:"to-port"`
 }
 
 func XmlConnectNew(from, to, fromPort, toPort string) *XmlConnect {
@@ -6,11 +6,11 @@ import (
 )
 
 type XmlGraphHint struct {
-	XMLName        xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints"`
-	Ref            string           `xml:"ref,attr"`
-	InputNode      []XmlNodePosHint `xml:"input-node"`
-	OutputNode     []XmlNodePosHint `xml:"output-node"`
-	ProcessingNode []XmlNodePosHint `xml:"processing-node"`
+	XMLName        xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints" json:"-"`
+	Ref            string           `xml:"ref,attr" json:"ref"`
+	InputNode      []XmlNodePosHint `xml:"input-node" json:"input-node,omitempty"`
+	OutputNode     []XmlNodePosHint `xml:"output-node" json:"output-node,omitempty"`
+	ProcessingNode []XmlNodePosHint `xml:"processing-node" json:"processing-node,omitempty"`
 }
 
 func XmlGraphHintNew(ref string) *XmlGraphHint {
@@ -35,11 +35,11 @@ func (h *XmlGraphHint) Write() (data []byte, err error) {
 }
 
 type XmlNodePosHint struct {
-	Name string `xml:"name,attr"`
+	Name string `xml:"name,attr" json:"name"`
 	XmlModeHint
-	Expanded bool             `xml:"expanded,attr"`
-	InPorts  []XmlPortPosHint `xml:"in-port"`
-	OutPorts []XmlPortPosHint `xml:"out-port"`
+	Expanded bool             `xml:"expanded,attr" json:"expanded"`
+	InPorts  []XmlPortPosHint `xml:"in-port" json:"in-port,omitempty"`
+	OutPorts []XmlPortPosHint `xml:"out-port" json:"out-port,omitempty"`
 }
 
 func XmlNodePosHintNew(name string) *XmlNodePosHint {
@@ -64,7 +64,7 @@ func (h *XmlNodePosHint) Write() (data []byte, err error) {
 }
 
 type XmlPortPosHint struct {
-	Name string `xml:"name,attr"`
+	Name string `xml:"name,attr" json:"name"`
 	XmlModeHint
 }
 
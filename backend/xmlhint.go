@@ -6,8 +6,8 @@ import (
 )
 
 type XmlHint struct {
-	X int `xml:"x,attr"`
-	Y int `xml:"y,attr"`
+	X int `xml:"x,attr" json:"x"`
+	Y int `xml:"y,attr" json:"y"`
 }
 
 func XmlHintNew(x, y int) *XmlHint {
@@ -32,8 +32,8 @@ func (h *XmlHint) Write() (data []byte, err error) {
 }
 
 type XmlShape struct {
-	W int `xml:"w,attr"`
-	H int `xml:"h,attr"`
+	W int `xml:"w,attr" json:"w"`
+	H int `xml:"h,attr" json:"h"`
 }
 
 func XmlShapeNew(w, h int) *XmlShape {
@@ -84,7 +84,7 @@ func (h *XmlRectangle) Write() (data []byte, err error) {
 }
 
 type XmlModeHint struct {
-	Entry []XmlModeHintEntry `xml:"hint"`
+	Entry []XmlModeHintEntry `xml:"hint" json:"hint,omitempty"`
 }
 
 func XmlModeHintNew() *XmlModeHint {
@@ -109,7 +109,7 @@ func (h *XmlModeHint) Write() (data []byte, err error) {
 }
 
 type XmlModeRectangle struct {
-	Entry []XmlModeRectEntry `xml:"hint"`
+	Entry []XmlModeRectEntry `xml:"hint" json:"hint,omitempty"`
 }
 
 func XmlModeRectNew() *XmlModeRectangle {
@@ -134,7 +134,7 @@ func (h *XmlModeRectangle) Write() (data []byte, err error) {
 }
 
 type XmlModeHintEntry struct {
-	Mode string `xml:"mode,attr"`
+	Mode string `xml:"mode,attr" json:"mode"`
 	XmlHint
 }
 
@@ -160,7 +160,7 @@ func (h *XmlModeHintEntry) Write() (data []byte, err error) {
 }
 
 type XmlModeRectEntry struct {
-	Mode string `xml:"mode,attr"`
+	Mode string `xml:"mode,attr" json:"mode"`
 	XmlHint
 	XmlShape
 }
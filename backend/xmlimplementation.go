@@ -4,12 +4,15 @@ import (
 	"encoding/xml"
 )
 
+// Graph implementations either embed their signal graph, or refer to a
+// signal graph file by GraphRef (resolved like library references).
 type XmlImplementation struct {
-	XMLName     xml.Name         `xml:"implementation"`
-	Name        string           `xml:"name,attr"`
-	SignalGraph []XmlSignalGraph `xml:"signal-graph"`
+	XMLName     xml.Name         `xml:"implementation" json:"-"`
+	Name        string           `xml:"name,attr" json:"name"`
+	GraphRef    string           `xml:"graph,attr,omitempty" json:"graph,omitempty"`
+	SignalGraph []XmlSignalGraph `xml:"signal-graph" json:"signal-graph,omitempty"`
 }
 
 func XmlImplementationNew(name string) *XmlImplementation {
-	return &XmlImplementation{xml.Name{freespNamespace, "implementation"}, name, nil}
+	return &XmlImplementation{xml.Name{freespNamespace, "implementation"}, name, "", nil}
 }
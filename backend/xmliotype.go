@@ -6,9 +6,9 @@ import (
 )
 
 type XmlIOType struct {
-	XMLName xml.Name  `xml:"io-type"`
-	Name    string    `xml:"name,attr"`
-	Mode    XmlIOMode `xml:"mode,attr"`
+	XMLName xml.Name  `xml:"io-type" json:"-"`
+	Name    string    `xml:"name,attr" json:"name"`
+	Mode    XmlIOMode `xml:"mode,attr" json:"mode"`
 }
 
 func XmlIOTypeNew(name string, mode XmlIOMode) *XmlIOType {
@@ -0,0 +1,257 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/axel-freesp/sge/tool"
+	"log"
+	"reflect"
+)
+
+// Every document (and hint document) can be stored either as XML or
+// as JSON. The JSON encoding uses the XML element and attribute names
+// as keys, so converting between both is lossless, except for unknown
+// XML content (see Preserver), which JSON cannot carry. Which one is
+// used is decided by the file name: a trailing ".json" selects JSON.
+
+type Document interface {
+	Read(data []byte) (cnt int, err error)
+	Write() (data []byte, err error)
+	ReadJson(data []byte) (cnt int, err error)
+	WriteJson() (data []byte, err error)
+	schema() xmlDocumentSchema
+}
+
+var _ Document = (*XmlSignalGraph)(nil)
+var _ Document = (*XmlLibrary)(nil)
+var _ Document = (*XmlPlatform)(nil)
+var _ Document = (*XmlMapping)(nil)
+var _ Document = (*XmlProject)(nil)
+var _ Document = (*XmlGraphHint)(nil)
+var _ Document = (*XmlPlatformHint)(nil)
+var _ Document = (*XmlMappingHint)(nil)
+
+func IsJsonFile(filepath string) bool {
+	return tool.IsJson(filepath)
+}
+
+// Decode data according to the encoding selected by filepath.
+// Data of older format versions is migrated, then validated; warnings
+// are logged. JSON data is checked in its XML encoding.
+func DocumentRead(doc Document, data []byte, filepath string) (cnt int, err error) {
+	return defaultPreserver.DocumentRead(doc, data, filepath)
+}
+
+// DocumentRead keeps the unknown XML content in pr.
+func (pr *Preserver) DocumentRead(doc Document, data []byte, filepath string) (cnt int, err error) {
+	if IsJsonFile(filepath) {
+		cnt, err = doc.ReadJson(data)
+		if err == nil {
+			err = checkJson(doc, filepath)
+		}
+		return
+	}
+	data, err = migrateDocument(data, filepath)
+	if err != nil {
+		cnt = len(data)
+		return
+	}
+	err = validateDocument(doc, data, filepath)
+	if err != nil {
+		cnt = len(data)
+		return
+	}
+	cnt, err = doc.Read(data)
+	if err == nil {
+		pr.preserveDocument(doc, data, filepath)
+	}
+	return
+}
+
+// Validate data, logging the warnings.
+func validateDocument(doc Document, data []byte, filepath string) error {
+	problems := Validate(doc, data, filepath)
+	for _, w := range problems.Warnings() {
+		log.Println(w)
+	}
+	errs := problems.Errors()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Migrate and validate doc, read from JSON, like XML documents. JSON
+// carries no unknown content, there is nothing to preserve.
+func checkJson(doc Document, filepath string) (err error) {
+	data, err := doc.Write()
+	if err != nil {
+		return
+	}
+	migrated, err := migrateDocument(data, filepath)
+	if err != nil {
+		return
+	}
+	err = validateDocument(doc, migrated, filepath)
+	if err != nil || bytes.Equal(migrated, data) {
+		return
+	}
+	v := reflect.ValueOf(doc).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	_, err = doc.Read(migrated)
+	return
+}
+
+// Encode doc according to the encoding selected by filepath.
+// Unknown XML content of the document read from filepath is kept.
+// In canonical mode, a sorted copy of doc is written (see Canonicalize),
+// doc itself is not changed.
+func DocumentWrite(doc Document, filepath string) (data []byte, err error) {
+	return defaultPreserver.DocumentWrite(doc, filepath)
+}
+
+// DocumentWrite restores the unknown XML content kept in pr.
+func (pr *Preserver) DocumentWrite(doc Document, filepath string) (data []byte, err error) {
+	if canonical {
+		doc, err = canonicalCopy(doc)
+		if err != nil {
+			return
+		}
+	}
+	if IsJsonFile(filepath) {
+		return doc.WriteJson()
+	}
+	data, err = doc.Write()
+	if err != nil {
+		return
+	}
+	data, err = pr.restoreDocument(data, filepath)
+	if err == nil && canonical {
+		data = canonicalText(data)
+	}
+	return
+}
+
+func readJson(data []byte, v interface{}, typename string) (cnt int, err error) {
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		err = fmt.Errorf("%s.ReadJson error: %v", typename, err)
+	}
+	cnt = len(data)
+	return
+}
+
+func writeJson(v interface{}, typename string) (data []byte, err error) {
+	data, err = json.MarshalIndent(v, "", "   ")
+	if err != nil {
+		err = fmt.Errorf("%s.WriteJson error: %v", typename, err)
+		return
+	}
+	data = append(data, '\n')
+	return
+}
+
+func (g *XmlSignalGraph) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, g, "XmlSignalGraph")
+}
+
+func (g *XmlSignalGraph) WriteJson() (data []byte, err error) {
+	return writeJson(g, "XmlSignalGraph")
+}
+
+func (g *XmlLibrary) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, g, "XmlLibrary")
+}
+
+func (g *XmlLibrary) WriteJson() (data []byte, err error) {
+	return writeJson(g, "XmlLibrary")
+}
+
+func (p *XmlPlatform) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, p, "XmlPlatform")
+}
+
+func (p *XmlPlatform) WriteJson() (data []byte, err error) {
+	return writeJson(p, "XmlPlatform")
+}
+
+func (m *XmlMapping) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, m, "XmlMapping")
+}
+
+func (m *XmlMapping) WriteJson() (data []byte, err error) {
+	return writeJson(m, "XmlMapping")
+}
+
+func (p *XmlProject) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, p, "XmlProject")
+}
+
+func (p *XmlProject) WriteJson() (data []byte, err error) {
+	return writeJson(p, "XmlProject")
+}
+
+func (h *XmlGraphHint) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, h, "XmlGraphHint")
+}
+
+func (h *XmlGraphHint) WriteJson() (data []byte, err error) {
+	return writeJson(h, "XmlGraphHint")
+}
+
+func (h *XmlPlatformHint) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, h, "XmlPlatformHint")
+}
+
+func (h *XmlPlatformHint) WriteJson() (data []byte, err error) {
+	return writeJson(h, "XmlPlatformHint")
+}
+
+func (h *XmlMappingHint) ReadJson(data []byte) (cnt int, err error) {
+	return readJson(data, h, "XmlMappingHint")
+}
+
+func (h *XmlMappingHint) WriteJson() (data []byte, err error) {
+	return writeJson(h, "XmlMappingHint")
+}
+
+// Read doc from filepath, see DocumentRead.
+func (pr *Preserver) DocumentReadFile(doc Document, filepath string) error {
+	data, err := tool.ReadFile(filepath)
+	if err != nil {
+		return fmt.Errorf("DocumentReadFile error: Failed to read file %s", filepath)
+	}
+	_, err = pr.DocumentRead(doc, data, filepath)
+	if err != nil {
+		return fmt.Errorf("DocumentReadFile error: %v", err)
+	}
+	return nil
+}
+
+// Write doc to filepath, XML documents get the XML header prepended.
+func DocumentWriteFile(doc Document, filepath string) error {
+	return defaultPreserver.DocumentWriteFile(doc, filepath)
+}
+
+func (pr *Preserver) DocumentWriteFile(doc Document, filepath string) error {
+	data, err := pr.DocumentFormat(doc, filepath)
+	if err != nil {
+		return err
+	}
+	return tool.WriteFile(filepath, data)
+}
+
+// The file content written by DocumentWriteFile.
+func DocumentFormat(doc Document, filepath string) (data []byte, err error) {
+	return defaultPreserver.DocumentFormat(doc, filepath)
+}
+
+func (pr *Preserver) DocumentFormat(doc Document, filepath string) (data []byte, err error) {
+	data, err = pr.DocumentWrite(doc, filepath)
+	if err != nil || IsJsonFile(filepath) {
+		return
+	}
+	data = append([]byte(xmlHeader), data...)
+	return
+}
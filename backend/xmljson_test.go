@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestJsonRoundTrip(t *testing.T) {
+	case1 := []struct {
+		doc  Document
+		copy Document
+		text string
+	}{
+		{XmlLibraryNew(), XmlLibraryNew(), `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s1" scope="local" mode="sync" c-type="int" message-id="m1"></signal-type>
+   <node-type name="Test">
+      <intype port="i" type="s1"></intype>
+      <outtype port="o" type="s1"></outtype>
+   </node-type>
+</library>`},
+		{XmlSignalGraphNew(), XmlSignalGraphNew(), `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="test.alml"></library>
+   <nodes>
+      <input port="" name="sensor" type="">
+         <outtype port="" type="s1"></outtype>
+      </input>
+      <output port="" name="actuator" type="">
+         <intype port="" type="s1"></intype>
+      </output>
+      <processing-node name="test" type="Test"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="sensor" to="test" from-port="" to-port=""></connect>
+      <connect from="test" to="actuator" from-port="" to-port=""></connect>
+   </connections>
+</signal-graph>`},
+		{XmlPlatformNew(), XmlPlatformNew(), `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p1">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="a1/p2"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t1" source="a1/p1"></input-channel>
+      </process>
+   </arch>
+</platform>`},
+		{XmlMappingNew("", ""), XmlMappingNew("", ""), `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p.spml">
+   <map-ionode name="sensor" process="a1/p1"></map-ionode>
+   <map-node name="test" process="a1/p2"></map-node>
+</mapping>`},
+		{XmlGraphHintNew(""), XmlGraphHintNew(""), `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="test" expanded="true">
+      <hint mode="normal" x="10" y="20"></hint>
+      <in-port name="i">
+         <hint mode="normal" x="1" y="2"></hint>
+      </in-port>
+   </processing-node>
+</hints>`},
+		{XmlPlatformHintNew(""), XmlPlatformHintNew(""), `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="p.spml">
+   <arch name="a1">
+      <arch-port channel="p1/out0">
+         <hint mode="normal" x="3" y="4"></hint>
+      </arch-port>
+      <process name="p1">
+         <out-channel>
+            <hint mode="normal" x="5" y="6"></hint>
+         </out-channel>
+         <hint mode="normal" x="30" y="40"></hint>
+      </process>
+      <hint mode="normal" x="10" y="20"></hint>
+   </arch>
+</hints>`},
+		{XmlMappingHintNew(""), XmlMappingHintNew(""), `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="m.mml">
+   <mapped-node name="test" expanded="false">
+      <hint mode="normal" x="7" y="8"></hint>
+   </mapped-node>
+   <arch name="a1">
+      <process name="p2">
+         <hint mode="expanded" x="50" y="60"></hint>
+      </process>
+      <hint mode="normal" x="10" y="20"></hint>
+   </arch>
+</hints>`},
+	}
+
+	for i, c := range case1 {
+		_, err := c.doc.Read([]byte(c.text))
+		if err != nil {
+			t.Errorf("Testcase %d: Failed to read XML: %v", i, err)
+			continue
+		}
+		js, err := c.doc.WriteJson()
+		if err != nil {
+			t.Errorf("Testcase %d: Failed to write JSON: %v", i, err)
+			continue
+		}
+		_, err = c.copy.ReadJson(js)
+		if err != nil {
+			t.Errorf("Testcase %d: Failed to read JSON: %v", i, err)
+			continue
+		}
+		xml, err := c.copy.Write()
+		if err != nil {
+			t.Errorf("Testcase %d: Failed to write XML: %v", i, err)
+			continue
+		}
+		if string(xml) != c.text {
+			t.Errorf("Testcase %d: XML mismatch after round trip:\n%s\n%s", i, c.text, string(xml))
+		}
+	}
+}
+
+// JSON documents are validated like XML documents.
+func TestJsonRead(t *testing.T) {
+	valid := `{"version": "1.0", "signal-type": [{"name": "s1", "scope": "local", "mode": "sync", "c-type": "int", "message-id": ""}]}`
+	invalid := `{"version": "1.0", "signal-type": [{"name": "s1", "scope": "local", "mode": "bogus", "c-type": "int", "message-id": ""}]}`
+	l := XmlLibraryNew()
+	_, err := DocumentRead(l, []byte(valid), "l.alml.json")
+	if err != nil || len(l.SignalTypes) != 1 || l.SignalTypes[0].Name != "s1" {
+		t.Errorf("Failed to read valid JSON: %v, %v", err, l)
+	}
+	_, err = DocumentRead(XmlLibraryNew(), []byte(invalid), "l.alml.json")
+	if err == nil {
+		t.Errorf("Invalid JSON accepted")
+	}
+}
@@ -7,15 +7,16 @@ import (
 )
 
 type XmlLibrary struct {
-	XMLName     xml.Name        `xml:"http://www.freesp.de/xml/freeSP library"`
-	Version     string          `xml:"version,attr"`
-	Libraries   []XmlLibraryRef `xml:"library"`
-	SignalTypes []XmlSignalType `xml:"signal-type"`
-	NodeTypes   []XmlNodeType   `xml:"node-type"`
+	XMLName     xml.Name        `xml:"http://www.freesp.de/xml/freeSP library" json:"-"`
+	Version     string          `xml:"version,attr" json:"version"`
+	Libraries   []XmlLibraryRef `xml:"library" json:"library,omitempty"`
+	SignalTypes []XmlSignalType `xml:"signal-type" json:"signal-type,omitempty"`
+	NodeTypes   []XmlNodeType   `xml:"node-type" json:"node-type,omitempty"`
+	Compatible  []XmlCompatible `xml:"compatible" json:"compatible,omitempty"`
 }
 
 func XmlLibraryNew() *XmlLibrary {
-	return &XmlLibrary{xml.Name{freespNamespace, "library"}, "1.0", nil, nil, nil}
+	return &XmlLibrary{xml.Name{freespNamespace, "library"}, FormatVersion, nil, nil, nil, nil}
 }
 
 func (g *XmlLibrary) Read(data []byte) (cnt int, err error) {
@@ -40,7 +41,7 @@ func (g *XmlLibrary) ReadFile(filepath string) error {
 	if err != nil {
 		return fmt.Errorf("XmlLibrary.ReadFile error: Failed to read file %s", filepath)
 	}
-	_, err = g.Read(data)
+	_, err = DocumentRead(g, data, filepath)
 	if err != nil {
 		return fmt.Errorf("XmlLibrary.ReadFile error: %v", err)
 	}
@@ -48,27 +49,34 @@ func (g *XmlLibrary) ReadFile(filepath string) error {
 }
 
 func (g *XmlLibrary) WriteFile(filepath string) error {
-	data, err := g.Write()
-	if err != nil {
-		return err
-	}
-	buf := make([]byte, len(data)+len(xmlHeader))
-	for i := 0; i < len(xmlHeader); i++ {
-		buf[i] = xmlHeader[i]
-	}
-	for i := 0; i < len(data); i++ {
-		buf[i+len(xmlHeader)] = data[i]
-	}
-	return tool.WriteFile(filepath, buf)
+	return DocumentWriteFile(g, filepath)
 }
 
 ///////////////////////////////////////
 
 type XmlLibraryRef struct {
-	XMLName xml.Name `xml:"library"`
-	Name    string   `xml:"ref,attr"`
+	XMLName xml.Name `xml:"library" json:"-"`
+	Name    string   `xml:"ref,attr" json:"ref"`
 }
 
 func XmlLibraryRefNew(filename string) *XmlLibraryRef {
 	return &XmlLibraryRef{xml.Name{freespNamespace, "library"}, filename}
 }
+
+///////////////////////////////////////
+
+// Compatibility relation between two signal types: an output of type
+// From may be connected to an input of type To. Kind is one of
+// "identity", "subtype" or "convertible"; convertible connections need
+// the node type Converter in between.
+type XmlCompatible struct {
+	XMLName   xml.Name `xml:"compatible" json:"-"`
+	From      string   `xml:"from,attr" json:"from"`
+	To        string   `xml:"to,attr" json:"to"`
+	Kind      string   `xml:"kind,attr" json:"kind"`
+	Converter string   `xml:"converter,attr,omitempty" json:"converter,omitempty"`
+}
+
+func XmlCompatibleNew(from, to, kind, converter string) *XmlCompatible {
+	return &XmlCompatible{xml.Name{freespNamespace, "compatible"}, from, to, kind, converter}
+}
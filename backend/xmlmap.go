@@ -6,17 +6,17 @@ import (
 )
 
 type XmlMap struct {
-	Name    string `xml:"name,attr"`
-	Process string `xml:"process,attr"`
+	Name    string `xml:"name,attr" json:"name"`
+	Process string `xml:"process,attr" json:"process"`
 }
 
 type XmlIOMap struct {
-	XMLName xml.Name `xml:"map-ionode"`
+	XMLName xml.Name `xml:"map-ionode" json:"-"`
 	XmlMap
 }
 
 type XmlNodeMap struct {
-	XMLName xml.Name `xml:"map-node"`
+	XMLName xml.Name `xml:"map-node" json:"-"`
 	XmlMap
 }
 
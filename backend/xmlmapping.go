@@ -7,11 +7,11 @@ import (
 )
 
 type XmlMapping struct {
-	XMLName     xml.Name     `xml:"http://www.freesp.de/xml/freeSP mapping"`
-	SignalGraph string       `xml:"graph,attr"`
-	Platform    string       `xml:"platform,attr"`
-	IOMappings  []XmlIOMap   `xml:"map-ionode"`
-	Mappings    []XmlNodeMap `xml:"map-node"`
+	XMLName     xml.Name     `xml:"http://www.freesp.de/xml/freeSP mapping" json:"-"`
+	SignalGraph string       `xml:"graph,attr" json:"graph"`
+	Platform    string       `xml:"platform,attr" json:"platform"`
+	IOMappings  []XmlIOMap   `xml:"map-ionode" json:"map-ionode,omitempty"`
+	Mappings    []XmlNodeMap `xml:"map-node" json:"map-node,omitempty"`
 }
 
 func XmlMappingNew(graph, platform string) *XmlMapping {
@@ -40,7 +40,7 @@ func (m *XmlMapping) ReadFile(filepath string) error {
 	if err != nil {
 		return fmt.Errorf("XmlMapping.ReadFile error: Failed to read file %s", filepath)
 	}
-	_, err = m.Read(data)
+	_, err = DocumentRead(m, data, filepath)
 	if err != nil {
 		return fmt.Errorf("XmlMapping.ReadFile error: %v", err)
 	}
@@ -48,16 +48,5 @@ func (m *XmlMapping) ReadFile(filepath string) error {
 }
 
 func (m *XmlMapping) WriteFile(filepath string) error {
-	data, err := m.Write()
-	if err != nil {
-		return err
-	}
-	buf := make([]byte, len(data)+len(xmlHeader))
-	for i := 0; i < len(xmlHeader); i++ {
-		buf[i] = xmlHeader[i]
-	}
-	for i := 0; i < len(data); i++ {
-		buf[i+len(xmlHeader)] = data[i]
-	}
-	return tool.WriteFile(filepath, buf)
+	return DocumentWriteFile(m, filepath)
 }
@@ -6,10 +6,10 @@ import (
 )
 
 type XmlMappingHint struct {
-	XMLName     xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints"`
-	Ref         string           `xml:"ref,attr"`
-	MappedNodes []XmlNodePosHint `xml:"mapped-node"`
-	Arch        []XmlArchPosHint `xml:"arch"`
+	XMLName     xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints" json:"-"`
+	Ref         string           `xml:"ref,attr" json:"ref"`
+	MappedNodes []XmlNodePosHint `xml:"mapped-node" json:"mapped-node,omitempty"`
+	Arch        []XmlArchPosHint `xml:"arch" json:"arch,omitempty"`
 }
 
 func XmlMappingHintNew(ref string) *XmlMappingHint {
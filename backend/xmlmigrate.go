@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Format versions of freeSP documents. Each document kind (identified by
+// its root element) carries a version attribute; a missing attribute
+// means the initial version. Documents of older versions are upgraded
+// on read by applying the registered migration steps in order.
+
+// Version written by this program.
+const FormatVersion = "1.0"
+
+const initialFormatVersion = "1.0"
+
+type MigrationStep struct {
+	Root     string // root element of the document kind
+	From, To string
+	Comment  string
+	Apply    func(root *XmlElement) error
+}
+
+type MigrationResult struct {
+	From, To string
+	Steps    []string // comments of all applied steps
+	Newer    bool     // document is newer than FormatVersion
+}
+
+func (r MigrationResult) Migrated() bool {
+	return len(r.Steps) > 0
+}
+
+var migrationSteps []MigrationStep
+
+// RegisterMigration adds a step to the registry. Steps may be registered
+// in any order, there must not be two steps for the same document kind
+// starting at the same version.
+func RegisterMigration(s MigrationStep) {
+	for _, m := range migrationSteps {
+		if m.Root == s.Root && m.From == s.From {
+			log.Panicf("RegisterMigration error: duplicate step %s %s -> %s\n", s.Root, s.From, s.To)
+		}
+	}
+	if CompareVersions(s.From, s.To) >= 0 {
+		log.Panicf("RegisterMigration error: step %s %s -> %s does not upgrade\n", s.Root, s.From, s.To)
+	}
+	migrationSteps = append(migrationSteps, s)
+}
+
+func migrationStep(root, from string) (s MigrationStep, ok bool) {
+	for _, s = range migrationSteps {
+		if s.Root == root && s.From == from {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+// CompareVersions compares dotted version numbers numerically and
+// returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+func DocumentVersion(root *XmlElement) string {
+	v, ok := root.AttrValue("version")
+	if !ok || len(v) == 0 {
+		return initialFormatVersion
+	}
+	return v
+}
+
+// Migrate upgrades an XML encoded document to FormatVersion. If nothing
+// needs to be done, data is returned unchanged.
+func Migrate(data []byte) (out []byte, result MigrationResult, err error) {
+	out = data
+	prolog, root, err := XmlTreeRead(data)
+	if err != nil {
+		err = fmt.Errorf("Migrate error: %v", err)
+		return
+	}
+	version := DocumentVersion(root)
+	result.From, result.To = version, version
+	switch CompareVersions(version, FormatVersion) {
+	case 0:
+		return
+	case 1:
+		result.Newer = true
+		return
+	}
+	for CompareVersions(version, FormatVersion) < 0 {
+		s, ok := migrationStep(root.Name.Local, version)
+		if !ok {
+			err = fmt.Errorf("Migrate error: no migration of <%s> from version %s", root.Name.Local, version)
+			return
+		}
+		err = s.Apply(root)
+		if err != nil {
+			err = fmt.Errorf("Migrate error: %s -> %s: %v", s.From, s.To, err)
+			return
+		}
+		version = s.To
+		root.SetAttr("version", version)
+		result.Steps = append(result.Steps, s.Comment)
+	}
+	result.To = version
+	out = XmlTreeWrite(prolog, root)
+	return
+}
+
+// Migrate data read from filepath, logging what was done.
+func migrateDocument(data []byte, filepath string) (out []byte, err error) {
+	var result MigrationResult
+	out, result, err = Migrate(data)
+	if err != nil && len(result.From) == 0 {
+		// syntax errors are reported by the validator
+		return data, nil
+	}
+	if err != nil {
+		return
+	}
+	if result.Newer {
+		log.Printf("warning: %s: document version %s is newer than supported version %s\n",
+			filepath, result.From, FormatVersion)
+	} else if result.Migrated() {
+		log.Printf("%s: migrated from version %s to %s\n", filepath, result.From, result.To)
+		for _, s := range result.Steps {
+			log.Printf("   %s\n", s)
+		}
+	}
+	return
+}
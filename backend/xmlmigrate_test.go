@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	// the steps below are fakes, keep them out of other tests
+	saved := migrationSteps
+	defer func() { migrationSteps = saved }()
+	migrationSteps = append([]MigrationStep(nil), saved...)
+	RegisterMigration(MigrationStep{"signal-graph", "0.8", "0.9", "rename src to from",
+		func(root *XmlElement) error {
+			for _, c := range root.Elements("connections") {
+				for _, e := range c.Elements("connect") {
+					e.RenameAttr("src", "from")
+				}
+			}
+			return nil
+		}})
+	RegisterMigration(MigrationStep{"signal-graph", "0.9", FormatVersion, "rename dst to to",
+		func(root *XmlElement) error {
+			root.Walk(func(e *XmlElement) {
+				if e.Name.Local == "connect" {
+					e.RenameAttr("dst", "to")
+				}
+			})
+			return nil
+		}})
+
+	case1 := []struct {
+		text, result    string
+		from, to        string
+		steps           int
+		newer, hasError bool
+	}{
+		{`<signal-graph version="0.8"><!-- c --><connections><connect src="a" dst="b"/></connections></signal-graph>`,
+			`<signal-graph version="1.0"><!-- c --><connections><connect from="a" to="b"></connect></connections></signal-graph>`,
+			"0.8", "1.0", 2, false, false},
+		{`<signal-graph version="1.0"/>`, `<signal-graph version="1.0"/>`, "1.0", "1.0", 0, false, false},
+		{`<signal-graph version="1.1"/>`, `<signal-graph version="1.1"/>`, "1.1", "1.1", 0, true, false},
+		{`<platform version="0.9"/>`, ``, "0.9", "0.9", 0, false, true},
+	}
+	for i, c := range case1 {
+		out, result, err := Migrate([]byte(c.text))
+		if (err != nil) != c.hasError {
+			t.Errorf("Testcase %d: unexpected error status: %v", i, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if string(out) != c.result {
+			t.Errorf("Testcase %d: result mismatch:\n%s\n%s", i, out, c.result)
+		}
+		if result.From != c.from || result.To != c.to || len(result.Steps) != c.steps || result.Newer != c.newer {
+			t.Errorf("Testcase %d: result mismatch: %v", i, result)
+		}
+	}
+}
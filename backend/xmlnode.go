@@ -6,26 +6,27 @@ import (
 )
 
 type XmlNode struct {
-	NName   string       `xml:"name,attr"`
-	NType   string       `xml:"type,attr"`
-	InPort  []XmlInPort  `xml:"intype"`
-	OutPort []XmlOutPort `xml:"outtype"`
+	NName   string          `xml:"name,attr" json:"name"`
+	NType   string          `xml:"type,attr" json:"type"`
+	InPort  []XmlInPort     `xml:"intype" json:"intype,omitempty"`
+	OutPort []XmlOutPort    `xml:"outtype" json:"outtype,omitempty"`
+	Param   []XmlParamValue `xml:"param" json:"param,omitempty"`
 }
 
 type XmlInputNode struct {
-	XMLName xml.Name `xml:"input"`
-	NPort   string   `xml:"port,attr"`
+	XMLName xml.Name `xml:"input" json:"-"`
+	NPort   string   `xml:"port,attr" json:"port"`
 	XmlNode
 }
 
 type XmlOutputNode struct {
-	XMLName xml.Name `xml:"output"`
-	NPort   string   `xml:"port,attr"`
+	XMLName xml.Name `xml:"output" json:"-"`
+	NPort   string   `xml:"port,attr" json:"port"`
 	XmlNode
 }
 
 type XmlProcessingNode struct {
-	XMLName xml.Name `xml:"processing-node"`
+	XMLName xml.Name `xml:"processing-node" json:"-"`
 	XmlNode
 }
 
@@ -47,7 +48,7 @@ func (n *XmlInputNode) Write() (data []byte, err error) {
 }
 
 func XmlInputNodeNew(nName, nType string) *XmlInputNode {
-	return &XmlInputNode{xml.Name{freespNamespace, "input"}, "", XmlNode{nName, nType, nil, nil}}
+	return &XmlInputNode{xml.Name{freespNamespace, "input"}, "", XmlNode{nName, nType, nil, nil, nil}}
 }
 
 func (n *XmlOutputNode) Write() (data []byte, err error) {
@@ -59,7 +60,7 @@ func (n *XmlOutputNode) Write() (data []byte, err error) {
 }
 
 func XmlOutputNodeNew(nName, nType string) *XmlOutputNode {
-	return &XmlOutputNode{xml.Name{freespNamespace, "output"}, "", XmlNode{nName, nType, nil, nil}}
+	return &XmlOutputNode{xml.Name{freespNamespace, "output"}, "", XmlNode{nName, nType, nil, nil, nil}}
 }
 
 func (n *XmlProcessingNode) Write() (data []byte, err error) {
@@ -71,6 +72,6 @@ func (n *XmlProcessingNode) Write() (data []byte, err error) {
 }
 
 func XmlProcessingNodeNew(nName, nType string) (xmln *XmlProcessingNode) {
-	xmln = &XmlProcessingNode{xml.Name{freespNamespace, "processing-node"}, XmlNode{nName, nType, nil, nil}}
+	xmln = &XmlProcessingNode{xml.Name{freespNamespace, "processing-node"}, XmlNode{nName, nType, nil, nil, nil}}
 	return
 }
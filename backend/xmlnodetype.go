@@ -6,15 +6,16 @@ import (
 )
 
 type XmlNodeType struct {
-	XMLName        xml.Name            `xml:"node-type"`
-	TypeName       string              `xml:"name,attr"`
-	InPort         []XmlInPort         `xml:"intype"`
-	OutPort        []XmlOutPort        `xml:"outtype"`
-	Implementation []XmlImplementation `xml:"implementation"`
+	XMLName        xml.Name            `xml:"node-type" json:"-"`
+	TypeName       string              `xml:"name,attr" json:"name"`
+	InPort         []XmlInPort         `xml:"intype" json:"intype,omitempty"`
+	OutPort        []XmlOutPort        `xml:"outtype" json:"outtype,omitempty"`
+	Parameter      []XmlParameter      `xml:"parameter" json:"parameter,omitempty"`
+	Implementation []XmlImplementation `xml:"implementation" json:"implementation,omitempty"`
 }
 
 func XmlNodeTypeNew(name string) *XmlNodeType {
-	return &XmlNodeType{xml.Name{freespNamespace, "node-type"}, name, nil, nil, nil}
+	return &XmlNodeType{xml.Name{freespNamespace, "node-type"}, name, nil, nil, nil, nil}
 }
 
 func (n *XmlNodeType) Read(data []byte) (cnt int, err error) {
@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"encoding/xml"
+)
+
+// Parameter declaration of a node type. Type is one of int, float,
+// string or enum; Values lists the comma separated enum values. Min and
+// Max are optional range limits of numeric parameters.
+type XmlParameter struct {
+	XMLName xml.Name `xml:"parameter" json:"-"`
+	Name    string   `xml:"name,attr" json:"name"`
+	PType   string   `xml:"type,attr" json:"type"`
+	Default string   `xml:"default,attr,omitempty" json:"default,omitempty"`
+	Min     string   `xml:"min,attr,omitempty" json:"min,omitempty"`
+	Max     string   `xml:"max,attr,omitempty" json:"max,omitempty"`
+	Values  string   `xml:"values,attr,omitempty" json:"values,omitempty"`
+}
+
+func XmlParameterNew(name, pType string) *XmlParameter {
+	return &XmlParameter{xml.Name{freespNamespace, "parameter"}, name, pType, "", "", "", ""}
+}
+
+// Parameter value of a node instance. Within graph implementations the
+// value may be an expression referring to parameters of the enclosing
+// node type.
+type XmlParamValue struct {
+	XMLName xml.Name `xml:"param" json:"-"`
+	Name    string   `xml:"name,attr" json:"name"`
+	Value   string   `xml:"value,attr" json:"value"`
+}
+
+func XmlParamValueNew(name, value string) *XmlParamValue {
+	return &XmlParamValue{xml.Name{freespNamespace, "param"}, name, value}
+}
@@ -7,15 +7,15 @@ import (
 )
 
 type XmlPlatform struct {
-	XMLName    xml.Name  `xml:"http://www.freesp.de/xml/freeSP platform"`
-	Version    string    `xml:"version,attr"`
-	PlatformId string    `xml:"platform-id,attr"`
-	Arch       []XmlArch `xml:"arch"`
+	XMLName    xml.Name  `xml:"http://www.freesp.de/xml/freeSP platform" json:"-"`
+	Version    string    `xml:"version,attr" json:"version"`
+	PlatformId string    `xml:"platform-id,attr" json:"platform-id"`
+	Arch       []XmlArch `xml:"arch" json:"arch,omitempty"`
 	//Shape      XmlShape  `xml:"hint"`
 }
 
 func XmlPlatformNew() *XmlPlatform {
-	return &XmlPlatform{xml.Name{freespNamespace, "platform"}, "1.0", "", nil}
+	return &XmlPlatform{xml.Name{freespNamespace, "platform"}, FormatVersion, "", nil}
 }
 
 func (p *XmlPlatform) ReadFile(filepath string) (err error) {
@@ -25,28 +25,15 @@ func (p *XmlPlatform) ReadFile(filepath string) (err error) {
 		err = fmt.Errorf("XmlPlatform.ReadFile error: Failed to read file %s", filepath)
 		return
 	}
-	_, err = p.Read(data)
+	_, err = DocumentRead(p, data, filepath)
 	if err != nil {
 		err = fmt.Errorf("XmlPlatform.ReadFile error: %v", err)
 	}
 	return
 }
 
-func (p *XmlPlatform) WriteFile(filepath string) (err error) {
-	var data []byte
-	data, err = p.Write()
-	if err != nil {
-		return
-	}
-	buf := make([]byte, len(data)+len(xmlHeader))
-	for i := 0; i < len(xmlHeader); i++ {
-		buf[i] = xmlHeader[i]
-	}
-	for i := 0; i < len(data); i++ {
-		buf[i+len(xmlHeader)] = data[i]
-	}
-	err = tool.WriteFile(filepath, buf)
-	return
+func (p *XmlPlatform) WriteFile(filepath string) error {
+	return DocumentWriteFile(p, filepath)
 }
 
 func (p *XmlPlatform) Read(data []byte) (cnt int, err error) {
@@ -6,9 +6,9 @@ import (
 )
 
 type XmlPlatformHint struct {
-	XMLName xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints"`
-	Ref     string           `xml:"ref,attr"`
-	Arch    []XmlArchPosHint `xml:"arch"`
+	XMLName xml.Name         `xml:"http://www.freesp.de/xml/freeSP hints" json:"-"`
+	Ref     string           `xml:"ref,attr" json:"ref"`
+	Arch    []XmlArchPosHint `xml:"arch" json:"arch,omitempty"`
 }
 
 func XmlPlatformHintNew(ref string) *XmlPlatformHint {
@@ -33,9 +33,9 @@ func (h *XmlPlatformHint) Write() (data []byte, err error) {
 }
 
 type XmlArchPosHint struct {
-	Name      string               `xml:"name,attr"`
-	ArchPorts []XmlArchPortPosHint `xml:"arch-port"`
-	Processes []XmlProcessPosHint  `xml:"process"`
+	Name      string               `xml:"name,attr" json:"name"`
+	ArchPorts []XmlArchPortPosHint `xml:"arch-port" json:"arch-port,omitempty"`
+	Processes []XmlProcessPosHint  `xml:"process" json:"process,omitempty"`
 	XmlModeHint
 }
 
@@ -43,18 +43,21 @@ func XmlArchPosHintNew(name string) *XmlArchPosHint {
 	return &XmlArchPosHint{name, nil, nil, XmlModeHint{}}
 }
 
+// Channel identifies the channel of the arch port, older hint files
+// have none and are applied by position.
 type XmlArchPortPosHint struct {
+	Channel string `xml:"channel,attr,omitempty" json:"channel,omitempty"`
 	XmlModeHint
 }
 
-func XmlArchPortPosHintNew() *XmlArchPortPosHint {
-	return &XmlArchPortPosHint{XmlModeHint{}}
+func XmlArchPortPosHintNew(channel string) *XmlArchPortPosHint {
+	return &XmlArchPortPosHint{channel, XmlModeHint{}}
 }
 
 type XmlProcessPosHint struct {
-	Name        string              `xml:"name,attr"`
-	InChannels  []XmlChannelPosHint `xml:"in-channel"`
-	OutChannels []XmlChannelPosHint `xml:"out-channel"`
+	Name        string              `xml:"name,attr" json:"name"`
+	InChannels  []XmlChannelPosHint `xml:"in-channel" json:"in-channel,omitempty"`
+	OutChannels []XmlChannelPosHint `xml:"out-channel" json:"out-channel,omitempty"`
 	XmlModeHint
 }
 
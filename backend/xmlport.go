@@ -6,13 +6,13 @@ import (
 )
 
 type XmlPort struct {
-	PName string `xml:"port,attr"`
-	PType string `xml:"type,attr"`
+	PName string `xml:"port,attr" json:"port"`
+	PType string `xml:"type,attr" json:"type"`
 	XmlModeHint
 }
 
 type XmlInPort struct {
-	XMLName xml.Name `xml:"intype"`
+	XMLName xml.Name `xml:"intype" json:"-"`
 	XmlPort
 }
 
@@ -23,7 +23,7 @@ func XmlInPortNew(pName, pType string) (xmlp *XmlInPort) {
 }
 
 type XmlOutPort struct {
-	XMLName xml.Name `xml:"outtype"`
+	XMLName xml.Name `xml:"outtype" json:"-"`
 	XmlPort
 }
 
@@ -0,0 +1,293 @@
+package backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Content of XML documents that is not modelled by the Xml* structs
+// (attributes and elements unknown to the schema, elements of other
+// namespaces, comments) is preserved: the tree of every document read
+// is remembered by file name, and when the document is written again,
+// the unknown content of each element is merged into the new tree at
+// its original position, i.e. after the same known sibling.
+
+type xmlPreservedDoc struct {
+	prolog []interface{}
+	root   *XmlElement
+	spec   *xmlElementSpec
+}
+
+// Preserver keeps the trees of the documents read through it. The
+// editor uses the default preserver (see DocumentRead), each headless
+// context one of its own, such that reading other versions of a
+// document (e.g. to compare or merge them) does not change what the
+// editor writes. A nil *Preserver keeps nothing.
+type Preserver struct {
+	mutex sync.Mutex
+	docs  map[string]*xmlPreservedDoc
+}
+
+func PreserverNew() *Preserver {
+	return &Preserver{docs: make(map[string]*xmlPreservedDoc)}
+}
+
+var defaultPreserver = PreserverNew()
+
+// DefaultPreserver returns the preserver of the package level
+// functions DocumentRead, DocumentWrite etc.
+func DefaultPreserver() *Preserver {
+	return defaultPreserver
+}
+
+func preserveKey(path string) string {
+	return filepath.Clean(path)
+}
+
+func (pr *Preserver) preserveDocument(doc Document, data []byte, path string) {
+	if pr == nil {
+		return
+	}
+	prolog, root, err := XmlTreeRead(data)
+	if err != nil {
+		return
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.docs[preserveKey(path)] = &xmlPreservedDoc{prolog, root, doc.schema().spec}
+}
+
+// Preserved tells if the document read from path has unknown content
+// kept, which other encodings than XML cannot carry.
+func (pr *Preserver) Preserved(path string) bool {
+	if pr == nil {
+		return false
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	p, ok := pr.docs[preserveKey(path)]
+	if !ok {
+		return false
+	}
+	for _, t := range p.prolog {
+		if _, ok := t.(xml.ProcInst); !ok && !isWhitespace(t) {
+			return true
+		}
+	}
+	return hasUnknown(p.root, p.spec)
+}
+
+func hasUnknown(e *XmlElement, spec *xmlElementSpec) bool {
+	for _, a := range e.Attr {
+		if !isKnownAttr(a, spec) {
+			return true
+		}
+	}
+	for _, c := range e.Children {
+		switch c.(type) {
+		case *XmlElement:
+			cs, known := isKnownElement(c.(*XmlElement), spec)
+			if !known || hasUnknown(c.(*XmlElement), cs) {
+				return true
+			}
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			return true
+		}
+	}
+	return false
+}
+
+// DocumentRenamed tells the backend that the document formerly stored
+// as oldpath is now stored as newpath, such that its unknown content is
+// written to the new file.
+func DocumentRenamed(oldpath, newpath string) {
+	defaultPreserver.DocumentRenamed(oldpath, newpath)
+}
+
+func (pr *Preserver) DocumentRenamed(oldpath, newpath string) {
+	if pr == nil {
+		return
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	p, ok := pr.docs[preserveKey(oldpath)]
+	if !ok {
+		return
+	}
+	delete(pr.docs, preserveKey(oldpath))
+	pr.docs[preserveKey(newpath)] = p
+}
+
+// Merge the unknown content of the document formerly read from path
+// into data.
+func (pr *Preserver) restoreDocument(data []byte, path string) (out []byte, err error) {
+	out = data
+	if pr == nil {
+		return
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	p, ok := pr.docs[preserveKey(path)]
+	if !ok {
+		return
+	}
+	_, root, err := XmlTreeRead(data)
+	if err != nil {
+		err = fmt.Errorf("restoreDocument error: %v", err)
+		return
+	}
+	restoreElement(p.root, root, p.spec, "\n")
+	var prolog []interface{}
+	for _, t := range p.prolog {
+		if _, ok := t.(xml.ProcInst); !ok {
+			prolog = append(prolog, t)
+		}
+	}
+	out = XmlTreeWrite(prolog, root)
+	pr.docs[preserveKey(path)] = &xmlPreservedDoc{p.prolog, root, p.spec}
+	return
+}
+
+func isKnownElement(e *XmlElement, spec *xmlElementSpec) (child *xmlElementSpec, ok bool) {
+	if len(e.Name.Space) > 0 {
+		return
+	}
+	child, ok = spec.children[e.Name.Local]
+	return
+}
+
+func isKnownAttr(a xml.Attr, spec *xmlElementSpec) bool {
+	if len(a.Name.Space) > 0 {
+		return false
+	}
+	if a.Name.Local == "xmlns" {
+		return true
+	}
+	_, ok := spec.attr(a.Name.Local)
+	return ok
+}
+
+// Identity of a known element among its siblings: its name attribute,
+// or else all string valued attributes of the schema. Integer and boolean
+// attributes (positions, flags) may change without changing identity.
+func elementKey(e *XmlElement, spec *xmlElementSpec) string {
+	if name, ok := e.AttrValue("name"); ok {
+		return e.Name.Local + "|" + name
+	}
+	key := []string{e.Name.Local}
+	for _, a := range spec.attrs {
+		if a.kind == attrInt || a.kind == attrBool {
+			continue
+		}
+		v, _ := e.AttrValue(a.name)
+		key = append(key, v)
+	}
+	return strings.Join(key, "|")
+}
+
+// Pair the known children of old and new element by key and order.
+func matchChildren(old, new *XmlElement, spec *xmlElementSpec) map[*XmlElement]*XmlElement {
+	newByKey := make(map[string][]*XmlElement)
+	for _, c := range new.Children {
+		if ce, ok := c.(*XmlElement); ok {
+			if cs, ok := isKnownElement(ce, spec); ok {
+				key := elementKey(ce, cs)
+				newByKey[key] = append(newByKey[key], ce)
+			}
+		}
+	}
+	match := make(map[*XmlElement]*XmlElement)
+	for _, c := range old.Children {
+		if ce, ok := c.(*XmlElement); ok {
+			if cs, ok := isKnownElement(ce, spec); ok {
+				key := elementKey(ce, cs)
+				if len(newByKey[key]) > 0 {
+					match[ce] = newByKey[key][0]
+					newByKey[key] = newByKey[key][1:]
+				}
+			}
+		}
+	}
+	return match
+}
+
+func isWhitespace(t interface{}) bool {
+	cd, ok := t.(xml.CharData)
+	return ok && len(strings.TrimSpace(string(cd))) == 0
+}
+
+// Indentation (including the line break) of the children of an element
+// indented by indent.
+func childIndent(e *XmlElement, indent string) string {
+	for i, c := range e.Children {
+		if _, ok := c.(*XmlElement); ok && i > 0 && isWhitespace(e.Children[i-1]) {
+			return string(e.Children[i-1].(xml.CharData))
+		}
+	}
+	return indent + "   "
+}
+
+func restoreElement(old, new *XmlElement, spec *xmlElementSpec, indent string) {
+	for _, a := range old.Attr {
+		if isKnownAttr(a, spec) {
+			continue
+		}
+		if _, ok := new.AttrValue(a.Name.Local); ok && len(a.Name.Space) == 0 {
+			continue
+		}
+		new.Attr = append(new.Attr, a)
+	}
+	match := matchChildren(old, new, spec)
+	cindent := childIndent(new, indent)
+	var anchor *XmlElement // last matched known sibling
+	var pending []interface{}
+	insert := func() {
+		if len(pending) == 0 {
+			return
+		}
+		var items []interface{}
+		for _, t := range pending {
+			items = append(items, xml.CharData(cindent), t)
+		}
+		pos := 0
+		if anchor != nil {
+			for i, c := range new.Children {
+				if c == anchor {
+					pos = i + 1
+					break
+				}
+			}
+		}
+		if len(new.Children) == 0 {
+			items = append(items, xml.CharData(indent))
+		}
+		tail := append(items, new.Children[pos:]...)
+		new.Children = append(new.Children[:pos:pos], tail...)
+		pending = nil
+	}
+	for _, c := range old.Children {
+		switch c.(type) {
+		case *XmlElement:
+			ce := c.(*XmlElement)
+			cs, known := isKnownElement(ce, spec)
+			if !known {
+				pending = append(pending, ce)
+				continue
+			}
+			nc, ok := match[ce]
+			if !ok {
+				// element was removed, its unknown content is lost
+				continue
+			}
+			insert()
+			anchor = nc
+			restoreElement(ce, nc, cs, cindent)
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			pending = append(pending, c)
+		}
+	}
+	insert()
+}
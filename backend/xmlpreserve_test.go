@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreserve(t *testing.T) {
+	text := `<!-- generated by tool x -->
+<signal-graph xmlns="http://www.freesp.de/xml/freeSP" xmlns:x="http://example.com/x" version="1.0" x:id="42">
+   <nodes>
+      <!-- sensors -->
+      <input name="sensor" x:rate="100">
+         <outtype type="s1"></outtype>
+      </input>
+      <x:note>keep me</x:note>
+      <processing-node name="test" type="Test" priority="3"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="sensor" to="test"><x:delay>2</x:delay></connect>
+   </connections>
+</signal-graph>`
+	expect := `<!-- generated by tool x -->
+<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0" xmlns:x="http://example.com/x" x:id="42">
+   <nodes>
+      <!-- sensors -->
+      <input port="" name="sensor" type="" x:rate="100">
+         <outtype port="" type="s1"></outtype>
+      </input>
+      <x:note>keep me</x:note>
+      <processing-node name="test" type="Test" priority="3"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="sensor" to="test" from-port="" to-port="">
+         <x:delay>2</x:delay>
+      </connect>
+   </connections>
+</signal-graph>`
+	g := XmlSignalGraphNew()
+	_, err := DocumentRead(g, []byte(text), "p.sml")
+	if err != nil {
+		t.Errorf("Failed to read: %v", err)
+		return
+	}
+	data, err := DocumentWrite(g, "p.sml")
+	if err != nil {
+		t.Errorf("Failed to write: %v", err)
+		return
+	}
+	if string(data) != expect {
+		t.Errorf("Round trip mismatch:\n%s\n%s", data, expect)
+	}
+	if !DefaultPreserver().Preserved("p.sml") {
+		t.Errorf("Unknown content of p.sml not reported\n")
+	}
+
+	// other preservers neither see nor change the content kept above
+	plain := `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes></nodes>
+   <connections></connections>
+</signal-graph>`
+	var keepNothing *Preserver
+	for _, pr := range []*Preserver{PreserverNew(), keepNothing} {
+		g = XmlSignalGraphNew()
+		_, err = pr.DocumentRead(g, []byte(plain), "p.sml")
+		if err != nil {
+			t.Errorf("Failed to read: %v", err)
+			return
+		}
+		data, err = pr.DocumentWrite(g, "p.sml")
+		if err != nil || string(data) != plain {
+			t.Errorf("Other preserver: wrong result %v:\n%s", err, data)
+		}
+		if pr.Preserved("p.sml") {
+			t.Errorf("Other preserver: unknown content reported for plain document\n")
+		}
+	}
+	data, _ = DocumentWrite(XmlSignalGraphNew(), "p.sml")
+	if !strings.Contains(string(data), "keep me") {
+		t.Errorf("Default preserver lost content:\n%s", data)
+	}
+}
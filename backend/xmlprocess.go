@@ -6,10 +6,10 @@ import (
 )
 
 type XmlProcess struct {
-	XMLName        xml.Name        `xml:"process"`
-	Name           string          `xml:"name,attr"`
-	InputChannels  []XmlInChannel  `xml:"input-channel"`
-	OutputChannels []XmlOutChannel `xml:"output-channel"`
+	XMLName        xml.Name        `xml:"process" json:"-"`
+	Name           string          `xml:"name,attr" json:"name"`
+	InputChannels  []XmlInChannel  `xml:"input-channel" json:"input-channel,omitempty"`
+	OutputChannels []XmlOutChannel `xml:"output-channel" json:"output-channel,omitempty"`
 }
 
 func XmlProcessNew(name string) *XmlProcess {
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/axel-freesp/sge/tool"
+)
+
+// A project lists the documents that belong together, the directories
+// to search for referenced files and the directories where new files
+// of each kind are stored. Relative paths are relative to the directory
+// of the project file.
+
+const ProjectSuffix = "sgeproj"
+
+type XmlProject struct {
+	XMLName     xml.Name           `xml:"http://www.freesp.de/xml/freeSP project" json:"-"`
+	Version     string             `xml:"version,attr" json:"version"`
+	Name        string             `xml:"name,attr" json:"name"`
+	Canonical   bool               `xml:"canonical,attr,omitempty" json:"canonical,omitempty"`
+	SearchPaths []XmlSearchPath    `xml:"search-path" json:"search-path,omitempty"`
+	OutputDirs  []XmlOutputDir     `xml:"output-dir" json:"output-dir,omitempty"`
+	Graphs      []XmlProjectMember `xml:"graph" json:"graph,omitempty"`
+	Libraries   []XmlProjectMember `xml:"library" json:"library,omitempty"`
+	Platforms   []XmlProjectMember `xml:"platform" json:"platform,omitempty"`
+	Mappings    []XmlProjectMember `xml:"mapping" json:"mapping,omitempty"`
+	Lint        []XmlLintRule      `xml:"lint" json:"lint,omitempty"`
+}
+
+func XmlProjectNew(name string) *XmlProject {
+	return &XmlProject{xml.Name{freespNamespace, "project"}, FormatVersion, name, false, nil, nil, nil, nil, nil, nil, nil}
+}
+
+func (p *XmlProject) Read(data []byte) (cnt int, err error) {
+	err = xml.Unmarshal(data, p)
+	if err != nil {
+		err = fmt.Errorf("XmlProject.Read error: %v", err)
+	}
+	cnt = len(data)
+	return
+}
+
+func (p *XmlProject) Write() (data []byte, err error) {
+	data, err = xml.MarshalIndent(p, "", "   ")
+	if err != nil {
+		err = fmt.Errorf("XmlProject.Write error: %v", err)
+	}
+	return
+}
+
+func (p *XmlProject) ReadFile(filepath string) error {
+	data, err := tool.ReadFile(filepath)
+	if err != nil {
+		return fmt.Errorf("XmlProject.ReadFile error: Failed to read file %s", filepath)
+	}
+	_, err = DocumentRead(p, data, filepath)
+	if err != nil {
+		return fmt.Errorf("XmlProject.ReadFile error: %v", err)
+	}
+	return err
+}
+
+func (p *XmlProject) WriteFile(filepath string) error {
+	return DocumentWriteFile(p, filepath)
+}
+
+// Path of the output directory for files with the given suffix,
+// empty if the project does not specify one.
+func (p *XmlProject) OutputDir(suffix string) string {
+	for _, d := range p.OutputDirs {
+		if d.Kind == suffix {
+			return d.Path
+		}
+	}
+	return ""
+}
+
+///////////////////////////////////////
+
+type XmlSearchPath struct {
+	XMLName xml.Name `xml:"search-path" json:"-"`
+	Path    string   `xml:"path,attr" json:"path"`
+}
+
+func XmlSearchPathNew(path string) *XmlSearchPath {
+	return &XmlSearchPath{xml.Name{freespNamespace, "search-path"}, path}
+}
+
+///////////////////////////////////////
+
+type XmlOutputDir struct {
+	XMLName xml.Name `xml:"output-dir" json:"-"`
+	Kind    string   `xml:"kind,attr" json:"kind"`
+	Path    string   `xml:"path,attr" json:"path"`
+}
+
+func XmlOutputDirNew(kind, path string) *XmlOutputDir {
+	return &XmlOutputDir{xml.Name{freespNamespace, "output-dir"}, kind, path}
+}
+
+///////////////////////////////////////
+
+type XmlProjectMember struct {
+	Ref string `xml:"ref,attr" json:"ref"`
+}
+
+func XmlProjectMemberNew(ref string) *XmlProjectMember {
+	return &XmlProjectMember{ref}
+}
+
+///////////////////////////////////////
+
+// Setting of a lint rule: level is "off", "warning" or "error".
+type XmlLintRule struct {
+	XMLName xml.Name `xml:"lint" json:"-"`
+	Rule    string   `xml:"rule,attr" json:"rule"`
+	Level   string   `xml:"level,attr" json:"level"`
+}
+
+func XmlLintRuleNew(rule, level string) *XmlLintRule {
+	return &XmlLintRule{xml.Name{freespNamespace, "lint"}, rule, level}
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File references (e.g. library refs) are resolved as follows:
+//  - absolute paths are taken as they are,
+//  - explicit relative paths ("./x.alml", "../lib/x.alml") are relative
+//    to the directory of the referencing document only,
+//  - other names are looked up relative to the directory of the
+//    referencing document first, then along the search path.
+
+type RefResolution struct {
+	Ref        string
+	Dir        string   // directory of the referencing document
+	Path       string   // file which satisfies the reference
+	Base       string   // directory Path was found in, Path = Base/Ref
+	Candidates []string // all existing files the reference may denote
+}
+
+func (r RefResolution) Ambiguous() bool {
+	return len(r.Candidates) > 1
+}
+
+func (r RefResolution) String() string {
+	if len(r.Dir) > 0 {
+		return fmt.Sprintf("reference %s (from %s) resolved to %s", r.Ref, r.Dir, r.Path)
+	}
+	return fmt.Sprintf("reference %s resolved to %s", r.Ref, r.Path)
+}
+
+// AbsPath is the absolute path of the file the reference denotes, by
+// which loaded documents are identified.
+func (r RefResolution) AbsPath() string {
+	return AbsPath(r.Path)
+}
+
+// AbsPath returns the cleaned absolute form of path (path itself if
+// the working directory is unknown).
+func AbsPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// Warning text for an ambiguous reference.
+func (r RefResolution) Warning() string {
+	return fmt.Sprintf("warning: reference %s is ambiguous: using %s, also found %s",
+		r.Ref, r.Path, strings.Join(r.Candidates[1:], ", "))
+}
+
+func isExplicitRelative(ref string) bool {
+	return ref == "." || ref == ".." || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../")
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// ResolveRef finds the file denoted by ref within a document stored
+// in directory dir. An empty dir means the search path only.
+func ResolveRef(ref, dir string) (r RefResolution, err error) {
+	r.Ref, r.Dir = ref, dir
+	var bases, try []string
+	switch {
+	case filepath.IsAbs(ref):
+		bases = append(bases, "")
+	case isExplicitRelative(ref):
+		bases = append(bases, dir)
+	default:
+		if len(dir) > 0 {
+			bases = append(bases, dir)
+		}
+		bases = append(bases, XmlSearchPaths()...)
+	}
+	for _, base := range bases {
+		path := filepath.Join(base, ref)
+		try = append(try, path)
+		if !fileExists(path) {
+			continue
+		}
+		if len(r.Candidates) == 0 {
+			r.Path, r.Base = path, base
+		}
+		r.Candidates = appendPath(r.Candidates, path)
+	}
+	if len(r.Candidates) == 0 {
+		err = fmt.Errorf("ResolveRef error: %s not found (tried %s)", ref, strings.Join(try, ", "))
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveRef(t *testing.T) {
+	root, err := ioutil.TempDir("", "resolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, f := range []string{"graphs/g.alml", "lib/x.alml", "sp1/x.alml", "sp2/x.alml", "sp2/y.alml"} {
+		path := filepath.Join(root, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, nil, 0644)
+	}
+	saved := xmlSearchPaths
+	defer func() { xmlSearchPaths = saved }()
+	xmlSearchPaths = []string{filepath.Join(root, "sp1"), filepath.Join(root, "sp2")}
+
+	graphs := filepath.Join(root, "graphs")
+	case1 := []struct {
+		ref, dir, path string
+		candidates     int
+	}{
+		{"x.alml", graphs, "sp1/x.alml", 2},
+		{"y.alml", graphs, "sp2/y.alml", 1},
+		{"g.alml", graphs, "graphs/g.alml", 1},
+		{"../lib/x.alml", graphs, "lib/x.alml", 1},
+		{filepath.Join(root, "lib/x.alml"), graphs, "lib/x.alml", 1},
+		{"./x.alml", graphs, "", 0},
+		{"g.alml", "", "", 0},
+	}
+	for i, c := range case1 {
+		r, err := ResolveRef(c.ref, c.dir)
+		if len(c.path) == 0 {
+			if err == nil {
+				t.Errorf("Testcase %d: error expected, got %s", i, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Testcase %d: %v", i, err)
+			continue
+		}
+		if r.Path != filepath.Join(root, c.path) || len(r.Candidates) != c.candidates {
+			t.Errorf("Testcase %d: resolution mismatch: %s %v", i, r, r.Candidates)
+		}
+		if filepath.Join(r.Base, c.ref) != r.Path {
+			t.Errorf("Testcase %d: base mismatch: %s", i, r.Base)
+		}
+	}
+}
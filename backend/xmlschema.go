@@ -0,0 +1,173 @@
+package backend
+
+// Schema of the freeSP documents, as used by the validating reader.
+// The XSD files in the schema directory describe the same structure.
+
+type xmlAttrKind int
+
+const (
+	attrString xmlAttrKind = iota
+	attrInt
+	attrBool
+	attrEnum
+)
+
+type xmlAttrSpec struct {
+	name     string
+	required bool
+	kind     xmlAttrKind
+	values   []string // allowed values of attrEnum
+}
+
+type xmlElementSpec struct {
+	attrs    []xmlAttrSpec
+	children map[string]*xmlElementSpec
+}
+
+func (e *xmlElementSpec) attr(name string) (a *xmlAttrSpec, ok bool) {
+	for i := range e.attrs {
+		if e.attrs[i].name == name {
+			return &e.attrs[i], true
+		}
+	}
+	return
+}
+
+var (
+	scopeValues     = []string{"", "local", "global"}
+	modeValues      = []string{"", "sync", "async"}
+	ioModeValues    = []string{string(IOModeShmem), string(IOModeAsync), string(IOModeSync)}
+	docKindValues   = []string{"sml", "alml", "spml", "mml"}
+	paramTypeValues = []string{"int", "float", "string", "enum"}
+	compatValues    = []string{"identity", "subtype", "convertible"}
+	lintLevelValues = []string{"off", "warning", "error"}
+)
+
+func req(name string) xmlAttrSpec {
+	return xmlAttrSpec{name, true, attrString, nil}
+}
+
+func opt(name string) xmlAttrSpec {
+	return xmlAttrSpec{name, false, attrString, nil}
+}
+
+func reqKind(name string, kind xmlAttrKind) xmlAttrSpec {
+	return xmlAttrSpec{name, true, kind, nil}
+}
+
+func optKind(name string, kind xmlAttrKind) xmlAttrSpec {
+	return xmlAttrSpec{name, false, kind, nil}
+}
+
+func enum(name string, required bool, values []string) xmlAttrSpec {
+	return xmlAttrSpec{name, required, attrEnum, values}
+}
+
+func elem(attrs ...xmlAttrSpec) *xmlElementSpec {
+	return &xmlElementSpec{attrs, make(map[string]*xmlElementSpec)}
+}
+
+func (e *xmlElementSpec) child(name string, c *xmlElementSpec) *xmlElementSpec {
+	e.children[name] = c
+	return e
+}
+
+var (
+	modeHintSpec   = elem(reqKind("x", attrInt), reqKind("y", attrInt), req("mode"))
+	libraryRefSpec = elem(req("ref"))
+	portSpec       = elem(opt("port"), req("type")).child("hint", modeHintSpec)
+	ioNodeSpec     = elem(req("name"), opt("type"), opt("port")).
+			child("intype", portSpec).child("outtype", portSpec)
+	paramValueSpec     = elem(req("name"), req("value"))
+	processingNodeSpec = elem(req("name"), opt("type")).
+				child("intype", portSpec).child("outtype", portSpec).child("param", paramValueSpec)
+	nodesSpec = elem().child("input", ioNodeSpec).child("output", ioNodeSpec).
+			child("processing-node", processingNodeSpec)
+	connectSpec     = elem(req("from"), req("to"), opt("from-port"), opt("to-port"))
+	connectionsSpec = elem().child("connect", connectSpec)
+	signalGraphSpec = elem(opt("version")).child("library", libraryRefSpec).
+			child("nodes", nodesSpec).child("connections", connectionsSpec)
+
+	signalFieldSpec = elem(req("name"), req("type"), optKind("array", attrInt),
+		opt("unit"), opt("min"), opt("max"))
+	signalTypeSpec = elem(req("name"), enum("scope", false, scopeValues),
+		enum("mode", false, modeValues), opt("c-type"), opt("message-id")).
+		child("field", signalFieldSpec)
+	implementationSpec = elem(req("name"), opt("graph")).child("signal-graph", signalGraphSpec)
+	parameterSpec      = elem(req("name"), enum("type", true, paramTypeValues),
+		opt("default"), opt("min"), opt("max"), opt("values"))
+	nodeTypeSpec = elem(req("name")).child("intype", portSpec).
+			child("outtype", portSpec).child("parameter", parameterSpec).
+			child("implementation", implementationSpec)
+	compatibleSpec = elem(req("from"), req("to"), enum("kind", true, compatValues), opt("converter"))
+	librarySpec    = elem(opt("version")).child("library", libraryRefSpec).
+			child("signal-type", signalTypeSpec).child("node-type", nodeTypeSpec).
+			child("compatible", compatibleSpec)
+
+	ioTypeSpec      = elem(req("name"), enum("mode", true, ioModeValues))
+	inChannelSpec   = elem(req("io-type"), req("source"))
+	outChannelSpec  = elem(req("io-type"), req("dest"))
+	processSpec     = elem(req("name")).child("input-channel", inChannelSpec).child("output-channel", outChannelSpec)
+	archSpec        = elem(req("name")).child("io-type", ioTypeSpec).child("process", processSpec)
+	platformSpec    = elem(opt("version"), opt("platform-id")).child("arch", archSpec)
+	mapSpec         = elem(req("name"), opt("process"))
+	mappingSpec     = elem(req("graph"), req("platform")).child("map-ionode", mapSpec).child("map-node", mapSpec)
+	portPosHintSpec = elem(req("name")).child("hint", modeHintSpec)
+	nodePosHintSpec = elem(req("name"), optKind("expanded", attrBool)).child("hint", modeHintSpec).
+			child("in-port", portPosHintSpec).child("out-port", portPosHintSpec)
+	channelPosHintSpec = elem().child("hint", modeHintSpec)
+	processPosHintSpec = elem(req("name")).child("hint", modeHintSpec).
+				child("in-channel", channelPosHintSpec).child("out-channel", channelPosHintSpec)
+	archPortPosHintSpec = elem(opt("channel")).child("hint", modeHintSpec)
+	archPosHintSpec     = elem(req("name")).child("hint", modeHintSpec).
+				child("arch-port", archPortPosHintSpec).child("process", processPosHintSpec)
+	graphHintSpec = elem(opt("ref")).child("input-node", nodePosHintSpec).
+			child("output-node", nodePosHintSpec).child("processing-node", nodePosHintSpec)
+	platformHintSpec = elem(opt("ref")).child("arch", archPosHintSpec)
+	mappingHintSpec  = elem(opt("ref")).child("mapped-node", nodePosHintSpec).child("arch", archPosHintSpec)
+
+	projectMemberSpec = elem(req("ref"))
+	projectSpec       = elem(opt("version"), opt("name"), optKind("canonical", attrBool)).child("search-path", elem(req("path"))).
+				child("output-dir", elem(enum("kind", true, docKindValues), req("path"))).
+				child("graph", projectMemberSpec).child("library", projectMemberSpec).
+				child("platform", projectMemberSpec).child("mapping", projectMemberSpec).
+				child("lint", elem(req("rule"), enum("level", true, lintLevelValues)))
+)
+
+// Each document kind is identified by its root element.
+type xmlDocumentSchema struct {
+	root string
+	spec *xmlElementSpec
+}
+
+func (g *XmlSignalGraph) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"signal-graph", signalGraphSpec}
+}
+
+func (g *XmlLibrary) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"library", librarySpec}
+}
+
+func (p *XmlPlatform) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"platform", platformSpec}
+}
+
+func (m *XmlMapping) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"mapping", mappingSpec}
+}
+
+func (p *XmlProject) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"project", projectSpec}
+}
+
+func (h *XmlGraphHint) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"hints", graphHintSpec}
+}
+
+func (h *XmlPlatformHint) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"hints", platformHintSpec}
+}
+
+func (h *XmlMappingHint) schema() xmlDocumentSchema {
+	return xmlDocumentSchema{"hints", mappingHintSpec}
+}
@@ -7,17 +7,17 @@ import (
 )
 
 type XmlSignalGraph struct {
-	XMLName         xml.Name            `xml:"http://www.freesp.de/xml/freeSP signal-graph"`
-	Version         string              `xml:"version,attr"`
-	Libraries       []XmlLibraryRef     `xml:"library"`
-	InputNodes      []XmlInputNode      `xml:"nodes>input"`
-	OutputNodes     []XmlOutputNode     `xml:"nodes>output"`
-	ProcessingNodes []XmlProcessingNode `xml:"nodes>processing-node"`
-	Connections     []XmlConnect        `xml:"connections>connect"`
+	XMLName         xml.Name            `xml:"http://www.freesp.de/xml/freeSP signal-graph" json:"-"`
+	Version         string              `xml:"version,attr" json:"version"`
+	Libraries       []XmlLibraryRef     `xml:"library" json:"library,omitempty"`
+	InputNodes      []XmlInputNode      `xml:"nodes>input" json:"input,omitempty"`
+	OutputNodes     []XmlOutputNode     `xml:"nodes>output" json:"output,omitempty"`
+	ProcessingNodes []XmlProcessingNode `xml:"nodes>processing-node" json:"processing-node,omitempty"`
+	Connections     []XmlConnect        `xml:"connections>connect" json:"connect,omitempty"`
 }
 
 func XmlSignalGraphNew() *XmlSignalGraph {
-	return &XmlSignalGraph{xml.Name{freespNamespace, "signal-graph"}, "1.0", nil, nil, nil, nil, nil}
+	return &XmlSignalGraph{xml.Name{freespNamespace, "signal-graph"}, FormatVersion, nil, nil, nil, nil, nil}
 }
 
 func (g *XmlSignalGraph) Read(data []byte) (cnt int, err error) {
@@ -39,7 +39,7 @@ func (g *XmlSignalGraph) ReadFile(filepath string) error {
 	if err != nil {
 		return fmt.Errorf("XmlSignalGraph.ReadFile error: Failed to read file %s", filepath)
 	}
-	_, err = g.Read(data)
+	_, err = DocumentRead(g, data, filepath)
 	if err != nil {
 		return fmt.Errorf("XmlSignalGraph.ReadFile error: %v", err)
 	}
@@ -47,16 +47,20 @@ func (g *XmlSignalGraph) ReadFile(filepath string) error {
 }
 
 func (g *XmlSignalGraph) WriteFile(filepath string) error {
-	data, err := g.Write()
-	if err != nil {
-		return err
+	return DocumentWriteFile(g, filepath)
+}
+
+// Nodes returns the input, output and processing nodes of g, in this
+// order. Changes through the pointers go to g.
+func (g *XmlSignalGraph) Nodes() (nodes []*XmlNode) {
+	for i := range g.InputNodes {
+		nodes = append(nodes, &g.InputNodes[i].XmlNode)
 	}
-	buf := make([]byte, len(data)+len(xmlHeader))
-	for i := 0; i < len(xmlHeader); i++ {
-		buf[i] = xmlHeader[i]
+	for i := range g.OutputNodes {
+		nodes = append(nodes, &g.OutputNodes[i].XmlNode)
 	}
-	for i := 0; i < len(data); i++ {
-		buf[i+len(xmlHeader)] = data[i]
+	for i := range g.ProcessingNodes {
+		nodes = append(nodes, &g.ProcessingNodes[i].XmlNode)
 	}
-	return tool.WriteFile(filepath, buf)
+	return
 }
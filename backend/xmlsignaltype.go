@@ -6,16 +6,35 @@ import (
 )
 
 type XmlSignalType struct {
-	XMLName xml.Name `xml:"signal-type"`
-	Name    string   `xml:"name,attr"`
-	Scope   string   `xml:"scope,attr"`
-	Mode    string   `xml:"mode,attr"`
-	Ctype   string   `xml:"c-type,attr"`
-	Msgid   string   `xml:"message-id,attr"`
+	XMLName xml.Name         `xml:"signal-type" json:"-"`
+	Name    string           `xml:"name,attr" json:"name"`
+	Scope   string           `xml:"scope,attr" json:"scope"`
+	Mode    string           `xml:"mode,attr" json:"mode"`
+	Ctype   string           `xml:"c-type,attr" json:"c-type"`
+	Msgid   string           `xml:"message-id,attr" json:"message-id"`
+	Field   []XmlSignalField `xml:"field" json:"field,omitempty"`
 }
 
 func XmlSignalTypeNew(name, scope, mode, ctype, msgid string) *XmlSignalType {
-	return &XmlSignalType{xml.Name{freespNamespace, "signal-type"}, name, scope, mode, ctype, msgid}
+	return &XmlSignalType{xml.Name{freespNamespace, "signal-type"}, name, scope, mode, ctype, msgid, nil}
+}
+
+// Field of a structured signal type. Type is a primitive type or the
+// name of another signal type, Array is the number of elements of a
+// fixed size array field (empty for scalars). Unit, Min and Max
+// optionally describe the physical quantity of numeric fields.
+type XmlSignalField struct {
+	XMLName xml.Name `xml:"field" json:"-"`
+	Name    string   `xml:"name,attr" json:"name"`
+	FType   string   `xml:"type,attr" json:"type"`
+	Array   string   `xml:"array,attr,omitempty" json:"array,omitempty"`
+	Unit    string   `xml:"unit,attr,omitempty" json:"unit,omitempty"`
+	Min     string   `xml:"min,attr,omitempty" json:"min,omitempty"`
+	Max     string   `xml:"max,attr,omitempty" json:"max,omitempty"`
+}
+
+func XmlSignalFieldNew(name, fType string) *XmlSignalField {
+	return &XmlSignalField{xml.Name{freespNamespace, "field"}, name, fType, "", "", "", ""}
 }
 
 func (t *XmlSignalType) Read(data []byte) (cnt int, err error) {
@@ -34,3 +53,20 @@ func (t *XmlSignalType) Write() (data []byte, err error) {
 	}
 	return
 }
+
+func (f *XmlSignalField) Read(data []byte) (cnt int, err error) {
+	err = xml.Unmarshal(data, f)
+	if err != nil {
+		fmt.Printf("XmlSignalField.Read error: %v", err)
+	}
+	cnt = len(data)
+	return
+}
+
+func (f *XmlSignalField) Write() (data []byte, err error) {
+	data, err = xml.MarshalIndent(f, "", "   ")
+	if err != nil {
+		err = fmt.Errorf("XmlSignalField.Write error: %v", err)
+	}
+	return
+}
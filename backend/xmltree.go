@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XmlElement is a generic, untyped representation of an XML element.
+// Children are *XmlElement, xml.CharData, xml.Comment, xml.ProcInst or
+// xml.Directive. Names keep their namespace prefix as written, such that
+// a tree read by XmlTreeRead is written back unchanged except for
+// insignificant formatting.
+type XmlElement struct {
+	Name     xml.Name
+	Attr     []xml.Attr
+	Children []interface{}
+}
+
+func XmlElementNew(name string) *XmlElement {
+	return &XmlElement{xml.Name{"", name}, nil, nil}
+}
+
+// XmlTreeRead parses data into a document: the root element plus all
+// tokens preceding it (XML declaration, comments).
+func XmlTreeRead(data []byte) (prolog []interface{}, root *XmlElement, err error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*XmlElement
+	for {
+		var tok xml.Token
+		tok, err = d.RawToken()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("XmlTreeRead error: %v", err)
+			return
+		}
+		tok = xml.CopyToken(tok)
+		switch tok.(type) {
+		case xml.StartElement:
+			t := tok.(xml.StartElement)
+			e := &XmlElement{t.Name, t.Attr, nil}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			} else if root == nil {
+				root = e
+			} else {
+				err = fmt.Errorf("XmlTreeRead error: more than one root element")
+				return
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				err = fmt.Errorf("XmlTreeRead error: unexpected end element %s", tok.(xml.EndElement).Name.Local)
+				return
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, tok)
+			} else if root == nil {
+				if _, ok := tok.(xml.CharData); !ok {
+					prolog = append(prolog, tok)
+				}
+			}
+		}
+	}
+	if root == nil {
+		err = fmt.Errorf("XmlTreeRead error: no root element")
+	} else if len(stack) > 0 {
+		err = fmt.Errorf("XmlTreeRead error: element %s not closed", stack[len(stack)-1].Name.Local)
+	}
+	return
+}
+
+func XmlTreeWrite(prolog []interface{}, root *XmlElement) []byte {
+	var buf bytes.Buffer
+	for _, t := range prolog {
+		writeXmlToken(&buf, t)
+		buf.WriteByte('\n')
+	}
+	root.write(&buf)
+	return buf.Bytes()
+}
+
+// Unlike xml.EscapeText, keep line breaks readable.
+var charDataEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+func qualifiedName(n xml.Name) string {
+	if len(n.Space) > 0 {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+func writeXmlToken(buf *bytes.Buffer, t interface{}) {
+	switch t.(type) {
+	case *XmlElement:
+		t.(*XmlElement).write(buf)
+	case xml.CharData:
+		buf.WriteString(charDataEscaper.Replace(string(t.(xml.CharData))))
+	case xml.Comment:
+		buf.WriteString("<!--")
+		buf.Write(t.(xml.Comment))
+		buf.WriteString("-->")
+	case xml.ProcInst:
+		p := t.(xml.ProcInst)
+		buf.WriteString("<?" + p.Target)
+		if len(p.Inst) > 0 {
+			buf.WriteByte(' ')
+			buf.Write(p.Inst)
+		}
+		buf.WriteString("?>")
+	case xml.Directive:
+		buf.WriteString("<!")
+		buf.Write(t.(xml.Directive))
+		buf.WriteString(">")
+	}
+}
+
+func (e *XmlElement) write(buf *bytes.Buffer) {
+	buf.WriteString("<" + qualifiedName(e.Name))
+	for _, a := range e.Attr {
+		buf.WriteString(" " + qualifiedName(a.Name) + "=\"")
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString("\"")
+	}
+	buf.WriteString(">")
+	for _, c := range e.Children {
+		writeXmlToken(buf, c)
+	}
+	buf.WriteString("</" + qualifiedName(e.Name) + ">")
+}
+
+func (e *XmlElement) AttrValue(name string) (value string, ok bool) {
+	for _, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return
+}
+
+func (e *XmlElement) SetAttr(name, value string) {
+	for i, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			e.Attr[i].Value = value
+			return
+		}
+	}
+	e.Attr = append(e.Attr, xml.Attr{xml.Name{"", name}, value})
+}
+
+func (e *XmlElement) RemoveAttr(name string) {
+	for i, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			e.Attr = append(e.Attr[:i], e.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+func (e *XmlElement) RenameAttr(oldName, newName string) {
+	for i, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == oldName {
+			e.Attr[i].Name.Local = newName
+			return
+		}
+	}
+}
+
+// Elements returns the direct children with the given (unprefixed) name.
+func (e *XmlElement) Elements(name string) (ret []*XmlElement) {
+	for _, c := range e.Children {
+		if ce, ok := c.(*XmlElement); ok && ce.Name.Local == name {
+			ret = append(ret, ce)
+		}
+	}
+	return
+}
+
+func (e *XmlElement) AppendChild(c *XmlElement) {
+	e.Children = append(e.Children, c)
+}
+
+func (e *XmlElement) RemoveChild(c *XmlElement) {
+	for i, x := range e.Children {
+		if x == c {
+			e.Children = append(e.Children[:i], e.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// Walk calls f for e and all its descendant elements, depth first.
+func (e *XmlElement) Walk(f func(*XmlElement)) {
+	f(e)
+	for _, c := range e.Children {
+		if ce, ok := c.(*XmlElement); ok {
+			ce.Walk(f)
+		}
+	}
+}
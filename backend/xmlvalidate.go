@@ -0,0 +1,206 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ValidationError struct {
+	Filename     string
+	Line, Column int
+	Msg          string
+	Warning      bool
+}
+
+func (e ValidationError) Error() string {
+	if e.Warning {
+		return fmt.Sprintf("%s:%d:%d: warning: %s", e.Filename, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
+}
+
+type ValidationErrors []ValidationError
+
+func (l ValidationErrors) Error() string {
+	var lines []string
+	for _, e := range l {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (l ValidationErrors) Errors() (ret ValidationErrors) {
+	for _, e := range l {
+		if !e.Warning {
+			ret = append(ret, e)
+		}
+	}
+	return
+}
+
+func (l ValidationErrors) Warnings() (ret ValidationErrors) {
+	for _, e := range l {
+		if e.Warning {
+			ret = append(ret, e)
+		}
+	}
+	return
+}
+
+// Validate checks an XML encoded document against the freeSP schema
+// of doc. Unknown elements and attributes are reported as warnings,
+// missing required attributes, invalid values and syntax errors as errors.
+// Elements and attributes of other namespaces are ignored.
+func Validate(doc Document, data []byte, filename string) (problems ValidationErrors) {
+	v := validatorNew(doc.schema(), data, filename)
+	v.run()
+	return v.problems
+}
+
+type validatorFrame struct {
+	name string
+	spec *xmlElementSpec // nil within unknown elements
+}
+
+type validator struct {
+	schema   xmlDocumentSchema
+	data     []byte
+	filename string
+	lines    []int // byte offsets of line starts
+	stack    []validatorFrame
+	problems ValidationErrors
+}
+
+func validatorNew(schema xmlDocumentSchema, data []byte, filename string) *validator {
+	v := &validator{schema: schema, data: data, filename: filename}
+	v.lines = append(v.lines, 0)
+	for i, c := range data {
+		if c == '\n' {
+			v.lines = append(v.lines, i+1)
+		}
+	}
+	return v
+}
+
+func (v *validator) position(offset int64) (line, col int) {
+	i := sort.Search(len(v.lines), func(i int) bool { return int64(v.lines[i]) > offset }) - 1
+	return i + 1, int(offset) - v.lines[i] + 1
+}
+
+func (v *validator) report(offset int64, warning bool, format string, args ...interface{}) {
+	line, col := v.position(offset)
+	v.problems = append(v.problems, ValidationError{v.filename, line, col, fmt.Sprintf(format, args...), warning})
+}
+
+func (v *validator) run() {
+	d := xml.NewDecoder(bytes.NewReader(v.data))
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			if len(v.stack) == 0 && offset == 0 {
+				v.report(offset, false, "empty document")
+			}
+			return
+		}
+		if err != nil {
+			msg := err.Error()
+			if serr, ok := err.(*xml.SyntaxError); ok {
+				msg = serr.Msg
+			}
+			v.report(d.InputOffset(), false, "syntax error: %s", msg)
+			return
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			v.startElement(tok.(xml.StartElement), offset)
+		case xml.EndElement:
+			v.stack = v.stack[:len(v.stack)-1]
+		case xml.CharData:
+			if len(v.stack) == 0 {
+				break
+			}
+			top := v.stack[len(v.stack)-1]
+			if top.spec != nil && len(strings.TrimSpace(string(tok.(xml.CharData)))) > 0 {
+				v.report(offset, true, "unexpected text in <%s>", top.name)
+			}
+		}
+	}
+}
+
+func (v *validator) startElement(t xml.StartElement, offset int64) {
+	var spec *xmlElementSpec
+	if len(v.stack) == 0 {
+		if t.Name.Local != v.schema.root || t.Name.Space != freespNamespace {
+			v.report(offset, false, "invalid root element <%s>, expected <%s> in namespace %s",
+				t.Name.Local, v.schema.root, freespNamespace)
+		} else {
+			spec = v.schema.spec
+		}
+	} else {
+		parent := v.stack[len(v.stack)-1]
+		if parent.spec != nil && t.Name.Space == freespNamespace {
+			var ok bool
+			spec, ok = parent.spec.children[t.Name.Local]
+			if !ok {
+				v.report(offset, true, "unknown element <%s> in <%s>", t.Name.Local, parent.name)
+			}
+		}
+	}
+	v.stack = append(v.stack, validatorFrame{t.Name.Local, spec})
+	if spec != nil {
+		v.checkAttributes(t, spec, offset)
+	}
+}
+
+func (v *validator) checkAttributes(t xml.StartElement, spec *xmlElementSpec, offset int64) {
+	present := make(map[string]bool)
+	for _, a := range t.Attr {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		if a.Name.Space != "" {
+			continue
+		}
+		as, ok := spec.attr(a.Name.Local)
+		if !ok {
+			v.report(offset, true, "unknown attribute %s in <%s>", a.Name.Local, t.Name.Local)
+			continue
+		}
+		present[as.name] = true
+		switch as.kind {
+		case attrInt:
+			if _, err := strconv.Atoi(strings.TrimSpace(a.Value)); err != nil {
+				v.report(offset, false, "attribute %s in <%s>: %q is not an integer",
+					a.Name.Local, t.Name.Local, a.Value)
+			}
+		case attrBool:
+			if _, err := strconv.ParseBool(strings.TrimSpace(a.Value)); err != nil {
+				v.report(offset, false, "attribute %s in <%s>: %q is not a boolean",
+					a.Name.Local, t.Name.Local, a.Value)
+			}
+		case attrEnum:
+			var valid bool
+			for _, val := range as.values {
+				if val == a.Value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				v.report(offset, false, "attribute %s in <%s>: invalid value %q, expected one of %q",
+					a.Name.Local, t.Name.Local, a.Value, as.values)
+			}
+		}
+	}
+	for _, as := range spec.attrs {
+		if as.required && !present[as.name] {
+			v.report(offset, false, "missing required attribute %s in <%s>", as.name, t.Name.Local)
+		}
+	}
+}
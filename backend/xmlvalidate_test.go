@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	case1 := []struct {
+		doc      Document
+		text     string
+		problems []string
+	}{
+		{XmlSignalGraphNew(), `<?xml version="1.0" encoding="UTF-8"?>
+<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+    <nodes>
+        <input name="sensor">
+            <outtype type="s1"/>
+        </input>
+        <processing-node name="test" type="Test"></processing-node>
+    </nodes>
+    <connections>
+        <connect from="sensor" to="test"/>
+    </connections>
+</signal-graph>
+`, nil},
+		{XmlSignalGraphNew(), `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+    <nodes>
+        <input nme="sensor">
+            <outtype type="s1"/>
+        </input>
+        <processing-nod name="test" type="Test"></processing-nod>
+    </nodes>
+    <connections>
+        <connect from="sensor"/>
+    </connections>
+</signal-graph>
+`, []string{
+			"g.sml:3:9: warning: unknown attribute nme in <input>",
+			"g.sml:3:9: missing required attribute name in <input>",
+			"g.sml:6:9: warning: unknown element <processing-nod> in <nodes>",
+			"g.sml:9:9: missing required attribute to in <connect>",
+		}},
+		{XmlLibraryNew(), `<library xmlns="http://www.freesp.de/xml/freeSP" xmlns:x="http://example.com/x" version="1.0">
+   <signal-type name="s1" scope="locl" mode="sync" x:note="ignored"></signal-type>
+   <x:annotation>ignored</x:annotation>
+</library>`, []string{
+			`g.sml:2:4: attribute scope in <signal-type>: invalid value "locl", expected one of ["" "local" "global"]`,
+		}},
+		{XmlPlatformNew(), `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a1">
+      <io-type name="t1" mode="fast"></io-type>
+   </arch>
+</platform>`, []string{
+			`g.sml:3:7: attribute mode in <io-type>: invalid value "fast", expected one of ["shmem" "async" "sync"]`,
+		}},
+		{XmlMappingNew("", ""), `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml">
+   <map-node name="n1" process="a/p">
+</mapping>`, []string{
+			"g.sml:1:1: missing required attribute platform in <mapping>",
+			"g.sml:3:11: syntax error: element <map-node> closed by </mapping>",
+		}},
+		{XmlGraphHintNew(""), `<hints xmlns="http://www.freesp.de/xml/freeSP">
+   <input-node name="n" expanded="maybe"><hint mode="normal" x="1" y="z"/></input-node>
+</hints>`, []string{
+			`g.sml:2:4: attribute expanded in <input-node>: "maybe" is not a boolean`,
+			`g.sml:2:42: attribute y in <hint>: "z" is not an integer`,
+		}},
+		{XmlSignalGraphNew(), `<library xmlns="http://www.freesp.de/xml/freeSP"/>`, []string{
+			"g.sml:1:1: invalid root element <library>, expected <signal-graph> in namespace http://www.freesp.de/xml/freeSP",
+		}},
+	}
+
+	for i, c := range case1 {
+		problems := Validate(c.doc, []byte(c.text), "g.sml")
+		if len(problems) != len(c.problems) {
+			t.Errorf("Testcase %d: problem count mismatch:\n%s", i, problems)
+			continue
+		}
+		for j, p := range problems {
+			if p.Error() != c.problems[j] {
+				t.Errorf("Testcase %d: problem mismatch:\n%s\n%s", i, p, c.problems[j])
+			}
+		}
+	}
+}
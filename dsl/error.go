@@ -0,0 +1,35 @@
+package dsl
+
+import (
+	"strings"
+)
+
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func errorNew(pos Pos, msg string) *Error {
+	return &Error{pos, msg}
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	var lines []string
+	for _, e := range l {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,242 @@
+package dsl
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Pos struct {
+	Filename     string
+	Line, Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tNumber
+	tString
+	tSemicolon
+	tColon
+	tComma
+	tDot
+	tArrow
+	tLParen
+	tRParen
+	tLBrace
+	tRBrace
+)
+
+var tokenKindString = map[tokenKind]string{
+	tEOF:       "end of file",
+	tIdent:     "identifier",
+	tNumber:    "number",
+	tString:    "string",
+	tSemicolon: "';'",
+	tColon:     "':'",
+	tComma:     "','",
+	tDot:       "'.'",
+	tArrow:     "'->'",
+	tLParen:    "'('",
+	tRParen:    "')'",
+	tLBrace:    "'{'",
+	tRBrace:    "'}'",
+}
+
+func (k tokenKind) String() string {
+	return tokenKindString[k]
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tIdent:
+		return fmt.Sprintf("identifier %s", t.text)
+	case tNumber:
+		return fmt.Sprintf("number %s", t.text)
+	case tString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return t.kind.String()
+}
+
+type lexer struct {
+	src       []byte
+	offset    int
+	line, col int
+	filename  string
+}
+
+func lexerNew(filename string, src []byte) *lexer {
+	return &lexer{src, 0, 1, 1, filename}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{l.filename, l.line, l.col}
+}
+
+func (l *lexer) peek() (r rune, size int) {
+	if l.offset >= len(l.src) {
+		return -1, 0
+	}
+	return utf8.DecodeRune(l.src[l.offset:])
+}
+
+func (l *lexer) advance() rune {
+	r, size := l.peek()
+	l.offset += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else if size > 0 {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) skipSpaceAndComments() (err error) {
+	for {
+		r, _ := l.peek()
+		switch {
+		case r == -1:
+			return
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '/' && l.offset+1 < len(l.src) && l.src[l.offset+1] == '/':
+			for r != '\n' && r != -1 {
+				l.advance()
+				r, _ = l.peek()
+			}
+		case r == '/' && l.offset+1 < len(l.src) && l.src[l.offset+1] == '*':
+			start := l.pos()
+			l.advance()
+			l.advance()
+			for {
+				r = l.advance()
+				if r == -1 {
+					return errorNew(start, "unterminated comment")
+				}
+				if r == '*' {
+					if r2, _ := l.peek(); r2 == '/' {
+						l.advance()
+						break
+					}
+				}
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (l *lexer) next() (t token, err error) {
+	err = l.skipSpaceAndComments()
+	if err != nil {
+		return
+	}
+	t.pos = l.pos()
+	r, _ := l.peek()
+	switch {
+	case r == -1:
+		t.kind = tEOF
+	case isIdentStart(r):
+		start := l.offset
+		for isIdentChar(r) {
+			l.advance()
+			r, _ = l.peek()
+		}
+		t.kind = tIdent
+		t.text = string(l.src[start:l.offset])
+	case unicode.IsDigit(r):
+		// e.g. 16, 0.5, 1e3
+		start := l.offset
+		for isIdentChar(r) || r == '.' {
+			l.advance()
+			r, _ = l.peek()
+		}
+		t.kind = tNumber
+		t.text = string(l.src[start:l.offset])
+	case r == '"':
+		t.kind = tString
+		t.text, err = l.scanString()
+	default:
+		l.advance()
+		switch r {
+		case ';':
+			t.kind = tSemicolon
+		case ':':
+			t.kind = tColon
+		case ',':
+			t.kind = tComma
+		case '.':
+			t.kind = tDot
+		case '(':
+			t.kind = tLParen
+		case ')':
+			t.kind = tRParen
+		case '{':
+			t.kind = tLBrace
+		case '}':
+			t.kind = tRBrace
+		case '-':
+			if r2, _ := l.peek(); r2 == '>' {
+				l.advance()
+				t.kind = tArrow
+				break
+			}
+			fallthrough
+		default:
+			err = errorNew(t.pos, fmt.Sprintf("unexpected character %q", r))
+		}
+	}
+	return
+}
+
+func (l *lexer) scanString() (s string, err error) {
+	start := l.pos()
+	l.advance() // opening quote
+	var buf []rune
+	for {
+		r := l.advance()
+		switch r {
+		case -1, '\n':
+			err = errorNew(start, "unterminated string")
+			return
+		case '"':
+			s = string(buf)
+			return
+		case '\\':
+			r = l.advance()
+			switch r {
+			case '"', '\\':
+			case 'n':
+				r = '\n'
+			case 't':
+				r = '\t'
+			default:
+				err = errorNew(start, fmt.Sprintf("invalid escape sequence \\%c in string", r))
+				return
+			}
+		}
+		buf = append(buf, r)
+	}
+}
@@ -0,0 +1,572 @@
+package dsl
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"strings"
+)
+
+/*
+ *  Grammar of the textual notation:
+ *
+ *  graph      = { graphStmt } .
+ *  graphStmt  = "include" string ";"
+ *             | "version" string ";"
+ *             | ( "input" | "output" | "node" ) name [ ":" name ] [ "port" name ]
+ *               [ portList ] [ "in" portList ] [ "out" portList ]
+ *               [ "param" "(" [ param { "," param } ] ")" ] ";"
+ *             | endpoint "->" endpoint ";" .
+ *  endpoint   = name [ "." name ] .
+ *  portList   = "(" [ port { "," port } ] ")" .
+ *  port       = [ name ":" ] name .
+ *  param      = name ":" value .
+ *  value      = name | number .
+ *
+ *  library    = { libStmt } .
+ *  libStmt    = "include" string ";"
+ *             | "version" string ";"
+ *             | "signal" name { ( "ctype" | "msgid" | "scope" | "mode" ) name }
+ *               ( ";" | "{" { fieldStmt } "}" )
+ *             | "nodetype" name [ "in" portList ] [ "out" portList ]
+ *               ( ";" | "{" { paramStmt | implStmt } "}" )
+ *             | "compatible" name "->" name
+ *               ( "identity" | "subtype" | "convertible" "via" name ) ";" .
+ *  paramStmt  = "param" name ":" name [ "(" value { "," value } ")" ]
+ *               { ( "default" | "min" | "max" ) value } ";" .
+ *  fieldStmt  = "field" name ":" name
+ *               { ( "array" | "unit" | "min" | "max" ) value } ";" .
+ *  implStmt   = "implementation" name ( ";" | "graph" string ";" | "{" graph "}" ) .
+ *  name       = identifier | string .
+ *
+ *  A port list directly following an input (output) node declares its
+ *  out (in) ports. Parameter values that are expressions, e.g.
+ *  "$taps/2", must be quoted. Line comments start with "//", block comments are
+ *  enclosed in C style delimiters.
+ */
+
+// Suffix of text files, e.g. "graph.sml.sgt"
+const Suffix = "sgt"
+
+var scopeValues = []string{"", "local", "global"}
+var modeValues = []string{"", "sync", "async"}
+var paramTypeValues = []string{"", "int", "float", "string", "enum"}
+var compatValues = []string{"", "identity", "subtype", "convertible"}
+
+type parser struct {
+	lex    *lexer
+	tok    token
+	ahead  []token
+	errors ErrorList
+}
+
+// bailout is used to unwind the parser on syntax errors.
+type bailout struct{}
+
+func ParseSignalGraph(filename string, src []byte) (g *backend.XmlSignalGraph, err error) {
+	p := parserNew(filename, src)
+	defer p.recover(&err)
+	g = backend.XmlSignalGraphNew()
+	p.parseGraphBody(g, tEOF)
+	err = p.errors.Err()
+	return
+}
+
+func ParseLibrary(filename string, src []byte) (l *backend.XmlLibrary, err error) {
+	p := parserNew(filename, src)
+	defer p.recover(&err)
+	l = backend.XmlLibraryNew()
+	p.parseLibraryBody(l)
+	err = p.errors.Err()
+	return
+}
+
+func parserNew(filename string, src []byte) *parser {
+	p := &parser{lex: lexerNew(filename, src)}
+	return p
+}
+
+func (p *parser) recover(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, ok := r.(bailout); !ok {
+		panic(r)
+	}
+	*err = p.errors.Err()
+}
+
+func (p *parser) errorAt(pos Pos, format string, args ...interface{}) {
+	p.errors = append(p.errors, errorNew(pos, fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) fail(pos Pos, format string, args ...interface{}) {
+	p.errorAt(pos, format, args...)
+	panic(bailout{})
+}
+
+func (p *parser) scan() token {
+	t, err := p.lex.next()
+	if err != nil {
+		p.errors = append(p.errors, err.(*Error))
+		panic(bailout{})
+	}
+	return t
+}
+
+// next advances to the next token and returns the previous one.
+func (p *parser) next() (t token) {
+	t = p.tok
+	if len(p.ahead) > 0 {
+		p.tok = p.ahead[0]
+		p.ahead = p.ahead[1:]
+	} else {
+		p.tok = p.scan()
+	}
+	return
+}
+
+func (p *parser) peek() token {
+	if len(p.ahead) == 0 {
+		p.ahead = append(p.ahead, p.scan())
+	}
+	return p.ahead[0]
+}
+
+func (p *parser) start() {
+	if p.tok.pos.Line == 0 {
+		p.tok = p.scan()
+	}
+}
+
+func (p *parser) expect(k tokenKind) token {
+	if p.tok.kind != k {
+		p.fail(p.tok.pos, "expected %s, found %s", k, p.tok)
+	}
+	return p.next()
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	return p.tok.kind == tIdent && p.tok.text == kw
+}
+
+func (p *parser) name() token {
+	if p.tok.kind != tIdent && p.tok.kind != tString {
+		p.fail(p.tok.pos, "expected name, found %s", p.tok)
+	}
+	return p.next()
+}
+
+func (p *parser) value() token {
+	if p.tok.kind == tNumber {
+		return p.next()
+	}
+	return p.name()
+}
+
+func (p *parser) str() token {
+	return p.expect(tString)
+}
+
+func (p *parser) portList() (ports []backend.XmlPort) {
+	p.expect(tLParen)
+	for p.tok.kind != tRParen {
+		if len(ports) > 0 {
+			p.expect(tComma)
+		}
+		n := p.name()
+		var port backend.XmlPort
+		if p.tok.kind == tColon {
+			p.next()
+			port.PName = n.text
+			port.PType = p.name().text
+		} else {
+			port.PType = n.text
+		}
+		ports = append(ports, port)
+	}
+	p.next()
+	return
+}
+
+func inPorts(ports []backend.XmlPort) (ret []backend.XmlInPort) {
+	for _, pt := range ports {
+		ret = append(ret, *backend.XmlInPortNew(pt.PName, pt.PType))
+	}
+	return
+}
+
+func outPorts(ports []backend.XmlPort) (ret []backend.XmlOutPort) {
+	for _, pt := range ports {
+		ret = append(ret, *backend.XmlOutPortNew(pt.PName, pt.PType))
+	}
+	return
+}
+
+//
+//      Signal graphs
+//
+
+// positions of declarations and references, for semantic checks
+type graphInfo struct {
+	nodePos map[string]Pos
+	conns   []Pos
+}
+
+func (p *parser) parseGraphBody(g *backend.XmlSignalGraph, end tokenKind) {
+	p.start()
+	info := graphInfo{make(map[string]Pos), nil}
+	for p.tok.kind != end {
+		if p.tok.kind == tEOF {
+			p.fail(p.tok.pos, "unexpected end of file")
+		}
+		ahead := p.peek().kind
+		isDecl := p.tok.kind == tIdent && ahead != tArrow && ahead != tDot
+		switch {
+		case isDecl && p.tok.text == "include":
+			p.next()
+			g.Libraries = append(g.Libraries, *backend.XmlLibraryRefNew(p.str().text))
+			p.expect(tSemicolon)
+		case isDecl && p.tok.text == "version":
+			p.next()
+			g.Version = p.str().text
+			p.expect(tSemicolon)
+		case isDecl && (p.tok.text == "input" || p.tok.text == "output" || p.tok.text == "node"):
+			p.nodeDecl(g, &info)
+		default:
+			p.connection(g, &info)
+		}
+	}
+	p.checkConnections(g, &info)
+}
+
+func (p *parser) nodeDecl(g *backend.XmlSignalGraph, info *graphInfo) {
+	kind := p.next().text
+	nameTok := p.name()
+	var n backend.XmlNode
+	n.NName = nameTok.text
+	if pos, ok := info.nodePos[n.NName]; ok {
+		p.errorAt(nameTok.pos, "node %s already declared at %s", n.NName, pos)
+	} else {
+		info.nodePos[n.NName] = nameTok.pos
+	}
+	if p.tok.kind == tColon {
+		p.next()
+		n.NType = p.name().text
+	}
+	var nPort string
+	if p.isKeyword("port") {
+		p.next()
+		nPort = p.name().text
+	}
+	if p.tok.kind == tLParen {
+		switch kind {
+		case "input":
+			n.OutPort = append(n.OutPort, outPorts(p.portList())...)
+		case "output":
+			n.InPort = append(n.InPort, inPorts(p.portList())...)
+		default:
+			p.fail(p.tok.pos, "port list of a processing node must be preceded by \"in\" or \"out\"")
+		}
+	}
+	if p.isKeyword("in") {
+		p.next()
+		n.InPort = append(n.InPort, inPorts(p.portList())...)
+	}
+	if p.isKeyword("out") {
+		p.next()
+		n.OutPort = append(n.OutPort, outPorts(p.portList())...)
+	}
+	var params []backend.XmlParamValue
+	if p.isKeyword("param") {
+		params = p.paramValues()
+	}
+	p.expect(tSemicolon)
+	if len(params) > 0 && kind != "node" {
+		p.errorAt(nameTok.pos, "%s node %s cannot have parameters", kind, n.NName)
+	}
+	switch kind {
+	case "input":
+		xmln := backend.XmlInputNodeNew(n.NName, n.NType)
+		xmln.NPort = nPort
+		xmln.InPort, xmln.OutPort = n.InPort, n.OutPort
+		g.InputNodes = append(g.InputNodes, *xmln)
+	case "output":
+		xmln := backend.XmlOutputNodeNew(n.NName, n.NType)
+		xmln.NPort = nPort
+		xmln.InPort, xmln.OutPort = n.InPort, n.OutPort
+		g.OutputNodes = append(g.OutputNodes, *xmln)
+	default:
+		if len(nPort) > 0 {
+			p.errorAt(nameTok.pos, "processing node %s cannot be linked to a port", n.NName)
+		}
+		xmln := backend.XmlProcessingNodeNew(n.NName, n.NType)
+		xmln.InPort, xmln.OutPort = n.InPort, n.OutPort
+		xmln.Param = params
+		g.ProcessingNodes = append(g.ProcessingNodes, *xmln)
+	}
+}
+
+func (p *parser) paramValues() (params []backend.XmlParamValue) {
+	p.next()
+	p.expect(tLParen)
+	for p.tok.kind != tRParen {
+		if len(params) > 0 {
+			p.expect(tComma)
+		}
+		name := p.name().text
+		p.expect(tColon)
+		params = append(params, *backend.XmlParamValueNew(name, p.value().text))
+	}
+	p.next()
+	return
+}
+
+func (p *parser) endpoint() (node, port string) {
+	node = p.name().text
+	if p.tok.kind == tDot {
+		p.next()
+		port = p.name().text
+	}
+	return
+}
+
+func (p *parser) connection(g *backend.XmlSignalGraph, info *graphInfo) {
+	pos := p.tok.pos
+	from, fromPort := p.endpoint()
+	p.expect(tArrow)
+	to, toPort := p.endpoint()
+	p.expect(tSemicolon)
+	g.Connections = append(g.Connections, *backend.XmlConnectNew(from, to, fromPort, toPort))
+	info.conns = append(info.conns, pos)
+}
+
+func (p *parser) checkConnections(g *backend.XmlSignalGraph, info *graphInfo) {
+	for i, c := range g.Connections {
+		if _, ok := info.nodePos[c.From]; !ok {
+			p.errorAt(info.conns[i], "connection from undeclared node %s", c.From)
+		}
+		if _, ok := info.nodePos[c.To]; !ok {
+			p.errorAt(info.conns[i], "connection to undeclared node %s", c.To)
+		}
+	}
+}
+
+//
+//      Libraries
+//
+
+func (p *parser) parseLibraryBody(l *backend.XmlLibrary) {
+	p.start()
+	declared := make(map[string]Pos)
+	declare := func(kind string, t token) {
+		key := kind + " " + t.text
+		if pos, ok := declared[key]; ok {
+			p.errorAt(t.pos, "%s %s already declared at %s", kind, t.text, pos)
+			return
+		}
+		declared[key] = t.pos
+	}
+	for p.tok.kind != tEOF {
+		if p.tok.kind != tIdent {
+			p.fail(p.tok.pos, "expected declaration, found %s", p.tok)
+		}
+		switch p.tok.text {
+		case "include":
+			p.next()
+			l.Libraries = append(l.Libraries, *backend.XmlLibraryRefNew(p.str().text))
+			p.expect(tSemicolon)
+		case "version":
+			p.next()
+			l.Version = p.str().text
+			p.expect(tSemicolon)
+		case "signal":
+			p.next()
+			t := p.name()
+			declare("signal", t)
+			l.SignalTypes = append(l.SignalTypes, p.signalAttributes(t.text))
+		case "nodetype":
+			p.next()
+			t := p.name()
+			declare("nodetype", t)
+			l.NodeTypes = append(l.NodeTypes, p.nodeType(t.text))
+		case "compatible":
+			p.next()
+			t := p.tok
+			c := p.compatible()
+			t.text = fmt.Sprintf("%s -> %s", c.From, c.To)
+			declare("compatible", t)
+			l.Compatible = append(l.Compatible, c)
+		default:
+			p.fail(p.tok.pos, "expected declaration, found %s", p.tok)
+		}
+	}
+}
+
+func (p *parser) enumValue(kind string, values []string) string {
+	t := p.name()
+	for _, v := range values {
+		if v == t.text {
+			return v
+		}
+	}
+	p.errorAt(t.pos, "invalid %s %q, expected one of %q", kind, t.text, values[1:])
+	return t.text
+}
+
+func (p *parser) signalAttributes(name string) backend.XmlSignalType {
+	st := backend.XmlSignalTypeNew(name, "", "", "", "")
+	for p.tok.kind != tSemicolon && p.tok.kind != tLBrace {
+		if p.tok.kind != tIdent {
+			p.fail(p.tok.pos, "expected signal attribute, ';' or '{', found %s", p.tok)
+		}
+		attr := p.next()
+		switch attr.text {
+		case "ctype":
+			st.Ctype = p.name().text
+		case "msgid":
+			st.Msgid = p.name().text
+		case "scope":
+			st.Scope = p.enumValue("scope", scopeValues)
+		case "mode":
+			st.Mode = p.enumValue("mode", modeValues)
+		default:
+			p.fail(attr.pos, "unknown signal attribute %s", attr.text)
+		}
+	}
+	if p.tok.kind == tLBrace {
+		p.next()
+		for p.tok.kind != tRBrace {
+			if !p.isKeyword("field") {
+				p.fail(p.tok.pos, "expected field or '}', found %s", p.tok)
+			}
+			st.Field = append(st.Field, p.field())
+		}
+	}
+	p.next()
+	return *st
+}
+
+func (p *parser) field() backend.XmlSignalField {
+	p.next()
+	name := p.name().text
+	p.expect(tColon)
+	f := backend.XmlSignalFieldNew(name, p.name().text)
+	for p.tok.kind != tSemicolon {
+		if p.tok.kind != tIdent {
+			p.fail(p.tok.pos, "expected field attribute or ';', found %s", p.tok)
+		}
+		attr := p.next()
+		switch attr.text {
+		case "array":
+			f.Array = p.expect(tNumber).text
+		case "unit":
+			f.Unit = p.value().text
+		case "min":
+			f.Min = p.value().text
+		case "max":
+			f.Max = p.value().text
+		default:
+			p.fail(attr.pos, "unknown field attribute %s", attr.text)
+		}
+	}
+	p.next()
+	return *f
+}
+
+func (p *parser) compatible() backend.XmlCompatible {
+	from := p.name().text
+	p.expect(tArrow)
+	to := p.name().text
+	c := backend.XmlCompatibleNew(from, to, p.enumValue("compatibility", compatValues), "")
+	if c.Kind == "convertible" {
+		if !p.isKeyword("via") {
+			p.fail(p.tok.pos, "expected via, found %s", p.tok)
+		}
+		p.next()
+		c.Converter = p.name().text
+	}
+	p.expect(tSemicolon)
+	return *c
+}
+
+func (p *parser) nodeType(name string) backend.XmlNodeType {
+	nt := backend.XmlNodeTypeNew(name)
+	if p.isKeyword("in") {
+		p.next()
+		nt.InPort = inPorts(p.portList())
+	}
+	if p.isKeyword("out") {
+		p.next()
+		nt.OutPort = outPorts(p.portList())
+	}
+	if p.tok.kind == tSemicolon {
+		p.next()
+		return *nt
+	}
+	p.expect(tLBrace)
+	for p.tok.kind != tRBrace {
+		if p.isKeyword("param") {
+			nt.Parameter = append(nt.Parameter, p.parameter())
+			continue
+		}
+		if !p.isKeyword("implementation") {
+			p.fail(p.tok.pos, "expected param, implementation or '}', found %s", p.tok)
+		}
+		p.next()
+		impl := backend.XmlImplementationNew(p.name().text)
+		if p.tok.kind == tSemicolon {
+			p.next()
+		} else if p.isKeyword("graph") {
+			p.next()
+			impl.GraphRef = p.str().text
+			p.expect(tSemicolon)
+		} else {
+			p.expect(tLBrace)
+			g := backend.XmlSignalGraphNew()
+			p.parseGraphBody(g, tRBrace)
+			p.next()
+			impl.SignalGraph = append(impl.SignalGraph, *g)
+		}
+		nt.Implementation = append(nt.Implementation, *impl)
+	}
+	p.next()
+	return *nt
+}
+
+func (p *parser) parameter() backend.XmlParameter {
+	p.next()
+	name := p.name().text
+	p.expect(tColon)
+	par := backend.XmlParameterNew(name, p.enumValue("parameter type", paramTypeValues))
+	if p.tok.kind == tLParen {
+		p.next()
+		var values []string
+		for p.tok.kind != tRParen {
+			if len(values) > 0 {
+				p.expect(tComma)
+			}
+			values = append(values, p.value().text)
+		}
+		p.next()
+		par.Values = strings.Join(values, ",")
+	}
+	for p.tok.kind != tSemicolon {
+		if p.tok.kind != tIdent {
+			p.fail(p.tok.pos, "expected parameter attribute or ';', found %s", p.tok)
+		}
+		attr := p.next()
+		switch attr.text {
+		case "default":
+			par.Default = p.value().text
+		case "min":
+			par.Min = p.value().text
+		case "max":
+			par.Max = p.value().text
+		default:
+			p.fail(attr.pos, "unknown parameter attribute %s", attr.text)
+		}
+	}
+	p.next()
+	return *par
+}
@@ -0,0 +1,154 @@
+package dsl
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	"testing"
+)
+
+func TestGraphText(t *testing.T) {
+	case1 := []struct {
+		text               string
+		nodes, connections int
+	}{
+		{`include "test.alml";
+
+input sensor (s1);
+output actuator (s1);
+node test : Test;
+
+sensor -> test;
+test -> actuator;
+`, 3, 2},
+		{`include "fir.alml";
+
+input src (out: sample);
+output "sink-1" (in: sample);
+node fir1 : FIR;
+node input in (a: sample, b: sample) out (sample);
+node fir2 : FIR param (taps: 32, gain: 0.5, window: hann, shift: "$taps/2");
+
+src.out -> fir1.in;
+fir1.out -> "input".a;
+"input" -> "sink-1".in;
+`, 5, 3},
+	}
+
+	for i, c := range case1 {
+		g, err := ParseSignalGraph("test.sml.sgt", []byte(c.text))
+		if err != nil {
+			t.Errorf("Testcase %d: Failed to parse: %v", i, err)
+			continue
+		}
+		nodes := len(g.InputNodes) + len(g.OutputNodes) + len(g.ProcessingNodes)
+		if nodes != c.nodes {
+			t.Errorf("Testcase %d: node count mismatch: %d", i, nodes)
+		}
+		if len(g.Connections) != c.connections {
+			t.Errorf("Testcase %d: connection count mismatch: %d", i, len(g.Connections))
+		}
+		text := string(PrintSignalGraph(g))
+		if text != c.text {
+			t.Errorf("Testcase %d: pretty printer mismatch:\n%s\n%s", i, c.text, text)
+		}
+	}
+}
+
+func TestLibraryText(t *testing.T) {
+	text := `signal s1 ctype "int" scope local mode sync;
+signal s2;
+signal pos {
+	field xyz: float32 array 3 unit m min "-10" max 10;
+	field valid: bool;
+	field raw: s1 array 2;
+}
+
+nodetype Test in (i: s1) out (o: s1);
+
+nodetype Sub in (s1) out (s1) {
+	param taps: int default 16 min 1 max 64;
+	param window: enum (hann, flat) default hann;
+	implementation graph {
+		input i (s1);
+		output o (s1);
+		node t : Test param (n: "$taps*2");
+
+		i -> t;
+		t -> o;
+	}
+	implementation c;
+	implementation sub graph "sub/sub.sml";
+}
+
+compatible s2 -> s1 subtype;
+compatible s1 -> pos convertible via Test;
+`
+	l, err := ParseLibrary("test.alml.sgt", []byte(text))
+	if err != nil {
+		t.Errorf("Failed to parse: %v", err)
+		return
+	}
+	if len(l.SignalTypes) != 3 || len(l.NodeTypes) != 2 {
+		t.Errorf("type count mismatch")
+	}
+	if len(l.NodeTypes[1].Implementation) != 3 || len(l.NodeTypes[1].Implementation[0].SignalGraph) != 1 {
+		t.Errorf("implementation mismatch")
+	}
+	if l.NodeTypes[1].Implementation[2].GraphRef != "sub/sub.sml" {
+		t.Errorf("graph reference mismatch")
+	}
+	if f := l.SignalTypes[2].Field; len(f) != 3 || f[0].Array != "3" || f[0].Unit != "m" || f[0].Min != "-10" {
+		t.Errorf("field mismatch")
+	}
+	if len(l.NodeTypes[1].Parameter) != 2 || l.NodeTypes[1].Parameter[1].Values != "hann,flat" {
+		t.Errorf("parameter mismatch")
+	}
+	if c := l.Compatible; len(c) != 2 || c[0].Kind != "subtype" || c[1].Converter != "Test" {
+		t.Errorf("compatibility mismatch")
+	}
+	out := string(PrintLibrary(l))
+	if out != text {
+		t.Errorf("pretty printer mismatch:\n%s\n%s", text, out)
+	}
+	xmlText, err := l.Write()
+	if err != nil {
+		t.Errorf("Failed to write XML: %v", err)
+		return
+	}
+	l2 := backend.XmlLibraryNew()
+	_, err = l2.Read(xmlText)
+	if err != nil || string(PrintLibrary(l2)) != text {
+		t.Errorf("XML round trip mismatch: %v", err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	case1 := []struct {
+		text, err string
+	}{
+		{"node a : A;\nnode a : B;\n", "g.sgt:2:6: node a already declared at g.sgt:1:6"},
+		{"node a : A;\n  a -> b;\n", "g.sgt:2:3: connection to undeclared node b"},
+		{"node a : A\nnode b : B;\n", "g.sgt:2:1: expected ';', found identifier node"},
+		{"node a : A;\n\"a -> b;\n", "g.sgt:2:1: unterminated string"},
+		{"/* node a : A;\n", "g.sgt:1:1: unterminated comment"},
+		{"node a : A;\na -> #;\n", "g.sgt:2:6: unexpected character '#'"},
+		{"input a (s) param (n: 1);\n", "g.sgt:1:7: input node a cannot have parameters"},
+	}
+	for i, c := range case1 {
+		_, err := ParseSignalGraph("g.sgt", []byte(c.text))
+		if err == nil {
+			t.Errorf("Testcase %d: error expected", i)
+			continue
+		}
+		if err.Error() != c.err {
+			t.Errorf("Testcase %d: error mismatch: %s", i, err)
+		}
+	}
+	_, err := ParseLibrary("l.sgt", []byte("signal s scope private;\n"))
+	if err == nil || err.Error() != `l.sgt:1:16: invalid scope "private", expected one of ["local" "global"]` {
+		t.Errorf("enum error mismatch: %v", err)
+	}
+	_, err = ParseLibrary("l.sgt", []byte("compatible a -> b convertible;\n"))
+	if err == nil || err.Error() != "l.sgt:1:30: expected via, found ';'" {
+		t.Errorf("converter error mismatch: %v", err)
+	}
+}
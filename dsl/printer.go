@@ -0,0 +1,313 @@
+package dsl
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var keywords = map[string]bool{
+	"include":        true,
+	"version":        true,
+	"input":          true,
+	"output":         true,
+	"node":           true,
+	"port":           true,
+	"in":             true,
+	"out":            true,
+	"signal":         true,
+	"nodetype":       true,
+	"implementation": true,
+	"ctype":          true,
+	"msgid":          true,
+	"scope":          true,
+	"mode":           true,
+	"param":          true,
+	"default":        true,
+	"min":            true,
+	"max":            true,
+	"field":          true,
+	"array":          true,
+	"unit":           true,
+	"compatible":     true,
+	"via":            true,
+}
+
+const defaultVersion = "1.0"
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+// Ports of typed processing nodes are not printed, they are
+// given by the node type.
+func PrintSignalGraph(g *backend.XmlSignalGraph) []byte {
+	p := &printer{}
+	p.graph(g)
+	return p.buf.Bytes()
+}
+
+func PrintLibrary(l *backend.XmlLibrary) []byte {
+	p := &printer{}
+	p.library(l)
+	return p.buf.Bytes()
+}
+
+func quoteName(s string) string {
+	if len(s) == 0 {
+		return strconv.Quote(s)
+	}
+	for i, r := range s {
+		if !isIdentChar(r) || (i == 0 && !isIdentStart(r)) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// Values are printed as numbers if the lexer reads them as one.
+func valueText(s string) string {
+	for i, r := range s {
+		if !(isIdentChar(r) || r == '.') || (i == 0 && !unicode.IsDigit(r)) {
+			return quoteName(s)
+		}
+	}
+	if len(s) == 0 {
+		return quoteName(s)
+	}
+	return s
+}
+
+func (p *printer) line(format string, args ...interface{}) {
+	if len(format) > 0 {
+		p.buf.WriteString(strings.Repeat("\t", p.indent))
+		fmt.Fprintf(&p.buf, format, args...)
+	}
+	p.buf.WriteByte('\n')
+}
+
+func portList(ports []backend.XmlPort) string {
+	var list []string
+	for _, pt := range ports {
+		if len(pt.PName) > 0 {
+			list = append(list, fmt.Sprintf("%s: %s", quoteName(pt.PName), quoteName(pt.PType)))
+		} else {
+			list = append(list, quoteName(pt.PType))
+		}
+	}
+	return fmt.Sprintf("(%s)", strings.Join(list, ", "))
+}
+
+func xmlInPorts(ports []backend.XmlInPort) (ret []backend.XmlPort) {
+	for _, pt := range ports {
+		ret = append(ret, pt.XmlPort)
+	}
+	return
+}
+
+func xmlOutPorts(ports []backend.XmlOutPort) (ret []backend.XmlPort) {
+	for _, pt := range ports {
+		ret = append(ret, pt.XmlPort)
+	}
+	return
+}
+
+func (p *printer) header(version string, refs []backend.XmlLibraryRef) {
+	if version != defaultVersion {
+		p.line("version %s;", strconv.Quote(version))
+	}
+	for _, ref := range refs {
+		p.line("include %s;", strconv.Quote(ref.Name))
+	}
+	if version != defaultVersion || len(refs) > 0 {
+		p.line("")
+	}
+}
+
+func (p *printer) node(kind string, n backend.XmlNode, nPort string, printPorts bool) {
+	text := fmt.Sprintf("%s %s", kind, quoteName(n.NName))
+	if len(n.NType) > 0 {
+		text = fmt.Sprintf("%s : %s", text, quoteName(n.NType))
+	}
+	if len(nPort) > 0 {
+		text = fmt.Sprintf("%s port %s", text, quoteName(nPort))
+	}
+	if printPorts {
+		in, out := n.InPort, n.OutPort
+		switch kind {
+		case "input":
+			if len(out) > 0 {
+				text = fmt.Sprintf("%s %s", text, portList(xmlOutPorts(out)))
+				out = nil
+			}
+		case "output":
+			if len(in) > 0 {
+				text = fmt.Sprintf("%s %s", text, portList(xmlInPorts(in)))
+				in = nil
+			}
+		}
+		if len(in) > 0 {
+			text = fmt.Sprintf("%s in %s", text, portList(xmlInPorts(in)))
+		}
+		if len(out) > 0 {
+			text = fmt.Sprintf("%s out %s", text, portList(xmlOutPorts(out)))
+		}
+	}
+	if len(n.Param) > 0 {
+		var list []string
+		for _, v := range n.Param {
+			list = append(list, fmt.Sprintf("%s: %s", quoteName(v.Name), valueText(v.Value)))
+		}
+		text = fmt.Sprintf("%s param (%s)", text, strings.Join(list, ", "))
+	}
+	p.line("%s;", text)
+}
+
+// Node names starting a connection must not be mistaken for keywords.
+func endpoint(node, port string) string {
+	name := quoteName(node)
+	if keywords[node] {
+		name = strconv.Quote(node)
+	}
+	if len(port) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", name, quoteName(port))
+}
+
+func (p *printer) graph(g *backend.XmlSignalGraph) {
+	p.header(g.Version, g.Libraries)
+	for _, n := range g.InputNodes {
+		p.node("input", n.XmlNode, n.NPort, true)
+	}
+	for _, n := range g.OutputNodes {
+		p.node("output", n.XmlNode, n.NPort, true)
+	}
+	for _, n := range g.ProcessingNodes {
+		p.node("node", n.XmlNode, "", len(n.NType) == 0)
+	}
+	if len(g.Connections) > 0 {
+		p.line("")
+	}
+	for _, c := range g.Connections {
+		p.line("%s -> %s;", endpoint(c.From, c.FromPort), endpoint(c.To, c.ToPort))
+	}
+}
+
+func (p *printer) library(l *backend.XmlLibrary) {
+	p.header(l.Version, l.Libraries)
+	for _, st := range l.SignalTypes {
+		text := fmt.Sprintf("signal %s", quoteName(st.Name))
+		if len(st.Ctype) > 0 {
+			text = fmt.Sprintf("%s ctype %s", text, strconv.Quote(st.Ctype))
+		}
+		if len(st.Msgid) > 0 {
+			text = fmt.Sprintf("%s msgid %s", text, strconv.Quote(st.Msgid))
+		}
+		if len(st.Scope) > 0 {
+			text = fmt.Sprintf("%s scope %s", text, quoteName(st.Scope))
+		}
+		if len(st.Mode) > 0 {
+			text = fmt.Sprintf("%s mode %s", text, quoteName(st.Mode))
+		}
+		if len(st.Field) == 0 {
+			p.line("%s;", text)
+			continue
+		}
+		p.line("%s {", text)
+		p.indent++
+		for _, f := range st.Field {
+			text := fmt.Sprintf("field %s: %s", quoteName(f.Name), quoteName(f.FType))
+			if len(f.Array) > 0 {
+				text = fmt.Sprintf("%s array %s", text, f.Array)
+			}
+			if len(f.Unit) > 0 {
+				text = fmt.Sprintf("%s unit %s", text, valueText(f.Unit))
+			}
+			if len(f.Min) > 0 {
+				text = fmt.Sprintf("%s min %s", text, valueText(f.Min))
+			}
+			if len(f.Max) > 0 {
+				text = fmt.Sprintf("%s max %s", text, valueText(f.Max))
+			}
+			p.line("%s;", text)
+		}
+		p.indent--
+		p.line("}")
+	}
+	for _, nt := range l.NodeTypes {
+		if p.buf.Len() > 0 {
+			p.line("")
+		}
+		text := fmt.Sprintf("nodetype %s", quoteName(nt.TypeName))
+		if len(nt.InPort) > 0 {
+			text = fmt.Sprintf("%s in %s", text, portList(xmlInPorts(nt.InPort)))
+		}
+		if len(nt.OutPort) > 0 {
+			text = fmt.Sprintf("%s out %s", text, portList(xmlOutPorts(nt.OutPort)))
+		}
+		if len(nt.Parameter)+len(nt.Implementation) == 0 {
+			p.line("%s;", text)
+			continue
+		}
+		p.line("%s {", text)
+		p.indent++
+		for _, par := range nt.Parameter {
+			p.parameter(par)
+		}
+		for _, impl := range nt.Implementation {
+			if len(impl.GraphRef) > 0 {
+				p.line("implementation %s graph %s;", quoteName(impl.Name), strconv.Quote(impl.GraphRef))
+				continue
+			}
+			if len(impl.SignalGraph) == 0 {
+				p.line("implementation %s;", quoteName(impl.Name))
+				continue
+			}
+			p.line("implementation %s {", quoteName(impl.Name))
+			p.indent++
+			for i := range impl.SignalGraph {
+				p.graph(&impl.SignalGraph[i])
+			}
+			p.indent--
+			p.line("}")
+		}
+		p.indent--
+		p.line("}")
+	}
+	if len(l.Compatible) > 0 && p.buf.Len() > 0 {
+		p.line("")
+	}
+	for _, c := range l.Compatible {
+		text := fmt.Sprintf("compatible %s -> %s %s", quoteName(c.From), quoteName(c.To), c.Kind)
+		if len(c.Converter) > 0 {
+			text = fmt.Sprintf("%s via %s", text, quoteName(c.Converter))
+		}
+		p.line("%s;", text)
+	}
+}
+
+func (p *printer) parameter(par backend.XmlParameter) {
+	text := fmt.Sprintf("param %s: %s", quoteName(par.Name), quoteName(par.PType))
+	if len(par.Values) > 0 {
+		var list []string
+		for _, v := range strings.Split(par.Values, ",") {
+			list = append(list, valueText(v))
+		}
+		text = fmt.Sprintf("%s (%s)", text, strings.Join(list, ", "))
+	}
+	if len(par.Default) > 0 {
+		text = fmt.Sprintf("%s default %s", text, valueText(par.Default))
+	}
+	if len(par.Min) > 0 {
+		text = fmt.Sprintf("%s min %s", text, valueText(par.Min))
+	}
+	if len(par.Max) > 0 {
+		text = fmt.Sprintf("%s max %s", text, valueText(par.Max))
+	}
+	p.line("%s;", text)
+}
@@ -2,6 +2,7 @@ package filemanager
 
 import (
 	"fmt"
+	"github.com/axel-freesp/sge/backend"
 	"github.com/axel-freesp/sge/tool"
 	"log"
 )
@@ -22,10 +23,23 @@ func (f *filenameFactory) NewFilename() (name string) {
 }
 
 func (f filenameFactory) HintFilename(filename string) (name string) {
-	if tool.Suffix(filename) != f.suffix {
+	if tool.DocSuffix(filename) != f.suffix {
 		log.Panicf("filenameFactory.HintFilename: invalid suffix\n")
 		return
 	}
-	name = fmt.Sprintf("%s-%s.hints.xml", tool.Prefix(filename), f.suffix)
-	return
+	return backend.HintFilename(filename)
+}
+
+// Let the backend know about a renamed document (and its hint file),
+// such that content it does not model is written to the new file.
+func (f filenameFactory) DocumentRenamed(pathPrefix, oldName, newName string) {
+	oldpath, newpath := oldName, newName
+	if len(pathPrefix) > 0 {
+		oldpath = fmt.Sprintf("%s/%s", pathPrefix, oldName)
+		newpath = fmt.Sprintf("%s/%s", pathPrefix, newName)
+	}
+	backend.DocumentRenamed(oldpath, newpath)
+	if tool.DocSuffix(oldpath) == f.suffix && tool.DocSuffix(newpath) == f.suffix {
+		backend.DocumentRenamed(f.HintFilename(oldpath), f.HintFilename(newpath))
+	}
 }
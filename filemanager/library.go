@@ -3,7 +3,9 @@ package filemanager
 import (
 	"fmt"
 	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
 	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/deps"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
@@ -14,12 +16,13 @@ type fileManagerLib struct {
 	filenameFactory
 	context    FilemanagerContextIf
 	libraryMap map[string]bh.LibraryIf
+	loading    map[string]bool // absolute paths of libraries being read
 }
 
 var _ mod.FileManagerIf = (*fileManagerLib)(nil)
 
 func FileManagerLibNew(context FilemanagerContextIf) *fileManagerLib {
-	return &fileManagerLib{FilenameFactoryInit("alml"), context, make(map[string]bh.LibraryIf)}
+	return &fileManagerLib{FilenameFactoryInit("alml"), context, make(map[string]bh.LibraryIf), make(map[string]bool)}
 }
 
 //
@@ -41,25 +44,78 @@ func (f *fileManagerLib) New() (lib tr.ToplevelTreeElementIf, err error) {
 }
 
 func (f *fileManagerLib) Access(name string) (lib tr.ToplevelTreeElementIf, err error) {
-	var ok bool
-	lib, ok = f.libraryMap[name]
+	// open libraries are accessed by name
+	lib, ok := f.libraryMap[name]
 	if ok {
 		return
 	}
-	lib = behaviour.LibraryNew(name, f.context)
-	var filedir string
-	for _, filedir := range backend.XmlSearchPaths() {
-		err = lib.ReadFile(fmt.Sprintf("%s/%s", filedir, name))
+	return f.AccessRef(name, "")
+}
+
+// AccessRef returns the library name refers to from a document stored
+// in dir. Libraries are identified by the absolute path they were
+// loaded from. Types are registered by library name, so a library of
+// the same name from another file cannot be loaded.
+func (f *fileManagerLib) AccessRef(name, dir string) (lib tr.ToplevelTreeElementIf, err error) {
+	reg := freesp.RegistryOf(f.context)
+	var res backend.RefResolution
+	res, err = backend.ResolveRef(name, dir)
+	if err != nil {
+		// not saved yet
+		var ok bool
+		lib, ok = f.libraryMap[name]
+		if ok {
+			err = nil
+			return
+		}
+		err = fmt.Errorf("fileManagerLib.Access: library file %s not found: %v", name, err)
+		return
+	}
+	path := res.AbsPath()
+	if l, ok := reg.GetLibraryByPath(path); ok {
+		lib = l
+		return
+	}
+	if l, ok := f.libraryMap[name]; ok {
+		loaded, ok := reg.LibraryPath(l)
+		if ok {
+			err = fmt.Errorf("fileManagerLib.Access: library %s from %s conflicts with %s loaded from %s",
+				name, path, name, loaded)
+			return
+		}
+		// created or renamed, and saved since
+		reg.RegisterLibraryPath(l, path)
+		lib = l
+		return
+	}
+	if f.loading[path] {
+		// a library referring back to one still being read
+		err = deps.CycleError(path)
 		if err == nil {
-			break
+			err = fmt.Errorf("%s is referenced while it is read", path)
 		}
+		err = freesp.InvalidOperationError("fileManagerLib.Access", "%s", err)
+		return
+	}
+	log.Printf("fileManagerLib.Access: %s\n", res)
+	if res.Ambiguous() {
+		log.Printf("fileManagerLib.Access: %s\n", res.Warning())
+	}
+	lib = behaviour.LibraryNew(name, f.context)
+	f.loading[path] = true
+	err = lib.ReadFile(res.Path)
+	delete(f.loading, path)
+	if _, ok := freesp.ErrorKindOf(err); ok {
+		// keep the kind of reference cycles (see library.Read)
+		return
 	}
 	if err != nil {
-		err = fmt.Errorf("fileManagerLib.Access: library file %s not found", name)
+		err = fmt.Errorf("fileManagerLib.Access: %s", err)
 		return
 	}
-	lib.SetPathPrefix(filedir)
+	lib.SetPathPrefix(res.Base)
 	f.libraryMap[name] = lib.(bh.LibraryIf)
+	reg.RegisterLibraryPath(lib.(bh.LibraryIf), path)
 	var newId string
 	newId, err = f.context.FTS().AddToplevel(lib.(bh.LibraryIf))
 	if err != nil {
@@ -67,10 +123,6 @@ func (f *fileManagerLib) Access(name string) (lib tr.ToplevelTreeElementIf, err
 		return
 	}
 	f.context.FTV().SelectId(newId)
-	if err != nil {
-		err = fmt.Errorf("fileManagerLib.Access: %s", err)
-		return
-	}
 	log.Printf("fileManagerLib.Access: library %s successfully loaded\n", name)
 	return
 }
@@ -113,6 +165,7 @@ func (f *fileManagerLib) Rename(oldName, newName string) (err error) {
 	id, _ := f.context.FTS().GetToplevelId(lib)
 	f.context.FTS().SetValueById(id, newName)
 	delete(f.libraryMap, oldName)
+	f.DocumentRenamed(lib.PathPrefix(), oldName, newName)
 	lib.SetFilename(newName)
 	f.libraryMap[newName] = lib
 	log.Printf("fileManagerLib.Access: library %s successfully renamed to %d\n", oldName, newName)
@@ -112,7 +112,7 @@ func (f *fileManagerMap) Access(name string) (m tr.ToplevelTreeElementIf, err er
 	var buf []byte
 	buf, err = tool.ReadFile(hintfilename)
 	if err == nil {
-		_, err = hint.Read(buf)
+		_, err = backend.DocumentRead(hint, buf, hintfilename)
 		if err != nil {
 			return
 		}
@@ -239,6 +239,7 @@ func (f *fileManagerMap) Rename(oldName, newName string) (err error) {
 	f.context.FTS().SetValueById(id, newName)
 	f.context.GVC().Rename(oldName, newName)
 	delete(f.mappingMap, oldName)
+	f.DocumentRenamed(m.PathPrefix(), oldName, newName)
 	m.SetFilename(newName)
 	f.mappingMap[newName] = m
 	return
@@ -275,7 +276,7 @@ func (f *fileManagerMap) Store(name string) (err error) {
 	hint := mapping.CreateXmlMappingHint(m)
 	hintfilename := f.HintFilename(filename)
 	var buf []byte
-	buf, err = hint.Write()
+	buf, err = backend.DocumentWrite(hint, hintfilename)
 	if err != nil {
 		return
 	}
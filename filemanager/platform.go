@@ -31,7 +31,7 @@ func FileManagerPFNew(context FilemanagerContextIf) *fileManagerPF {
 
 func (f *fileManagerPF) New() (pl tr.ToplevelTreeElementIf, err error) {
 	filename := f.NewFilename()
-	pl = platform.PlatformNew(filename)
+	pl = platform.PlatformNew(filename, f.context)
 	f.platformMap[filename] = pl.(pf.PlatformIf)
 	var newId string
 	newId, err = f.context.FTS().AddToplevel(pl.(pf.PlatformIf))
@@ -57,7 +57,7 @@ func (f *fileManagerPF) Access(name string) (pl tr.ToplevelTreeElementIf, err er
 	if ok {
 		return
 	}
-	pl = platform.PlatformNew(name)
+	pl = platform.PlatformNew(name, f.context)
 	var filedir string
 	for _, filedir = range backend.XmlSearchPaths() {
 		err = pl.ReadFile(fmt.Sprintf("%s/%s", filedir, name))
@@ -83,7 +83,7 @@ func (f *fileManagerPF) Access(name string) (pl tr.ToplevelTreeElementIf, err er
 	var buf []byte
 	buf, err = tool.ReadFile(hintfilename)
 	if err == nil {
-		_, err = hint.Read(buf)
+		_, err = backend.DocumentRead(hint, buf, hintfilename)
 		if err != nil {
 			return
 		}
@@ -123,7 +123,7 @@ func (f *fileManagerPF) Remove(name string) {
 	}
 	for _, a := range pl.Arch() {
 		for _, iot := range a.IOTypes() {
-			freesp.RemoveRegisteredIOType(iot)
+			freesp.RegistryOf(f.context).RemoveRegisteredIOType(iot)
 		}
 	}
 	delete(f.platformMap, name)
@@ -147,6 +147,7 @@ func (f *fileManagerPF) Rename(oldName, newName string) (err error) {
 	f.context.FTS().SetValueById(id, newName)
 	f.context.GVC().Rename(oldName, newName)
 	delete(f.platformMap, oldName)
+	f.DocumentRenamed(pl.PathPrefix(), oldName, newName)
 	pl.SetFilename(newName)
 	f.platformMap[newName] = pl
 	return
@@ -171,7 +172,7 @@ func (f *fileManagerPF) Store(name string) (err error) {
 	hint := platform.CreateXmlPlatformHint(pl)
 	hintfilename := f.HintFilename(filename)
 	var buf []byte
-	buf, err = hint.Write()
+	buf, err = backend.DocumentWrite(hint, hintfilename)
 	if err != nil {
 		return
 	}
@@ -16,12 +16,14 @@ type fileManagerSG struct {
 	filenameFactory
 	context        FilemanagerContextIf
 	signalGraphMap map[string]bh.SignalGraphIf
+	graphPaths     map[string]bh.SignalGraphIf // by absolute path
 }
 
-var _ mod.FileManagerIf = (*fileManagerSG)(nil)
+var _ mod.FileManagerSignalGraphIf = (*fileManagerSG)(nil)
 
 func FileManagerSGNew(context FilemanagerContextIf) *fileManagerSG {
-	return &fileManagerSG{FilenameFactoryInit("sml"), context, make(map[string]bh.SignalGraphIf)}
+	return &fileManagerSG{FilenameFactoryInit("sml"), context, make(map[string]bh.SignalGraphIf),
+		make(map[string]bh.SignalGraphIf)}
 }
 
 //
@@ -51,36 +53,65 @@ func (f *fileManagerSG) New() (sg tr.ToplevelTreeElementIf, err error) {
 }
 
 func (f *fileManagerSG) Access(name string) (sg tr.ToplevelTreeElementIf, err error) {
-	var ok bool
-	sg, ok = f.signalGraphMap[name]
+	// open graphs are accessed by name
+	sg, ok := f.signalGraphMap[name]
 	if ok {
 		return
 	}
-	sg = behaviour.SignalGraphNew(name, f.context)
-	var filedir string
-	for _, filedir = range backend.XmlSearchPaths() {
-		err = sg.ReadFile(fmt.Sprintf("%s/%s", filedir, name))
-		if err == nil {
-			break
-		}
-	}
+	return f.AccessRef(name, "")
+}
+
+// AccessRef returns the graph name refers to from a document stored in
+// dir. Graphs are identified by the absolute path they were loaded
+// from; graph views are kept by name, so a graph of the same name from
+// another file cannot be loaded.
+func (f *fileManagerSG) AccessRef(name, dir string) (sg tr.ToplevelTreeElementIf, err error) {
+	var res backend.RefResolution
+	res, err = backend.ResolveRef(name, dir)
 	if err != nil {
+		// not saved yet
+		var ok bool
+		sg, ok = f.signalGraphMap[name]
+		if ok {
+			err = nil
+			return
+		}
 		err = fmt.Errorf("fileManagerSG.Access: graph file %s not found.", name)
 		return
 	}
-	var filename string
-	if len(filedir) > 0 {
-		filename = fmt.Sprintf("%s/%s", filedir, name)
-	} else {
-		filename = name
+	path := res.AbsPath()
+	if g, ok := f.graphPaths[path]; ok {
+		sg = g
+		return
+	}
+	if g, ok := f.signalGraphMap[name]; ok {
+		loaded, ok := f.pathOf(g)
+		if ok {
+			err = fmt.Errorf("fileManagerSG.Access: graph %s from %s conflicts with %s loaded from %s",
+				name, path, name, loaded)
+			return
+		}
+		// created or renamed, and saved since
+		f.graphPaths[path] = g
+		sg = g
+		return
 	}
-	sg.SetPathPrefix(filedir)
+	if res.Ambiguous() {
+		log.Printf("fileManagerSG.Access: %s\n", res.Warning())
+	}
+	sg = behaviour.SignalGraphNew(name, f.context)
+	err = sg.ReadFile(res.Path)
+	if err != nil {
+		err = fmt.Errorf("fileManagerSG.Access: %s", err)
+		return
+	}
+	sg.SetPathPrefix(res.Base)
 	hint := backend.XmlGraphHintNew(name)
-	hintfilename := f.HintFilename(filename)
+	hintfilename := f.HintFilename(res.Path)
 	var buf []byte
 	buf, err = tool.ReadFile(hintfilename)
 	if err == nil {
-		_, err = hint.Read(buf)
+		_, err = backend.DocumentRead(hint, buf, hintfilename)
 		if err != nil {
 			return
 		}
@@ -106,11 +137,27 @@ func (f *fileManagerSG) Access(name string) (sg tr.ToplevelTreeElementIf, err er
 	}
 	f.context.GVC().Add(gv, name)
 	f.signalGraphMap[name] = sg.(bh.SignalGraphIf)
+	f.graphPaths[path] = sg.(bh.SignalGraphIf)
 	log.Printf("fileManagerSG.Access: graph %s successfully loaded.\n", name)
 
 	return
 }
 
+func (f *fileManagerSG) pathOf(sg bh.SignalGraphIf) (path string, ok bool) {
+	for p, g := range f.graphPaths {
+		if g == sg {
+			return p, true
+		}
+	}
+	return
+}
+
+func (f *fileManagerSG) forgetPath(sg bh.SignalGraphIf) {
+	if path, ok := f.pathOf(sg); ok {
+		delete(f.graphPaths, path)
+	}
+}
+
 func (f *fileManagerSG) Remove(name string) {
 	// TODO: check if used in existing mappings
 	sg, ok := f.signalGraphMap[name]
@@ -119,6 +166,7 @@ func (f *fileManagerSG) Remove(name string) {
 		return
 	}
 	delete(f.signalGraphMap, name)
+	f.forgetPath(sg)
 	var nodes []bh.NodeIf
 	for _, n := range sg.ItsType().Nodes() {
 		nodes = append(nodes, n)
@@ -145,6 +193,9 @@ func (f *fileManagerSG) Rename(oldName, newName string) (err error) {
 	f.context.FTS().SetValueById(id, newName)
 	f.context.GVC().Rename(oldName, newName)
 	delete(f.signalGraphMap, oldName)
+	// stored under another path from now on
+	f.forgetPath(sg)
+	f.DocumentRenamed(sg.PathPrefix(), oldName, newName)
 	sg.SetFilename(newName)
 	f.signalGraphMap[newName] = sg
 	return
@@ -169,7 +220,7 @@ func (f *fileManagerSG) Store(name string) (err error) {
 	hint := behaviour.CreateXmlGraphHint(sg)
 	hintfilename := f.HintFilename(filename)
 	var buf []byte
-	buf, err = hint.Write()
+	buf, err = backend.DocumentWrite(hint, hintfilename)
 	if err != nil {
 		return
 	}
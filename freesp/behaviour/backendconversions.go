@@ -1,14 +1,14 @@
 package behaviour
 
 import (
+	"fmt"
 	"github.com/axel-freesp/sge/backend"
 	"github.com/axel-freesp/sge/freesp"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	"github.com/axel-freesp/sge/tool"
-	//"log"
-	"fmt"
 	"image"
+	"log"
 	"strings"
 )
 
@@ -92,31 +92,31 @@ func CreateXmlIONodePosHint(n bh.NodeIf, path string) (xmln *backend.XmlNodePosH
 //
 
 func CreateXmlInPort(p bh.PortIf) (xmlp *backend.XmlInPort) {
-	xmlp = backend.XmlInPortNew(p.Name(), p.SignalType().TypeName())
+	xmlp = backend.XmlInPortNew(p.Name(), signalTypeRefName(p.SignalType()))
 	xmlp.Entry = freesp.CreateXmlModePosition(p).Entry
 	return
 }
 
 func CreateXmlOutPort(p bh.PortIf) (xmlp *backend.XmlOutPort) {
-	xmlp = backend.XmlOutPortNew(p.Name(), p.SignalType().TypeName())
+	xmlp = backend.XmlOutPortNew(p.Name(), signalTypeRefName(p.SignalType()))
 	xmlp.Entry = freesp.CreateXmlModePosition(p).Entry
 	return
 }
 
 func CreateXmlNamedInPort(p bh.PortTypeIf) (xmlp *backend.XmlInPort) {
-	xmlp = backend.XmlInPortNew(p.Name(), p.SignalType().TypeName())
+	xmlp = backend.XmlInPortNew(p.Name(), signalTypeRefName(p.SignalType()))
 	//xmlp.Entry = freesp.CreateXmlModePosition(p).Entry
 	return
 }
 
 func CreateXmlNamedOutPort(p bh.PortTypeIf) (xmlp *backend.XmlOutPort) {
-	xmlp = backend.XmlOutPortNew(p.Name(), p.SignalType().TypeName())
+	xmlp = backend.XmlOutPortNew(p.Name(), signalTypeRefName(p.SignalType()))
 	//xmlp.Entry = freesp.CreateXmlModePosition(p).Entry
 	return
 }
 
 func CreateXmlInputNode(n bh.NodeIf) *backend.XmlInputNode {
-	tName := n.ItsType().TypeName()
+	tName := nodeTypeRefName(n.ItsType())
 	if strings.HasPrefix(tName, "autoInputNodeType-") {
 		tName = ""
 	}
@@ -131,7 +131,7 @@ func CreateXmlInputNode(n bh.NodeIf) *backend.XmlInputNode {
 }
 
 func CreateXmlOutputNode(n bh.NodeIf) *backend.XmlOutputNode {
-	tName := n.ItsType().TypeName()
+	tName := nodeTypeRefName(n.ItsType())
 	if strings.HasPrefix(tName, "autoOutputNodeType-") {
 		tName = ""
 	}
@@ -146,13 +146,19 @@ func CreateXmlOutputNode(n bh.NodeIf) *backend.XmlOutputNode {
 }
 
 func CreateXmlProcessingNode(n bh.NodeIf) *backend.XmlProcessingNode {
-	ret := backend.XmlProcessingNodeNew(n.Name(), n.ItsType().TypeName())
+	ret := backend.XmlProcessingNodeNew(n.Name(), nodeTypeRefName(n.ItsType()))
 	for _, p := range n.InPorts() {
 		ret.InPort = append(ret.InPort, *CreateXmlInPort(p))
 	}
 	for _, p := range n.OutPorts() {
 		ret.OutPort = append(ret.OutPort, *CreateXmlOutPort(p))
 	}
+	for _, p := range n.ItsType().Parameters() {
+		v, ok := n.ParamValue(p.Name())
+		if ok {
+			ret.Param = append(ret.Param, *backend.XmlParamValueNew(p.Name(), v))
+		}
+	}
 	return ret
 }
 
@@ -164,15 +170,29 @@ func CreateXmlNodeType(t bh.NodeTypeIf) *backend.XmlNodeType {
 	for _, p := range t.OutPorts() {
 		ret.OutPort = append(ret.OutPort, *CreateXmlNamedOutPort(p))
 	}
+	for _, p := range t.Parameters() {
+		ret.Parameter = append(ret.Parameter, *CreateXmlParameter(p))
+	}
 	for _, impl := range t.Implementation() {
 		ret.Implementation = append(ret.Implementation, *CreateXmlImplementation(impl))
 	}
 	return ret
 }
 
+func CreateXmlParameter(p bh.ParameterIf) *backend.XmlParameter {
+	ret := backend.XmlParameterNew(p.Name(), ParamTypeString(p.ParamType()))
+	ret.Default = p.Default()
+	ret.Min = p.Min()
+	ret.Max = p.Max()
+	ret.Values = strings.Join(p.Values(), ",")
+	return ret
+}
+
 func CreateXmlImplementation(impl bh.ImplementationIf) *backend.XmlImplementation {
 	ret := backend.XmlImplementationNew(impl.ElementName())
-	if impl.ImplementationType() == bh.NodeTypeGraph {
+	if len(impl.GraphRef()) > 0 {
+		ret.GraphRef = impl.GraphRef()
+	} else if impl.ImplementationType() == bh.NodeTypeGraph {
 		ret.SignalGraph = append(ret.SignalGraph, *CreateXmlSignalGraphType(impl.Graph()))
 	}
 	return ret
@@ -199,11 +219,25 @@ func CreateXmlSignalType(s bh.SignalTypeIf) *backend.XmlSignalType {
 	if s.Mode() == bh.Synchronous {
 		mode = "sync"
 	}
-	return backend.XmlSignalTypeNew(s.TypeName(), scope, mode, s.CType(), s.ChannelId())
+	ret := backend.XmlSignalTypeNew(s.TypeName(), scope, mode, s.CType(), s.ChannelId())
+	for _, f := range s.Fields() {
+		ret.Field = append(ret.Field, *CreateXmlSignalField(f))
+	}
+	return ret
+}
+
+func CreateXmlSignalField(f bh.SignalFieldIf) *backend.XmlSignalField {
+	ret := backend.XmlSignalFieldNew(f.Name(), f.FieldType())
+	if f.ArraySize() > 0 {
+		ret.Array = fmt.Sprintf("%d", f.ArraySize())
+	}
+	ret.Unit, ret.Min, ret.Max = f.Unit(), f.Min(), f.Max()
+	return ret
 }
 
 func CreateXmlLibrary(l bh.LibraryIf) *backend.XmlLibrary {
 	fname := l.Filename()
+	reg := libraryRegistry(l)
 	ret := backend.XmlLibraryNew()
 	reflist := tool.StringListInit()
 	for _, t := range l.SignalTypes() {
@@ -212,6 +246,17 @@ func CreateXmlLibrary(l bh.LibraryIf) *backend.XmlLibrary {
 		if !ok && ref != fname {
 			reflist.Append(ref)
 		}
+		for _, f := range t.Fields() {
+			ft, ok := reg.GetSignalTypeByName(f.FieldType())
+			if !ok {
+				continue
+			}
+			ref := ft.DefinedAt()
+			_, ok = reflist.Find(ref)
+			if !ok && len(ref) > 0 && ref != fname {
+				reflist.Append(ref)
+			}
+		}
 	}
 	for _, t := range l.NodeTypes() {
 		ref := t.DefinedAt()
@@ -219,8 +264,17 @@ func CreateXmlLibrary(l bh.LibraryIf) *backend.XmlLibrary {
 		if !ok && ref != fname {
 			reflist.Append(ref)
 		}
+		// signal types of ports may be defined elsewhere (e.g. moved)
+		for _, p := range append(t.InPorts(), t.OutPorts()...) {
+			ref := p.SignalType().DefinedAt()
+			_, ok := reflist.Find(ref)
+			if !ok && len(ref) > 0 && ref != fname {
+				reflist.Append(ref)
+			}
+		}
 		for _, impl := range t.Implementation() {
-			if impl.ImplementationType() == bh.NodeTypeGraph {
+			// referenced graph files refer to their libraries themselves
+			if impl.ImplementationType() == bh.NodeTypeGraph && len(impl.GraphRef()) == 0 {
 				for _, lib := range impl.Graph().Libraries() {
 					ref := lib.Filename()
 					_, ok := reflist.Find(ref)
@@ -231,6 +285,14 @@ func CreateXmlLibrary(l bh.LibraryIf) *backend.XmlLibrary {
 			}
 		}
 	}
+	for _, c := range l.Compatibilities() {
+		for _, ref := range compatibilityLibraries(reg, c) {
+			_, ok := reflist.Find(ref)
+			if !ok && len(ref) > 0 && ref != fname {
+				reflist.Append(ref)
+			}
+		}
+	}
 	for _, ref := range reflist.Strings() {
 		ret.Libraries = append(ret.Libraries, *CreateXmlLibraryRef(ref))
 	}
@@ -240,6 +302,9 @@ func CreateXmlLibrary(l bh.LibraryIf) *backend.XmlLibrary {
 	for _, t := range l.NodeTypes() {
 		ret.NodeTypes = append(ret.NodeTypes, *CreateXmlNodeType(t))
 	}
+	for _, c := range l.Compatibilities() {
+		ret.Compatible = append(ret.Compatible, *CreateXmlCompatible(c))
+	}
 	return ret
 }
 
@@ -250,7 +315,7 @@ func CreateXmlSignalGraph(g bh.SignalGraphIf) *backend.XmlSignalGraph {
 func CreateXmlSignalGraphType(t bh.SignalGraphTypeIf) *backend.XmlSignalGraph {
 	ret := backend.XmlSignalGraphNew()
 	for _, l := range t.Libraries() {
-		ret.Libraries = append(ret.Libraries, *CreateXmlLibraryRef(l.Filename()))
+		ret.Libraries = append(ret.Libraries, *CreateXmlLibraryRef(t.LibraryRef(l)))
 	}
 	for _, n := range t.InputNodes() {
 		ret.InputNodes = append(ret.InputNodes, *CreateXmlInputNode(n))
@@ -264,7 +329,11 @@ func CreateXmlSignalGraphType(t bh.SignalGraphTypeIf) *backend.XmlSignalGraph {
 	for _, n := range t.Nodes() {
 		for _, p := range n.OutPorts() {
 			for _, c := range p.Connections() {
-				conn := p.Connection(c)
+				conn, err := p.Connection(c)
+				if err != nil {
+					log.Printf("CreateXmlSignalGraphType: %s\n", err)
+					continue
+				}
 				ret.Connections = append(ret.Connections, *CreateXmlConnection(conn))
 			}
 		}
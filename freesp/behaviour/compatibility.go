@@ -0,0 +1,317 @@
+package behaviour
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"strings"
+)
+
+// Libraries declare compatibilities between signal types. Outputs may
+// be connected to inputs of the same type, or of a type reached by
+// identity (both directions) and subtype (from the subtype to the super
+// type) relations. A convertible relation allows to connect outputs
+// of type From to inputs of type To through a node of the converter
+// node type, which has a single input accepting From and a single
+// output feeding To.
+
+type compatibility struct {
+	from, to  string
+	kind      bh.CompatibilityKind
+	converter string
+}
+
+var _ bh.CompatibilityIf = (*compatibility)(nil)
+
+var compatKindNames = []string{"identity", "subtype", "convertible"}
+
+func CompatibilityKindString(k bh.CompatibilityKind) string {
+	return compatKindNames[k]
+}
+
+func CompatibilityKindFromString(s string) (k bh.CompatibilityKind, ok bool) {
+	for i, name := range compatKindNames {
+		if name == s {
+			return bh.CompatibilityKind(i), true
+		}
+	}
+	return
+}
+
+func CompatibilityNew(from, to string, kind bh.CompatibilityKind, converter string) (c *compatibility, err error) {
+	if from == to {
+		err = fmt.Errorf("CompatibilityNew error: signal type %s is compatible to itself", from)
+		return
+	}
+	if kind == bh.CompatConvertible && len(converter) == 0 {
+		err = fmt.Errorf("CompatibilityNew error: %s -> %s: convertible types need a converter", from, to)
+		return
+	}
+	if kind != bh.CompatConvertible && len(converter) > 0 {
+		err = fmt.Errorf("CompatibilityNew error: %s -> %s: converter applies to convertible types only", from, to)
+		return
+	}
+	c = &compatibility{from, to, kind, converter}
+	return
+}
+
+func (c *compatibility) From() string {
+	return c.from
+}
+
+func (c *compatibility) To() string {
+	return c.to
+}
+
+func (c *compatibility) Kind() bh.CompatibilityKind {
+	return c.kind
+}
+
+func (c *compatibility) Converter() string {
+	return c.converter
+}
+
+func (c *compatibility) String() string {
+	s := fmt.Sprintf("%s -> %s %s", c.from, c.to, CompatibilityKindString(c.kind))
+	if c.kind == bh.CompatConvertible {
+		s = fmt.Sprintf("%s via %s", s, c.converter)
+	}
+	return s
+}
+
+// All compatibilities declared by the libraries registered in reg.
+func registeredCompatibilities(reg *freesp.Registry) (ret []bh.CompatibilityIf) {
+	for _, lib := range reg.GetRegisteredLibraries() {
+		ret = append(ret, lib.Compatibilities()...)
+	}
+	return
+}
+
+// Qualified registered name of the signal type typeName (which may be
+// an alias).
+func canonicalTypeName(reg *freesp.Registry, typeName string) string {
+	st, ok := reg.GetSignalTypeByName(typeName)
+	if !ok {
+		return typeName
+	}
+	return freesp.QualifiedTypeName(st.DefinedAt(), st.TypeName())
+}
+
+// SignalTypesCompatible tells if an output of type out may be connected
+// to an input of type in, without conversion.
+func SignalTypesCompatible(out, in bh.SignalTypeIf) bool {
+	reg := signalTypeRegistry(out)
+	return typeNamesCompatible(reg, qualifiedSignalTypeName(out), qualifiedSignalTypeName(in), registeredCompatibilities(reg))
+}
+
+func qualifiedSignalTypeName(st bh.SignalTypeIf) string {
+	return freesp.QualifiedTypeName(st.DefinedAt(), st.TypeName())
+}
+
+func typeNamesCompatible(reg *freesp.Registry, out, in string, list []bh.CompatibilityIf) bool {
+	out, in = canonicalTypeName(reg, out), canonicalTypeName(reg, in)
+	reached := map[string]bool{out: true}
+	todo := []string{out}
+	for len(todo) > 0 {
+		t := todo[0]
+		todo = todo[1:]
+		if t == in {
+			return true
+		}
+		for _, c := range list {
+			from, to := canonicalTypeName(reg, c.From()), canonicalTypeName(reg, c.To())
+			var next string
+			switch {
+			case c.Kind() == bh.CompatConvertible:
+				continue
+			case from == t:
+				next = to
+			case to == t && c.Kind() == bh.CompatIdentity:
+				next = from
+			default:
+				continue
+			}
+			if !reached[next] {
+				reached[next] = true
+				todo = append(todo, next)
+			}
+		}
+	}
+	return false
+}
+
+// Converter returns the node type converting outputs of type out to
+// inputs of type in, if a library declares one.
+func Converter(out, in bh.SignalTypeIf) (nt bh.NodeTypeIf, ok bool) {
+	reg := signalTypeRegistry(out)
+	list := registeredCompatibilities(reg)
+	for _, c := range list {
+		if c.Kind() != bh.CompatConvertible {
+			continue
+		}
+		if typeNamesCompatible(reg, qualifiedSignalTypeName(out), c.From(), list) &&
+			typeNamesCompatible(reg, c.To(), qualifiedSignalTypeName(in), list) {
+			nt, ok = reg.GetNodeTypeByName(c.Converter())
+			if ok {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Signal types and converter of c must be registered, the converter
+// must have one input accepting From and one output feeding To.
+func checkCompatibility(reg *freesp.Registry, c bh.CompatibilityIf, list []bh.CompatibilityIf) error {
+	for _, name := range []string{c.From(), c.To()} {
+		_, ok := reg.GetSignalTypeByName(name)
+		if !ok {
+			return fmt.Errorf("compatibility %s -> %s: unknown signal type %s", c.From(), c.To(), name)
+		}
+	}
+	if c.Kind() != bh.CompatConvertible {
+		return nil
+	}
+	nt, ok := reg.GetNodeTypeByName(c.Converter())
+	if !ok {
+		return fmt.Errorf("compatibility %s -> %s: unknown converter %s", c.From(), c.To(), c.Converter())
+	}
+	if len(nt.InPorts()) != 1 || len(nt.OutPorts()) != 1 {
+		return fmt.Errorf("compatibility %s -> %s: converter %s needs one input and one output",
+			c.From(), c.To(), c.Converter())
+	}
+	if !typeNamesCompatible(reg, c.From(), qualifiedSignalTypeName(nt.InPorts()[0].SignalType()), list) ||
+		!typeNamesCompatible(reg, qualifiedSignalTypeName(nt.OutPorts()[0].SignalType()), c.To(), list) {
+		return fmt.Errorf("compatibility %s -> %s: ports of converter %s do not match",
+			c.From(), c.To(), c.Converter())
+	}
+	return nil
+}
+
+func (l *library) Compatibilities() []bh.CompatibilityIf {
+	return l.compatibilities
+}
+
+func (l *library) AddCompatibility(c bh.CompatibilityIf) error {
+	for _, x := range l.compatibilities {
+		if x.From() == c.From() && x.To() == c.To() {
+			return fmt.Errorf("library.AddCompatibility error: duplicate compatibility %s -> %s", c.From(), c.To())
+		}
+	}
+	err := checkCompatibility(l.registry(), c, append(registeredCompatibilities(l.registry()), c))
+	if err != nil {
+		return fmt.Errorf("library.AddCompatibility error: %s", err)
+	}
+	l.compatibilities = append(l.compatibilities, c)
+	return nil
+}
+
+func (l *library) RemoveCompatibility(c bh.CompatibilityIf) {
+	for i, x := range l.compatibilities {
+		if x == c {
+			l.compatibilities = append(l.compatibilities[:i], l.compatibilities[i+1:]...)
+			return
+		}
+	}
+}
+
+func createCompatibilityFromXml(xmlc backend.XmlCompatible) (c *compatibility, err error) {
+	kind, ok := CompatibilityKindFromString(xmlc.Kind)
+	if !ok {
+		err = fmt.Errorf("compatibility %s -> %s: unknown kind %s", xmlc.From, xmlc.To, xmlc.Kind)
+		return
+	}
+	return CompatibilityNew(xmlc.From, xmlc.To, kind, xmlc.Converter)
+}
+
+func CreateXmlCompatible(c bh.CompatibilityIf) *backend.XmlCompatible {
+	return backend.XmlCompatibleNew(c.From(), c.To(), CompatibilityKindString(c.Kind()), c.Converter())
+}
+
+// Libraries defining the signal types and converter of c.
+func compatibilityLibraries(reg *freesp.Registry, c bh.CompatibilityIf) (refs []string) {
+	for _, name := range []string{c.From(), c.To()} {
+		st, ok := reg.GetSignalTypeByName(name)
+		if ok {
+			refs = append(refs, st.DefinedAt())
+		}
+	}
+	if c.Kind() == bh.CompatConvertible {
+		nt, ok := reg.GetNodeTypeByName(c.Converter())
+		if ok {
+			refs = append(refs, nt.DefinedAt())
+		}
+	}
+	return
+}
+
+// Renaming signal and node types renames their compatibilities.
+// refersTo tells if a type name in the scope of a library refers to the
+// renamed type, defined at definedAt.
+func renameCompatibilities(reg *freesp.Registry, refersTo func(string, map[string]bool) bool, definedAt, oldName, newName string, signalType bool) {
+	rename := func(typeName string, scope map[string]bool) string {
+		if !refersTo(typeName, scope) {
+			return typeName
+		}
+		typeName, _ = freesp.RenameTypeName(typeName, definedAt, oldName, newName)
+		return typeName
+	}
+	for _, lib := range reg.GetRegisteredLibraries() {
+		scope := libraryScope(reg, lib.Filename())
+		for _, x := range lib.Compatibilities() {
+			c, ok := x.(*compatibility)
+			if !ok {
+				continue
+			}
+			if signalType {
+				c.from = rename(c.from, scope)
+				c.to = rename(c.to, scope)
+			} else if len(c.converter) > 0 {
+				c.converter = rename(c.converter, scope)
+			}
+		}
+	}
+}
+
+// SignalTypeUsedByCompatibility tells if a compatibility refers to st.
+func SignalTypeUsedByCompatibility(st bh.SignalTypeIf) bool {
+	reg := signalTypeRegistry(st)
+	name := qualifiedSignalTypeName(st)
+	for _, c := range registeredCompatibilities(reg) {
+		if canonicalTypeName(reg, c.From()) == name || canonicalTypeName(reg, c.To()) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeTypeUsedByCompatibility tells if nt is the converter of a
+// compatibility.
+func NodeTypeUsedByCompatibility(nt bh.NodeTypeIf) bool {
+	reg := nodeTypeRegistry(nt)
+	for _, c := range registeredCompatibilities(reg) {
+		if c.Kind() != bh.CompatConvertible {
+			continue
+		}
+		conv, ok := reg.GetNodeTypeByName(c.Converter())
+		if ok && conv == nt {
+			return true
+		}
+	}
+	return false
+}
+
+// Error of a connection from an output of type out to an input of
+// type in, nil if they are compatible.
+func connectionTypeError(out, in bh.SignalTypeIf) error {
+	if SignalTypesCompatible(out, in) {
+		return nil
+	}
+	msg := []string{fmt.Sprintf("type mismatch: %s -> %s", out.TypeName(), in.TypeName())}
+	nt, ok := Converter(out, in)
+	if ok {
+		msg = append(msg, fmt.Sprintf("insert a %s node to convert", nt.TypeName()))
+	}
+	return freesp.TypeMismatchError("portConnect", "%s", strings.Join(msg, ", "))
+}
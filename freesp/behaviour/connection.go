@@ -66,8 +66,8 @@ func (c *connection) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeE
 	return
 }
 
-func (c *connection) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatal("Connection.AddNewObject - nothing to remove.")
+func (c *connection) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("connection.RemoveObject", "nothing to remove")
 	return
 }
 
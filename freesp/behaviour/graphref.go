@@ -0,0 +1,157 @@
+package behaviour
+
+import (
+	"fmt"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	"log"
+	"strings"
+)
+
+// Graph implementations referring to a signal graph file share the
+// signal graph of that document. It is loaded on first access through
+// the signal graph file manager. Its input and output nodes are bound
+// to the ports of the node type, matched by name like the linked
+// io-nodes of embedded graphs: nodes without a port get a new port,
+// ports without a node get a new io-node. Later boundary changes of the
+// graph are propagated to the node type. Parameter values of its nodes
+// are checked against the parameters of the node type when bound.
+
+func (impl *implementation) loadGraph() {
+	f, err := impl.context.SignalGraphMgr().AccessRef(impl.graphRef, impl.refDir)
+	if err == nil {
+		g := f.(bh.SignalGraphIf).ItsType().(*signalGraphType)
+		impl.graph = g
+		if impl.nodeType == nil {
+			return
+		}
+		err = nodeTypeCycleError(NodeTypeCycle(impl.nodeType))
+		if err == nil {
+			impl.bind(g)
+			err = checkParamValues(g, impl.nodeType)
+			if err != nil {
+				log.Printf("implementation.Graph warning: graph %s: %s\n", impl.graphRef, err)
+			}
+			return
+		}
+		err = fmt.Errorf("graph %s: %s", impl.graphRef, err)
+	}
+	log.Printf("implementation.Graph warning: %s (using an empty graph)\n", err)
+	g := SignalGraphTypeNew(impl.context)
+	impl.graph = g
+	if impl.nodeType != nil {
+		for _, p := range impl.nodeType.InPorts() {
+			g.addInputNodeFromPortType(p)
+		}
+		for _, p := range impl.nodeType.OutPorts() {
+			g.addOutputNodeFromPortType(p)
+		}
+	}
+}
+
+// Returns the graph of a graph implementation if it is loaded, nil
+// otherwise.
+func (impl *implementation) loadedGraph() *signalGraphType {
+	if impl.implementationType != bh.NodeTypeGraph || impl.graph == nil {
+		return nil
+	}
+	return impl.graph.(*signalGraphType)
+}
+
+func (impl *implementation) bind(g *signalGraphType) {
+	t := impl.nodeType
+	for _, n := range g.InputNodes() {
+		impl.bindNode(n.(*node), gr.InPort)
+	}
+	for _, n := range g.OutputNodes() {
+		impl.bindNode(n.(*node), gr.OutPort)
+	}
+	for _, p := range t.InPorts() {
+		if g.linkedNode(p) == nil {
+			g.addInputNodeFromPortType(p)
+		}
+	}
+	for _, p := range t.OutPorts() {
+		if g.linkedNode(p) == nil {
+			g.addOutputNodeFromPortType(p)
+		}
+	}
+	g.bound = append(g.bound, impl)
+}
+
+func (impl *implementation) unbind() {
+	g := impl.loadedGraph()
+	if g == nil {
+		return
+	}
+	for i, b := range g.bound {
+		if b == impl {
+			g.bound = append(g.bound[:i], g.bound[i+1:]...)
+			break
+		}
+	}
+	for _, n := range append(g.InputNodes(), g.OutputNodes()...) {
+		if impl.nodeType.hasPortType(n.(*node).portlink) {
+			n.(*node).portlink = nil
+		}
+	}
+	impl.graph = nil
+}
+
+func (impl *implementation) bindNode(n *node, dir gr.PortDirection) {
+	t := impl.nodeType
+	pt := t.doResolvePort(n.Name(), dir)
+	if pt == nil {
+		var prefix string
+		var st bh.SignalTypeIf
+		if dir == gr.InPort {
+			prefix, st = "in-", n.OutPorts()[0].SignalType()
+		} else {
+			prefix, st = "out-", n.InPorts()[0].SignalType()
+		}
+		pt = &portType{st, strings.TrimPrefix(n.Name(), prefix), dir}
+		n.portlink = pt
+		t.AddNamedPortType(pt)
+		return
+	}
+	n.portlink = pt
+	var p bh.PortIf
+	if dir == gr.InPort {
+		p = n.OutPorts()[0]
+	} else {
+		p = n.InPorts()[0]
+	}
+	if p.SignalType() != pt.SignalType() {
+		log.Printf("implementation.bindNode warning: node %s of graph %s has signal type %s, port %s of %s has %s\n",
+			n.Name(), impl.graphRef, p.SignalType().TypeName(), pt.Name(), t.TypeName(), pt.SignalType().TypeName())
+	}
+}
+
+func (impl *implementation) boundaryNodeAdded(n *node) {
+	if n.portlink != nil {
+		// created for a port of a node type
+		return
+	}
+	if len(n.OutPorts()) > 0 {
+		impl.bindNode(n, gr.InPort)
+	} else {
+		impl.bindNode(n, gr.OutPort)
+	}
+}
+
+func (impl *implementation) boundaryNodeRemoved(n *node) {
+	pt := n.portlink
+	if pt == nil || !impl.nodeType.hasPortType(pt) {
+		return
+	}
+	n.portlink = nil
+	impl.nodeType.RemoveNamedPortType(pt)
+}
+
+// Graph implementations referring to g lose their graph, it is read
+// again on next access.
+func (g *signalGraphType) unbindAll() {
+	for len(g.bound) > 0 {
+		g.bound[0].unbind()
+	}
+}
@@ -7,25 +7,42 @@ import (
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/tool"
 	"log"
+	"strings"
 )
 
 type library struct {
-	filename    string
-	pathPrefix  string
-	signalTypes signalTypeList
-	nodeTypes   nodeTypeList
-	context     mod.ModelContextIf
+	filename        string
+	pathPrefix      string
+	signalTypes     signalTypeList
+	nodeTypes       nodeTypeList
+	compatibilities []bh.CompatibilityIf
+	context         mod.ModelContextIf
 }
 
 var _ bh.LibraryIf = (*library)(nil)
 
 func LibraryNew(filename string, context mod.ModelContextIf) *library {
-	ret := &library{filename, "", signalTypeListInit(), nodeTypeListInit(), context}
-	freesp.RegisterLibrary(ret)
+	ret := &library{filename, "", signalTypeListInit(), nodeTypeListInit(), nil, context}
+	ret.registry().RegisterLibrary(ret)
 	return ret
 }
 
+// Libraries and their types are registered in the registry of their
+// context.
+func (l *library) registry() *freesp.Registry {
+	return freesp.RegistryOf(l.context)
+}
+
+func libraryRegistry(lib bh.LibraryIf) *freesp.Registry {
+	l, ok := lib.(*library)
+	if ok {
+		return l.registry()
+	}
+	return freesp.DefaultRegistry()
+}
+
 func LibraryUsesNodeType(l bh.LibraryIf, nt bh.NodeTypeIf) bool {
 	for _, t := range l.NodeTypes() {
 		if t.TypeName() == nt.TypeName() {
@@ -66,12 +83,12 @@ func (l library) Filename() string {
 
 func (l *library) SetFilename(filename string) {
 	// TODO: check if new name is existing
-	freesp.RemoveRegisteredLibrary(l)
+	l.registry().RemoveRegisteredLibrary(l)
 	l.filename = filename
 	for _, t := range l.NodeTypes() {
 		t.(*nodeType).definedAt = filename
 	}
-	freesp.RegisterLibrary(l)
+	l.registry().RegisterLibrary(l)
 }
 
 func (l library) PathPrefix() string {
@@ -90,14 +107,21 @@ func (l library) NodeTypes() []bh.NodeTypeIf {
 	return l.nodeTypes.NodeTypes()
 }
 
-func (l *library) createLibFromXml(xmlLib *backend.XmlLibrary) error {
+// Library references are resolved relative to refDir.
+func (l *library) createLibFromXml(xmlLib *backend.XmlLibrary, refDir string) error {
 	libMgr := l.context.LibraryMgr()
 	for _, ref := range xmlLib.Libraries {
-		_, err := libMgr.Access(ref.Name)
+		_, err := libMgr.AccessRef(ref.Name, refDir)
+		if k, ok := freesp.ErrorKindOf(err); ok && k == freesp.ErrInvalidOperation {
+			// a reference cycle, passed on as is to the libraries
+			// referring to this one
+			return err
+		}
 		if err != nil {
 			log.Println("library.Read warning:", err)
 		}
 	}
+	var sTypes []bh.SignalTypeIf
 	for _, st := range xmlLib.SignalTypes {
 		var scope bh.Scope
 		var mode bh.Mode
@@ -113,19 +137,53 @@ func (l *library) createLibFromXml(xmlLib *backend.XmlLibrary) error {
 		default:
 			mode = bh.Asynchronous
 		}
-		sType, err := SignalTypeNew(st.Name, st.Ctype, st.Msgid, scope, mode, l.Filename())
+		sType, err := SignalTypeNew(st.Name, st.Ctype, st.Msgid, scope, mode, l.Filename(), l.context)
 		if err != nil {
 			return err
 		}
 		l.AddSignalType(sType)
+		sTypes = append(sTypes, sType)
+	}
+	for i, st := range xmlLib.SignalTypes {
+		err := createFieldsFromXml(sTypes[i], st, l.Filename())
+		if err != nil {
+			return fmt.Errorf("library.Read error: %s", err)
+		}
+	}
+	var refs []string
+	for _, ref := range xmlLib.Libraries {
+		refs = append(refs, ref.Name)
+	}
+	scope := reachableLibraries(l.registry(), l.Filename(), refs)
+	err := checkFieldLibraries(l.registry(), l.SignalTypes(), l.Filename(), refs)
+	if err != nil {
+		return fmt.Errorf("library.Read error: %s", err)
+	}
+	err = nodeTypeCycleError(xmlNodeTypeCycle(l.registry(), xmlLib.NodeTypes))
+	if err != nil {
+		return fmt.Errorf("library.Read error: %s", err)
 	}
 	for _, n := range xmlLib.NodeTypes {
-		nType := createNodeTypeFromXml(n, l.Filename(), l.context)
-		err := l.AddNodeType(nType)
+		nType, err := createNodeTypeFromXml(n, l.Filename(), refDir, l.context, scope)
+		if err != nil {
+			return fmt.Errorf("library.Read error: %s", err)
+		}
+		err = l.AddNodeType(nType)
 		if err != nil {
 			log.Println("library.Read warning:", err)
 		}
 	}
+	// converters may be node types of this library
+	for _, xmlc := range xmlLib.Compatible {
+		c, err := createCompatibilityFromXml(xmlc)
+		if err != nil {
+			return fmt.Errorf("library.Read error: %s", err)
+		}
+		err = l.AddCompatibility(c)
+		if err != nil {
+			return fmt.Errorf("library.Read error: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -136,17 +194,17 @@ func (l *library) Read(data []byte) (cnt int, err error) {
 		err = fmt.Errorf("library.Read: %v", err)
 		return
 	}
-	err = l.createLibFromXml(xmllib)
+	err = l.createLibFromXml(xmllib, "")
 	return
 }
 
 func (l *library) ReadFile(filepath string) error {
 	xmllib := backend.XmlLibraryNew()
-	err := xmllib.ReadFile(filepath)
+	err := freesp.PreserverOf(l.context).DocumentReadFile(xmllib, filepath)
 	if err != nil {
 		return fmt.Errorf("library.Read: %v", err)
 	}
-	return l.createLibFromXml(xmllib)
+	return l.createLibFromXml(xmllib, tool.Dirname(filepath))
 }
 
 func (l library) Write() (data []byte, err error) {
@@ -157,22 +215,24 @@ func (l library) Write() (data []byte, err error) {
 
 func (l library) WriteFile(filepath string) error {
 	xmllib := CreateXmlLibrary(&l)
-	return xmllib.WriteFile(filepath)
+	return freesp.PreserverOf(l.context).DocumentWriteFile(xmllib, filepath)
 }
 
 func (l *library) RemoveFromTree(tree tr.TreeIf) {
 	tree.Remove(tree.Cursor(l))
-	freesp.RemoveRegisteredLibrary(l)
+	l.registry().RemoveRegisteredLibrary(l)
 }
 
+// Node types of other libraries may have the same name, they are told
+// apart by qualified names.
 func (l *library) AddNodeType(t bh.NodeTypeIf) error {
-	nType, ok := freesp.GetNodeTypeByName(t.TypeName())
+	nType, ok := l.registry().GetNodeTypeByName(freesp.QualifiedTypeName(t.DefinedAt(), t.TypeName()))
 	if ok {
 		log.Printf(`library.AddNodeType: warning: adding existing node
 			type definition %s (taking the existing one)`, t.TypeName())
 	} else {
 		nType = t.(*nodeType)
-		freesp.RegisterNodeType(nType)
+		l.registry().RegisterNodeType(nType)
 		log.Println("library.AddNodeType: registered ", t.TypeName())
 	}
 	for _, nt := range l.nodeTypes.NodeTypes() {
@@ -189,7 +249,7 @@ func (l *library) RemoveNodeType(nt bh.NodeTypeIf) {
 	for _, n := range nt.(*nodeType).instances.Nodes() {
 		n.(*node).context.RemoveNode(n)
 	}
-	freesp.RemoveRegisteredNodeType(nt)
+	l.registry().RemoveRegisteredNodeType(nt)
 	l.nodeTypes.Remove(nt)
 }
 
@@ -207,8 +267,8 @@ func (l *library) AddSignalType(s bh.SignalTypeIf) (ok bool) {
 }
 
 func (l *library) RemoveSignalType(st bh.SignalTypeIf) {
-	for _, ntName := range freesp.GetRegisteredNodeTypes() {
-		nt, ok := freesp.GetNodeTypeByName(ntName)
+	for _, ntName := range l.registry().GetRegisteredNodeTypes() {
+		nt, ok := l.registry().GetNodeTypeByName(ntName)
 		if !ok {
 			log.Panicf("library.RemoveSignalType internal error: node type %s\n", ntName)
 		}
@@ -278,6 +338,11 @@ func (l *library) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElem
 
 	case bh.NodeTypeIf:
 		t := obj.(bh.NodeTypeIf)
+		err = nodeTypeCycleError(NodeTypeCycle(t))
+		if err != nil {
+			err = fmt.Errorf("library.AddNewObject error: %s", err)
+			return
+		}
 		err = l.AddNodeType(t)
 		if err != nil {
 			err = fmt.Errorf("library.AddNewObject error: AddNodeType failed: %s", err)
@@ -292,28 +357,32 @@ func (l *library) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElem
 	return
 }
 
-func (l *library) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (l *library) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if l != tree.Object(parent) {
-		log.Fatal("library.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("library.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
 	case bh.SignalTypeIf:
 		st := tree.Object(cursor).(bh.SignalTypeIf)
+		if SignalTypeUsedByField(st) {
+			err = freesp.InvalidOperationError("library.RemoveObject", "signal type %s is still in use by a field", st.TypeName())
+			return
+		}
+		if SignalTypeUsedByCompatibility(st) {
+			err = freesp.InvalidOperationError("library.RemoveObject", "signal type %s is still in use by a compatibility", st.TypeName())
+			return
+		}
 		for _, nt := range l.NodeTypes() {
-			for _, p := range nt.InPorts() {
-				if p.SignalType().TypeName() == st.TypeName() {
-					log.Printf(`library.RemoveObject warning:
-						bh.SignalTypeIf %v is still in use\n`, st)
-					return
-				}
-			}
-			for _, p := range nt.OutPorts() {
-				if p.SignalType().TypeName() == st.TypeName() {
-					log.Printf(`library.RemoveObject warning:
-						bh.SignalTypeIf %v is still in use\n`, st)
-					return
+			for _, list := range [][]bh.PortTypeIf{nt.InPorts(), nt.OutPorts()} {
+				for _, p := range list {
+					if p.SignalType().TypeName() == st.TypeName() {
+						err = freesp.InvalidOperationError("library.RemoveObject", "signal type %s is still in use by node type %s",
+							st.TypeName(), nt.TypeName())
+						return
+					}
 				}
 			}
 		}
@@ -323,13 +392,17 @@ func (l *library) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.I
 
 	case bh.NodeTypeIf:
 		nt := tree.Object(cursor).(bh.NodeTypeIf)
-		log.Printf("library.RemoveObject: nt=%v\n", nt)
 		if len(nt.Instances()) > 0 {
-			log.Printf(`library.RemoveObject warning: The following nodes
-				are still instances of bh.NodeTypeIf %s:\n`, nt.TypeName())
+			var names []string
 			for _, n := range nt.Instances() {
-				log.Printf("	%s\n", n.Name())
+				names = append(names, n.Name())
 			}
+			err = freesp.InvalidOperationError("library.RemoveObject", "node type %s is still instantiated by %s",
+				nt.TypeName(), strings.Join(names, ", "))
+			return
+		}
+		if NodeTypeUsedByCompatibility(nt) {
+			err = freesp.InvalidOperationError("library.RemoveObject", "node type %s is still in use as converter", nt.TypeName())
 			return
 		}
 		prefix, index := tree.Remove(cursor)
@@ -337,7 +410,7 @@ func (l *library) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.I
 		l.RemoveNodeType(nt)
 
 	default:
-		log.Fatalf("library.RemoveObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("library.RemoveObject", "cannot remove %T from a library", obj)
 	}
 	return
 }
@@ -0,0 +1,139 @@
+package behaviour
+
+import (
+	"fmt"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+)
+
+// Moving a type to another library changes where it is defined: graphs
+// using it then need a reference to the new library, the reference to
+// the old one may become obsolete. Libraries compute their references
+// when written (see CreateXmlLibrary).
+
+// MoveNodeType moves nt from library from to library to and updates
+// the library references of all graphs with instances of nt.
+func MoveNodeType(nt bh.NodeTypeIf, from, to bh.LibraryIf) error {
+	err := CheckMoveNodeType(nt, from, to)
+	if err != nil {
+		return err
+	}
+	src, dst := from.(*library), to.(*library)
+	src.nodeTypes.Remove(nt)
+	dst.nodeTypes.Append(nt)
+	nt.(*nodeType).definedAt = to.Filename()
+	for _, n := range nt.Instances() {
+		updateLibraryRefs(n.Context().(*signalGraphType), from, to)
+	}
+	return nil
+}
+
+// MoveSignalType moves st from library from to library to and updates
+// the library references of all graphs with ports of type st.
+func MoveSignalType(st bh.SignalTypeIf, from, to bh.LibraryIf) error {
+	err := CheckMoveSignalType(st, from, to)
+	if err != nil {
+		return err
+	}
+	src, dst := from.(*library), to.(*library)
+	src.signalTypes.Remove(st)
+	dst.signalTypes.Append(st)
+	st.(*signalType).definedAt = to.Filename()
+	reg := src.registry()
+	for _, name := range reg.GetRegisteredNodeTypes() {
+		nt, ok := reg.GetNodeTypeByName(name)
+		if !ok || !nodeTypeUsesSignalType(nt, st) {
+			continue
+		}
+		for _, n := range nt.Instances() {
+			updateLibraryRefs(n.Context().(*signalGraphType), from, to)
+		}
+	}
+	return nil
+}
+
+// CheckMoveNodeType tells why nt cannot be moved from library from to
+// library to, nil if it can. Nothing is changed.
+func CheckMoveNodeType(nt bh.NodeTypeIf, from, to bh.LibraryIf) error {
+	if !containsNodeType(from.(*library), nt) {
+		return fmt.Errorf("MoveNodeType error: node type %s not defined in %s", nt.TypeName(), from.Filename())
+	}
+	for _, t := range to.NodeTypes() {
+		if t.TypeName() == nt.TypeName() {
+			return fmt.Errorf("MoveNodeType error: node type %s already defined in %s", nt.TypeName(), to.Filename())
+		}
+	}
+	return nil
+}
+
+// CheckMoveSignalType tells why st cannot be moved from library from
+// to library to, nil if it can. Nothing is changed.
+func CheckMoveSignalType(st bh.SignalTypeIf, from, to bh.LibraryIf) error {
+	if !containsSignalType(from.(*library), st) {
+		return fmt.Errorf("MoveSignalType error: signal type %s not defined in %s", st.TypeName(), from.Filename())
+	}
+	for _, t := range to.SignalTypes() {
+		if t.TypeName() == st.TypeName() {
+			return fmt.Errorf("MoveSignalType error: signal type %s already defined in %s", st.TypeName(), to.Filename())
+		}
+	}
+	return nil
+}
+
+func containsNodeType(l *library, nt bh.NodeTypeIf) bool {
+	for _, t := range l.NodeTypes() {
+		if t == nt {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSignalType(l *library, st bh.SignalTypeIf) bool {
+	for _, t := range l.SignalTypes() {
+		if t == st {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeTypeUsesSignalType(nt bh.NodeTypeIf, st bh.SignalTypeIf) bool {
+	for _, p := range append(nt.InPorts(), nt.OutPorts()...) {
+		if p.SignalType() == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Libraries t needs: the ones defining its node types (except the
+// automatic ones of input and output nodes) and signal types.
+func (t *signalGraphType) usesLibrary(libname string) bool {
+	for _, n := range t.Nodes() {
+		if !isAutoType(n.ItsType()) && n.ItsType().DefinedAt() == libname {
+			return true
+		}
+		for _, p := range append(n.InPorts(), n.OutPorts()...) {
+			if p.SignalType().DefinedAt() == libname {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Add a reference to to if needed, drop the one to from if obsolete.
+func updateLibraryRefs(t *signalGraphType, from, to bh.LibraryIf) {
+	if t.usesLibrary(to.Filename()) && !t.containsLibRef(to.Filename()) {
+		t.libraries = append(t.libraries, to)
+	}
+	if t.usesLibrary(from.Filename()) {
+		return
+	}
+	for i, l := range t.libraries {
+		if l == from {
+			t.libraries = append(t.libraries[:i], t.libraries[i+1:]...)
+			break
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/axel-freesp/sge/freesp"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
+	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
 	//"github.com/axel-freesp/sge/tool"
 	//"image"
@@ -19,6 +20,7 @@ type node struct {
 	inPort   portList
 	outPort  portList
 	portlink bh.PortTypeIf
+	params   map[string]string
 	expanded bool
 }
 
@@ -39,7 +41,7 @@ func NodeNew(name string, ntype bh.NodeTypeIf, context bh.SignalGraphTypeIf) (re
 		return
 	}
 	ret = &node{*gr.PathModePositionerObjectNew(), context, name, ntype,
-		portListInit(), portListInit(), nil, false}
+		portListInit(), portListInit(), nil, make(map[string]string), false}
 	for _, p := range ntype.InPorts() {
 		ret.addInPort(p)
 	}
@@ -51,37 +53,47 @@ func NodeNew(name string, ntype bh.NodeTypeIf, context bh.SignalGraphTypeIf) (re
 }
 
 func InputNodeNew(name string, stypeName string, context bh.SignalGraphTypeIf) (ret *node, err error) {
-	st, ok := freesp.GetSignalTypeByName(stypeName)
+	reg := freesp.RegistryOf(graphContext(context))
+	st, ok := reg.GetSignalTypeByName(stypeName)
 	if !ok {
 		err = fmt.Errorf("InputNodeNew error: signaltype %s not defined", stypeName)
 		return
 	}
-	ntName := createInputNodeTypeName(stypeName)
-	nt, ok := freesp.GetNodeTypeByName(ntName)
+	ntName := createInputNodeTypeName(autoTypeName(st))
+	nt, ok := reg.GetNodeTypeByName(ntName)
 	if !ok {
-		nt = NodeTypeNew(ntName, "")
+		nt = NodeTypeNew(ntName, "", graphContext(context))
 		nt.(*nodeType).addOutPort("", st)
-		freesp.RegisterNodeType(nt)
+		reg.RegisterNodeType(nt)
 	}
 	return NodeNew(name, nt, context)
 }
 
 func OutputNodeNew(name string, stypeName string, context bh.SignalGraphTypeIf) (ret *node, err error) {
-	st, ok := freesp.GetSignalTypeByName(stypeName)
+	reg := freesp.RegistryOf(graphContext(context))
+	st, ok := reg.GetSignalTypeByName(stypeName)
 	if !ok {
 		err = fmt.Errorf("InputNodeNew error: signaltype %s not defined", stypeName)
 		return
 	}
-	ntName := createOutputNodeTypeName(stypeName)
-	nt, ok := freesp.GetNodeTypeByName(ntName)
+	ntName := createOutputNodeTypeName(autoTypeName(st))
+	nt, ok := reg.GetNodeTypeByName(ntName)
 	if !ok {
-		nt = NodeTypeNew(ntName, "")
+		nt = NodeTypeNew(ntName, "", graphContext(context))
 		nt.(*nodeType).addInPort("", st)
-		freesp.RegisterNodeType(nt)
+		reg.RegisterNodeType(nt)
 	}
 	return NodeNew(name, nt, context)
 }
 
+// Model context of graph g, if any.
+func graphContext(g bh.SignalGraphTypeIf) mod.ModelContextIf {
+	if g == nil {
+		return nil
+	}
+	return g.Context()
+}
+
 func (n *node) ItsType() bh.NodeTypeIf {
 	return n.nodetype
 }
@@ -94,6 +106,33 @@ func (n *node) OutPorts() []bh.PortIf {
 	return n.outPort.Ports()
 }
 
+// Parameter values are literals or expressions (see parameter.go).
+func (n *node) ParamValue(name string) (value string, ok bool) {
+	value, ok = n.params[name]
+	return
+}
+
+// An empty value resets the parameter to its default. Literal values
+// are checked, expressions when the graph is evaluated.
+func (n *node) SetParamValue(name, value string) error {
+	p, ok := parameterByName(n.nodetype, name)
+	if !ok {
+		return fmt.Errorf("node.SetParamValue error: type %s of node %s has no parameter %s", n.nodetype.TypeName(), n.name, name)
+	}
+	if len(value) == 0 {
+		delete(n.params, name)
+		return nil
+	}
+	if !hasParamRef(value) {
+		_, err := EvalParam(p, value, nil)
+		if err != nil {
+			return fmt.Errorf("node.SetParamValue error: node %s: %s", n.name, err)
+		}
+	}
+	n.params[name] = value
+	return nil
+}
+
 func (n *node) Context() bh.SignalGraphTypeIf {
 	return n.context
 }
@@ -216,10 +255,11 @@ func (n *node) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElement
 	return
 }
 
-func (n *node) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (n *node) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parentId := tree.Parent(cursor)
 	if n != tree.Object(parentId) {
-		log.Fatal("node.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("node.RemoveObject", "not removing child of mine")
+		return
 	}
 	nt := n.ItsType()
 	obj := tree.Object(cursor)
@@ -227,7 +267,11 @@ func (n *node) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWi
 	case bh.PortIf:
 		p := obj.(bh.PortIf)
 		for index, c := range p.Connections() {
-			conn := p.Connection(c)
+			conn, err := p.Connection(c)
+			if err != nil {
+				log.Printf("node.RemoveObject: %s\n", err)
+				continue
+			}
 			removed = append(removed, tr.IdWithObject{cursor.Path, index, conn})
 		}
 		var list portTypeList
@@ -244,7 +288,7 @@ func (n *node) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWi
 		tree.Remove(cursor)
 
 	default:
-		log.Fatal("bh.NodeIf.RemoveObject error: invalid type %T", obj)
+		err = freesp.InvalidOperationError("node.RemoveObject", "cannot remove %T from a node", obj)
 	}
 	return
 }
@@ -309,17 +353,21 @@ func (n *node) removePort(pt bh.PortTypeIf) {
 	} else {
 		list = &n.outPort
 	}
-	var i int
-	for i = 0; i < len(list.Ports()); i++ {
-		if list.Ports()[i].Name() == pt.Name() {
-			break
-		}
+	toRemove, ok, _ := list.Find(n.Name(), pt.Name())
+	if !ok {
+		log.Printf("node.removePort: node %s has no port %s\n", n.Name(), pt.Name())
+		return
 	}
-	toRemove := list.Ports()[i]
 	for _, c := range toRemove.Connections() {
-		c.RemoveConnection(toRemove)
+		err := c.RemoveConnection(toRemove)
+		if err != nil {
+			log.Printf("node.removePort: %s\n", err)
+		}
+	}
+	err := list.Remove(toRemove)
+	if err != nil {
+		log.Printf("node.removePort: %s\n", err)
 	}
-	list.Remove(toRemove)
 }
 
 func isParentReadOnly(tree tr.TreeIf, cursor tr.Cursor) bool {
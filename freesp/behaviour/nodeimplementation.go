@@ -13,18 +13,27 @@ type implementation struct {
 	implementationType bh.ImplementationType
 	elementName        string
 	graph              bh.SignalGraphTypeIf
+	graphRef, refDir   string
+	nodeType           *nodeType
+	context            mod.ModelContextIf
 }
 
 var _ bh.ImplementationIf = (*implementation)(nil)
 
 func ImplementationNew(iName string, iType bh.ImplementationType, context mod.ModelContextIf) *implementation {
-	ret := &implementation{iType, iName, nil}
+	ret := &implementation{iType, iName, nil, "", "", nil, context}
 	if iType == bh.NodeTypeGraph {
 		ret.graph = SignalGraphTypeNew(context)
 	}
 	return ret
 }
 
+// The graph file graphRef is resolved relative to refDir, the directory
+// of the referencing library (empty if unknown).
+func GraphRefImplementationNew(iName, graphRef, refDir string, context mod.ModelContextIf) *implementation {
+	return &implementation{bh.NodeTypeGraph, iName, nil, graphRef, refDir, nil, context}
+}
+
 func (n *implementation) ImplementationType() bh.ImplementationType {
 	return n.implementationType
 }
@@ -38,9 +47,16 @@ func (n *implementation) SetElemName(newName string) {
 }
 
 func (n *implementation) Graph() bh.SignalGraphTypeIf {
+	if n.graph == nil && len(n.graphRef) > 0 {
+		n.loadGraph()
+	}
 	return n.graph
 }
 
+func (n *implementation) GraphRef() string {
+	return n.graphRef
+}
+
 func (n *implementation) CreateXml() (buf []byte, err error) {
 	switch n.ImplementationType() {
 	case bh.NodeTypeElement:
@@ -57,7 +73,9 @@ func (n *implementation) CreateXml() (buf []byte, err error) {
  */
 
 func (n *implementation) String() string {
-	if n.implementationType == bh.NodeTypeGraph {
+	if len(n.graphRef) > 0 {
+		return fmt.Sprintf("bh.ImplementationIf graph %s", n.graphRef)
+	} else if n.implementationType == bh.NodeTypeGraph {
 		return fmt.Sprintf("bh.ImplementationIf graph {\n%v\n}", n.graph)
 	} else {
 		return fmt.Sprintf("bh.ImplementationIf module %s", n.elementName)
@@ -89,7 +107,10 @@ func (impl *implementation) AddToTree(tree tr.TreeIf, cursor tr.Cursor) {
 	default:
 		log.Fatalf("implementation.AddToTree error: invalid parent type: %T\n", parent)
 	}
-	if impl.ImplementationType() == bh.NodeTypeGraph {
+	if len(impl.GraphRef()) > 0 {
+		image = tr.SymbolImplGraph
+		text = impl.GraphRef()
+	} else if impl.ImplementationType() == bh.NodeTypeGraph {
 		image = tr.SymbolImplGraph
 		text = "Graph"
 	} else {
@@ -127,51 +148,23 @@ func (impl *implementation) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj t
 	return
 }
 
-func (impl *implementation) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (impl *implementation) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if impl != tree.Object(parent) {
-		log.Fatal("implementation.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("implementation.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
 	case bh.NodeIf:
-		if impl.ImplementationType() == bh.NodeTypeGraph {
-			log.Println("implementation.RemoveObject: delegate to signalGraphType\n")
-			return impl.Graph().RemoveObject(tree, cursor)
-		} else {
-			log.Fatalf("implementation.RemoveObject error: cannot remove node from elementary implementation.\n")
-		}
-		return
-
-		n := obj.(bh.NodeIf)
-		if !IsProcessingNode(n) {
-			// Removed Input- and Output nodes are NOT stored (they are
-			// created automatically when adding the implementation graph).
-			// Within an implementation, it is therefore not allowed to
-			// remove such IO nodes.
+		if impl.ImplementationType() != bh.NodeTypeGraph {
+			err = freesp.InvalidOperationError("implementation.RemoveObject", "cannot remove node from elementary implementation")
 			return
 		}
-		// Remove all connections first
-		for _, p := range n.OutPorts() {
-			pCursor := tree.CursorAt(cursor, p)
-			for index, c := range p.Connections() {
-				conn := p.Connection(c)
-				removed = append(removed, tr.IdWithObject{pCursor.Path, index, conn})
-			}
-		}
-		for _, p := range n.InPorts() {
-			pCursor := tree.CursorAt(cursor, p)
-			for index, c := range p.Connections() {
-				conn := p.Connection(c)
-				removed = append(removed, tr.IdWithObject{pCursor.Path, index, conn})
-			}
-		}
-		prefix, index := tree.Remove(cursor)
-		removed = append(removed, tr.IdWithObject{prefix, index, obj})
-		impl.Graph().RemoveNode(n)
+		return impl.Graph().RemoveObject(tree, cursor)
 
 	default:
-		log.Fatalf("implementation.RemoveObject error: invalid type %T", obj)
+		err = freesp.InvalidOperationError("implementation.RemoveObject", "cannot remove %T from an implementation", obj)
 	}
 	return
 }
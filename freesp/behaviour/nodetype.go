@@ -10,7 +10,7 @@ import (
 	tr "github.com/axel-freesp/sge/interface/tree"
 	//"image"
 	"log"
-	//"strings"
+	"strings"
 )
 
 type nodeType struct {
@@ -18,14 +18,32 @@ type nodeType struct {
 	definedAt         string
 	inPorts, outPorts portTypeList
 	implementation    implementationList
+	parameters        []bh.ParameterIf
 	instances         nodeList
+	registry          *freesp.Registry
 }
 
 var _ bh.NodeTypeIf = (*nodeType)(nil)
 
-func NodeTypeNew(name, definedAt string) *nodeType {
+// Node types are registered by their creator, in the registry of
+// context.
+func NodeTypeNew(name, definedAt string, context mod.ModelContextIf) *nodeType {
 	return &nodeType{name, definedAt, portTypeListInit(),
-		portTypeListInit(), implementationListInit(), nodeListInit()}
+		portTypeListInit(), implementationListInit(), nil, nodeListInit(),
+		freesp.RegistryOf(context)}
+}
+
+// Registry nt is registered in.
+func nodeTypeRegistry(nt bh.NodeTypeIf) *freesp.Registry {
+	t, ok := nt.(*nodeType)
+	if ok && t.registry != nil {
+		return t.registry
+	}
+	return freesp.DefaultRegistry()
+}
+
+func nodeTypeRefName(nt bh.NodeTypeIf) string {
+	return nodeTypeRegistry(nt).NodeTypeRefName(nt)
 }
 
 func (t *nodeType) AddNamedPortType(p bh.PortTypeIf) {
@@ -42,36 +60,52 @@ func (t *nodeType) AddNamedPortType(p bh.PortTypeIf) {
 		}
 	}
 	for _, impl := range t.implementation.Implementations() {
-		if impl.ImplementationType() == bh.NodeTypeGraph {
-			if p.Direction() == gr.InPort {
-				impl.Graph().(*signalGraphType).addInputNodeFromPortType(p)
-			} else {
-				impl.Graph().(*signalGraphType).addOutputNodeFromPortType(p)
-			}
+		// referenced graphs not loaded yet are bound when loaded
+		g := impl.(*implementation).loadedGraph()
+		if g == nil || g.linkedNode(p) != nil {
+			continue
+		}
+		if p.Direction() == gr.InPort {
+			g.addInputNodeFromPortType(p)
+		} else {
+			g.addOutputNodeFromPortType(p)
 		}
 	}
 }
 
 func (t *nodeType) RemoveNamedPortType(p bh.PortTypeIf) {
-	for _, impl := range t.implementation.Implementations() {
-		if impl.ImplementationType() == bh.NodeTypeGraph {
-			if p.Direction() == gr.InPort {
-				impl.Graph().(*signalGraphType).removeInputNodeFromPortType(p)
-			} else {
-				impl.Graph().(*signalGraphType).removeOutputNodeFromPortType(p)
-			}
-		}
-	}
-	for _, n := range t.instances.Nodes() {
-		n.(*node).removePort(p.(*portType))
-	}
 	var list *portTypeList
 	if p.Direction() == gr.InPort {
 		list = &t.inPorts
 	} else {
 		list = &t.outPorts
 	}
+	// Remove p first: removing the linked io-node of a referenced
+	// graph must not remove p again.
 	list.Remove(p)
+	for _, impl := range t.implementation.Implementations() {
+		g := impl.(*implementation).loadedGraph()
+		if g == nil {
+			continue
+		}
+		if p.Direction() == gr.InPort {
+			g.removeInputNodeFromPortType(p)
+		} else {
+			g.removeOutputNodeFromPortType(p)
+		}
+	}
+	for _, n := range t.instances.Nodes() {
+		n.(*node).removePort(p.(*portType))
+	}
+}
+
+func (t *nodeType) hasPortType(p bh.PortTypeIf) bool {
+	for _, pt := range append(t.InPorts(), t.OutPorts()...) {
+		if pt == p {
+			return true
+		}
+	}
+	return false
 }
 
 func (t *nodeType) Instances() []bh.NodeIf {
@@ -87,7 +121,10 @@ func (t *nodeType) removeInstance(n *node) {
 }
 
 func (t *nodeType) RemoveImplementation(imp bh.ImplementationIf) {
-	if imp.ImplementationType() == bh.NodeTypeGraph {
+	if len(imp.GraphRef()) > 0 {
+		// the referenced graph belongs to its signal graph document
+		imp.(*implementation).unbind()
+	} else if imp.ImplementationType() == bh.NodeTypeGraph {
 		gt := imp.Graph()
 		for len(gt.Nodes()) > 0 {
 			gt.RemoveNode(gt.Nodes()[0].(*node))
@@ -97,7 +134,13 @@ func (t *nodeType) RemoveImplementation(imp bh.ImplementationIf) {
 }
 
 func (t *nodeType) AddImplementation(imp bh.ImplementationIf) {
-	if imp.ImplementationType() == bh.NodeTypeGraph {
+	imp.(*implementation).nodeType = t
+	if len(imp.GraphRef()) > 0 {
+		g := imp.(*implementation).loadedGraph()
+		if g != nil {
+			imp.(*implementation).bind(g)
+		}
+	} else if imp.ImplementationType() == bh.NodeTypeGraph {
 		if imp.Graph() == nil {
 			log.Fatal("nodeType.AddImplementation: missing graph")
 		}
@@ -117,7 +160,14 @@ func (t *nodeType) TypeName() string {
 }
 
 func (t *nodeType) SetTypeName(newTypeName string) {
+	oldName := t.name
+	refersTo := func(typeName string, scope map[string]bool) bool {
+		nt, err := t.registry.ResolveNodeType(typeName, scope)
+		return err == nil && nt == bh.NodeTypeIf(t)
+	}
+	renameCompatibilities(t.registry, refersTo, t.definedAt, oldName, newTypeName, false)
 	t.name = newTypeName
+	t.registry.RenameRegisteredNodeType(t, oldName)
 }
 
 func (t *nodeType) DefinedAt() string {
@@ -136,24 +186,66 @@ func (t *nodeType) Implementation() []bh.ImplementationIf {
 	return t.implementation.Implementations()
 }
 
-func createNodeTypeFromXmlNode(n backend.XmlNode, ntName string) *nodeType {
-	nt := NodeTypeNew(ntName, "")
+func (t *nodeType) Parameters() []bh.ParameterIf {
+	return t.parameters
+}
+
+func parameterByName(t bh.NodeTypeIf, name string) (p bh.ParameterIf, ok bool) {
+	for _, p = range t.Parameters() {
+		if p.Name() == name {
+			ok = true
+			return
+		}
+	}
+	p = nil
+	return
+}
+
+func (t *nodeType) AddParameter(p bh.ParameterIf) error {
+	_, ok := parameterByName(t, p.Name())
+	if ok {
+		return fmt.Errorf("nodeType.AddParameter error: %s already has parameter %s", t.name, p.Name())
+	}
+	t.parameters = append(t.parameters, p)
+	return nil
+}
+
+// Values the instances set for p are removed with p.
+func (t *nodeType) RemoveParameter(p bh.ParameterIf) {
+	for i, q := range t.parameters {
+		if q == p {
+			t.parameters = append(t.parameters[:i], t.parameters[i+1:]...)
+			break
+		}
+	}
+	for _, n := range t.instances.Nodes() {
+		delete(n.(*node).params, p.Name())
+	}
+}
+
+func createNodeTypeFromXmlNode(n backend.XmlNode, ntName string, context mod.ModelContextIf,
+	scope map[string]bool) (nt *nodeType, err error) {
+	nt = NodeTypeNew(ntName, "", context)
 	for _, p := range n.InPort {
-		pType, ok := freesp.GetSignalTypeByName(p.PType)
-		if !ok {
-			log.Fatalf("createNodeTypeFromXmlNode error: FIXME signal type '%s' not found\n", p.PType)
+		var pType bh.SignalTypeIf
+		pType, err = nt.registry.ResolveSignalType(p.PType, scope)
+		if err != nil {
+			err = fmt.Errorf("createNodeTypeFromXmlNode error: node %s: %s", n.NName, err)
+			return
 		}
 		nt.addInPort(p.PName, pType)
 	}
 	for _, p := range n.OutPort {
-		pType, ok := freesp.GetSignalTypeByName(p.PType)
-		if !ok {
-			log.Fatalf("createNodeTypeFromXmlNode error: FIXME signal type '%s' not found\n", p.PType)
+		var pType bh.SignalTypeIf
+		pType, err = nt.registry.ResolveSignalType(p.PType, scope)
+		if err != nil {
+			err = fmt.Errorf("createNodeTypeFromXmlNode error: node %s: %s", n.NName, err)
+			return
 		}
 		nt.addOutPort(p.PName, pType)
 	}
-	freesp.RegisterNodeType(nt)
-	return nt
+	nt.registry.RegisterNodeType(nt)
+	return
 }
 
 func (t *nodeType) CreateXml() (buf []byte, err error) {
@@ -164,11 +256,11 @@ func (t *nodeType) CreateXml() (buf []byte, err error) {
 
 // TODO: These are possibly redundant..
 func (t *nodeType) addInPort(name string, pType bh.SignalTypeIf) {
-	t.inPorts.Append(PortTypeNew(name, pType.TypeName(), gr.InPort))
+	t.inPorts.Append(&portType{pType, name, gr.InPort})
 }
 
 func (t *nodeType) addOutPort(name string, pType bh.SignalTypeIf) {
-	t.outPorts.Append(PortTypeNew(name, pType.TypeName(), gr.OutPort))
+	t.outPorts.Append(&portType{pType, name, gr.OutPort})
 }
 
 func (t *nodeType) doResolvePort(name string, dir gr.PortDirection) *portType {
@@ -201,14 +293,19 @@ func (t *nodeType) doResolvePort(name string, dir gr.PortDirection) *portType {
 	return nil
 }
 
-func createNodeTypeFromXml(xmlnt backend.XmlNodeType, filename string, context mod.ModelContextIf) *nodeType {
-	nt := NodeTypeNew(xmlnt.TypeName, filename)
+// Parameter declarations and the parameter values of embedded graph
+// implementations are checked.
+func createNodeTypeFromXml(xmlnt backend.XmlNodeType, filename, refDir string, context mod.ModelContextIf,
+	scope map[string]bool) (nt *nodeType, err error) {
+	nt = NodeTypeNew(xmlnt.TypeName, filename, context)
 	for _, xmlp := range xmlnt.InPort {
-		pType, ok := freesp.GetSignalTypeByName(xmlp.PType)
-		if !ok {
-			log.Fatalf("createNodeTypeFromXml error: FIXME: signal type '%s' not found\n", xmlp.PType)
+		var pType bh.SignalTypeIf
+		pType, err = nt.registry.ResolveSignalType(xmlp.PType, scope)
+		if err != nil {
+			err = fmt.Errorf("node type %s: %s", xmlnt.TypeName, err)
+			return
 		}
-		pt := PortTypeNew(xmlp.PName, pType.TypeName(), gr.InPort)
+		pt := &portType{pType, xmlp.PName, gr.InPort}
 		//for _, xmlmp := range xmlp.Entry {
 		//	pt.position[freesp.ModeFromString[xmlmp.Mode]] = image.Point{xmlmp.X, xmlmp.Y}
 		//}
@@ -216,18 +313,37 @@ func createNodeTypeFromXml(xmlnt backend.XmlNodeType, filename string, context m
 		//nt.addInPort(xmlp.PName, pType)
 	}
 	for _, xmlp := range xmlnt.OutPort {
-		pType, ok := freesp.GetSignalTypeByName(xmlp.PType)
-		if !ok {
-			log.Fatalf("createNodeTypeFromXml error: FIXME: signal type '%s' not found\n", xmlp.PType)
+		var pType bh.SignalTypeIf
+		pType, err = nt.registry.ResolveSignalType(xmlp.PType, scope)
+		if err != nil {
+			err = fmt.Errorf("node type %s: %s", xmlnt.TypeName, err)
+			return
 		}
-		pt := PortTypeNew(xmlp.PName, pType.TypeName(), gr.OutPort)
+		pt := &portType{pType, xmlp.PName, gr.OutPort}
 		//for _, xmlmp := range xmlp.Entry {
 		//	pt.position[freesp.ModeFromString[xmlmp.Mode]] = image.Point{xmlmp.X, xmlmp.Y}
 		//}
 		nt.outPorts.Append(pt)
 		//nt.addOutPort(xmlp.PName, pType)
 	}
+	for _, xmlp := range xmlnt.Parameter {
+		var p *parameter
+		p, err = createParameterFromXml(xmlp)
+		if err == nil {
+			err = nt.AddParameter(p)
+		}
+		if err != nil {
+			err = fmt.Errorf("createNodeTypeFromXml error: node type %s: %s", xmlnt.TypeName, err)
+			return
+		}
+	}
 	for _, i := range xmlnt.Implementation {
+		if len(i.GraphRef) > 0 {
+			impl := GraphRefImplementationNew(i.Name, i.GraphRef, refDir, context)
+			impl.nodeType = nt
+			nt.implementation.Append(impl)
+			continue
+		}
 		var iType bh.ImplementationType
 		if len(i.SignalGraph) == 1 {
 			iType = bh.NodeTypeGraph
@@ -235,22 +351,40 @@ func createNodeTypeFromXml(xmlnt backend.XmlNodeType, filename string, context m
 			iType = bh.NodeTypeElement
 		}
 		impl := ImplementationNew(i.Name, iType, context)
+		impl.nodeType = nt
 		nt.implementation.Append(impl)
 		switch iType {
 		case bh.NodeTypeElement:
 			impl.elementName = i.Name
 		default:
-			var err error
 			var resolvePort = func(name string, dir gr.PortDirection) *portType {
 				return nt.doResolvePort(name, dir)
 			}
-			impl.graph, err = createSignalGraphTypeFromXml(&i.SignalGraph[0], xmlnt.TypeName, context, resolvePort)
+			impl.graph, err = createSignalGraphTypeFromXml(&i.SignalGraph[0], xmlnt.TypeName, refDir, context, scope, resolvePort)
 			if err != nil {
 				log.Fatal(err)
 			}
+			err = checkParamValues(impl.graph, nt)
+			if err != nil {
+				err = fmt.Errorf("createNodeTypeFromXml error: implementation %s of %s: %s", i.Name, xmlnt.TypeName, err)
+				return
+			}
 		}
 	}
-	return nt
+	return
+}
+
+func createParameterFromXml(xmlp backend.XmlParameter) (p *parameter, err error) {
+	pType, ok := ParamTypeFromString(xmlp.PType)
+	if !ok {
+		err = fmt.Errorf("parameter %s: unknown type '%s'", xmlp.Name, xmlp.PType)
+		return
+	}
+	var values []string
+	if len(xmlp.Values) > 0 {
+		values = strings.Split(xmlp.Values, ",")
+	}
+	return ParameterNew(xmlp.Name, pType, xmlp.Default, xmlp.Min, xmlp.Max, values)
 }
 
 /*
@@ -407,10 +541,11 @@ func (t *nodeType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEle
 	return
 }
 
-func (t *nodeType) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (t *nodeType) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parentId := tree.Parent(cursor)
 	if t != tree.Object(parentId) {
-		log.Fatal("nodeType.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("nodeType.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
@@ -427,7 +562,11 @@ func (t *nodeType) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed [
 				for _, p := range n.OutPorts() {
 					pCursor := tree.CursorAt(nCursor, p)
 					for index, c := range p.Connections() {
-						conn := p.Connection(c)
+						conn, err := p.Connection(c)
+						if err != nil {
+							log.Printf("nodeType.RemoveObject: %s\n", err)
+							continue
+						}
 						removed = append(removed, tr.IdWithObject{pCursor.Path, index, conn})
 					}
 				}
@@ -454,20 +593,29 @@ func (t *nodeType) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed [
 					n = g.findOutputNodeFromPortType(nt)
 				}
 				if n == nil {
-					log.Fatalf("nodeType.RemoveObject error: invalid implementation...\n")
+					err = freesp.InconsistentModelError("nodeType.RemoveObject", "implementation of %s has no node for port %s", t.TypeName(), nt.Name())
+					return
 				}
 				nCursor := tree.CursorAt(parentId, n)
 				for _, p := range n.InPorts() {
 					pCursor := tree.CursorAt(nCursor, p)
 					for _, c := range p.Connections() {
-						conn := p.Connection(c)
+						conn, err := p.Connection(c)
+						if err != nil {
+							log.Printf("nodeType.RemoveObject: %s\n", err)
+							continue
+						}
 						removed = append(removed, tr.IdWithObject{pCursor.Path, -1, conn})
 					}
 				}
 				for _, p := range n.OutPorts() {
 					pCursor := tree.CursorAt(nCursor, p)
 					for _, c := range p.Connections() {
-						conn := p.Connection(c)
+						conn, err := p.Connection(c)
+						if err != nil {
+							log.Printf("nodeType.RemoveObject: %s\n", err)
+							continue
+						}
 						removed = append(removed, tr.IdWithObject{pCursor.Path, -1, conn})
 					}
 				}
@@ -477,16 +625,17 @@ func (t *nodeType) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed [
 		}
 
 	default:
-		log.Fatalf("nodeType.RemoveObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("nodeType.RemoveObject", "cannot remove %T from a node type", obj)
 	}
 	return
 }
 
 // Remove object mirrored in all instance node type
-func (t *nodeType) treeRemoveInstObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (t *nodeType) treeRemoveInstObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parentId := tree.Parent(cursor)
 	if t != tree.Object(parentId) {
-		log.Fatal("nodeType.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("nodeType.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
@@ -510,43 +659,64 @@ func (t *nodeType) treeRemoveInstObject(tree tr.TreeIf, cursor tr.Cursor) (remov
 			} else {
 				list = n.OutPorts()
 			}
-			for _, p = range list {
-				if p.Name() == nt.Name() {
+			for _, pp := range list {
+				if pp.Name() == nt.Name() {
+					p = pp
 					break
 				}
 			}
-			_ = p.(*port)
+			if p == nil {
+				err = freesp.InconsistentModelError("nodeType.RemoveObject", "instance %s has no port %s", n.Name(), nt.Name())
+				return
+			}
 			pCursor := tree.CursorAt(nCursor, p)
 			prefix, index := tree.Remove(pCursor)
 			removed = append(removed, tr.IdWithObject{prefix, index, p})
 			tCursor := tree.CursorAt(nCursor, obj)
-			del := t.treeRemoveObject(tree, tCursor)
+			var del []tr.IdWithObject
+			del, err = t.treeRemoveObject(tree, tCursor)
 			for _, d := range del {
 				removed = append(removed, d)
 			}
+			if err != nil {
+				return
+			}
 			tree.Remove(tCursor)
 		}
 
 	default:
-		log.Fatalf("nodeType.RemoveObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("nodeType.RemoveObject", "cannot remove %T from a node type", obj)
 	}
 	return
 }
 
-func (t *nodeType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (t *nodeType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parentId := tree.Parent(cursor)
 	if t != tree.Object(parentId) {
-		log.Fatal("nodeType.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("nodeType.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
-	del := t.treeRemoveObject(tree, cursor)
+	switch obj.(type) {
+	case bh.ImplementationIf, bh.PortTypeIf:
+	default:
+		err = freesp.InvalidOperationError("nodeType.RemoveObject", "cannot remove %T from a node type", obj)
+		return
+	}
+	del, err := t.treeRemoveObject(tree, cursor)
 	for _, d := range del {
 		removed = append(removed, d)
 	}
-	del = t.treeRemoveInstObject(tree, cursor)
+	if err != nil {
+		return
+	}
+	del, err = t.treeRemoveInstObject(tree, cursor)
 	for _, d := range del {
 		removed = append(removed, d)
 	}
+	if err != nil {
+		return
+	}
 	prefix, index := tree.Remove(cursor)
 	removed = append(removed, tr.IdWithObject{prefix, index, obj})
 
@@ -559,9 +729,6 @@ func (t *nodeType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.
 	case bh.PortTypeIf:
 		nt := obj.(bh.PortTypeIf)
 		t.RemoveNamedPortType(nt)
-
-	default:
-		log.Fatalf("nodeType.RemoveObject error: invalid type %T\n", obj)
 	}
 	return
 }
@@ -0,0 +1,670 @@
+package behaviour
+
+import (
+	"fmt"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Node types declare typed parameters, their nodes set values for
+// them. Parameters a node does not set take their default; parameters
+// without default must be set by every node (except string
+// parameters, which default to the empty string).
+//
+// Within graph implementations, values are expressions over the
+// parameters of the enclosing node type: $name and ${name} are
+// replaced by the value of that parameter, int and float values may
+// then use + - * / % and parentheses. Values of int parameters must
+// evaluate to integral numbers. Evaluation proceeds from the top level
+// graph downwards, the values of a node being the environment of the
+// nodes in its implementation graph.
+
+type parameter struct {
+	name          string
+	pType         bh.ParamType
+	def, min, max string
+	values        []string
+}
+
+var _ bh.ParameterIf = (*parameter)(nil)
+
+var paramTypeNames = []string{"int", "float", "string", "enum"}
+
+func ParamTypeString(t bh.ParamType) string {
+	return paramTypeNames[t]
+}
+
+func ParamTypeFromString(s string) (t bh.ParamType, ok bool) {
+	for i, name := range paramTypeNames {
+		if name == s {
+			return bh.ParamType(i), true
+		}
+	}
+	return
+}
+
+func ParameterNew(name string, pType bh.ParamType, def, min, max string, values []string) (p *parameter, err error) {
+	if !isParamName(name) {
+		err = fmt.Errorf("ParameterNew error: invalid parameter name '%s'", name)
+		return
+	}
+	p = &parameter{name, pType, def, min, max, values}
+	switch pType {
+	case bh.ParamInt, bh.ParamFloat:
+		if len(values) > 0 {
+			err = fmt.Errorf("ParameterNew error: parameter %s: values apply to enum parameters only", name)
+			return
+		}
+		for _, limit := range []string{min, max} {
+			if len(limit) > 0 {
+				_, err = p.number(limit)
+				if err != nil {
+					err = fmt.Errorf("ParameterNew error: %s", err)
+					return
+				}
+			}
+		}
+		if len(min) > 0 && len(max) > 0 {
+			lo, _ := p.number(min)
+			hi, _ := p.number(max)
+			if lo > hi {
+				err = fmt.Errorf("ParameterNew error: parameter %s: empty range %s..%s", name, min, max)
+				return
+			}
+		}
+	case bh.ParamEnum:
+		if len(values) == 0 {
+			err = fmt.Errorf("ParameterNew error: enum parameter %s has no values", name)
+			return
+		}
+		fallthrough
+	default:
+		if len(min) > 0 || len(max) > 0 {
+			err = fmt.Errorf("ParameterNew error: parameter %s: ranges apply to int and float parameters only", name)
+			return
+		}
+	}
+	if len(def) > 0 {
+		err = p.CheckValue(def)
+		if err != nil {
+			err = fmt.Errorf("ParameterNew error: default: %s", err)
+			return
+		}
+	}
+	return
+}
+
+func (p *parameter) Name() string {
+	return p.name
+}
+
+func (p *parameter) ParamType() bh.ParamType {
+	return p.pType
+}
+
+func (p *parameter) Default() string {
+	return p.def
+}
+
+func (p *parameter) Min() string {
+	return p.min
+}
+
+func (p *parameter) Max() string {
+	return p.max
+}
+
+func (p *parameter) Values() []string {
+	return p.values
+}
+
+// CheckValue checks a literal value (no expression).
+func (p *parameter) CheckValue(value string) error {
+	switch p.pType {
+	case bh.ParamInt, bh.ParamFloat:
+		v, err := p.number(value)
+		if err != nil {
+			return err
+		}
+		if len(p.min) > 0 {
+			lo, _ := p.number(p.min)
+			if v < lo {
+				return fmt.Errorf("parameter %s: %s below minimum %s", p.name, value, p.min)
+			}
+		}
+		if len(p.max) > 0 {
+			hi, _ := p.number(p.max)
+			if v > hi {
+				return fmt.Errorf("parameter %s: %s above maximum %s", p.name, value, p.max)
+			}
+		}
+	case bh.ParamEnum:
+		for _, v := range p.values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("parameter %s: %s is none of %s", p.name, value, strings.Join(p.values, ", "))
+	}
+	return nil
+}
+
+func (p *parameter) number(value string) (v float64, err error) {
+	if p.pType == bh.ParamInt {
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		v = float64(i)
+	} else {
+		v, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		err = fmt.Errorf("parameter %s: %s is no %s", p.name, value, ParamTypeString(p.pType))
+	}
+	return
+}
+
+func (p *parameter) String() (s string) {
+	s = fmt.Sprintf("%s: %s", p.name, ParamTypeString(p.pType))
+	if p.pType == bh.ParamEnum {
+		s = fmt.Sprintf("%s(%s)", s, strings.Join(p.values, ","))
+	}
+	if len(p.def) > 0 {
+		s = fmt.Sprintf("%s = %s", s, p.def)
+	}
+	if len(p.min) > 0 || len(p.max) > 0 {
+		s = fmt.Sprintf("%s [%s..%s]", s, p.min, p.max)
+	}
+	return
+}
+
+// ParseParameter reads a parameter declaration in the notation of
+// String, e.g. "taps: int = 16 [1..64]" or "window: enum(hann,flat)".
+func ParseParameter(text string) (p *parameter, err error) {
+	colon := strings.Index(text, ":")
+	if colon < 0 {
+		err = fmt.Errorf("ParseParameter error: missing ':' in '%s'", text)
+		return
+	}
+	name := strings.TrimSpace(text[:colon])
+	rest := strings.TrimSpace(text[colon+1:])
+	var min, max, def string
+	var values []string
+	if i := strings.Index(rest, "["); i >= 0 {
+		j := strings.Index(rest, "]")
+		var limits []string
+		if j > i {
+			limits = strings.Split(rest[i+1:j], "..")
+		}
+		if len(limits) != 2 {
+			err = fmt.Errorf("ParseParameter error: invalid range in '%s'", text)
+			return
+		}
+		min, max = strings.TrimSpace(limits[0]), strings.TrimSpace(limits[1])
+		rest = strings.TrimSpace(rest[:i] + rest[j+1:])
+	}
+	if i := strings.Index(rest, "="); i >= 0 {
+		def = strings.TrimSpace(rest[i+1:])
+		rest = strings.TrimSpace(rest[:i])
+	}
+	if i := strings.Index(rest, "("); i >= 0 {
+		if !strings.HasSuffix(rest, ")") {
+			err = fmt.Errorf("ParseParameter error: invalid values in '%s'", text)
+			return
+		}
+		for _, v := range strings.Split(rest[i+1:len(rest)-1], ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		rest = strings.TrimSpace(rest[:i])
+	}
+	pType, ok := ParamTypeFromString(rest)
+	if !ok {
+		err = fmt.Errorf("ParseParameter error: unknown parameter type '%s'", rest)
+		return
+	}
+	return ParameterNew(name, pType, def, min, max, values)
+}
+
+func isParamName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, c := range name {
+		if !isParamNameChar(c) || (i == 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isParamNameChar(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func hasParamRef(expr string) bool {
+	return strings.Contains(expr, "$")
+}
+
+// EvalParam evaluates the value expression expr of parameter p in the
+// environment env (parameter name -> value) and checks the result.
+func EvalParam(p bh.ParameterIf, expr string, env map[string]string) (value string, err error) {
+	value, err = substParams(expr, env)
+	if err != nil {
+		err = fmt.Errorf("parameter %s: %s", p.Name(), err)
+		return
+	}
+	switch p.ParamType() {
+	case bh.ParamInt, bh.ParamFloat:
+		var v float64
+		v, err = evalArith(value)
+		if err != nil {
+			err = fmt.Errorf("parameter %s: %s: %s", p.Name(), expr, err)
+			return
+		}
+		if p.ParamType() == bh.ParamInt {
+			if v != math.Trunc(v) {
+				err = fmt.Errorf("parameter %s: %s is not integral", p.Name(), expr)
+				return
+			}
+			value = strconv.FormatInt(int64(v), 10)
+		} else {
+			value = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	err = p.CheckValue(value)
+	return
+}
+
+func substParams(expr string, env map[string]string) (s string, err error) {
+	for {
+		i := strings.Index(expr, "$")
+		if i < 0 {
+			s += expr
+			return
+		}
+		s += expr[:i]
+		expr = expr[i+1:]
+		var name string
+		if strings.HasPrefix(expr, "{") {
+			j := strings.Index(expr, "}")
+			if j < 0 {
+				err = fmt.Errorf("missing '}'")
+				return
+			}
+			name, expr = expr[1:j], expr[j+1:]
+		} else {
+			j := 0
+			for j < len(expr) && isParamNameChar(rune(expr[j])) {
+				j++
+			}
+			name, expr = expr[:j], expr[j:]
+		}
+		v, ok := env[name]
+		if !ok {
+			err = fmt.Errorf("unknown parameter $%s", name)
+			return
+		}
+		s += v
+	}
+}
+
+// Evaluates arithmetic expressions over float numbers.
+type arith struct {
+	text string
+	pos  int
+}
+
+func evalArith(text string) (v float64, err error) {
+	a := &arith{text, 0}
+	v, err = a.sum()
+	if err == nil && a.peek() != 0 {
+		err = fmt.Errorf("unexpected '%c'", a.peek())
+	}
+	return
+}
+
+func (a *arith) peek() byte {
+	for a.pos < len(a.text) && (a.text[a.pos] == ' ' || a.text[a.pos] == '\t') {
+		a.pos++
+	}
+	if a.pos < len(a.text) {
+		return a.text[a.pos]
+	}
+	return 0
+}
+
+func (a *arith) sum() (v float64, err error) {
+	v, err = a.product()
+	for err == nil {
+		op := a.peek()
+		if op != '+' && op != '-' {
+			return
+		}
+		a.pos++
+		var w float64
+		w, err = a.product()
+		if op == '+' {
+			v += w
+		} else {
+			v -= w
+		}
+	}
+	return
+}
+
+func (a *arith) product() (v float64, err error) {
+	v, err = a.unary()
+	for err == nil {
+		op := a.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return
+		}
+		a.pos++
+		var w float64
+		w, err = a.unary()
+		if err != nil {
+			return
+		}
+		switch {
+		case op == '*':
+			v *= w
+		case w == 0:
+			err = fmt.Errorf("division by zero")
+		case op == '/':
+			v /= w
+		default:
+			v = math.Mod(v, w)
+		}
+	}
+	return
+}
+
+func (a *arith) unary() (v float64, err error) {
+	switch a.peek() {
+	case '-':
+		a.pos++
+		v, err = a.unary()
+		v = -v
+		return
+	case '+':
+		a.pos++
+		return a.unary()
+	case '(':
+		a.pos++
+		v, err = a.sum()
+		if err == nil {
+			if a.peek() != ')' {
+				err = fmt.Errorf("missing ')'")
+				return
+			}
+			a.pos++
+		}
+		return
+	case 0:
+		err = fmt.Errorf("unexpected end")
+		return
+	}
+	start := a.pos
+	for a.pos < len(a.text) && strings.IndexByte("0123456789.eE", a.text[a.pos]) >= 0 {
+		if (a.text[a.pos] == 'e' || a.text[a.pos] == 'E') && a.pos+1 < len(a.text) &&
+			(a.text[a.pos+1] == '-' || a.text[a.pos+1] == '+') {
+			a.pos++
+		}
+		a.pos++
+	}
+	if start == a.pos {
+		err = fmt.Errorf("unexpected '%c'", a.text[a.pos])
+		return
+	}
+	v, err = strconv.ParseFloat(a.text[start:a.pos], 64)
+	if err != nil {
+		err = fmt.Errorf("invalid number %s", a.text[start:a.pos])
+	}
+	return
+}
+
+// ParamValues returns the values of all parameters of n's type,
+// evaluated in env: the values of the node whose implementation graph
+// contains n (nil at top level).
+func ParamValues(n bh.NodeIf, env map[string]string) (values map[string]string, err error) {
+	values = make(map[string]string)
+	for _, p := range n.ItsType().Parameters() {
+		v, ok := n.ParamValue(p.Name())
+		if !ok {
+			if len(p.Default()) == 0 && p.ParamType() != bh.ParamString {
+				err = fmt.Errorf("node %s: parameter %s not set", n.Name(), p.Name())
+				return
+			}
+			values[p.Name()] = p.Default()
+			continue
+		}
+		values[p.Name()], err = EvalParam(p, v, env)
+		if err != nil {
+			err = fmt.Errorf("node %s: %s", n.Name(), err)
+			return
+		}
+	}
+	return
+}
+
+// PathParamValues returns the parameter values of the node at path
+// within g, node names separated by '/' like in NodeByPath. The values
+// of each node on the path are propagated into its implementation
+// graph.
+func PathParamValues(g bh.SignalGraphTypeIf, path string) (values map[string]string, err error) {
+	var env map[string]string
+	names := strings.Split(path, "/")
+	for i, name := range names {
+		n, ok := g.NodeByName(name)
+		if !ok {
+			err = fmt.Errorf("PathParamValues error: node %s not found", strings.Join(names[:i+1], "/"))
+			return
+		}
+		values, err = ParamValues(n, env)
+		if err != nil {
+			err = fmt.Errorf("PathParamValues error: %s", err)
+			return
+		}
+		if i == len(names)-1 {
+			break
+		}
+		g = graphImplementation(n.ItsType())
+		if g == nil {
+			err = fmt.Errorf("PathParamValues error: node %s has no graph implementation", strings.Join(names[:i+1], "/"))
+			return
+		}
+		env = values
+	}
+	return
+}
+
+func graphImplementation(nt bh.NodeTypeIf) bh.SignalGraphTypeIf {
+	for _, impl := range nt.Implementation() {
+		if impl.ImplementationType() == bh.NodeTypeGraph {
+			return impl.Graph()
+		}
+	}
+	return nil
+}
+
+// A value for each parameter of nt, used to check the expressions of
+// its implementation graphs.
+func sampleParamValues(nt bh.NodeTypeIf) map[string]string {
+	env := make(map[string]string)
+	for _, p := range nt.Parameters() {
+		var v string
+		switch {
+		case len(p.Default()) > 0:
+			v = p.Default()
+		case len(p.Min()) > 0:
+			v = p.Min()
+		case len(p.Max()) > 0:
+			v = p.Max()
+		case p.ParamType() == bh.ParamEnum:
+			v = p.Values()[0]
+		case p.ParamType() != bh.ParamString:
+			v = "0"
+		}
+		env[p.Name()] = v
+	}
+	return env
+}
+
+// Checks the parameter values of the processing nodes of g. In
+// implementation graphs of nt, references are resolved with sample
+// values of the parameters of nt. In signal graph documents (nt nil),
+// values referring to parameters are skipped: the document may be the
+// implementation of a node type.
+func checkParamValues(g bh.SignalGraphTypeIf, nt bh.NodeTypeIf) error {
+	var env map[string]string
+	if nt != nil {
+		env = sampleParamValues(nt)
+	}
+	for _, n := range g.ProcessingNodes() {
+		err := checkNodeParamValues(n, nt, env)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckNodeParamValues checks the parameter values of n, a node of an
+// implementation graph of outer (nil for signal graph documents), like
+// done when reading documents.
+func CheckNodeParamValues(n bh.NodeIf, outer bh.NodeTypeIf) error {
+	var env map[string]string
+	if outer != nil {
+		env = sampleParamValues(outer)
+	}
+	return checkNodeParamValues(n, outer, env)
+}
+
+func checkNodeParamValues(n bh.NodeIf, nt bh.NodeTypeIf, env map[string]string) error {
+	for _, p := range n.ItsType().Parameters() {
+		v, ok := n.ParamValue(p.Name())
+		if !ok {
+			if len(p.Default()) == 0 && p.ParamType() != bh.ParamString {
+				return fmt.Errorf("node %s: parameter %s not set", n.Name(), p.Name())
+			}
+			continue
+		}
+		if nt == nil && hasParamRef(v) {
+			continue
+		}
+		_, err := EvalParam(p, v, env)
+		if err != nil {
+			return fmt.Errorf("node %s: %s", n.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Parameter declarations and values in the notation of the edit
+// dialogs: declarations "taps: int = 16 [1..64]; win: enum(hann,flat)",
+// values "taps=32; win=flat".
+
+func ParametersString(list []bh.ParameterIf) string {
+	var decls []string
+	for _, p := range list {
+		decls = append(decls, fmt.Sprint(p))
+	}
+	return strings.Join(decls, "; ")
+}
+
+func ParseParameters(text string) (list []bh.ParameterIf, err error) {
+	for _, decl := range strings.Split(text, ";") {
+		if len(strings.TrimSpace(decl)) == 0 {
+			continue
+		}
+		var p *parameter
+		p, err = ParseParameter(decl)
+		if err != nil {
+			return
+		}
+		for _, q := range list {
+			if q.Name() == p.Name() {
+				err = fmt.Errorf("ParseParameters error: parameter %s declared twice", p.Name())
+				return
+			}
+		}
+		list = append(list, p)
+	}
+	return
+}
+
+// SetParameters replaces the parameter declarations of nt. Values of
+// its instances are kept as far as they are valid for the new
+// declarations.
+func SetParameters(nt bh.NodeTypeIf, list []bh.ParameterIf) {
+	saved := make(map[bh.NodeIf]map[string]string)
+	for _, n := range nt.Instances() {
+		saved[n] = make(map[string]string)
+		for _, p := range nt.Parameters() {
+			v, ok := n.ParamValue(p.Name())
+			if ok {
+				saved[n][p.Name()] = v
+			}
+		}
+	}
+	for len(nt.Parameters()) > 0 {
+		nt.RemoveParameter(nt.Parameters()[0])
+	}
+	for _, p := range list {
+		nt.AddParameter(p)
+	}
+	for n, values := range saved {
+		for name, v := range values {
+			if _, ok := parameterByName(nt, name); !ok {
+				continue
+			}
+			err := n.SetParamValue(name, v)
+			if err != nil {
+				log.Printf("SetParameters warning: value dropped: %s\n", err)
+			}
+		}
+	}
+}
+
+func ParamValuesString(n bh.NodeIf) string {
+	var values []string
+	for _, p := range n.ItsType().Parameters() {
+		v, ok := n.ParamValue(p.Name())
+		if ok {
+			values = append(values, fmt.Sprintf("%s=%s", p.Name(), v))
+		}
+	}
+	return strings.Join(values, "; ")
+}
+
+// SetParamValues sets the values of n given in text, the other
+// parameters are reset to their defaults. On error n is unchanged.
+func SetParamValues(n bh.NodeIf, text string) (err error) {
+	old := make(map[string]string)
+	for _, p := range n.ItsType().Parameters() {
+		old[p.Name()], _ = n.ParamValue(p.Name())
+		n.SetParamValue(p.Name(), "")
+	}
+	for _, assign := range strings.Split(text, ";") {
+		if len(strings.TrimSpace(assign)) == 0 {
+			continue
+		}
+		i := strings.Index(assign, "=")
+		if i < 0 {
+			err = fmt.Errorf("SetParamValues error: missing '=' in '%s'", assign)
+			break
+		}
+		err = n.SetParamValue(strings.TrimSpace(assign[:i]), strings.TrimSpace(assign[i+1:]))
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		for name, v := range old {
+			n.SetParamValue(name, v)
+		}
+	}
+	return
+}
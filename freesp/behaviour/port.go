@@ -37,7 +37,7 @@ func newPort(pt bh.PortTypeIf, n bh.NodeIf) *port {
  *  	bh.NodeIf() bh.NodeIf
  *      bh.ConnectionIf(c *port) bh.ConnectionIf
  *  	AddConnection(bh.PortIf) error
- *  	RemoveConnection(c bh.PortIf)
+ *  	RemoveConnection(c bh.PortIf) error
  *  }
  *
  *  func PortConnect(port1, port2 bh.PortIf) error
@@ -48,8 +48,9 @@ func (p *port) Name() string {
 	return p.itsType.Name()
 }
 
-func (p *port) SetName(newName string) {
-	log.Panicf("port.SetName: not allowed.\n")
+// Ports are named by their port type.
+func (p *port) SetName(newName string) error {
+	return freesp.InvalidOperationError("port.SetName", "port %s/%s is named by its port type", p.node.Name(), p.Name())
 }
 
 func (p *port) SignalType() bh.SignalTypeIf {
@@ -60,8 +61,8 @@ func (p *port) Direction() gr.PortDirection {
 	return p.itsType.Direction()
 }
 
-func (p *port) SetDirection(gr.PortDirection) {
-	log.Panicf("port.SetDirection() is not allowed!\n")
+func (p *port) SetDirection(gr.PortDirection) error {
+	return freesp.InvalidOperationError("port.SetDirection", "port %s/%s has the direction of its port type", p.node.Name(), p.Name())
 }
 
 func (p *port) Connections() []bh.PortIf {
@@ -76,24 +77,30 @@ func (p *port) AddConnection(c bh.ConnectionIf) error {
 	return portConnect(p, c.(*connection))
 }
 
-func (p *port) RemoveConnection(c bh.PortIf) {
+func (p *port) RemoveConnection(c bh.PortIf) error {
 	_, ok, index := p.connected.Find(c.Node().Name(), c.Name())
 	if !ok {
-		log.Fatalf("port.bh.ConnectionIf error: port %v not in connected list of port %v\n", c, p)
+		return freesp.NotFoundError("port.RemoveConnection", "port %s/%s is not connected to %s/%s",
+			p.node.Name(), p.Name(), c.Node().Name(), c.Name())
+	}
+	err := p.connected.Remove(c)
+	if err != nil {
+		return err
 	}
-	p.connected.Remove(c)
 	for index++; index < len(p.conn); index++ {
 		p.conn[index-1] = p.conn[index]
 	}
 	p.conn = p.conn[:len(p.conn)-1]
+	return nil
 }
 
-func (p *port) Connection(c bh.PortIf) bh.ConnectionIf {
+func (p *port) Connection(c bh.PortIf) (bh.ConnectionIf, error) {
 	_, ok, index := p.connected.Find(c.Node().Name(), c.Name())
 	if !ok {
-		log.Fatalf("port.bh.ConnectionIf error: port %v not in connected list of port %v\n", c, p)
+		return nil, freesp.NotFoundError("port.Connection", "port %s/%s is not connected to %s/%s",
+			p.node.Name(), p.Name(), c.Node().Name(), c.Name())
 	}
-	return p.conn[index]
+	return p.conn[index], nil
 }
 
 func portConnect(port1 bh.PortIf, c *connection) error {
@@ -106,11 +113,12 @@ func portConnect(port1 bh.PortIf, c *connection) error {
 		port2 = c.from
 	}
 	p2 = port2.(*port)
-	if port1.SignalType().TypeName() != port2.SignalType().TypeName() {
-		return fmt.Errorf("type mismatch")
+	err := connectionTypeError(c.from.SignalType(), c.to.SignalType())
+	if err != nil {
+		return err
 	}
 	if port1.Direction() == port2.Direction() {
-		return fmt.Errorf("direction mismatch")
+		return freesp.InvalidOperationError("portConnect", "direction mismatch")
 	}
 	p1.connected.Append(port2.(*port))
 	p1.conn = append(p1.conn, c)
@@ -169,8 +177,13 @@ func (p *port) AddToTree(tree tr.TreeIf, cursor tr.Cursor) {
 	t := p.SignalType()
 	t.AddToTree(tree, child)
 	for _, c := range p.Connections() {
+		conn, err := p.Connection(c)
+		if err != nil {
+			log.Printf("port.AddToTree: %s\n", err)
+			continue
+		}
 		child = tree.Append(cursor)
-		p.Connection(c).AddToTree(tree, child)
+		conn.AddToTree(tree, child)
 	}
 	return
 }
@@ -198,17 +211,20 @@ func (p *port) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElement
 			thisPort = conn.From()
 		}
 		if p != thisPort {
-			log.Println("p =", p)
-			log.Println("thisPort =", thisPort)
-			log.Fatal("port.AddNewObject error: invalid connection ", conn)
+			err = freesp.InvalidOperationError("port.AddNewObject", "%v does not connect port %s/%s", conn, p.node.Name(), p.Name())
+			return
 		}
 		sgt := thisPort.Node().Context()
 		if sgt != otherPort.Node().Context() {
-			log.Fatal("port.AddNewObject error: nodes have different context: ", conn)
+			err = freesp.InvalidOperationError("port.AddNewObject", "nodes of %v have different context", conn)
+			return
 		}
 		contextCursor := tree.Parent(tree.Parent(cursor))
 
-		thisPort.AddConnection(conn)
+		err = thisPort.AddConnection(conn)
+		if err != nil {
+			return
+		}
 		newCursor = p.treeAddNewObject(tree, cursor, conn, otherPort)
 
 		context := tree.Object(contextCursor)
@@ -227,11 +243,11 @@ func (p *port) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElement
 			}
 
 		default:
-			log.Fatalf("port.AddNewObject error: wrong context type %T: %v\n", context, context)
+			err = freesp.InconsistentModelError("port.AddNewObject", "wrong context type %T", context)
 		}
 
 	default:
-		log.Fatalf("port.AddNewObject error: invalid type %T: %v\n", obj, obj)
+		err = freesp.InvalidOperationError("port.AddNewObject", "cannot add %T to a port", obj)
 	}
 	return
 }
@@ -246,10 +262,11 @@ func (p *port) treeRemoveObject(tree tr.TreeIf, cursor tr.Cursor, conn bh.Connec
 	return
 }
 
-func (p *port) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (p *port) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if p != tree.Object(parent) {
-		log.Fatal("port.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("port.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
@@ -259,22 +276,24 @@ func (p *port) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWi
 		if p.Direction() == gr.InPort {
 			otherPort = conn.From()
 			thisPort = conn.To()
-			if p != thisPort {
-				log.Fatal("port.RemoveObject error: invalid connection ", conn)
-			}
 		} else {
 			otherPort = conn.To()
 			thisPort = conn.From()
-			if p != thisPort {
-				log.Fatal("port.RemoveObject error: invalid connection ", conn)
-			}
+		}
+		if p != thisPort {
+			err = freesp.InconsistentModelError("port.RemoveObject", "%v does not connect port %s/%s", conn, p.node.Name(), p.Name())
+			return
 		}
 		contextCursor := tree.Parent(tree.Parent(tree.Parent(cursor)))
-		removed = p.treeRemoveObject(tree, cursor, conn, otherPort)
 		context := tree.Object(contextCursor)
 		switch context.(type) {
-		case bh.SignalGraphIf:
-		case bh.ImplementationIf:
+		case bh.SignalGraphIf, bh.ImplementationIf:
+		default:
+			err = freesp.InconsistentModelError("port.RemoveObject", "wrong context type %T", context)
+			return
+		}
+		removed = p.treeRemoveObject(tree, cursor, conn, otherPort)
+		if _, ok := context.(bh.ImplementationIf); ok {
 			// propagate removed edge to all instances of embracing type
 			nt := tree.Object(tree.Parent(contextCursor))
 			for _, nn := range nt.(bh.NodeTypeIf).Instances() {
@@ -284,15 +303,15 @@ func (p *port) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWi
 				cCursor := tree.CursorAt(pCursor, conn)
 				p.treeRemoveObject(tree, cCursor, conn, otherPort)
 			}
-
-		default:
-			log.Fatalf("port.RemoveObject error: wrong context type %T: %v\n", context, context)
 		}
-		p.RemoveConnection(otherPort)
-		otherPort.RemoveConnection(p)
+		for _, e := range []error{p.RemoveConnection(otherPort), otherPort.RemoveConnection(p)} {
+			if e != nil {
+				log.Printf("port.RemoveObject: %s\n", e)
+			}
+		}
 
 	default:
-		log.Fatalf("bh.PortIf.RemoveObject error: invalid type %T: %v\n", obj, obj)
+		err = freesp.InvalidOperationError("port.RemoveObject", "cannot remove %T from a port", obj)
 	}
 	return
 }
@@ -326,23 +345,16 @@ func (l *portList) Append(p *port) {
 	l.ports = append(l.ports, p)
 }
 
-func (l *portList) Remove(p bh.PortIf) {
-	var i int
-	for i = range l.ports {
-		if p.Node().Name() == l.ports[i].Node().Name() && p.Name() == l.ports[i].Name() {
-			break
-		}
-	}
-	if i >= len(l.ports) {
-		for _, v := range l.ports {
-			log.Printf("portList.RemovePort have bh.PortIf %v\n", v)
-		}
-		log.Fatalf("portList.RemovePort error: bh.PortIf %v not in this list\n", p)
+func (l *portList) Remove(p bh.PortIf) error {
+	_, ok, i := l.Find(p.Node().Name(), p.Name())
+	if !ok {
+		return freesp.NotFoundError("portList.Remove", "port %s/%s not in this list", p.Node().Name(), p.Name())
 	}
 	for i++; i < len(l.ports); i++ {
 		l.ports[i-1] = l.ports[i]
 	}
 	l.ports = l.ports[:len(l.ports)-1]
+	return nil
 }
 
 func (l *portList) Ports() []bh.PortIf {
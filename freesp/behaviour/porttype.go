@@ -5,6 +5,7 @@ import (
 	"github.com/axel-freesp/sge/freesp"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
+	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
 	//"image"
 	"log"
@@ -20,8 +21,8 @@ type portType struct {
 
 var _ bh.PortTypeIf = (*portType)(nil)
 
-func PortTypeNew(name string, pTypeName string, dir gr.PortDirection) *portType {
-	st, ok := freesp.GetSignalTypeByName(pTypeName)
+func PortTypeNew(name string, pTypeName string, dir gr.PortDirection, context mod.ModelContextIf) *portType {
+	st, ok := freesp.RegistryOf(context).GetSignalTypeByName(pTypeName)
 	if !ok {
 		log.Fatalf("NamedPortTypeNew error: FIXME: signal type '%s' not defined\n", pTypeName)
 	}
@@ -48,8 +49,9 @@ func (t *portType) Direction() gr.PortDirection {
 	return t.direction
 }
 
-func (t *portType) SetDirection(newDir gr.PortDirection) {
+func (t *portType) SetDirection(newDir gr.PortDirection) error {
 	t.direction = newDir
+	return nil
 }
 
 func (t *portType) String() (s string) {
@@ -101,8 +103,8 @@ func (p *portType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEle
 	return
 }
 
-func (p *portType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatal("bh.PortTypeIf.AddNewObject - nothing to remove.")
+func (p *portType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("portType.RemoveObject", "nothing to remove")
 	return
 }
 
@@ -0,0 +1,142 @@
+package behaviour
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"strings"
+)
+
+// A node type must not instantiate itself in its graph implementations,
+// neither directly nor through other node types: expanding its nodes
+// would never end. Node types are identified by name, like in the
+// registry.
+
+// CheckNodeInstance returns an error naming the cycle of node types if
+// a node of type inner must not be added to a graph implementation of
+// outer.
+func CheckNodeInstance(outer, inner bh.NodeTypeIf) error {
+	known := map[string]bh.NodeTypeIf{outer.TypeName(): outer, inner.TypeName(): inner}
+	instantiated := modelInstances(nodeTypeRegistry(outer), known)
+	children := func(name string) []string {
+		list := instantiated(name)
+		if name == outer.TypeName() {
+			list = append(list, inner.TypeName())
+		}
+		return list
+	}
+	return nodeTypeCycleError(findTypeCycle(outer.TypeName(), children, make(map[string]int), nil))
+}
+
+// NodeTypeCycle returns the node types through which nt instantiates
+// itself, beginning and ending with nt; nil if nt is not recursive.
+func NodeTypeCycle(nt bh.NodeTypeIf) []string {
+	known := map[string]bh.NodeTypeIf{nt.TypeName(): nt}
+	return findTypeCycle(nt.TypeName(), modelInstances(nodeTypeRegistry(nt), known), make(map[string]int), nil)
+}
+
+// Checks the node types defined in a library document, node types
+// defined elsewhere are taken from the registry reg.
+func xmlNodeTypeCycle(reg *freesp.Registry, nts []backend.XmlNodeType) []string {
+	defined := make(map[string]*backend.XmlNodeType)
+	for i := range nts {
+		defined[nts[i].TypeName] = &nts[i]
+	}
+	instantiated := modelInstances(reg, make(map[string]bh.NodeTypeIf))
+	children := func(name string) []string {
+		if nt, ok := defined[name]; ok {
+			return xmlInstances(nt)
+		}
+		return instantiated(name)
+	}
+	state := make(map[string]int)
+	for _, nt := range nts {
+		cycle := findTypeCycle(nt.TypeName, children, state, nil)
+		if cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func nodeTypeCycleError(cycle []string) error {
+	if cycle == nil {
+		return nil
+	}
+	return fmt.Errorf("recursive node type: %s", strings.Join(cycle, " -> "))
+}
+
+// Returns a function listing the node types instantiated by the graph
+// implementations of a node type. Node types are looked up in known,
+// which is extended by the instantiated ones, then in the registry reg.
+func modelInstances(reg *freesp.Registry, known map[string]bh.NodeTypeIf) func(string) []string {
+	return func(name string) (list []string) {
+		nt, ok := known[name]
+		if !ok {
+			nt, ok = reg.GetNodeTypeByName(name)
+			if !ok {
+				return
+			}
+		}
+		for _, impl := range nt.Implementation() {
+			if impl.ImplementationType() != bh.NodeTypeGraph {
+				continue
+			}
+			for _, n := range impl.Graph().Nodes() {
+				t := n.ItsType()
+				known[t.TypeName()] = t
+				list = append(list, t.TypeName())
+			}
+		}
+		return
+	}
+}
+
+func xmlInstances(nt *backend.XmlNodeType) (list []string) {
+	for _, impl := range nt.Implementation {
+		for _, g := range impl.SignalGraph {
+			for _, n := range g.Nodes() {
+				list = appendTypeName(list, n.NType)
+			}
+		}
+	}
+	return
+}
+
+func appendTypeName(list []string, name string) []string {
+	if len(name) == 0 {
+		return list
+	}
+	return append(list, name)
+}
+
+const (
+	typeUnvisited = iota
+	typeOnStack
+	typeDone
+)
+
+// Depth first search for a cycle reachable from name.
+func findTypeCycle(name string, children func(string) []string, state map[string]int, stack []string) []string {
+	switch state[name] {
+	case typeOnStack:
+		for i, s := range stack {
+			if s == name {
+				return append(append([]string{}, stack[i:]...), name)
+			}
+		}
+	case typeDone:
+		return nil
+	}
+	state[name] = typeOnStack
+	stack = append(stack, name)
+	for _, c := range children(name) {
+		cycle := findTypeCycle(c, children, state, stack)
+		if cycle != nil {
+			return cycle
+		}
+	}
+	state[name] = typeDone
+	return nil
+}
@@ -0,0 +1,464 @@
+package behaviour
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Signal types without fields are opaque: their payload is described by
+// the c-type only. Structured signal types list their fields instead,
+// each of a primitive type or of another signal type, optionally as a
+// fixed size array. Fields are packed without padding in their order,
+// which gives the size of the payload.
+//
+// Field types must be defined in the library of the signal type or in
+// the libraries it refers to, directly or indirectly. Signal types must
+// not contain themselves.
+
+var primitiveSizes = map[string]int{
+	"int8": 1, "int16": 2, "int32": 4, "int64": 8,
+	"uint8": 1, "uint16": 2, "uint32": 4, "uint64": 8,
+	"float32": 4, "float64": 8, "bool": 1, "char": 1,
+}
+
+// Sizes of opaque signal types, by their c-type.
+var cTypeSizes = map[string]int{
+	"char": 1, "short": 2, "int": 4, "long long": 8, "float": 4, "double": 8,
+	"int8_t": 1, "int16_t": 2, "int32_t": 4, "int64_t": 8,
+	"uint8_t": 1, "uint16_t": 2, "uint32_t": 4, "uint64_t": 8,
+}
+
+// PrimitiveTypes lists the primitive field types.
+func PrimitiveTypes() []string {
+	return []string{"int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "bool", "char"}
+}
+
+func IsPrimitiveType(name string) bool {
+	_, ok := primitiveSizes[name]
+	return ok
+}
+
+func isNumericType(name string) bool {
+	return IsPrimitiveType(name) && name != "bool" && name != "char"
+}
+
+type signalField struct {
+	name, fType    string
+	arraySize      int
+	unit, min, max string
+	signalType     *signalType
+}
+
+var _ bh.SignalFieldIf = (*signalField)(nil)
+
+func SignalFieldNew(name, fType string, arraySize int, unit, min, max string) (f *signalField, err error) {
+	if !isParamName(name) {
+		err = fmt.Errorf("SignalFieldNew error: invalid field name '%s'", name)
+		return
+	}
+	if len(fType) == 0 {
+		err = fmt.Errorf("SignalFieldNew error: field %s has no type", name)
+		return
+	}
+	if arraySize < 0 {
+		err = fmt.Errorf("SignalFieldNew error: field %s: negative array size %d", name, arraySize)
+		return
+	}
+	f = &signalField{name, fType, arraySize, unit, "", "", nil}
+	err = f.SetRange(min, max)
+	if err != nil {
+		err = fmt.Errorf("SignalFieldNew error: %s", err)
+	}
+	return
+}
+
+func (f *signalField) Name() string {
+	return f.name
+}
+
+func (f *signalField) SetName(name string) error {
+	if !isParamName(name) {
+		return fmt.Errorf("signalField.SetName error: invalid field name '%s'", name)
+	}
+	if f.signalType != nil && name != f.name {
+		if _, ok := f.signalType.field(name); ok {
+			return fmt.Errorf("signalField.SetName error: duplicate field %s in signal type %s",
+				name, f.signalType.TypeName())
+		}
+	}
+	f.name = name
+	return nil
+}
+
+func (f *signalField) FieldType() string {
+	return f.fType
+}
+
+func (f *signalField) SetFieldType(fType string) error {
+	if (len(f.min) > 0 || len(f.max) > 0) && !isNumericType(fType) {
+		return fmt.Errorf("signalField.SetFieldType error: field %s has a range, %s is not numeric", f.name, fType)
+	}
+	if f.signalType != nil {
+		err := checkFieldType(f.signalType, fType)
+		if err != nil {
+			return fmt.Errorf("signalField.SetFieldType error: %s", err)
+		}
+	}
+	f.fType = fType
+	return nil
+}
+
+func (f *signalField) ArraySize() int {
+	return f.arraySize
+}
+
+func (f *signalField) SetArraySize(n int) error {
+	if n < 0 {
+		return fmt.Errorf("signalField.SetArraySize error: field %s: negative array size %d", f.name, n)
+	}
+	f.arraySize = n
+	return nil
+}
+
+func (f *signalField) Unit() string {
+	return f.unit
+}
+
+func (f *signalField) SetUnit(unit string) {
+	f.unit = unit
+}
+
+func (f *signalField) Min() string {
+	return f.min
+}
+
+func (f *signalField) Max() string {
+	return f.max
+}
+
+func (f *signalField) SetRange(min, max string) error {
+	if len(min) == 0 && len(max) == 0 {
+		f.min, f.max = min, max
+		return nil
+	}
+	if !isNumericType(f.fType) {
+		return fmt.Errorf("field %s: ranges apply to numeric fields only", f.name)
+	}
+	var lo, hi float64
+	var err error
+	if len(min) > 0 {
+		lo, err = strconv.ParseFloat(min, 64)
+		if err != nil {
+			return fmt.Errorf("field %s: minimum %s is no number", f.name, min)
+		}
+	}
+	if len(max) > 0 {
+		hi, err = strconv.ParseFloat(max, 64)
+		if err != nil {
+			return fmt.Errorf("field %s: maximum %s is no number", f.name, max)
+		}
+	}
+	if len(min) > 0 && len(max) > 0 && lo > hi {
+		return fmt.Errorf("field %s: empty range %s..%s", f.name, min, max)
+	}
+	f.min, f.max = min, max
+	return nil
+}
+
+func (f *signalField) SignalType() bh.SignalTypeIf {
+	return f.signalType
+}
+
+func (f *signalField) CreateXml() (buf []byte, err error) {
+	xmlf := CreateXmlSignalField(f)
+	buf, err = xmlf.Write()
+	return
+}
+
+/*
+ *	fmt.Stringer API
+ */
+
+// String gives the field in the notation of the library tree, e.g.
+// "pos: float32[3] m [0..10]".
+func (f *signalField) String() (s string) {
+	s = fmt.Sprintf("%s: %s", f.name, f.fType)
+	if f.arraySize > 0 {
+		s = fmt.Sprintf("%s[%d]", s, f.arraySize)
+	}
+	if len(f.unit) > 0 {
+		s = fmt.Sprintf("%s %s", s, f.unit)
+	}
+	if len(f.min) > 0 || len(f.max) > 0 {
+		s = fmt.Sprintf("%s [%s..%s]", s, f.min, f.max)
+	}
+	return
+}
+
+// SetSignalField changes all attributes of f, or none if one of them is
+// invalid.
+func SetSignalField(f bh.SignalFieldIf, name, fType string, arraySize int, unit, min, max string) (err error) {
+	sf := f.(*signalField)
+	saved := *sf
+	defer func() {
+		if err != nil {
+			*sf = saved
+		}
+	}()
+	err = sf.SetName(name)
+	if err != nil {
+		return
+	}
+	sf.SetRange("", "")
+	err = sf.SetFieldType(fType)
+	if err != nil {
+		return
+	}
+	err = sf.SetArraySize(arraySize)
+	if err != nil {
+		return
+	}
+	sf.SetUnit(unit)
+	return sf.SetRange(min, max)
+}
+
+/*
+ *  tr.TreeElementIf API
+ */
+
+var _ tr.TreeElementIf = (*signalField)(nil)
+
+// AddToTree cannot fail, errors are logged and the field is left out
+// of the tree.
+func (f *signalField) AddToTree(tree tr.TreeIf, cursor tr.Cursor) {
+	parent := tree.Object(tree.Parent(cursor))
+	if _, ok := parent.(bh.SignalTypeIf); !ok {
+		log.Printf("signalField.AddToTree error: invalid parent type %T\n", parent)
+		return
+	}
+	prop := freesp.PropertyNew(true, true, true)
+	err := tree.AddEntry(cursor, tr.SymbolSignalField, f.String(), f, prop)
+	if err != nil {
+		log.Printf("signalField.AddToTree error: AddEntry failed: %s\n", err)
+	}
+}
+
+func (f *signalField) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElementIf) (newCursor tr.Cursor, err error) {
+	err = freesp.InvalidOperationError("signalField.AddNewObject", "cannot add %T to a signal field", obj)
+	return
+}
+
+func (f *signalField) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("signalField.RemoveObject", "nothing to remove")
+	return
+}
+
+/*
+ *	Field types and sizes
+ */
+
+// Field types are primitive or registered signal types, which must not
+// contain st.
+func checkFieldType(st bh.SignalTypeIf, fType string) error {
+	if IsPrimitiveType(fType) {
+		return nil
+	}
+	ft, ok := signalTypeRegistry(st).GetSignalTypeByName(fType)
+	if !ok {
+		return fmt.Errorf("signal type %s: unknown field type %s", st.TypeName(), fType)
+	}
+	if signalTypeContains(ft, st) {
+		return fmt.Errorf("signal type %s: field type %s contains %s", st.TypeName(), fType, st.TypeName())
+	}
+	return nil
+}
+
+func signalTypeContains(t, st bh.SignalTypeIf) bool {
+	if t == st {
+		return true
+	}
+	for _, f := range t.Fields() {
+		ft, ok := signalTypeRegistry(t).GetSignalTypeByName(f.FieldType())
+		if ok && signalTypeContains(ft, st) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalTypeSize gives the size in bytes of the payload of st. Opaque
+// signal types have a size only if their c-type is a primitive C type.
+func SignalTypeSize(st bh.SignalTypeIf) (size int, err error) {
+	if len(st.Fields()) == 0 {
+		var ok bool
+		size, ok = cTypeSizes[st.CType()]
+		if !ok {
+			err = fmt.Errorf("SignalTypeSize error: signal type %s is opaque (c-type '%s')",
+				st.TypeName(), st.CType())
+		}
+		return
+	}
+	for _, f := range st.Fields() {
+		var n int
+		n, err = fieldTypeSize(signalTypeRegistry(st), f.FieldType())
+		if err != nil {
+			return
+		}
+		if f.ArraySize() > 0 {
+			n *= f.ArraySize()
+		}
+		size += n
+	}
+	return
+}
+
+func fieldTypeSize(reg *freesp.Registry, fType string) (size int, err error) {
+	size, ok := primitiveSizes[fType]
+	if ok {
+		return
+	}
+	ft, ok := reg.GetSignalTypeByName(fType)
+	if !ok {
+		err = fmt.Errorf("SignalTypeSize error: unknown field type %s", fType)
+		return
+	}
+	return SignalTypeSize(ft)
+}
+
+// Libraries reachable from the library named filename, which refers to
+// the libraries refs: itself and the libraries it refers to, directly
+// or indirectly.
+func reachableLibraries(reg *freesp.Registry, filename string, refs []string) map[string]bool {
+	reach := map[string]bool{filename: true}
+	var visit func(names []string)
+	visit = func(names []string) {
+		for _, name := range names {
+			if reach[name] {
+				continue
+			}
+			reach[name] = true
+			lib, ok := reg.GetLibraryByName(name)
+			if !ok {
+				continue
+			}
+			var next []string
+			for _, r := range CreateXmlLibrary(lib).Libraries {
+				next = append(next, r.Name)
+			}
+			visit(next)
+		}
+	}
+	visit(refs)
+	return reach
+}
+
+// Scope of the type names used by the library named filename, nil
+// (all libraries) if it is not registered.
+func libraryScope(reg *freesp.Registry, filename string) map[string]bool {
+	lib, ok := reg.GetLibraryByName(filename)
+	if !ok {
+		return nil
+	}
+	var refs []string
+	for _, r := range CreateXmlLibrary(lib).Libraries {
+		refs = append(refs, r.Name)
+	}
+	return reachableLibraries(reg, filename, refs)
+}
+
+// Adds the fields of xmlst to st (or compares them to the fields of st,
+// if st has been defined before). Field types may refer to all signal
+// types of the library, so this follows the creation of the types.
+func createFieldsFromXml(st bh.SignalTypeIf, xmlst backend.XmlSignalType, filename string) (err error) {
+	var fields []bh.SignalFieldIf
+	for _, xmlf := range xmlst.Field {
+		var f bh.SignalFieldIf
+		f, err = createSignalFieldFromXml(xmlf)
+		if err != nil {
+			return
+		}
+		fields = append(fields, f)
+	}
+	if st.DefinedAt() != filename || len(st.Fields()) > 0 {
+		if signalFieldsString(fields) != signalFieldsString(st.Fields()) {
+			err = fmt.Errorf("signal type %s: fields differ from the definition in %s",
+				st.TypeName(), st.DefinedAt())
+		}
+		return
+	}
+	for _, f := range fields {
+		err = st.AddField(f)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func createSignalFieldFromXml(xmlf backend.XmlSignalField) (f bh.SignalFieldIf, err error) {
+	var n int
+	if len(xmlf.Array) > 0 {
+		n, err = strconv.Atoi(xmlf.Array)
+		if err != nil {
+			err = fmt.Errorf("field %s: invalid array size %s", xmlf.Name, xmlf.Array)
+			return
+		}
+	}
+	return SignalFieldNew(xmlf.Name, xmlf.FType, n, xmlf.Unit, xmlf.Min, xmlf.Max)
+}
+
+// The signal types of library filename may use field types defined in
+// reachable libraries only.
+func checkFieldLibraries(reg *freesp.Registry, types []bh.SignalTypeIf, filename string, refs []string) error {
+	reach := reachableLibraries(reg, filename, refs)
+	for _, st := range types {
+		if st.DefinedAt() != filename {
+			continue
+		}
+		for _, f := range st.Fields() {
+			if IsPrimitiveType(f.FieldType()) {
+				continue
+			}
+			ft, _ := reg.GetSignalTypeByName(f.FieldType())
+			if !reach[ft.DefinedAt()] {
+				return fmt.Errorf("signal type %s: field type %s is defined in %s, which is not referenced",
+					st.TypeName(), f.FieldType(), ft.DefinedAt())
+			}
+		}
+	}
+	return nil
+}
+
+func signalFieldsString(list []bh.SignalFieldIf) string {
+	var s []string
+	for _, f := range list {
+		s = append(s, f.(*signalField).String())
+	}
+	return strings.Join(s, "; ")
+}
+
+// SignalTypeUsedByField tells if a field of another signal type refers
+// to st.
+func SignalTypeUsedByField(st bh.SignalTypeIf) bool {
+	reg := signalTypeRegistry(st)
+	for _, name := range reg.GetRegisteredSignalTypes() {
+		t, ok := reg.GetSignalTypeByName(name)
+		if !ok || t == st {
+			continue
+		}
+		for _, f := range t.Fields() {
+			ft, ok := reg.GetSignalTypeByName(f.FieldType())
+			if ok && ft == st {
+				return true
+			}
+		}
+	}
+	return false
+}
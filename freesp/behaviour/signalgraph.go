@@ -8,11 +8,12 @@ import (
 	gr "github.com/axel-freesp/sge/interface/graph"
 	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/tool"
 	"log"
 )
 
 func SignalGraphNew(filename string, context mod.ModelContextIf) *signalGraph {
-	return &signalGraph{filename, "", SignalGraphTypeNew(context)}
+	return &signalGraph{filename, "", SignalGraphTypeNew(context), freesp.Diagnostics{}}
 }
 
 func SignalGraphUsesNodeType(s bh.SignalGraphIf, nt bh.NodeTypeIf) bool {
@@ -28,26 +29,30 @@ func SignalGraphApplyHints(s bh.SignalGraphIf, xmlhints *backend.XmlGraphHint) (
 		err = fmt.Errorf("SignalGraphApplyHints error: filename mismatch\n")
 		return
 	}
+	var d *freesp.Diagnostics
+	if sg, ok := s.(*signalGraph); ok {
+		d = &sg.Diagnostics
+	}
 	g := s.ItsType()
 	for _, xmln := range xmlhints.InputNode {
 		n, ok := g.NodeByName(xmln.Name)
 		if ok {
 			n.SetExpanded(false)
-			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint)
+			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint, d)
 		}
 	}
 	for _, xmln := range xmlhints.OutputNode {
 		n, ok := g.NodeByName(xmln.Name)
 		if ok {
 			n.SetExpanded(false)
-			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint)
+			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint, d)
 		}
 	}
-	NodesApplyHints("", g, xmlhints.ProcessingNode)
+	NodesApplyHints("", g, xmlhints.ProcessingNode, d)
 	return
 }
 
-func NodesApplyHints(path string, g bh.SignalGraphTypeIf, xmlh []backend.XmlNodePosHint) {
+func NodesApplyHints(path string, g bh.SignalGraphTypeIf, xmlh []backend.XmlNodePosHint, d *freesp.Diagnostics) {
 	for _, n := range g.ProcessingNodes() {
 		var p string
 		if len(path) == 0 {
@@ -65,28 +70,28 @@ func NodesApplyHints(path string, g bh.SignalGraphTypeIf, xmlh []backend.XmlNode
 		}
 		if ok {
 			n.SetExpanded(xmln.Expanded)
-			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint)
+			freesp.PathModePositionerApplyHints(n, xmln.XmlModeHint, d)
 			for i, p := range n.InPorts() {
-				ok = p.Name() == xmln.InPorts[i].Name
+				ok = i < len(xmln.InPorts) && p.Name() == xmln.InPorts[i].Name
 				if !ok {
-					log.Printf("NodesApplyHints FIXME: name mismatch\n")
+					d.Warnf("hints for node %s: no hint for input port %s", xmln.Name, p.Name())
 					continue
 				}
-				freesp.ModePositionerApplyHints(p, xmln.InPorts[i].XmlModeHint)
+				freesp.ModePositionerApplyHints(p, xmln.InPorts[i].XmlModeHint, d)
 			}
 			for i, p := range n.OutPorts() {
-				ok = p.Name() == xmln.OutPorts[i].Name
+				ok = i < len(xmln.OutPorts) && p.Name() == xmln.OutPorts[i].Name
 				if !ok {
-					log.Printf("NodesApplyHints FIXME: name mismatch\n")
+					d.Warnf("hints for node %s: no hint for output port %s", xmln.Name, p.Name())
 					continue
 				}
-				freesp.ModePositionerApplyHints(p, xmln.OutPorts[i].XmlModeHint)
+				freesp.ModePositionerApplyHints(p, xmln.OutPorts[i].XmlModeHint, d)
 			}
 		}
 		nt := n.ItsType()
 		for _, impl := range nt.Implementation() {
 			if impl.ImplementationType() == bh.NodeTypeGraph {
-				NodesApplyHints(p, impl.Graph(), xmlh)
+				NodesApplyHints(p, impl.Graph(), xmlh, d)
 				break
 			}
 		}
@@ -97,6 +102,7 @@ type signalGraph struct {
 	filename   string
 	pathPrefix string
 	itsType    bh.SignalGraphTypeIf
+	freesp.Diagnostics
 }
 
 /*
@@ -118,20 +124,30 @@ func (s *signalGraph) Read(data []byte) (cnt int, err error) {
 	if err != nil {
 		err = fmt.Errorf("signalGraph.Read error: %v", err)
 	}
-	s.itsType, err = createSignalGraphTypeFromXml(g, s.filename, s.itsType.(*signalGraphType).context,
+	s.itsType, err = createSignalGraphTypeFromXml(g, s.filename, "", s.itsType.(*signalGraphType).context, nil,
 		func(_ string, _ gr.PortDirection) *portType { return nil })
+	if err == nil {
+		err = checkParamValues(s.itsType, nil)
+	}
 	return
 }
 
 func (s *signalGraph) ReadFile(filepath string) error {
 	g := backend.XmlSignalGraphNew()
-	err := g.ReadFile(filepath)
+	err := freesp.PreserverOf(s.itsType.(*signalGraphType).context).DocumentReadFile(g, filepath)
 	if err != nil {
 		return fmt.Errorf("signalGraph.ReadFile error: %v", err)
 	}
-	s.itsType, err = createSignalGraphTypeFromXml(g, s.filename, s.itsType.(*signalGraphType).context,
+	s.itsType, err = createSignalGraphTypeFromXml(g, s.filename, tool.Dirname(filepath), s.itsType.(*signalGraphType).context, nil,
 		func(_ string, _ gr.PortDirection) *portType { return nil })
-	return err
+	if err != nil {
+		return err
+	}
+	err = checkParamValues(s.itsType, nil)
+	if err != nil {
+		return fmt.Errorf("signalGraph.ReadFile error: %s", err)
+	}
+	return nil
 }
 
 func (s *signalGraph) Write() (data []byte, err error) {
@@ -142,7 +158,7 @@ func (s *signalGraph) Write() (data []byte, err error) {
 
 func (s *signalGraph) WriteFile(filepath string) error {
 	xmlsignalgraph := CreateXmlSignalGraph(s)
-	return xmlsignalgraph.WriteFile(filepath)
+	return freesp.PreserverOf(s.itsType.(*signalGraphType).context).DocumentWriteFile(xmlsignalgraph, filepath)
 }
 
 func (s *signalGraph) SetFilename(filename string) {
@@ -160,6 +176,7 @@ func (s *signalGraph) SetPathPrefix(newP string) {
 func (s *signalGraph) RemoveFromTree(tree tr.TreeIf) {
 	gt := s.ItsType()
 	tree.Remove(tree.Cursor(s))
+	gt.(*signalGraphType).unbindAll()
 	for len(gt.Nodes()) > 0 {
 		gt.RemoveNode(gt.Nodes()[0].(*node))
 	}
@@ -194,6 +211,6 @@ func (t *signalGraph) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.Tree
 	return t.ItsType().AddNewObject(tree, cursor, obj)
 }
 
-func (t *signalGraph) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (t *signalGraph) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	return t.ItsType().RemoveObject(tree, cursor)
 }
@@ -16,8 +16,10 @@ import (
 type signalGraphType struct {
 	context                                  mod.ModelContextIf
 	libraries                                []bh.LibraryIf
+	libraryRefs                              map[bh.LibraryIf]string // as read
 	nodes                                    nodeList
 	inputNodes, outputNodes, processingNodes []bh.NodeIf
+	bound                                    []*implementation
 }
 
 /*
@@ -27,7 +29,7 @@ type signalGraphType struct {
 var _ bh.SignalGraphTypeIf = (*signalGraphType)(nil)
 
 func SignalGraphTypeNew(context mod.ModelContextIf) *signalGraphType {
-	return &signalGraphType{context, nil, nodeListInit(), nil, nil, nil}
+	return &signalGraphType{context, nil, make(map[bh.LibraryIf]string), nodeListInit(), nil, nil, nil, nil}
 }
 
 func SignalGraphTypeUsesNodeType(t bh.SignalGraphTypeIf, nt bh.NodeTypeIf) bool {
@@ -111,6 +113,15 @@ func (t *signalGraphType) Libraries() []bh.LibraryIf {
 	return t.libraries
 }
 
+// LibraryRef returns the reference to lib as read (relative to the
+// directory of the graph), or its filename for libraries added since.
+func (t *signalGraphType) LibraryRef(lib bh.LibraryIf) string {
+	if ref, ok := t.libraryRefs[lib]; ok {
+		return ref
+	}
+	return lib.Filename()
+}
+
 func (t *signalGraphType) InputNodes() []bh.NodeIf {
 	return t.inputNodes
 }
@@ -131,7 +142,7 @@ func (t *signalGraphType) AddNode(n bh.NodeIf) error {
 			return fmt.Errorf("signalGraphType.AddNode error: node type %s has no DefinedAt...", nType.TypeName())
 		}
 		if !t.containsLibRef(libname) {
-			lib, ok := freesp.GetLibraryByName(libname)
+			lib, ok := t.registry().GetLibraryByName(libname)
 			if !ok {
 				return fmt.Errorf("signalGraphType.AddNode error: library %s not registered", libname)
 			}
@@ -144,7 +155,10 @@ func (t *signalGraphType) AddNode(n bh.NodeIf) error {
 func (t *signalGraphType) RemoveNode(n bh.NodeIf) {
 	for _, p := range n.(*node).inPort.Ports() {
 		for _, c := range p.Connections() {
-			c.RemoveConnection(p)
+			err := c.RemoveConnection(p)
+			if err != nil {
+				log.Printf("signalGraphType.RemoveNode: %s\n", err)
+			}
 		}
 	}
 	t.nodes.Remove(n)
@@ -152,12 +166,22 @@ func (t *signalGraphType) RemoveNode(n bh.NodeIf) {
 	RemNode(&t.outputNodes, n.(*node))
 	RemNode(&t.processingNodes, n.(*node))
 	n.ItsType().(*nodeType).removeInstance(n.(*node))
+	if !IsProcessingNode(n) {
+		for _, impl := range t.bound {
+			impl.boundaryNodeRemoved(n.(*node))
+		}
+	}
 }
 
 func (t *signalGraphType) Context() mod.ModelContextIf {
 	return t.context
 }
 
+// Types and libraries are registered in the registry of the context.
+func (t *signalGraphType) registry() *freesp.Registry {
+	return freesp.RegistryOf(t.context)
+}
+
 func (t *signalGraphType) containsLibRef(libname string) bool {
 	for _, l := range t.libraries {
 		if l.Filename() == libname {
@@ -203,30 +227,70 @@ func (t *signalGraphType) addNode(n bh.NodeIf) error {
 		}
 	}
 	t.nodes.Append(n.(*node))
+	if !IsProcessingNode(n) {
+		for _, impl := range t.bound {
+			impl.boundaryNodeAdded(n.(*node))
+		}
+	}
 	return nil
 }
 
-func createSignalGraphTypeFromXml(g *backend.XmlSignalGraph, name string, context mod.ModelContextIf,
-	resolvePort func(portname string, dir gr.PortDirection) *portType) (t *signalGraphType, err error) {
+// Resolves ref relative to refDir first and returns the library stored
+// there. Libraries are identified by that path, such that graphs in
+// different directories get their own libraries of the same name (or an
+// error, see LibraryMgr). Unresolvable refs denote libraries not saved
+// yet, these are looked up by name.
+func (t *signalGraphType) accessLibrary(ref, refDir string) (l bh.LibraryIf, err error) {
+	res, rerr := backend.ResolveRef(ref, refDir)
+	if rerr != nil {
+		var ok bool
+		l, ok = t.registry().GetLibraryByName(ref)
+		if !ok {
+			err = freesp.NotFoundError("createSignalGraphTypeFromXml", "referenced library file %s not found", ref)
+		}
+		return
+	}
+	l, ok := t.registry().GetLibraryByPath(res.AbsPath())
+	if ok {
+		return
+	}
+	var f fd.FileDataIf
+	f, err = t.context.LibraryMgr().AccessRef(ref, refDir)
+	if err != nil {
+		err = freesp.NotFoundError("createSignalGraphTypeFromXml", "referenced library %s: %v", ref, err)
+		return
+	}
+	l = f.(bh.LibraryIf)
+	t.registry().RegisterLibraryPath(l, res.AbsPath())
+	log.Printf("createSignalGraphTypeFromXml: %s, library %s successfully loaded\n", res, l.Filename())
+	return
+}
+
+// Library references are resolved relative to refDir, the directory
+// of the file g was read from (empty if unknown). Type names are
+// resolved within the referenced libraries and the libraries of outer
+// (the scope of the library embedding g, nil for graph files).
+func createSignalGraphTypeFromXml(g *backend.XmlSignalGraph, name, refDir string, context mod.ModelContextIf,
+	outer map[string]bool, resolvePort func(portname string, dir gr.PortDirection) *portType) (t *signalGraphType, err error) {
 	t = SignalGraphTypeNew(context)
+	var refs []string
 	for _, ref := range g.Libraries {
-		l, ok := freesp.GetLibraryByName(ref.Name)
-		if !ok {
-			var f fd.FileDataIf
-			f, err = t.context.LibraryMgr().Access(ref.Name)
-			if err != nil {
-				err = fmt.Errorf("createSignalGraphTypeFromXml error: referenced library file %s not found", ref.Name)
-				return
-			}
-			l = f.(bh.LibraryIf)
-			freesp.RegisterLibrary(l)
-			fmt.Println("createSignalGraphTypeFromXml: library", ref.Name, "successfully loaded")
+		var l bh.LibraryIf
+		l, err = t.accessLibrary(ref.Name, refDir)
+		if err != nil {
+			return
 		}
 		t.libraries = append(t.libraries, l)
+		t.libraryRefs[l] = ref.Name
+		refs = append(refs, l.Filename())
+	}
+	scope := reachableLibraries(t.registry(), "", refs)
+	for f := range outer {
+		scope[f] = true
 	}
 	for _, n := range g.InputNodes {
 		var nnode *node
-		nnode, err = t.createInputNodeFromXml(n, resolvePort)
+		nnode, err = t.createInputNodeFromXml(n, scope, resolvePort)
 		if err != nil {
 			return
 		}
@@ -235,7 +299,7 @@ func createSignalGraphTypeFromXml(g *backend.XmlSignalGraph, name string, contex
 	}
 	for _, n := range g.OutputNodes {
 		var nnode *node
-		nnode, err = t.createOutputNodeFromXml(n, resolvePort)
+		nnode, err = t.createOutputNodeFromXml(n, scope, resolvePort)
 		if err != nil {
 			return
 		}
@@ -243,42 +307,39 @@ func createSignalGraphTypeFromXml(g *backend.XmlSignalGraph, name string, contex
 		t.nodes.Append(nnode)
 	}
 	for _, n := range g.ProcessingNodes {
-		nnode := t.createNodeFromXml(n.XmlNode)
+		var nnode *node
+		nnode, err = t.createNodeFromXml(n.XmlNode, scope)
+		if err != nil {
+			return
+		}
 		t.processingNodes = append(t.processingNodes, nnode)
 		t.nodes.Append(nnode)
 	}
 	for i, c := range g.Connections {
 		n1, ok := t.NodeByName(c.From)
 		if !ok {
-			dump, _ := g.Write()
-			log.Println("createSignalGraphTypeFromXml error:")
-			log.Fatal(fmt.Sprintf("invalid edge %d: node %s not found\n%s", i, c.From, dump))
+			err = freesp.NotFoundError("createSignalGraphTypeFromXml", "edge %d: node %s not found", i, c.From)
+			return
 		}
 		n2, ok := t.NodeByName(c.To)
 		if !ok {
-			dump, _ := g.Write()
-			log.Println("createSignalGraphTypeFromXml error:")
-			log.Fatal(fmt.Sprintf("invalid edge %d: node %s not found\n%s", i, c.To, dump))
+			err = freesp.NotFoundError("createSignalGraphTypeFromXml", "edge %d: node %s not found", i, c.To)
+			return
 		}
-		p1, err := n1.(*node).outPortFromName(c.FromPort)
+		var p1, p2 bh.PortIf
+		p1, err = n1.(*node).outPortFromName(c.FromPort)
 		if err != nil {
-			dump, _ := g.Write()
-			log.Println("createSignalGraphTypeFromXml error:")
-			log.Printf("edge = %v\n", c)
-			log.Printf("node = %v, missing port = %s\n", n1, c.FromPort)
-			log.Fatal(fmt.Sprintf("invalid edge %d outPortFromName failed: %s\n%s", i, err, dump))
+			err = freesp.NotFoundError("createSignalGraphTypeFromXml", "edge %d: node %s has no output port %q", i, c.From, c.FromPort)
+			return
 		}
-		p2, err := n2.(*node).inPortFromName(c.ToPort)
+		p2, err = n2.(*node).inPortFromName(c.ToPort)
 		if err != nil {
-			dump, _ := g.Write()
-			log.Println("createSignalGraphTypeFromXml error:")
-			log.Fatal(fmt.Sprintf("invalid edge %d inPortFromName failed: %s\n%s", i, err, dump))
+			err = freesp.NotFoundError("createSignalGraphTypeFromXml", "edge %d: node %s has no input port %q", i, c.To, c.ToPort)
+			return
 		}
 		err = p1.AddConnection(ConnectionNew(p1, p2))
 		if err != nil {
-			dump, _ := g.Write()
-			log.Println("createSignalGraphTypeFromXml error:")
-			log.Fatal(fmt.Sprintf("invalid edge %d PortConnect failed: %s\n%s", i, err, dump))
+			return
 		}
 	}
 	return
@@ -300,6 +361,14 @@ func createOutputNodeTypeName(name string) string {
 	return fmt.Sprintf("autoOutputNodeType-%s", name)
 }
 
+// Node types of input and output nodes are named after their signal
+// type, qualified (but not looking qualified) to keep the types of
+// different libraries apart.
+func autoTypeName(st bh.SignalTypeIf) string {
+	name := freesp.QualifiedTypeName(st.DefinedAt(), st.TypeName())
+	return strings.Replace(name, freesp.NamespaceSeparator, ".", -1)
+}
+
 func isAutoType(nt bh.NodeTypeIf) bool {
 	if strings.HasPrefix(nt.TypeName(), "autoInputNodeType-") {
 		return true
@@ -310,29 +379,46 @@ func isAutoType(nt bh.NodeTypeIf) bool {
 	return false
 }
 
-func (t *signalGraphType) createNodeFromXml(xmln backend.XmlNode) (nd *node) {
+func (t *signalGraphType) createNodeFromXml(xmln backend.XmlNode, scope map[string]bool) (nd *node, err error) {
 	nName := xmln.NName
 	ntName := xmln.NType
 	if len(ntName) == 0 {
 		ntName = createNodeTypeName(xmln)
 	}
-	nt, ok := freesp.GetNodeTypeByName(ntName)
-	if !ok {
-		nt = createNodeTypeFromXmlNode(xmln, ntName)
+	nt, err := t.registry().ResolveNodeType(ntName, scope)
+	if _, ambiguous := err.(*freesp.AmbiguousNameError); ambiguous {
+		err = fmt.Errorf("signalGraphType.createNodeFromXml: node %s: %s", nName, err)
+		return
+	}
+	if err != nil {
+		nt, err = createNodeTypeFromXmlNode(xmln, ntName, t.context, scope)
+		if err != nil {
+			return
+		}
 	}
-	var err error
 	nd, err = NodeNew(nName, nt, t)
 	if err != nil {
-		log.Fatal("signalGraphType.createNodeFromXml: TODO: error handling")
+		err = fmt.Errorf("signalGraphType.createNodeFromXml: %s", err)
+		return
+	}
+	for _, p := range xmln.Param {
+		err = nd.SetParamValue(p.Name, p.Value)
+		if err != nil {
+			err = fmt.Errorf("signalGraphType.createNodeFromXml: %s", err)
+			return
+		}
 	}
 	return
 }
 
-func (t *signalGraphType) createInputNodeFromXml(n backend.XmlInputNode,
+func (t *signalGraphType) createInputNodeFromXml(n backend.XmlInputNode, scope map[string]bool,
 	resolvePort func(portname string, dir gr.PortDirection) *portType) (ret *node, err error) {
 	nName := n.NName
 	ntName := createInputNodeTypeName(nName)
-	nt := createNodeTypeFromXmlNode(n.XmlNode, ntName)
+	nt, err := createNodeTypeFromXmlNode(n.XmlNode, ntName, t.context, scope)
+	if err != nil {
+		return
+	}
 	ret, err = NodeNew(nName, nt, t)
 	if err != nil {
 		err = fmt.Errorf("signalGraphType.createInputNodeFromXml: %s", err)
@@ -350,11 +436,14 @@ func (t *signalGraphType) createInputNodeFromXml(n backend.XmlInputNode,
 	return
 }
 
-func (t *signalGraphType) createOutputNodeFromXml(n backend.XmlOutputNode,
+func (t *signalGraphType) createOutputNodeFromXml(n backend.XmlOutputNode, scope map[string]bool,
 	resolvePort func(portname string, dir gr.PortDirection) *portType) (ret *node, err error) {
 	nName := n.NName
 	ntName := createOutputNodeTypeName(nName)
-	nt := createNodeTypeFromXmlNode(n.XmlNode, ntName)
+	nt, err := createNodeTypeFromXmlNode(n.XmlNode, ntName, t.context, scope)
+	if err != nil {
+		return
+	}
 	ret, err = NodeNew(nName, nt, t)
 	if err != nil {
 		err = fmt.Errorf("signalGraphType.createOutputNodeFromXml: %s", err)
@@ -374,12 +463,12 @@ func (t *signalGraphType) createOutputNodeFromXml(n backend.XmlOutputNode,
 
 func (g *signalGraphType) addInputNodeFromPortType(p bh.PortTypeIf) {
 	st := p.SignalType()
-	ntName := createInputNodeTypeName(st.TypeName())
-	nt, ok := freesp.GetNodeTypeByName(ntName)
+	ntName := createInputNodeTypeName(autoTypeName(st))
+	nt, ok := g.registry().GetNodeTypeByName(ntName)
 	if !ok {
-		nt = NodeTypeNew(ntName, "")
+		nt = NodeTypeNew(ntName, "", g.context)
 		nt.(*nodeType).addOutPort("", st)
-		freesp.RegisterNodeType(nt)
+		g.registry().RegisterNodeType(nt)
 	}
 	if len(nt.(*nodeType).outPorts.PortTypes()) == 0 {
 		log.Fatal("signalGraphType.addInputNodeFromNamedPortType: invalid input node type")
@@ -397,12 +486,12 @@ func (g *signalGraphType) addInputNodeFromPortType(p bh.PortTypeIf) {
 
 func (g *signalGraphType) addOutputNodeFromPortType(p bh.PortTypeIf) {
 	st := p.SignalType()
-	ntName := createOutputNodeTypeName(st.TypeName())
-	nt, ok := freesp.GetNodeTypeByName(ntName)
+	ntName := createOutputNodeTypeName(autoTypeName(st))
+	nt, ok := g.registry().GetNodeTypeByName(ntName)
 	if !ok {
-		nt = NodeTypeNew(ntName, "")
+		nt = NodeTypeNew(ntName, "", g.context)
 		nt.(*nodeType).addInPort("", st)
-		freesp.RegisterNodeType(nt)
+		g.registry().RegisterNodeType(nt)
 	}
 	if len(nt.(*nodeType).inPorts.PortTypes()) == 0 {
 		log.Fatal("signalGraphType.addOutputNodeFromNamedPortType: invalid output node type")
@@ -418,9 +507,19 @@ func (g *signalGraphType) addOutputNodeFromPortType(p bh.PortTypeIf) {
 	}
 }
 
+// Returns the io-node linked to port type p, nil if there is none.
+func (g *signalGraphType) linkedNode(p bh.PortTypeIf) bh.NodeIf {
+	for _, n := range append(g.InputNodes(), g.OutputNodes()...) {
+		if n.(*node).portlink == p {
+			return n
+		}
+	}
+	return nil
+}
+
 func (g *signalGraphType) removeInputNodeFromPortType(p bh.PortTypeIf) {
 	for _, n := range g.InputNodes() {
-		if n.Name() == fmt.Sprintf("in-%s", p.Name()) {
+		if n.(*node).portlink == p || n.Name() == fmt.Sprintf("in-%s", p.Name()) {
 			g.RemoveNode(n)
 			return
 		}
@@ -429,7 +528,7 @@ func (g *signalGraphType) removeInputNodeFromPortType(p bh.PortTypeIf) {
 
 func (g *signalGraphType) removeOutputNodeFromPortType(p bh.PortTypeIf) {
 	for _, n := range g.OutputNodes() {
-		if n.Name() == fmt.Sprintf("out-%s", p.Name()) {
+		if n.(*node).portlink == p || n.Name() == fmt.Sprintf("out-%s", p.Name()) {
 			g.RemoveNode(n)
 			return
 		}
@@ -438,7 +537,7 @@ func (g *signalGraphType) removeOutputNodeFromPortType(p bh.PortTypeIf) {
 
 func (g *signalGraphType) findInputNodeFromPortType(p bh.PortTypeIf) bh.NodeIf {
 	for _, n := range g.InputNodes() {
-		if n.Name() == fmt.Sprintf("in-%s", p.Name()) {
+		if n.(*node).portlink == p || n.Name() == fmt.Sprintf("in-%s", p.Name()) {
 			return n
 		}
 	}
@@ -447,7 +546,7 @@ func (g *signalGraphType) findInputNodeFromPortType(p bh.PortTypeIf) bh.NodeIf {
 
 func (g *signalGraphType) findOutputNodeFromPortType(p bh.PortTypeIf) bh.NodeIf {
 	for _, n := range g.OutputNodes() {
-		if n.Name() == fmt.Sprintf("out-%s", p.Name()) {
+		if n.(*node).portlink == p || n.Name() == fmt.Sprintf("out-%s", p.Name()) {
 			return n
 		}
 	}
@@ -492,7 +591,25 @@ func (t *signalGraphType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.
 	case bh.NodeIf:
 		// TODO: Check if IO node and exists: copy position only and return
 		n := obj.(bh.NodeIf)
-		err = t.AddNode(n)
+		parent := tree.Object(cursor)
+		switch parent.(type) {
+		case bh.SignalGraphIf, bh.ImplementationIf:
+		default:
+			err = freesp.InconsistentModelError("signalGraphType.AddNewObject", "wrong parent type %T", parent)
+			return
+		}
+		if _, ok := parent.(bh.ImplementationIf); ok {
+			outer := tree.Object(tree.Parent(cursor)).(bh.NodeTypeIf)
+			err = CheckNodeInstance(outer, n.ItsType())
+		}
+		for _, impl := range t.bound {
+			if err == nil {
+				err = CheckNodeInstance(impl.nodeType, n.ItsType())
+			}
+		}
+		if err == nil {
+			err = t.AddNode(n)
+		}
 		if err != nil {
 			err = fmt.Errorf("signalGraphType.AddNewObject error: %s", err)
 			nt := n.ItsType().(*nodeType)
@@ -506,10 +623,7 @@ func (t *signalGraphType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.
 		}
 		newCursor = t.treeAddNewObject(tree, cursor, n)
 
-		parent := tree.Object(cursor)
-		switch parent.(type) {
-		case bh.SignalGraphIf:
-		case bh.ImplementationIf:
+		if _, ok := parent.(bh.ImplementationIf); ok {
 			// propagate new node to all instances of embracing type
 			pCursor := tree.Parent(cursor)
 			nt := tree.Object(pCursor)
@@ -519,9 +633,6 @@ func (t *signalGraphType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.
 				tCursor.Position = cursor.Position
 				t.treeAddNewObject(tree, tCursor, n)
 			}
-
-		default:
-			log.Fatalf("signalGraphType.AddNewObject error: wrong parent type %T: %v\n", parent, parent)
 		}
 
 	case bh.ConnectionIf:
@@ -540,42 +651,46 @@ func (t *signalGraphType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.
 			}
 		}
 	default:
-		log.Fatalf("signalGraphType.AddNewObject error: wrong type %t: %v\n", obj, obj)
+		err = freesp.InvalidOperationError("signalGraphType.AddNewObject", "cannot add %T to a signal graph", obj)
 	}
 	return
 }
 
-func (t *signalGraphType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (t *signalGraphType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	obj := tree.Object(cursor)
 	switch obj.(type) {
 	case bh.NodeIf:
 		n := obj.(bh.NodeIf)
-		// Remove all connections first
-		for _, p := range n.OutPorts() {
-			for _, c := range p.Connections() {
-				conn := p.Connection(c)
-				cCursor := tree.CursorAt(cursor, conn)
-				del := p.RemoveObject(tree, cCursor)
-				for _, d := range del {
-					removed = append(removed, d)
-				}
-			}
+		parentCursor := tree.Parent(cursor)
+		parent := tree.Object(parentCursor)
+		switch parent.(type) {
+		case bh.SignalGraphIf, bh.ImplementationIf:
+		default:
+			err = freesp.InconsistentModelError("signalGraphType.RemoveObject", "wrong parent type %T", parent)
+			return
 		}
-		for _, p := range n.InPorts() {
-			for _, c := range p.Connections() {
-				conn := p.Connection(c)
-				cCursor := tree.CursorAt(cursor, conn)
-				del := p.RemoveObject(tree, cCursor)
-				for _, d := range del {
-					removed = append(removed, d)
+		// Remove all connections first
+		for _, list := range [][]bh.PortIf{n.OutPorts(), n.InPorts()} {
+			for _, p := range list {
+				for _, c := range p.Connections() {
+					conn, e := p.Connection(c)
+					if e != nil {
+						log.Printf("signalGraphType.RemoveObject: %s\n", e)
+						continue
+					}
+					cCursor := tree.CursorAt(cursor, conn)
+					var del []tr.IdWithObject
+					del, err = p.RemoveObject(tree, cCursor)
+					for _, d := range del {
+						removed = append(removed, d)
+					}
+					if err != nil {
+						return
+					}
 				}
 			}
 		}
-		parentCursor := tree.Parent(cursor)
-		parent := tree.Object(parentCursor)
-		switch parent.(type) {
-		case bh.SignalGraphIf:
-		case bh.ImplementationIf:
+		if _, ok := parent.(bh.ImplementationIf); ok {
 			// propagate new node to all instances of embracing type
 			pCursor := tree.Parent(parentCursor)
 			nt := tree.Object(pCursor)
@@ -584,16 +699,13 @@ func (t *signalGraphType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (remove
 				tCursor := tree.CursorAt(nCursor, parent)
 				tree.Remove(tree.CursorAt(tCursor, n))
 			}
-
-		default:
-			log.Fatalf("signalGraphType.RemoveObject error: wrong parent type %t: %v\n", parent, parent)
 		}
 		prefix, index := tree.Remove(cursor)
 		removed = append(removed, tr.IdWithObject{prefix, index, obj})
 		t.RemoveNode(n)
 
 	default:
-		log.Fatalf("signalGraphType.RemoveObject error: wrong type %t: %v", obj, obj)
+		err = freesp.InvalidOperationError("signalGraphType.RemoveObject", "cannot remove %T from a signal graph", obj)
 	}
 	return
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/axel-freesp/sge/freesp"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mod "github.com/axel-freesp/sge/interface/model"
 	tr "github.com/axel-freesp/sge/interface/tree"
 	"log"
 )
@@ -15,6 +16,8 @@ type signalType struct {
 	scope              bh.Scope
 	mode               bh.Mode
 	definedAt          string
+	fields             []*signalField
+	registry           *freesp.Registry
 }
 
 /*
@@ -23,9 +26,15 @@ type signalType struct {
 
 var _ bh.SignalTypeIf = (*signalType)(nil)
 
-func SignalTypeNew(name, ctype, msgid string, scope bh.Scope, mode bh.Mode, definedAt string) (t *signalType, err error) {
-	newT := &signalType{name, ctype, msgid, scope, mode, definedAt}
-	sType, ok := freesp.GetSignalTypeByName(name)
+// A signal type defined again by the same library (e.g. when it is
+// read twice) is the existing one, signal types of other libraries
+// may have the same name. Signal types are registered in the registry
+// of context.
+func SignalTypeNew(name, ctype, msgid string, scope bh.Scope, mode bh.Mode, definedAt string,
+	context mod.ModelContextIf) (t *signalType, err error) {
+	reg := freesp.RegistryOf(context)
+	newT := &signalType{name, ctype, msgid, scope, mode, definedAt, nil, reg}
+	sType, ok := reg.GetSignalTypeByName(freesp.QualifiedTypeName(definedAt, name))
 	if ok {
 		if !signalTypeCpmpatible(newT, sType) {
 			err = fmt.Errorf(`SignalTypeNew error: adding existing signal
@@ -37,7 +46,7 @@ func SignalTypeNew(name, ctype, msgid string, scope bh.Scope, mode bh.Mode, defi
 		t = sType.(*signalType)
 	} else {
 		t = newT
-		freesp.RegisterSignalType(t)
+		reg.RegisterSignalType(t)
 	}
 	return
 }
@@ -66,16 +75,57 @@ func (t signalType) DefinedAt() string {
 }
 
 func SignalTypeDestroy(t bh.SignalTypeIf) {
-	freesp.RemoveRegisteredSignalType(t)
+	signalTypeRegistry(t).RemoveRegisteredSignalType(t)
+}
+
+// Registry st is registered in.
+func signalTypeRegistry(st bh.SignalTypeIf) *freesp.Registry {
+	t, ok := st.(*signalType)
+	if ok && t.registry != nil {
+		return t.registry
+	}
+	return freesp.DefaultRegistry()
+}
+
+// Name documents refer to st by.
+func signalTypeRefName(st bh.SignalTypeIf) string {
+	return signalTypeRegistry(st).SignalTypeRefName(st)
 }
 
 func (t *signalType) TypeName() string {
 	return t.name
 }
 
+// All ports refer to t, documents written after renaming use the new
+// name. Fields and compatibilities refer to t by name and are renamed
+// as well, if the name resolves to t in the scope of their library.
+// Other documents are updated by package refactor.
 func (t *signalType) SetTypeName(newName string) {
-	// TODO: how to make this consistent in all cases?
-	log.Println("signalType.SetTypeName WARNING: this is not yet implemented!")
+	oldName := t.name
+	reg := signalTypeRegistry(t)
+	refersTo := func(typeName string, scope map[string]bool) bool {
+		st, err := reg.ResolveSignalType(typeName, scope)
+		return err == nil && st == bh.SignalTypeIf(t)
+	}
+	for _, name := range reg.GetRegisteredSignalTypes() {
+		st, ok := reg.GetSignalTypeByName(name)
+		if !ok {
+			continue
+		}
+		scope := libraryScope(reg, st.DefinedAt())
+		for _, f := range st.Fields() {
+			if !refersTo(f.FieldType(), scope) {
+				continue
+			}
+			fType, ok := freesp.RenameTypeName(f.FieldType(), t.definedAt, oldName, newName)
+			if ok {
+				f.(*signalField).fType = fType
+			}
+		}
+	}
+	renameCompatibilities(reg, refersTo, t.definedAt, oldName, newName, true)
+	t.name = newName
+	reg.RenameRegisteredSignalType(t, oldName)
 }
 
 func (t *signalType) CType() string {
@@ -110,6 +160,47 @@ func (t *signalType) SetMode(newMode bh.Mode) {
 	t.mode = newMode
 }
 
+func (t *signalType) Fields() (list []bh.SignalFieldIf) {
+	for _, f := range t.fields {
+		list = append(list, f)
+	}
+	return
+}
+
+func (t *signalType) field(name string) (f *signalField, ok bool) {
+	for _, f = range t.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func (t *signalType) AddField(f bh.SignalFieldIf) error {
+	if _, ok := t.field(f.Name()); ok {
+		return fmt.Errorf("signalType.AddField error: duplicate field %s in signal type %s", f.Name(), t.name)
+	}
+	err := checkFieldType(t, f.FieldType())
+	if err != nil {
+		return fmt.Errorf("signalType.AddField error: %s", err)
+	}
+	sf := f.(*signalField)
+	sf.signalType = t
+	t.fields = append(t.fields, sf)
+	return nil
+}
+
+func (t *signalType) RemoveField(f bh.SignalFieldIf) {
+	for i, sf := range t.fields {
+		if sf == f {
+			t.fields = append(t.fields[:i], t.fields[i+1:]...)
+			sf.signalType = nil
+			return
+		}
+	}
+	log.Printf("signalType.RemoveField warning: field %s not in signal type %s\n", f.Name(), t.name)
+}
+
 func (t *signalType) CreateXml() (buf []byte, err error) {
 	if t != nil {
 		xmlsignaltype := CreateXmlSignalType(t)
@@ -148,15 +239,52 @@ func (t *signalType) AddToTree(tree tr.TreeIf, cursor tr.Cursor) {
 	if err != nil {
 		log.Fatalf("signalType.AddToTree error: AddEntry failed: %s\n", err)
 	}
+	// fields are edited in the library only
+	if _, ok := parent.(bh.LibraryIf); ok {
+		for _, f := range t.fields {
+			child := tree.Append(cursor)
+			f.AddToTree(tree, child)
+		}
+	}
 }
 
 func (t *signalType) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElementIf) (newCursor tr.Cursor, err error) {
-	log.Fatal("signalType.AddNewObject - nothing to add.")
+	if obj == nil {
+		err = fmt.Errorf("signalType.AddNewObject error: nil object")
+		return
+	}
+	switch obj.(type) {
+	case bh.SignalFieldIf:
+		f := obj.(bh.SignalFieldIf)
+		err = t.AddField(f)
+		if err != nil {
+			return
+		}
+		cursor.Position = len(t.fields) - 1
+		newCursor = tree.Insert(cursor)
+		f.AddToTree(tree, newCursor)
+	default:
+		err = freesp.InvalidOperationError("signalType.AddNewObject", "cannot add %T to a signal type", obj)
+	}
 	return
 }
 
-func (t *signalType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatal("signalType.AddNewObject - nothing to remove.")
+func (t *signalType) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	parent := tree.Parent(cursor)
+	if t != tree.Object(parent) {
+		err = freesp.InconsistentModelError("signalType.RemoveObject", "not removing child of mine")
+		return
+	}
+	obj := tree.Object(cursor)
+	switch obj.(type) {
+	case bh.SignalFieldIf:
+		f := obj.(bh.SignalFieldIf)
+		t.RemoveField(f)
+		prefix, index := tree.Remove(cursor)
+		removed = append(removed, tr.IdWithObject{prefix, index, f})
+	default:
+		err = freesp.InvalidOperationError("signalType.RemoveObject", "cannot remove %T from a signal type", obj)
+	}
 	return
 }
 
@@ -0,0 +1,552 @@
+package deps
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/tool"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The dependency graph links signal graphs and libraries to the
+// libraries they reference. Documents are identified by their absolute
+// path. Referenced libraries are read as needed, so adding the graphs
+// of interest is enough to analyze all their dependencies.
+
+type Document struct {
+	Filename string
+	Library  bool
+	Refs     []Ref
+	defines  map[string]bool
+	uses     map[string]bool
+}
+
+// Ref is a library reference of a document. Path is empty if the
+// reference could not be resolved, Unused tells that no type used by
+// the document is defined in the library or the libraries it depends
+// on.
+type Ref struct {
+	Name   string
+	Path   string
+	Unused bool
+}
+
+func (r Ref) Missing() bool {
+	return len(r.Path) == 0
+}
+
+type Graph struct {
+	docs map[string]*Document
+}
+
+func GraphNew() *Graph {
+	return &Graph{make(map[string]*Document)}
+}
+
+// Document returns the document stored as filename, if it is part of
+// the graph.
+func (g *Graph) Document(filename string) (d *Document, ok bool) {
+	d, ok = g.docs[backend.AbsPath(filename)]
+	return
+}
+
+// Documents lists all documents ordered by filename.
+func (g *Graph) Documents() (list []*Document) {
+	for _, d := range g.docs {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Filename < list[j].Filename
+	})
+	return
+}
+
+// Add inserts doc, stored as filename. Documents are added once, later
+// versions are ignored: add open documents first to use them instead
+// of their files. Other documents than graphs and libraries are
+// ignored.
+func (g *Graph) Add(doc backend.Document, filename string) {
+	filename = backend.AbsPath(filename)
+	if _, ok := g.docs[filename]; ok {
+		return
+	}
+	d := &Document{Filename: filename, defines: make(map[string]bool), uses: make(map[string]bool)}
+	var refs []backend.XmlLibraryRef
+	switch x := doc.(type) {
+	case *backend.XmlSignalGraph:
+		refs = x.Libraries
+		d.useGraph(x)
+	case *backend.XmlLibrary:
+		refs = x.Libraries
+		d.Library = true
+		for _, st := range x.SignalTypes {
+			d.defines[signalType(st.Name)] = true
+			for _, f := range st.Field {
+				d.uses[signalType(f.FType)] = true
+			}
+		}
+		for _, nt := range x.NodeTypes {
+			d.defines[nodeType(nt.TypeName)] = true
+			d.usePorts(nt.InPort, nt.OutPort)
+			for _, impl := range nt.Implementation {
+				for k := range impl.SignalGraph {
+					d.useGraph(&impl.SignalGraph[k])
+				}
+			}
+		}
+		for _, c := range x.Compatible {
+			d.uses[signalType(c.From)] = true
+			d.uses[signalType(c.To)] = true
+			if len(c.Converter) > 0 {
+				d.uses[nodeType(c.Converter)] = true
+			}
+		}
+	default:
+		return
+	}
+	dir := filepath.Dir(filename)
+	for _, r := range refs {
+		ref := Ref{Name: r.Name}
+		res, err := backend.ResolveRef(r.Name, dir)
+		if err == nil {
+			ref.Path = res.AbsPath()
+		}
+		d.Refs = append(d.Refs, ref)
+	}
+	g.docs[filename] = d
+}
+
+// AddFile reads and adds a document, other files are ignored.
+func (g *Graph) AddFile(filename string) (err error) {
+	if _, ok := g.Document(filename); ok {
+		return
+	}
+	doc, ok := backend.DocumentNew(filename)
+	if !ok {
+		return
+	}
+	switch doc.(type) {
+	case *backend.XmlSignalGraph, *backend.XmlLibrary:
+	default:
+		return
+	}
+	data, err := tool.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("Graph.AddFile error: %v", err)
+		return
+	}
+	// documents are only read, their unknown content is not kept
+	var keepNothing *backend.Preserver
+	_, err = keepNothing.DocumentRead(doc, data, filename)
+	if err != nil {
+		err = fmt.Errorf("Graph.AddFile error: %s: %v", filename, err)
+		return
+	}
+	g.Add(doc, filename)
+	return
+}
+
+// AddDir adds all documents below dir, except in hidden directories.
+// Unreadable documents are skipped with a warning.
+func (g *Graph) AddDir(dir string) (err error) {
+	return tool.WalkFiles(dir, func(p string) error {
+		e := g.AddFile(p)
+		if e != nil {
+			log.Printf("Graph.AddDir warning: %s\n", e)
+		}
+		return nil
+	})
+}
+
+// AddProject adds the graphs and libraries of the current project.
+func (g *Graph) AddProject() {
+	p := backend.Project()
+	if p == nil {
+		return
+	}
+	for _, list := range [][]backend.XmlProjectMember{p.Graphs, p.Libraries} {
+		for _, m := range list {
+			err := g.AddFile(backend.ProjectPath(m.Ref))
+			if err != nil {
+				log.Printf("Graph.AddProject warning: %s\n", err)
+			}
+		}
+	}
+}
+
+// Analyze reads the referenced libraries not yet added and reports
+// cycles, missing and unused references.
+func (g *Graph) Analyze() *Report {
+	g.complete()
+	r := &Report{}
+	for _, d := range g.Documents() {
+		r.Documents = append(r.Documents, d.Filename)
+		for i := range d.Refs {
+			ref := &d.Refs[i]
+			if ref.Missing() {
+				r.Missing = append(r.Missing, Edge{d.Filename, ref.Name})
+				continue
+			}
+			ref.Unused = !g.uses(d, ref.Path)
+			e := Edge{d.Filename, ref.Path}
+			r.Edges = append(r.Edges, e)
+			if ref.Unused {
+				r.Unused = append(r.Unused, e)
+			}
+		}
+	}
+	r.Cycles = g.cycles()
+	return r
+}
+
+func (g *Graph) complete() {
+	for added := true; added; {
+		added = false
+		for _, d := range g.Documents() {
+			for _, ref := range d.Refs {
+				if ref.Missing() {
+					continue
+				}
+				if _, ok := g.docs[ref.Path]; ok {
+					continue
+				}
+				err := g.require(ref.Path)
+				if err != nil {
+					log.Printf("Graph.Analyze warning: %s\n", err)
+				}
+				added = true
+			}
+		}
+	}
+}
+
+// Adds the library stored as path unless added before.
+func (g *Graph) require(path string) (err error) {
+	if _, ok := g.docs[path]; ok {
+		return
+	}
+	err = g.AddFile(path)
+	if _, ok := g.docs[path]; !ok {
+		// unreadable, keep it as leaf
+		g.docs[path] = &Document{Filename: path, Library: true}
+	}
+	return
+}
+
+// CycleError returns an error naming the reference cycle through the
+// document stored as filename, nil if there is none. Loaders call it
+// when a document refers back to one still being loaded.
+func CycleError(filename string) error {
+	g := GraphNew()
+	err := g.AddFile(filename)
+	if err != nil {
+		return err
+	}
+	filename = backend.AbsPath(filename)
+	name := relTo(filepath.Dir(filename))
+	for _, c := range g.Analyze().Cycles {
+		for _, f := range c {
+			if f != filename {
+				continue
+			}
+			var names []string
+			for _, f := range c {
+				names = append(names, name(f))
+			}
+			return fmt.Errorf("reference cycle: %s", strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// Reaches tells if the library stored as lib is, or depends on, the
+// library stored as dep. Libraries not added are read.
+func (g *Graph) Reaches(lib, dep string) bool {
+	lib, dep = backend.AbsPath(lib), backend.AbsPath(dep)
+	err := g.require(lib)
+	if err != nil {
+		log.Printf("Graph.Reaches warning: %s\n", err)
+	}
+	g.complete()
+	return g.search(lib, func(l *Document) bool {
+		return l.Filename == dep
+	})
+}
+
+// ResolveSignalType returns the path of the library defining the
+// signal type typeName, as used by the document stored as filename.
+// Candidates are the libraries of the graph, see
+// freesp.Registry.ResolveSignalType.
+func (g *Graph) ResolveSignalType(filename, typeName string) (lib string, err error) {
+	return g.resolve(filename, "signal type", signalType(typeName), typeName)
+}
+
+// ResolveNodeType returns the path of the library defining the node
+// type typeName, see ResolveSignalType.
+func (g *Graph) ResolveNodeType(filename, typeName string) (lib string, err error) {
+	return g.resolve(filename, "node type", nodeType(typeName), typeName)
+}
+
+func (g *Graph) resolve(filename, kind, t, typeName string) (lib string, err error) {
+	filename = backend.AbsPath(filename)
+	err = g.require(filename)
+	if err != nil {
+		return
+	}
+	g.complete()
+	var definedAt []string
+	for _, l := range g.Documents() {
+		if l.Library && l.defines[t] {
+			definedAt = append(definedAt, l.Filename)
+		}
+	}
+	scope := make(map[string]bool)
+	g.search(filename, func(l *Document) bool {
+		scope[l.Filename] = true
+		return false
+	})
+	i, err := freesp.ResolveTypeName(kind, typeName, definedAt, scope)
+	if err == nil {
+		lib = definedAt[i]
+	}
+	return
+}
+
+// A reference of d to lib is used if a type used by d, but not defined
+// by d, is defined in lib or one of the libraries lib depends on.
+func (g *Graph) uses(d *Document, lib string) bool {
+	return g.search(lib, func(l *Document) bool {
+		for t := range l.defines {
+			if d.uses[t] && !d.defines[t] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Tells if found holds for lib or one of the libraries lib depends on.
+func (g *Graph) search(lib string, found func(l *Document) bool) bool {
+	seen := make(map[string]bool)
+	var search func(path string) bool
+	search = func(path string) bool {
+		if seen[path] {
+			return false
+		}
+		seen[path] = true
+		l, ok := g.docs[path]
+		if !ok {
+			return false
+		}
+		if found(l) {
+			return true
+		}
+		for _, r := range l.Refs {
+			if !r.Missing() && search(r.Path) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(lib)
+}
+
+// Strongly connected components with more than one document, or with
+// a document referencing itself (Tarjan).
+func (g *Graph) cycles() (cycles [][]string) {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var strongconnect func(v string)
+	strongconnect = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, r := range g.docs[v].Refs {
+			w := r.Path
+			if _, ok := g.docs[w]; !ok {
+				continue
+			}
+			if _, visited := index[w]; !visited {
+				strongconnect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 || g.refers(v, v) {
+			sort.Strings(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	for _, d := range g.Documents() {
+		if _, visited := index[d.Filename]; !visited {
+			strongconnect(d.Filename)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return
+}
+
+func (g *Graph) refers(from, to string) bool {
+	for _, r := range g.docs[from].Refs {
+		if r.Path == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Document) useGraph(x *backend.XmlSignalGraph) {
+	for _, n := range x.Nodes() {
+		if len(n.NType) > 0 {
+			d.uses[nodeType(n.NType)] = true
+		}
+		d.usePorts(n.InPort, n.OutPort)
+	}
+}
+
+func (d *Document) usePorts(in []backend.XmlInPort, out []backend.XmlOutPort) {
+	for _, p := range in {
+		d.uses[signalType(p.PType)] = true
+	}
+	for _, p := range out {
+		d.uses[signalType(p.PType)] = true
+	}
+}
+
+// Node and signal types have separate name spaces. Library namespaces
+// of qualified names are dropped, libraries are told apart by file.
+func nodeType(name string) string {
+	_, name = freesp.SplitTypeName(name)
+	return "n:" + name
+}
+
+func signalType(name string) string {
+	_, name = freesp.SplitTypeName(name)
+	return "s:" + name
+}
+
+//
+//	Report
+//
+
+// Edge is a library reference, To is the referenced path or, for
+// missing references, the text of the reference.
+type Edge struct {
+	From, To string
+}
+
+type Report struct {
+	Documents []string
+	Edges     []Edge
+	Cycles    [][]string
+	Missing   []Edge
+	Unused    []Edge
+}
+
+// Failed tells if there are cycles or missing references. Unused
+// references are harmless.
+func (r *Report) Failed() bool {
+	return len(r.Cycles) > 0 || len(r.Missing) > 0
+}
+
+// InCycle tells if e closes a cycle.
+func (r *Report) InCycle(e Edge) bool {
+	for _, c := range r.Cycles {
+		from, to := false, false
+		for _, f := range c {
+			from = from || f == e.From
+			to = to || f == e.To
+		}
+		if from && to {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report, file names relative to dir if possible.
+func (r *Report) WriteText(w io.Writer, dir string) {
+	name := relTo(dir)
+	for _, c := range r.Cycles {
+		var names []string
+		for _, f := range c {
+			names = append(names, name(f))
+		}
+		fmt.Fprintf(w, "cycle: %s\n", strings.Join(names, ", "))
+	}
+	for _, e := range r.Missing {
+		fmt.Fprintf(w, "%s: missing library %s\n", name(e.From), e.To)
+	}
+	for _, e := range r.Unused {
+		fmt.Fprintf(w, "%s: unused library %s\n", name(e.From), name(e.To))
+	}
+}
+
+// WriteDot writes the dependency graph in graphviz format. Cycles are
+// red, missing references dashed, unused ones dotted.
+func (r *Report) WriteDot(w io.Writer, dir string) {
+	name := relTo(dir)
+	fmt.Fprintf(w, "digraph dependencies {\n")
+	for _, f := range r.Documents {
+		fmt.Fprintf(w, "\t%q;\n", name(f))
+	}
+	for _, e := range r.Edges {
+		var attr []string
+		if r.InCycle(e) {
+			attr = append(attr, "color=red")
+		}
+		for _, u := range r.Unused {
+			if u == e {
+				attr = append(attr, "style=dotted")
+			}
+		}
+		fmt.Fprintf(w, "\t%q -> %q", name(e.From), name(e.To))
+		if len(attr) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(attr, ","))
+		}
+		fmt.Fprintf(w, ";\n")
+	}
+	for _, e := range r.Missing {
+		fmt.Fprintf(w, "\t%q [style=dashed];\n", e.To)
+		fmt.Fprintf(w, "\t%q -> %q [style=dashed];\n", name(e.From), e.To)
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+func relTo(dir string) func(string) string {
+	return func(path string) string {
+		if len(dir) == 0 {
+			return path
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return path
+		}
+		return rel
+	}
+}
@@ -0,0 +1,85 @@
+package deps
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var depsFiles = map[string]string{
+	"a.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="b.alml"></library>
+   <signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="A">
+      <intype port="i" type="t"></intype>
+   </node-type>
+</library>`,
+	"b.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="a.alml"></library>
+   <signal-type name="t" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="B">
+      <intype port="i" type="s"></intype>
+   </node-type>
+</library>`,
+	"c.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <node-type name="C"></node-type>
+</library>`,
+	"g.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="a.alml"></library>
+   <library ref="c.alml"></library>
+   <library ref="missing.alml"></library>
+   <nodes>
+      <processing-node name="b" type="B"></processing-node>
+   </nodes>
+</signal-graph>`,
+}
+
+func TestAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, text := range depsFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := GraphNew()
+	// the libraries are read as needed
+	err = g.AddFile(filepath.Join(dir, "g.sml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := g.Analyze()
+	var buf bytes.Buffer
+	r.WriteText(&buf, dir)
+	expected := `cycle: a.alml, b.alml
+g.sml: missing library missing.alml
+g.sml: unused library c.alml
+`
+	if buf.String() != expected {
+		t.Errorf("TestAnalyze failed: got\n%s", buf.String())
+	}
+	if !r.Failed() {
+		t.Errorf("TestAnalyze failed: report not failed")
+	}
+	case1 := []struct {
+		from, to string
+		inCycle  bool
+	}{
+		{"a.alml", "b.alml", true},
+		{"b.alml", "a.alml", true},
+		{"g.sml", "a.alml", false},
+		{"g.sml", "c.alml", false},
+	}
+	for i, c := range case1 {
+		e := Edge{filepath.Join(dir, c.from), filepath.Join(dir, c.to)}
+		if r.InCycle(e) != c.inCycle {
+			t.Errorf("testcase %d failed: InCycle(%v) = %v\n", i, e, !c.inCycle)
+		}
+	}
+}
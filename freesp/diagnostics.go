@@ -0,0 +1,40 @@
+package freesp
+
+import (
+	"fmt"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"log"
+)
+
+// Diagnostics collects the warnings found while loading a document
+// which are no reason to reject it, like unknown hint modes. Documents
+// embed Diagnostics, such that package validate reports the warnings
+// along with the problems of the document.
+type Diagnostics struct {
+	warnings []string
+}
+
+// Warnf records a load warning. Without a document (d == nil) the
+// warning is logged.
+func (d *Diagnostics) Warnf(format string, args ...interface{}) {
+	if d == nil {
+		log.Printf("warning: "+format+"\n", args...)
+		return
+	}
+	d.warnings = append(d.warnings, fmt.Sprintf(format, args...))
+}
+
+func (d *Diagnostics) LoadWarnings() []string {
+	return d.warnings
+}
+
+// LoadWarnings returns the load warnings of doc, if it collects any.
+func LoadWarnings(doc tr.ToplevelTreeElementIf) []string {
+	d, ok := doc.(interface {
+		LoadWarnings() []string
+	})
+	if !ok {
+		return nil
+	}
+	return d.LoadWarnings()
+}
@@ -0,0 +1,895 @@
+package diff
+
+import (
+	"fmt"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"sort"
+	"strings"
+)
+
+// Semantic differences between two versions of a model document.
+// Elements are matched by name, not by their position in the file,
+// so reordering alone never shows up. Layout changes (positions from
+// the hint files) are only reported with Options.Layout.
+
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Renamed
+	Changed
+	Moved
+)
+
+type Change struct {
+	Kind Kind
+	What string // kind of element, e.g. "node", "connection"
+	Path string // element within the document, as in the old version
+	Attr string // changed attribute, for Kind == Changed
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return withDetail(fmt.Sprintf("+ %s %s", c.What, c.Path), c.New)
+	case Removed:
+		return withDetail(fmt.Sprintf("- %s %s", c.What, c.Path), c.Old)
+	case Renamed:
+		return fmt.Sprintf("~ %s %s renamed to %s", c.What, c.Old, c.New)
+	case Changed:
+		return fmt.Sprintf("~ %s %s %s: %s -> %s", c.What, c.Path, c.Attr, c.Old, c.New)
+	case Moved:
+		return fmt.Sprintf("~ %s %s moved: %s -> %s", c.What, c.Path, c.Old, c.New)
+	}
+	return fmt.Sprintf("? %s %s", c.What, c.Path)
+}
+
+func withDetail(s, detail string) string {
+	if len(detail) == 0 {
+		return s
+	}
+	return fmt.Sprintf("%s (%s)", s, detail)
+}
+
+type Options struct {
+	Layout bool // report changed positions
+}
+
+// Documents compares two documents of the same kind.
+func Documents(a, b tr.ToplevelTreeElementIf, opts Options) (changes []Change, err error) {
+	switch a.(type) {
+	case bh.SignalGraphIf:
+		if g, ok := b.(bh.SignalGraphIf); ok {
+			return SignalGraphs(a.(bh.SignalGraphIf), g, opts), nil
+		}
+	case bh.LibraryIf:
+		if l, ok := b.(bh.LibraryIf); ok {
+			return Libraries(a.(bh.LibraryIf), l, opts), nil
+		}
+	case pf.PlatformIf:
+		if p, ok := b.(pf.PlatformIf); ok {
+			return Platforms(a.(pf.PlatformIf), p, opts), nil
+		}
+	case mp.MappingIf:
+		if m, ok := b.(mp.MappingIf); ok {
+			return Mappings(a.(mp.MappingIf), m, opts), nil
+		}
+	default:
+		err = fmt.Errorf("diff.Documents error: unsupported document type %T", a)
+		return
+	}
+	err = fmt.Errorf("diff.Documents error: cannot compare %T with %T", a, b)
+	return
+}
+
+//
+//      Signal graphs
+//
+
+func SignalGraphs(a, b bh.SignalGraphIf, opts Options) []Change {
+	return SignalGraphTypes(a.ItsType(), b.ItsType(), opts)
+}
+
+func SignalGraphTypes(a, b bh.SignalGraphTypeIf, opts Options) []Change {
+	var d differ
+	d.opts = opts
+	d.signalGraphType("", a, b)
+	return d.changes
+}
+
+type differ struct {
+	opts    Options
+	changes []Change
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) signalGraphType(prefix string, a, b bh.SignalGraphTypeIf) {
+	d.nameSets("library", prefix, libraryNames(a.Libraries()), libraryNames(b.Libraries()))
+	nodesA, nodesB := nodeMap(a.Nodes()), nodeMap(b.Nodes())
+	renamed := matchRenamedNodes(nodesA, nodesB)
+	for _, name := range sortedNodeKeys(nodesA) {
+		nb, ok := nodesB[name]
+		if !ok {
+			newName, ok := renamed[name]
+			if !ok {
+				d.add(Change{Kind: Removed, What: "node", Path: prefix + name, Old: nodeTypeName(nodesA[name])})
+				continue
+			}
+			d.add(Change{Kind: Renamed, What: "node", Path: prefix + name, Old: prefix + name, New: prefix + newName})
+			nb = nodesB[newName]
+		}
+		d.node(prefix, nodesA[name], nb)
+	}
+	isNewName := make(map[string]bool)
+	for _, newName := range renamed {
+		isNewName[newName] = true
+	}
+	for _, name := range sortedNodeKeys(nodesB) {
+		_, ok := nodesA[name]
+		if !ok && !isNewName[name] {
+			d.add(Change{Kind: Added, What: "node", Path: prefix + name, New: nodeTypeName(nodesB[name])})
+		}
+	}
+	connA, connB := connections(a.Nodes(), renamed), connections(b.Nodes(), nil)
+	for _, c := range sortedSetKeys(connA) {
+		if !connB[c] {
+			d.add(Change{Kind: Removed, What: "connection", Path: prefix + c})
+		}
+	}
+	for _, c := range sortedSetKeys(connB) {
+		if !connA[c] {
+			d.add(Change{Kind: Added, What: "connection", Path: prefix + c})
+		}
+	}
+}
+
+func (d *differ) node(prefix string, a, b bh.NodeIf) {
+	path := prefix + a.Name()
+	if nodeTypeName(a) != nodeTypeName(b) {
+		d.add(Change{Kind: Changed, What: "node", Path: path, Attr: "type", Old: nodeTypeName(a), New: nodeTypeName(b)})
+	} else {
+		d.ports(path, "input port", a.InPorts(), b.InPorts())
+		d.ports(path, "output port", a.OutPorts(), b.OutPorts())
+		d.paramValues(path, a, b)
+	}
+	if d.opts.Layout {
+		d.position("node", path, a, b)
+	}
+}
+
+// Parameters a node does not set are shown with an empty value.
+func (d *differ) paramValues(path string, a, b bh.NodeIf) {
+	valuesA, valuesB := paramValueMap(a), paramValueMap(b)
+	for _, name := range sortedStringKeys(valuesA) {
+		if valuesA[name] != valuesB[name] {
+			d.add(Change{Kind: Changed, What: "node", Path: path, Attr: "parameter " + name, Old: valuesA[name], New: valuesB[name]})
+		}
+	}
+	for _, name := range sortedStringKeys(valuesB) {
+		if _, ok := valuesA[name]; !ok {
+			d.add(Change{Kind: Changed, What: "node", Path: path, Attr: "parameter " + name, New: valuesB[name]})
+		}
+	}
+}
+
+func paramValueMap(n bh.NodeIf) map[string]string {
+	values := make(map[string]string)
+	for _, p := range n.ItsType().Parameters() {
+		v, ok := n.ParamValue(p.Name())
+		if ok {
+			values[p.Name()] = v
+		}
+	}
+	return values
+}
+
+func (d *differ) ports(path, what string, a, b []bh.PortIf) {
+	portsA, portsB := make(map[string]bh.PortIf), make(map[string]bh.PortIf)
+	for _, p := range a {
+		portsA[p.Name()] = p
+	}
+	for _, p := range b {
+		portsB[p.Name()] = p
+	}
+	for _, name := range sortedPortKeys(portsA) {
+		pb, ok := portsB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: what, Path: path + "." + name, Old: signalTypeName(portsA[name].SignalType())})
+			continue
+		}
+		d.signalTypeRef(what, path+"."+name, portsA[name].SignalType(), pb.SignalType())
+	}
+	for _, name := range sortedPortKeys(portsB) {
+		if _, ok := portsA[name]; !ok {
+			d.add(Change{Kind: Added, What: what, Path: path + "." + name, New: signalTypeName(portsB[name].SignalType())})
+		}
+	}
+}
+
+func (d *differ) signalTypeRef(what, path string, a, b bh.SignalTypeIf) {
+	if signalTypeName(a) != signalTypeName(b) {
+		d.add(Change{Kind: Changed, What: what, Path: path, Attr: "signal type", Old: signalTypeName(a), New: signalTypeName(b)})
+	}
+}
+
+func (d *differ) position(what, path string, a, b gr.ModePositioner) {
+	pa, pb := a.ModePosition(gr.PositionModeNormal), b.ModePosition(gr.PositionModeNormal)
+	if pa != pb {
+		d.add(Change{Kind: Moved, What: what, Path: path, Old: pa.String(), New: pb.String()})
+	}
+}
+
+// Report members only present in one of the name sets.
+func (d *differ) nameSets(what, prefix string, a, b map[string]bool) {
+	for _, name := range sortedSetKeys(a) {
+		if !b[name] {
+			d.add(Change{Kind: Removed, What: what, Path: prefix + name})
+		}
+	}
+	for _, name := range sortedSetKeys(b) {
+		if !a[name] {
+			d.add(Change{Kind: Added, What: what, Path: prefix + name})
+		}
+	}
+}
+
+// A removed and an added node are considered the same node renamed
+// when their type and connections agree, and the match is unique.
+func matchRenamedNodes(a, b map[string]bh.NodeIf) (renamed map[string]string) {
+	renamed = make(map[string]string)
+	removed, added := make(map[string][]string), make(map[string][]string)
+	for _, name := range sortedNodeKeys(a) {
+		if _, ok := b[name]; !ok {
+			sig := nodeSignature(a[name])
+			removed[sig] = append(removed[sig], name)
+		}
+	}
+	for _, name := range sortedNodeKeys(b) {
+		if _, ok := a[name]; !ok {
+			sig := nodeSignature(b[name])
+			added[sig] = append(added[sig], name)
+		}
+	}
+	for sig, names := range removed {
+		if len(names) == 1 && len(added[sig]) == 1 {
+			renamed[names[0]] = added[sig][0]
+		}
+	}
+	return
+}
+
+func nodeSignature(n bh.NodeIf) string {
+	var conn []string
+	for _, p := range n.InPorts() {
+		for _, c := range p.Connections() {
+			conn = append(conn, fmt.Sprintf("%s<-%s.%s", p.Name(), c.Node().Name(), c.Name()))
+		}
+	}
+	for _, p := range n.OutPorts() {
+		for _, c := range p.Connections() {
+			conn = append(conn, fmt.Sprintf("%s->%s.%s", p.Name(), c.Node().Name(), c.Name()))
+		}
+	}
+	sort.Strings(conn)
+	return fmt.Sprintf("%s{%s}", nodeTypeName(n), strings.Join(conn, ","))
+}
+
+// Connections of a graph as "from.port -> to.port", with node names
+// translated by rename.
+func connections(nodes []bh.NodeIf, rename map[string]string) map[string]bool {
+	name := func(n bh.NodeIf) string {
+		if newName, ok := rename[n.Name()]; ok {
+			return newName
+		}
+		return n.Name()
+	}
+	conn := make(map[string]bool)
+	for _, n := range nodes {
+		for _, p := range n.OutPorts() {
+			for _, c := range p.Connections() {
+				conn[fmt.Sprintf("%s.%s -> %s.%s", name(n), p.Name(), name(c.Node()), c.Name())] = true
+			}
+		}
+	}
+	return conn
+}
+
+func nodeMap(nodes []bh.NodeIf) map[string]bh.NodeIf {
+	m := make(map[string]bh.NodeIf)
+	for _, n := range nodes {
+		m[n.Name()] = n
+	}
+	return m
+}
+
+func libraryNames(libs []bh.LibraryIf) map[string]bool {
+	m := make(map[string]bool)
+	for _, l := range libs {
+		m[l.Filename()] = true
+	}
+	return m
+}
+
+func nodeTypeName(n bh.NodeIf) string {
+	if n.ItsType() == nil {
+		return ""
+	}
+	return n.ItsType().TypeName()
+}
+
+func signalTypeName(s bh.SignalTypeIf) string {
+	if s == nil {
+		return ""
+	}
+	return s.TypeName()
+}
+
+//
+//      Libraries
+//
+
+func Libraries(a, b bh.LibraryIf, opts Options) []Change {
+	var d differ
+	d.opts = opts
+	d.library(a, b)
+	return d.changes
+}
+
+func (d *differ) library(a, b bh.LibraryIf) {
+	stA, stB := make(map[string]bh.SignalTypeIf), make(map[string]bh.SignalTypeIf)
+	for _, s := range a.SignalTypes() {
+		stA[s.TypeName()] = s
+	}
+	for _, s := range b.SignalTypes() {
+		stB[s.TypeName()] = s
+	}
+	for _, name := range sortedSignalTypeKeys(stA) {
+		sb, ok := stB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "signal type", Path: name})
+			continue
+		}
+		d.signalType(stA[name], sb)
+	}
+	for _, name := range sortedSignalTypeKeys(stB) {
+		if _, ok := stA[name]; !ok {
+			d.add(Change{Kind: Added, What: "signal type", Path: name})
+		}
+	}
+	ntA, ntB := make(map[string]bh.NodeTypeIf), make(map[string]bh.NodeTypeIf)
+	for _, t := range a.NodeTypes() {
+		ntA[t.TypeName()] = t
+	}
+	for _, t := range b.NodeTypes() {
+		ntB[t.TypeName()] = t
+	}
+	for _, name := range sortedNodeTypeKeys(ntA) {
+		tb, ok := ntB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "node type", Path: name})
+			continue
+		}
+		d.nodeType(ntA[name], tb)
+	}
+	for _, name := range sortedNodeTypeKeys(ntB) {
+		if _, ok := ntA[name]; !ok {
+			d.add(Change{Kind: Added, What: "node type", Path: name})
+		}
+	}
+	d.compatibilities(a.Compatibilities(), b.Compatibilities())
+}
+
+// Compatibilities are identified by "from -> to" and compared in the
+// notation "from -> to kind [via converter]".
+func (d *differ) compatibilities(a, b []bh.CompatibilityIf) {
+	declA, declB := make(map[string]string), make(map[string]string)
+	for _, c := range a {
+		declA[fmt.Sprintf("%s -> %s", c.From(), c.To())] = fmt.Sprint(c)
+	}
+	for _, c := range b {
+		declB[fmt.Sprintf("%s -> %s", c.From(), c.To())] = fmt.Sprint(c)
+	}
+	for _, name := range sortedStringKeys(declA) {
+		db, ok := declB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "compatibility", Path: name, Old: declA[name]})
+		} else if db != declA[name] {
+			d.add(Change{Kind: Changed, What: "compatibility", Path: name, Attr: "declaration", Old: declA[name], New: db})
+		}
+	}
+	for _, name := range sortedStringKeys(declB) {
+		if _, ok := declA[name]; !ok {
+			d.add(Change{Kind: Added, What: "compatibility", Path: name, New: declB[name]})
+		}
+	}
+}
+
+func (d *differ) signalType(a, b bh.SignalTypeIf) {
+	attr := func(name, va, vb string) {
+		if va != vb {
+			d.add(Change{Kind: Changed, What: "signal type", Path: a.TypeName(), Attr: name, Old: va, New: vb})
+		}
+	}
+	attr("ctype", a.CType(), b.CType())
+	attr("msgid", a.ChannelId(), b.ChannelId())
+	attr("scope", scopeString(a.Scope()), scopeString(b.Scope()))
+	attr("mode", modeString(a.Mode()), modeString(b.Mode()))
+	d.fields(a.TypeName(), a.Fields(), b.Fields())
+}
+
+// Fields are compared in the notation "name: type[size] unit [min..max]"
+// of the library tree. As fields are packed in their order, reordering
+// is a change of the signal type.
+func (d *differ) fields(path string, a, b []bh.SignalFieldIf) {
+	declA, declB := make(map[string]string), make(map[string]string)
+	var orderA, orderB []string
+	for _, f := range a {
+		declA[f.Name()] = fmt.Sprint(f)
+		orderA = append(orderA, f.Name())
+	}
+	for _, f := range b {
+		declB[f.Name()] = fmt.Sprint(f)
+		orderB = append(orderB, f.Name())
+	}
+	for _, name := range sortedStringKeys(declA) {
+		db, ok := declB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "field", Path: path + "." + name, Old: declA[name]})
+		} else if db != declA[name] {
+			d.add(Change{Kind: Changed, What: "field", Path: path + "." + name, Attr: "declaration", Old: declA[name], New: db})
+		}
+	}
+	for _, name := range sortedStringKeys(declB) {
+		if _, ok := declA[name]; !ok {
+			d.add(Change{Kind: Added, What: "field", Path: path + "." + name, New: declB[name]})
+		}
+	}
+	oa, ob := strings.Join(orderA, ", "), strings.Join(orderB, ", ")
+	if oa != ob && strings.Join(sortedStringKeys(declA), ",") == strings.Join(sortedStringKeys(declB), ",") {
+		d.add(Change{Kind: Changed, What: "signal type", Path: path, Attr: "field order", Old: oa, New: ob})
+	}
+}
+
+func (d *differ) nodeType(a, b bh.NodeTypeIf) {
+	path := a.TypeName()
+	d.portTypes(path, "input port", a.InPorts(), b.InPorts())
+	d.portTypes(path, "output port", a.OutPorts(), b.OutPorts())
+	d.parameters(path, a.Parameters(), b.Parameters())
+	implA, implB := implementationMap(a.Implementation()), implementationMap(b.Implementation())
+	for _, name := range sortedImplementationKeys(implA) {
+		ib, ok := implB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "implementation", Path: path + "/" + name})
+			continue
+		}
+		ia := implA[name]
+		if len(ia.GraphRef()) > 0 || len(ib.GraphRef()) > 0 {
+			// referenced graph files are compared on their own
+			if ia.GraphRef() != ib.GraphRef() {
+				d.add(Change{Kind: Changed, What: "implementation", Path: path + "/" + name, Attr: "graph",
+					Old: ia.GraphRef(), New: ib.GraphRef()})
+			}
+		} else if ia.ImplementationType() == bh.NodeTypeGraph && ib.ImplementationType() == bh.NodeTypeGraph {
+			d.signalGraphType(path+"/", ia.Graph(), ib.Graph())
+		}
+	}
+	for _, name := range sortedImplementationKeys(implB) {
+		if _, ok := implA[name]; !ok {
+			d.add(Change{Kind: Added, What: "implementation", Path: path + "/" + name})
+		}
+	}
+}
+
+// Parameter declarations are compared in the notation
+// "name: type = default [min..max]".
+func (d *differ) parameters(path string, a, b []bh.ParameterIf) {
+	declA, declB := make(map[string]string), make(map[string]string)
+	for _, p := range a {
+		declA[p.Name()] = fmt.Sprint(p)
+	}
+	for _, p := range b {
+		declB[p.Name()] = fmt.Sprint(p)
+	}
+	for _, name := range sortedStringKeys(declA) {
+		db, ok := declB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "parameter", Path: path + "." + name, Old: declA[name]})
+		} else if db != declA[name] {
+			d.add(Change{Kind: Changed, What: "parameter", Path: path + "." + name, Attr: "declaration", Old: declA[name], New: db})
+		}
+	}
+	for _, name := range sortedStringKeys(declB) {
+		if _, ok := declA[name]; !ok {
+			d.add(Change{Kind: Added, What: "parameter", Path: path + "." + name, New: declB[name]})
+		}
+	}
+}
+
+func (d *differ) portTypes(path, what string, a, b []bh.PortTypeIf) {
+	portsA, portsB := make(map[string]bh.PortTypeIf), make(map[string]bh.PortTypeIf)
+	for _, p := range a {
+		portsA[p.Name()] = p
+	}
+	for _, p := range b {
+		portsB[p.Name()] = p
+	}
+	for _, name := range sortedPortTypeKeys(portsA) {
+		pb, ok := portsB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: what, Path: path + "." + name, Old: signalTypeName(portsA[name].SignalType())})
+			continue
+		}
+		d.signalTypeRef(what, path+"."+name, portsA[name].SignalType(), pb.SignalType())
+	}
+	for _, name := range sortedPortTypeKeys(portsB) {
+		if _, ok := portsA[name]; !ok {
+			d.add(Change{Kind: Added, What: what, Path: path + "." + name, New: signalTypeName(portsB[name].SignalType())})
+		}
+	}
+}
+
+// Implementations are keyed by their element name, graph
+// implementations by "graph".
+func implementationMap(list []bh.ImplementationIf) map[string]bh.ImplementationIf {
+	m := make(map[string]bh.ImplementationIf)
+	for _, i := range list {
+		if i.ImplementationType() == bh.NodeTypeGraph {
+			m["graph"] = i
+		} else {
+			m[i.ElementName()] = i
+		}
+	}
+	return m
+}
+
+func scopeString(s bh.Scope) string {
+	if s == bh.Global {
+		return "global"
+	}
+	return "local"
+}
+
+func modeString(m bh.Mode) string {
+	if m == bh.Asynchronous {
+		return "asynchronous"
+	}
+	return "synchronous"
+}
+
+//
+//      Platforms
+//
+
+func Platforms(a, b pf.PlatformIf, opts Options) []Change {
+	var d differ
+	d.opts = opts
+	d.platform(a, b)
+	return d.changes
+}
+
+func (d *differ) platform(a, b pf.PlatformIf) {
+	if a.PlatformId() != b.PlatformId() {
+		d.add(Change{Kind: Changed, What: "platform", Path: a.Filename(), Attr: "id", Old: a.PlatformId(), New: b.PlatformId()})
+	}
+	archA, archB := make(map[string]pf.ArchIf), make(map[string]pf.ArchIf)
+	for _, x := range a.Arch() {
+		archA[x.Name()] = x
+	}
+	for _, x := range b.Arch() {
+		archB[x.Name()] = x
+	}
+	for _, name := range sortedArchKeys(archA) {
+		xb, ok := archB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "arch", Path: name})
+			continue
+		}
+		d.arch(archA[name], xb)
+	}
+	for _, name := range sortedArchKeys(archB) {
+		if _, ok := archA[name]; !ok {
+			d.add(Change{Kind: Added, What: "arch", Path: name})
+		}
+	}
+}
+
+func (d *differ) arch(a, b pf.ArchIf) {
+	path := a.Name()
+	ioA, ioB := make(map[string]pf.IOTypeIf), make(map[string]pf.IOTypeIf)
+	for _, t := range a.IOTypes() {
+		ioA[t.Name()] = t
+	}
+	for _, t := range b.IOTypes() {
+		ioB[t.Name()] = t
+	}
+	for _, name := range sortedIOTypeKeys(ioA) {
+		tb, ok := ioB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "io type", Path: path + "/" + name, Old: string(ioA[name].IOMode())})
+			continue
+		}
+		if ioA[name].IOMode() != tb.IOMode() {
+			d.add(Change{Kind: Changed, What: "io type", Path: path + "/" + name, Attr: "mode",
+				Old: string(ioA[name].IOMode()), New: string(tb.IOMode())})
+		}
+	}
+	for _, name := range sortedIOTypeKeys(ioB) {
+		if _, ok := ioA[name]; !ok {
+			d.add(Change{Kind: Added, What: "io type", Path: path + "/" + name, New: string(ioB[name].IOMode())})
+		}
+	}
+	prA, prB := make(map[string]pf.ProcessIf), make(map[string]pf.ProcessIf)
+	for _, p := range a.Processes() {
+		prA[p.Name()] = p
+	}
+	for _, p := range b.Processes() {
+		prB[p.Name()] = p
+	}
+	for _, name := range sortedProcessKeys(prA) {
+		pb, ok := prB[name]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "process", Path: path + "/" + name})
+			continue
+		}
+		d.process(prA[name], pb)
+	}
+	for _, name := range sortedProcessKeys(prB) {
+		if _, ok := prA[name]; !ok {
+			d.add(Change{Kind: Added, What: "process", Path: path + "/" + name})
+		}
+	}
+	if d.opts.Layout {
+		d.position("arch", path, a, b)
+	}
+}
+
+func (d *differ) process(a, b pf.ProcessIf) {
+	path := processName(a)
+	d.channels(path, "input channel", a.InChannels(), b.InChannels())
+	d.channels(path, "output channel", a.OutChannels(), b.OutChannels())
+	if d.opts.Layout {
+		d.position("process", path, a, b)
+	}
+}
+
+// Channels are keyed by their link, since channel names contain the
+// io type (whose change is reported as such). Further channels with
+// the same link are numbered in their order: "-> a1/p2 (2)".
+func (d *differ) channels(path, what string, a, b []pf.ChannelIf) {
+	chA, chB := channelMap(a), channelMap(b)
+	for _, link := range sortedChannelKeys(chA) {
+		cb, ok := chB[link]
+		if !ok {
+			d.add(Change{Kind: Removed, What: what, Path: path + " " + link, Old: ioTypeName(chA[link])})
+			continue
+		}
+		if ioTypeName(chA[link]) != ioTypeName(cb) {
+			d.add(Change{Kind: Changed, What: what, Path: path + " " + link, Attr: "io type",
+				Old: ioTypeName(chA[link]), New: ioTypeName(cb)})
+		}
+	}
+	for _, link := range sortedChannelKeys(chB) {
+		if _, ok := chA[link]; !ok {
+			d.add(Change{Kind: Added, What: what, Path: path + " " + link, New: ioTypeName(chB[link])})
+		}
+	}
+}
+
+func channelMap(list []pf.ChannelIf) map[string]pf.ChannelIf {
+	m := make(map[string]pf.ChannelIf)
+	count := make(map[string]int)
+	for _, c := range list {
+		link := channelLink(c)
+		count[link]++
+		if count[link] > 1 {
+			link = fmt.Sprintf("%s (%d)", link, count[link])
+		}
+		m[link] = c
+	}
+	return m
+}
+
+func channelLink(c pf.ChannelIf) string {
+	if c.Link() == nil || c.Link().Process() == nil {
+		return "(unlinked)"
+	}
+	if c.Direction() == gr.InPort {
+		return "<- " + processName(c.Link().Process())
+	}
+	return "-> " + processName(c.Link().Process())
+}
+
+func ioTypeName(c pf.ChannelIf) string {
+	if c.IOType() == nil {
+		return ""
+	}
+	return c.IOType().Name()
+}
+
+func processName(p pf.ProcessIf) string {
+	return fmt.Sprintf("%s/%s", p.Arch().Name(), p.Name())
+}
+
+//
+//      Mappings
+//
+
+func Mappings(a, b mp.MappingIf, opts Options) []Change {
+	var d differ
+	d.opts = opts
+	d.mapping(a, b)
+	return d.changes
+}
+
+func (d *differ) mapping(a, b mp.MappingIf) {
+	ref := func(attr string, va, vb tr.ToplevelTreeElementIf) {
+		if va != nil && vb != nil && va.Filename() != vb.Filename() {
+			d.add(Change{Kind: Changed, What: "mapping", Path: a.Filename(), Attr: attr, Old: va.Filename(), New: vb.Filename()})
+		}
+	}
+	ref("graph", a.Graph(), b.Graph())
+	ref("platform", a.Platform(), b.Platform())
+	mapA, mapB := mappedProcesses(a), mappedProcesses(b)
+	for _, id := range sortedStringKeys(mapA) {
+		pb, ok := mapB[id]
+		if !ok {
+			d.add(Change{Kind: Removed, What: "mapped node", Path: id, Old: mapA[id]})
+			continue
+		}
+		if mapA[id] != pb {
+			d.add(Change{Kind: Changed, What: "mapped node", Path: id, Attr: "process", Old: mapA[id], New: pb})
+		}
+	}
+	for _, id := range sortedStringKeys(mapB) {
+		if _, ok := mapA[id]; !ok {
+			d.add(Change{Kind: Added, What: "mapped node", Path: id, New: mapB[id]})
+		}
+	}
+	if d.opts.Layout {
+		for _, nId := range a.MappedIds() {
+			ma, ok := a.MappedElement(nId)
+			if !ok {
+				continue
+			}
+			for _, nIdB := range b.MappedIds() {
+				if nIdB.String() != nId.String() {
+					continue
+				}
+				mb, ok := b.MappedElement(nIdB)
+				if ok {
+					d.position("mapped node", nId.String(), ma, mb)
+				}
+			}
+		}
+	}
+}
+
+// Mapped node ids with their process, "unmapped" if there is none.
+func mappedProcesses(m mp.MappingIf) map[string]string {
+	ret := make(map[string]string)
+	for _, nId := range m.MappedIds() {
+		melem, ok := m.MappedElement(nId)
+		if !ok {
+			continue
+		}
+		p, ok := melem.Process()
+		if ok && p != nil {
+			ret[nId.String()] = processName(p)
+		} else {
+			ret[nId.String()] = "unmapped"
+		}
+	}
+	return ret
+}
+
+//
+//      Helpers
+//
+
+// Sorted keys of the maps of this package, one helper per map type.
+
+func sortedSetKeys(m map[string]bool) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedStringKeys(m map[string]string) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedNodeKeys(m map[string]bh.NodeIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedPortKeys(m map[string]bh.PortIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedPortTypeKeys(m map[string]bh.PortTypeIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedSignalTypeKeys(m map[string]bh.SignalTypeIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedNodeTypeKeys(m map[string]bh.NodeTypeIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedImplementationKeys(m map[string]bh.ImplementationIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedArchKeys(m map[string]pf.ArchIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedIOTypeKeys(m map[string]pf.IOTypeIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedProcessKeys(m map[string]pf.ProcessIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func sortedChannelKeys(m map[string]pf.ChannelIf) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return
+}
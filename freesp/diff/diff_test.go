@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/platform"
+	"testing"
+)
+
+func TestChangeString(t *testing.T) {
+	case1 := []struct {
+		c    Change
+		text string
+	}{
+		{Change{Kind: Added, What: "node", Path: "x", New: "T"}, "+ node x (T)"},
+		{Change{Kind: Removed, What: "connection", Path: "a.o -> b.i"}, "- connection a.o -> b.i"},
+		{Change{Kind: Renamed, What: "node", Path: "a", Old: "a", New: "b"}, "~ node a renamed to b"},
+		{Change{Kind: Changed, What: "node", Path: "a", Attr: "type", Old: "T1", New: "T2"}, "~ node a type: T1 -> T2"},
+		{Change{Kind: Moved, What: "node", Path: "a", Old: "(0,0)", New: "(10,0)"}, "~ node a moved: (0,0) -> (10,0)"},
+	}
+	for i, c := range case1 {
+		if c.c.String() != c.text {
+			t.Errorf("testcase %d failed: %q, expected %q\n", i, c.c.String(), c.text)
+		}
+	}
+}
+
+const platformA = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p1">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <io-type name="t2" mode="async"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="a1/p2"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t1" source="a1/p1"></input-channel>
+      </process>
+   </arch>
+</platform>`
+
+// Reordered, io type t2 changed, channel type changed, process p3 added.
+const platformB = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p1">
+   <arch name="a1">
+      <io-type name="t2" mode="shmem"></io-type>
+      <io-type name="t1" mode="sync"></io-type>
+      <process name="p2">
+         <input-channel io-type="t2" source="a1/p1"></input-channel>
+      </process>
+      <process name="p1">
+         <output-channel io-type="t2" dest="a1/p2"></output-channel>
+      </process>
+      <process name="p3"></process>
+   </arch>
+</platform>`
+
+// A second channel from p1 to p2 added.
+const platformC = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p1">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <io-type name="t2" mode="async"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="a1/p2"></output-channel>
+         <output-channel io-type="t2" dest="a1/p2"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t1" source="a1/p1"></input-channel>
+         <input-channel io-type="t2" source="a1/p1"></input-channel>
+      </process>
+   </arch>
+</platform>`
+
+func TestPlatforms(t *testing.T) {
+	case1 := []struct {
+		a, b    string
+		changes []string
+	}{
+		{platformA, platformA, nil},
+		{platformA, platformB, []string{
+			"~ io type a1/t2 mode: Asynchronous -> Shared Memory",
+			"~ output channel a1/p1 -> a1/p2 io type: t1 -> t2",
+			"~ input channel a1/p2 <- a1/p1 io type: t1 -> t2",
+			"+ process a1/p3",
+		}},
+		{platformA, platformC, []string{
+			"+ output channel a1/p1 -> a1/p2 (2) (t2)",
+			"+ input channel a1/p2 <- a1/p1 (2) (t2)",
+		}},
+		{platformC, platformA, []string{
+			"- output channel a1/p1 -> a1/p2 (2) (t2)",
+			"- input channel a1/p2 <- a1/p1 (2) (t2)",
+		}},
+	}
+	for i, c := range case1 {
+		// each version with its own io type registry
+		freesp.Init()
+		a, b := platform.PlatformNew("a.spml", nil), platform.PlatformNew("b.spml", nil)
+		_, err := a.Read([]byte(c.a))
+		if err != nil {
+			t.Fatalf("testcase %d: %s\n", i, err)
+		}
+		freesp.Init()
+		_, err = b.Read([]byte(c.b))
+		if err != nil {
+			t.Fatalf("testcase %d: %s\n", i, err)
+		}
+		changes := Platforms(a, b, Options{})
+		if len(changes) != len(c.changes) {
+			t.Errorf("testcase %d failed: %d changes %v, expected %d\n", i, len(changes), changes, len(c.changes))
+			continue
+		}
+		for j, ch := range changes {
+			if ch.String() != c.changes[j] {
+				t.Errorf("testcase %d failed: change %d is %q, expected %q\n", i, j, ch, c.changes[j])
+			}
+		}
+	}
+}
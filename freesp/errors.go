@@ -0,0 +1,80 @@
+package freesp
+
+import (
+	"fmt"
+)
+
+// Model operations report recoverable failures as *Error, classified
+// by Kind, such that the editor can tell the user and go on instead of
+// terminating.
+
+type ErrorKind int
+
+const (
+	// An object referred to (by name or as argument) does not exist.
+	ErrNotFound ErrorKind = iota
+	// Signal types of ports or connections do not match.
+	ErrTypeMismatch
+	// The operation is not allowed on this object or with these
+	// arguments.
+	ErrInvalidOperation
+	// The model violates its own invariants, e.g. a connection missing
+	// at one of its ports. This indicates a bug, not a user error.
+	ErrInconsistentModel
+)
+
+var errorKindNames = []string{"not found", "type mismatch", "invalid operation", "inconsistent model"}
+
+func (k ErrorKind) String() string {
+	if int(k) < len(errorKindNames) {
+		return errorKindNames[k]
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+type Error struct {
+	Kind ErrorKind
+	Op   string // failed operation, e.g. "port.RemoveConnection"
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error: %s", e.Op, e.Msg)
+}
+
+// Is lets errors.Is match errors of the same kind: errors.Is(err,
+// &Error{Kind: ErrNotFound}).
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+func errorNew(kind ErrorKind, op, format string, args ...interface{}) *Error {
+	return &Error{kind, op, fmt.Sprintf(format, args...)}
+}
+
+func NotFoundError(op, format string, args ...interface{}) *Error {
+	return errorNew(ErrNotFound, op, format, args...)
+}
+
+func TypeMismatchError(op, format string, args ...interface{}) *Error {
+	return errorNew(ErrTypeMismatch, op, format, args...)
+}
+
+func InvalidOperationError(op, format string, args ...interface{}) *Error {
+	return errorNew(ErrInvalidOperation, op, format, args...)
+}
+
+func InconsistentModelError(op, format string, args ...interface{}) *Error {
+	return errorNew(ErrInconsistentModel, op, format, args...)
+}
+
+// ErrorKindOf returns the kind of err, ok is false for errors other
+// than *Error.
+func ErrorKindOf(err error) (kind ErrorKind, ok bool) {
+	e, ok := err.(*Error)
+	if ok {
+		kind = e.Kind
+	}
+	return
+}
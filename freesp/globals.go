@@ -3,107 +3,125 @@ package freesp
 import (
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	pf "github.com/axel-freesp/sge/interface/platform"
-	"github.com/axel-freesp/sge/tool"
+	"sync"
 )
 
-var signalTypes map[string]bh.SignalTypeIf
-var nodeTypes map[string]bh.NodeTypeIf
-var libraries map[string]bh.LibraryIf
-var ioTypes map[string]pf.IOTypeIf
-var registeredNodeTypes tool.StringList
-var registeredSignalTypes tool.StringList
-var registeredIOTypes tool.StringList
+// The package functions below work on the default registry, used by
+// contexts which do not own a registry. Init replaces it by an empty
+// one.
+
+var defaultRegistry = RegistryNew()
+var defaultMutex sync.RWMutex
 
 func Init() {
-	signalTypes = make(map[string]bh.SignalTypeIf)
-	nodeTypes = make(map[string]bh.NodeTypeIf)
-	// TODO: is this redundant with global.libraries?
-	libraries = make(map[string]bh.LibraryIf)
-	ioTypes = make(map[string]pf.IOTypeIf)
-	registeredNodeTypes = tool.StringListInit()
-	registeredSignalTypes = tool.StringListInit()
-	registeredIOTypes = tool.StringListInit()
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultRegistry = RegistryNew()
+}
+
+func DefaultRegistry() *Registry {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultRegistry
+}
+
+// SaveRegistry returns the default registry. Together with Init and
+// RestoreRegistry, this allows to load a document apart from all
+// documents loaded so far; contexts owning a registry do not need this.
+func SaveRegistry() *Registry {
+	return DefaultRegistry()
+}
+
+func RestoreRegistry(r *Registry) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultRegistry = r
 }
 
-// TODO: Turn into interface
 func GetRegisteredNodeTypes() []string {
-	return registeredNodeTypes.Strings()
+	return DefaultRegistry().GetRegisteredNodeTypes()
 }
 
 func GetRegisteredSignalTypes() []string {
-	return registeredSignalTypes.Strings()
+	return DefaultRegistry().GetRegisteredSignalTypes()
+}
+
+func GetNodeTypeByName(typeName string) (bh.NodeTypeIf, bool) {
+	return DefaultRegistry().GetNodeTypeByName(typeName)
+}
+
+func GetSignalTypeByName(typeName string) (bh.SignalTypeIf, bool) {
+	return DefaultRegistry().GetSignalTypeByName(typeName)
 }
 
-func GetNodeTypeByName(typeName string) (nType bh.NodeTypeIf, ok bool) {
-	nType, ok = nodeTypes[typeName]
-	return
+func ResolveSignalType(typeName string, scope map[string]bool) (bh.SignalTypeIf, error) {
+	return DefaultRegistry().ResolveSignalType(typeName, scope)
 }
 
-func GetSignalTypeByName(typeName string) (sType bh.SignalTypeIf, ok bool) {
-	sType, ok = signalTypes[typeName]
-	return
+func ResolveNodeType(typeName string, scope map[string]bool) (bh.NodeTypeIf, error) {
+	return DefaultRegistry().ResolveNodeType(typeName, scope)
 }
 
-func GetLibraryByName(filename string) (lib bh.LibraryIf, ok bool) {
-	lib, ok = libraries[filename]
-	return
+func SignalTypeRefName(st bh.SignalTypeIf) string {
+	return DefaultRegistry().SignalTypeRefName(st)
+}
+
+func NodeTypeRefName(nt bh.NodeTypeIf) string {
+	return DefaultRegistry().NodeTypeRefName(nt)
+}
+
+func GetLibraryByName(filename string) (bh.LibraryIf, bool) {
+	return DefaultRegistry().GetLibraryByName(filename)
+}
+
+func GetRegisteredLibraries() []bh.LibraryIf {
+	return DefaultRegistry().GetRegisteredLibraries()
 }
 
 func GetRegisteredIOTypes() []string {
-	return registeredIOTypes.Strings()
+	return DefaultRegistry().GetRegisteredIOTypes()
 }
 
-func GetIOTypeByName(name string) (ioType pf.IOTypeIf, ok bool) {
-	ioType, ok = ioTypes[name]
-	return
+func GetIOTypeByName(name string) (pf.IOTypeIf, bool) {
+	return DefaultRegistry().GetIOTypeByName(name)
 }
 
 func RegisterSignalType(st bh.SignalTypeIf) {
-	signalTypes[st.TypeName()] = st
-	registeredSignalTypes.Append(st.TypeName())
+	DefaultRegistry().RegisterSignalType(st)
 }
 
 func RegisterNodeType(nt bh.NodeTypeIf) {
-	nodeTypes[nt.TypeName()] = nt
-	registeredNodeTypes.Append(nt.TypeName())
+	DefaultRegistry().RegisterNodeType(nt)
+}
+
+func RenameRegisteredSignalType(st bh.SignalTypeIf, oldName string) {
+	DefaultRegistry().RenameRegisteredSignalType(st, oldName)
+}
+
+func RenameRegisteredNodeType(nt bh.NodeTypeIf, oldName string) {
+	DefaultRegistry().RenameRegisteredNodeType(nt, oldName)
 }
 
 func RegisterLibrary(lib bh.LibraryIf) {
-	libraries[lib.Filename()] = lib
+	DefaultRegistry().RegisterLibrary(lib)
 }
 
 func RegisterIOType(iot pf.IOTypeIf) {
-	ioTypes[iot.Name()] = iot
-	registeredIOTypes.Append(iot.Name())
+	DefaultRegistry().RegisterIOType(iot)
 }
 
 func RemoveRegisteredLibrary(lib bh.LibraryIf) {
-	delete(libraries, lib.Filename())
+	DefaultRegistry().RemoveRegisteredLibrary(lib)
 }
 
 func RemoveRegisteredNodeType(nt bh.NodeTypeIf) {
-	name := nt.TypeName()
-	delete(nodeTypes, name)
-	_, ok := registeredNodeTypes.Find(name)
-	if ok {
-		registeredNodeTypes.Remove(name)
-	}
+	DefaultRegistry().RemoveRegisteredNodeType(nt)
 }
 
 func RemoveRegisteredSignalType(st bh.SignalTypeIf) {
-	name := st.TypeName()
-	delete(signalTypes, name)
-	_, ok := registeredSignalTypes.Find(name)
-	if ok {
-		registeredSignalTypes.Remove(name)
-	}
+	DefaultRegistry().RemoveRegisteredSignalType(st)
 }
 
 func RemoveRegisteredIOType(iot pf.IOTypeIf) {
-	name := iot.Name()
-	delete(ioTypes, name)
-	_, ok := registeredIOTypes.Find(name)
-	if ok {
-		registeredIOTypes.Remove(name)
-	}
+	DefaultRegistry().RemoveRegisteredIOType(iot)
 }
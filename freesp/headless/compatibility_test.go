@@ -0,0 +1,96 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"strings"
+	"testing"
+)
+
+const compatLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="celsius" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <signal-type name="kelvin" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <signal-type name="temp" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <signal-type name="degree" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="celsius"></outtype>
+   </node-type>
+   <node-type name="Sink">
+      <intype port="i" type="kelvin"></intype>
+   </node-type>
+   <node-type name="Avg">
+      <intype port="i" type="temp"></intype>
+   </node-type>
+   <node-type name="C2K">
+      <intype port="i" type="temp"></intype>
+      <outtype port="o" type="kelvin"></outtype>
+   </node-type>
+   <compatible from="celsius" to="degree" kind="identity"></compatible>
+   <compatible from="degree" to="temp" kind="subtype"></compatible>
+   <compatible from="temp" to="kelvin" kind="convertible" converter="C2K"></compatible>
+</library>`
+
+func TestCompatibility(t *testing.T) {
+	freesp.Init()
+	c := ContextNew("")
+	lib := behaviour.LibraryNew("compat.alml", c)
+	_, err := lib.Read([]byte(compatLibrary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	celsius, _ := freesp.GetSignalTypeByName("celsius")
+	kelvin, _ := freesp.GetSignalTypeByName("kelvin")
+	temp, _ := freesp.GetSignalTypeByName("temp")
+	if !behaviour.SignalTypesCompatible(celsius, temp) {
+		t.Errorf("identity and subtype not combined\n")
+	}
+	if behaviour.SignalTypesCompatible(temp, celsius) {
+		t.Errorf("super type accepted for subtype\n")
+	}
+	if behaviour.SignalTypesCompatible(celsius, kelvin) {
+		t.Errorf("convertible types accepted without converter\n")
+	}
+	conv, ok := behaviour.Converter(celsius, kelvin)
+	if !ok || conv.TypeName() != "C2K" {
+		t.Errorf("converter not found\n")
+	}
+
+	g := behaviour.SignalGraphTypeNew(c)
+	src, _ := behaviour.NodeNew("src", lib.NodeTypes()[0], g)
+	sink, _ := behaviour.NodeNew("sink", lib.NodeTypes()[1], g)
+	avg, _ := behaviour.NodeNew("avg", lib.NodeTypes()[2], g)
+	err = src.OutPorts()[0].AddConnection(behaviour.ConnectionNew(src.OutPorts()[0], avg.InPorts()[0]))
+	if err != nil {
+		t.Errorf("compatible connection rejected: %v\n", err)
+	}
+	err = src.OutPorts()[0].AddConnection(behaviour.ConnectionNew(src.OutPorts()[0], sink.InPorts()[0]))
+	if err == nil || !strings.Contains(err.Error(), "insert a C2K node") {
+		t.Errorf("wrong error for convertible connection: %v\n", err)
+	}
+
+	xmllib := behaviour.CreateXmlLibrary(lib)
+	if len(xmllib.Compatible) != 3 || xmllib.Compatible[2].Converter != "C2K" {
+		t.Errorf("wrong XML compatibilities %v\n", xmllib.Compatible)
+	}
+	temp.SetTypeName("temperature")
+	if lib.Compatibilities()[1].To() != "temperature" || lib.Compatibilities()[2].From() != "temperature" {
+		t.Errorf("compatibility not renamed\n")
+	}
+
+	case1 := []struct {
+		compat, err string
+	}{
+		{`<compatible from="celsius" to="fahrenheit" kind="subtype"></compatible>`, "unknown signal type fahrenheit"},
+		{`<compatible from="celsius" to="kelvin" kind="convertible"></compatible>`, "convertible types need a converter"},
+		{`<compatible from="kelvin" to="celsius" kind="convertible" converter="C2K"></compatible>`, "ports of converter C2K do not match"},
+		{`<compatible from="celsius" to="kelvin" kind="convertible" converter="Sink"></compatible>`, "needs one input and one output"},
+	}
+	for i, c1 := range case1 {
+		freesp.Init()
+		text := strings.Replace(compatLibrary, "</library>", c1.compat+"\n</library>", 1)
+		_, err = behaviour.LibraryNew("bad.alml", c).Read([]byte(text))
+		if err == nil || !strings.Contains(err.Error(), c1.err) {
+			t.Errorf("testcase %d: got error '%v', expected '%s'\n", i, err, c1.err)
+		}
+	}
+}
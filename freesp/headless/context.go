@@ -0,0 +1,388 @@
+package headless
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/freesp/mapping"
+	"github.com/axel-freesp/sge/freesp/platform"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	mod "github.com/axel-freesp/sge/interface/model"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/tool"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Context is a model context without GUI, for command line tools.
+// Documents are read on demand, together with their hint files, and
+// kept by name. References are resolved relative to Dir first. The
+// unknown XML content of the documents is kept apart from the editor
+// and all other contexts.
+type Context struct {
+	Dir            string
+	registry       *freesp.Registry
+	preserver      *backend.Preserver
+	signalGraphMgr *fileManager
+	libraryMgr     *fileManager
+	platformMgr    *fileManager
+	mappingMgr     *fileManager
+}
+
+var _ mod.ModelContextIf = (*Context)(nil)
+
+// ContextNew returns a context using the default registry.
+func ContextNew(dir string) *Context {
+	return ContextWithRegistryNew(dir, nil)
+}
+
+// ContextWithRegistryNew returns a context registering its types in
+// reg, apart from all other contexts.
+func ContextWithRegistryNew(dir string, reg *freesp.Registry) *Context {
+	c := &Context{Dir: dir, registry: reg, preserver: backend.PreserverNew()}
+	c.signalGraphMgr = fileManagerNew(c, "sml")
+	c.libraryMgr = fileManagerNew(c, "alml")
+	c.platformMgr = fileManagerNew(c, "spml")
+	c.mappingMgr = fileManagerNew(c, "mml")
+	return c
+}
+
+// Registry returns the registry of c, nil for the default registry.
+func (c *Context) Registry() *freesp.Registry {
+	return c.registry
+}
+
+// Preserver returns the preserver of unknown XML content of c.
+func (c *Context) Preserver() *backend.Preserver {
+	return c.preserver
+}
+
+func (c *Context) SignalGraphMgr() mod.FileManagerSignalGraphIf {
+	return c.signalGraphMgr
+}
+
+func (c *Context) LibraryMgr() mod.FileManagerLibraryIf {
+	return c.libraryMgr
+}
+
+func (c *Context) PlatformMgr() mod.FileManagerIf {
+	return c.platformMgr
+}
+
+func (c *Context) MappingMgr() mod.FileManagerMappingIf {
+	return c.mappingMgr
+}
+
+// Load reads the document at path with a new context owning a fresh
+// registry, such that different versions of the same document can be
+// loaded one after the other and compared. The registry of the caller
+// is not changed.
+func Load(path string) (doc tr.ToplevelTreeElementIf, err error) {
+	return LoadAs(path, filepath.Base(path), filepath.Dir(path))
+}
+
+// LoadAs reads the file at path as document name. References are
+// resolved relative to dir. This allows to read temporary copies of
+// a document, as handed over by version control tools.
+func LoadAs(path, name, dir string) (doc tr.ToplevelTreeElementIf, err error) {
+	c := ContextWithRegistryNew(dir, freesp.RegistryNew())
+	mgr, err := c.fileMgr(tool.DocSuffix(name))
+	if err != nil {
+		return
+	}
+	return mgr.load(name, path, dir)
+}
+
+func (c *Context) fileMgr(suffix string) (mgr *fileManager, err error) {
+	switch suffix {
+	case "sml":
+		mgr = c.signalGraphMgr
+	case "alml":
+		mgr = c.libraryMgr
+	case "spml":
+		mgr = c.platformMgr
+	case "mml":
+		mgr = c.mappingMgr
+	default:
+		err = fmt.Errorf("headless.Context error: unknown document type %s", suffix)
+	}
+	return
+}
+
+//
+//      fileManager
+//
+
+type fileManager struct {
+	context        *Context
+	suffix         string
+	docs           map[string]tr.ToplevelTreeElementIf
+	paths          map[string]tr.ToplevelTreeElementIf // loaded documents by absolute path
+	loading        map[string]bool                     // absolute paths of documents being read
+	index          int
+	graphForNew    bh.SignalGraphIf
+	platformForNew pf.PlatformIf
+}
+
+var _ mod.FileManagerLibraryIf = (*fileManager)(nil)
+var _ mod.FileManagerSignalGraphIf = (*fileManager)(nil)
+var _ mod.FileManagerMappingIf = (*fileManager)(nil)
+
+func fileManagerNew(context *Context, suffix string) *fileManager {
+	return &fileManager{context, suffix, make(map[string]tr.ToplevelTreeElementIf),
+		make(map[string]tr.ToplevelTreeElementIf), make(map[string]bool), 0, nil, nil}
+}
+
+func (f *fileManager) newDocument(name string) (doc tr.ToplevelTreeElementIf) {
+	switch f.suffix {
+	case "sml":
+		doc = behaviour.SignalGraphNew(name, f.context)
+	case "alml":
+		doc = behaviour.LibraryNew(name, f.context)
+	case "spml":
+		doc = platform.PlatformNew(name, f.context)
+	case "mml":
+		doc = mapping.MappingNew(name, f.context)
+	}
+	return
+}
+
+func (f *fileManager) New() (doc tr.ToplevelTreeElementIf, err error) {
+	name := fmt.Sprintf("new-file-%d.%s", f.index, f.suffix)
+	f.index++
+	doc = f.newDocument(name)
+	if m, ok := doc.(mp.MappingIf); ok {
+		if f.graphForNew == nil || f.platformForNew == nil {
+			err = fmt.Errorf("headless.fileManager.New error: mapping not completely prepared")
+			return
+		}
+		m.SetGraph(f.graphForNew)
+		m.SetPlatform(f.platformForNew)
+	}
+	f.docs[name] = doc
+	return
+}
+
+func (f *fileManager) Access(name string) (doc tr.ToplevelTreeElementIf, err error) {
+	// open documents are accessed by name
+	var ok bool
+	doc, ok = f.docs[name]
+	if ok {
+		return
+	}
+	return f.AccessRef(name, f.context.Dir)
+}
+
+// AccessRef returns the document name refers to from a document stored
+// in dir. Documents are identified by the absolute path they were
+// loaded from. Types are registered by library name, so a library of
+// the same name from another file cannot be loaded; other documents of
+// the same name are loaded, but accessed by path only.
+func (f *fileManager) AccessRef(name, dir string) (doc tr.ToplevelTreeElementIf, err error) {
+	res, err := backend.ResolveRef(name, dir)
+	if err != nil {
+		// not saved yet
+		var ok bool
+		doc, ok = f.docs[name]
+		if ok {
+			err = nil
+		}
+		return
+	}
+	path := res.AbsPath()
+	var ok bool
+	doc, ok = f.paths[path]
+	if ok {
+		return
+	}
+	if f.loading[path] {
+		err = loadCycleError(path)
+		return
+	}
+	if d, ok := f.docs[name]; ok {
+		loaded, ok := f.pathOf(d)
+		switch {
+		case !ok:
+			// created or renamed, and saved since
+			f.paths[path] = d
+			doc = d
+			return
+		case f.suffix == "alml":
+			err = fmt.Errorf("headless.fileManager.AccessRef error: library %s from %s conflicts with %s loaded from %s",
+				name, path, name, loaded)
+			return
+		}
+	}
+	if res.Ambiguous() {
+		log.Println(res.Warning())
+	}
+	return f.load(name, res.Path, res.Base)
+}
+
+func (f *fileManager) pathOf(doc tr.ToplevelTreeElementIf) (path string, ok bool) {
+	for p, d := range f.paths {
+		if d == doc {
+			return p, true
+		}
+	}
+	return
+}
+
+// A document referring back to one still being read.
+func loadCycleError(path string) error {
+	err := deps.CycleError(path)
+	if err == nil {
+		err = fmt.Errorf("%s is referenced while it is read", path)
+	}
+	return freesp.InvalidOperationError("headless.fileManager.AccessRef", "%s", err)
+}
+
+func (f *fileManager) load(name, path, base string) (doc tr.ToplevelTreeElementIf, err error) {
+	doc = f.newDocument(name)
+	f.loading[backend.AbsPath(path)] = true
+	err = doc.ReadFile(path)
+	delete(f.loading, backend.AbsPath(path))
+	if err != nil {
+		return
+	}
+	doc.SetPathPrefix(base)
+	if f.suffix == "alml" {
+		freesp.RegistryOf(f.context).RegisterLibrary(doc.(bh.LibraryIf))
+		freesp.RegistryOf(f.context).RegisterLibraryPath(doc.(bh.LibraryIf), backend.AbsPath(path))
+	}
+	err = f.applyHints(doc, path)
+	if err != nil {
+		return
+	}
+	if _, ok := f.docs[name]; !ok {
+		f.docs[name] = doc
+	}
+	f.paths[backend.AbsPath(path)] = doc
+	return
+}
+
+func (f *fileManager) applyHints(doc tr.ToplevelTreeElementIf, path string) (err error) {
+	hintfilename := backend.HintFilename(path)
+	if _, err = os.Stat(hintfilename); err != nil {
+		// hints are optional
+		return nil
+	}
+	buf, err := tool.ReadFile(hintfilename)
+	if err != nil {
+		return
+	}
+	name := doc.Filename()
+	switch doc.(type) {
+	case bh.SignalGraphIf:
+		hint := backend.XmlGraphHintNew(name)
+		_, err = f.context.preserver.DocumentRead(hint, buf, hintfilename)
+		if err == nil {
+			err = behaviour.SignalGraphApplyHints(doc.(bh.SignalGraphIf), hint)
+		}
+	case pf.PlatformIf:
+		hint := backend.XmlPlatformHintNew(name)
+		_, err = f.context.preserver.DocumentRead(hint, buf, hintfilename)
+		if err == nil {
+			err = platform.PlatformApplyHints(doc.(pf.PlatformIf), hint)
+		}
+	case mp.MappingIf:
+		hint := backend.XmlMappingHintNew(name)
+		_, err = f.context.preserver.DocumentRead(hint, buf, hintfilename)
+		if err == nil {
+			err = mapping.MappingApplyHints(doc.(mp.MappingIf), hint)
+		}
+	}
+	return
+}
+
+func (f *fileManager) Remove(name string) {
+	if doc, ok := f.docs[name]; ok {
+		f.forgetPath(doc)
+	}
+	delete(f.docs, name)
+}
+
+func (f *fileManager) forgetPath(doc tr.ToplevelTreeElementIf) {
+	if path, ok := f.pathOf(doc); ok {
+		delete(f.paths, path)
+	}
+}
+
+func (f *fileManager) Rename(oldName, newName string) (err error) {
+	doc, ok := f.docs[oldName]
+	if !ok {
+		err = fmt.Errorf("headless.fileManager.Rename error: %s not found", oldName)
+		return
+	}
+	_, ok = f.docs[newName]
+	if ok {
+		err = fmt.Errorf("headless.fileManager.Rename error: %s already exists", newName)
+		return
+	}
+	f.context.preserver.DocumentRenamed(documentPath(doc, oldName), documentPath(doc, newName))
+	delete(f.docs, oldName)
+	// stored under another path from now on
+	f.forgetPath(doc)
+	doc.SetFilename(newName)
+	f.docs[newName] = doc
+	return
+}
+
+func documentPath(doc tr.ToplevelTreeElementIf, name string) string {
+	if len(doc.PathPrefix()) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s/%s", doc.PathPrefix(), name)
+}
+
+// Store writes the document and its hints.
+func (f *fileManager) Store(name string) (err error) {
+	doc, ok := f.docs[name]
+	if !ok {
+		err = fmt.Errorf("headless.fileManager.Store error: %s not found", name)
+		return
+	}
+	filename := documentPath(doc, name)
+	err = doc.WriteFile(filename)
+	if err != nil {
+		return
+	}
+	var hint backend.Document
+	switch doc.(type) {
+	case bh.SignalGraphIf:
+		hint = behaviour.CreateXmlGraphHint(doc.(bh.SignalGraphIf))
+	case pf.PlatformIf:
+		hint = platform.CreateXmlPlatformHint(doc.(pf.PlatformIf))
+	case mp.MappingIf:
+		hint = mapping.CreateXmlMappingHint(doc.(mp.MappingIf))
+	default:
+		return
+	}
+	hintfilename := backend.HintFilename(filename)
+	buf, err := f.context.preserver.DocumentWrite(hint, hintfilename)
+	if err != nil {
+		return
+	}
+	return tool.WriteFile(hintfilename, buf)
+}
+
+func (f *fileManager) SetGraphForNew(g interface{}) {
+	f.graphForNew = g.(bh.SignalGraphIf)
+}
+
+func (f *fileManager) SetPlatformForNew(p interface{}) {
+	f.platformForNew = p.(pf.PlatformIf)
+}
+
+// Documents returns all documents currently loaded by f.
+func (f *fileManager) Documents() (list []tr.ToplevelTreeElementIf) {
+	for _, d := range f.docs {
+		list = append(list, d)
+	}
+	return
+}
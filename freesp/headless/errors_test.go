@@ -0,0 +1,52 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"testing"
+)
+
+const errorsLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="a" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <signal-type name="b" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="a"></outtype>
+   </node-type>
+   <node-type name="Sink">
+      <intype port="i" type="b"></intype>
+   </node-type>
+</library>`
+
+func TestModelErrors(t *testing.T) {
+	freesp.Init()
+	c := ContextNew("")
+	lib := behaviour.LibraryNew("errors.alml", c)
+	_, err := lib.Read([]byte(errorsLibrary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := behaviour.SignalGraphTypeNew(c)
+	src, _ := behaviour.NodeNew("src", lib.NodeTypes()[0], g)
+	sink, _ := behaviour.NodeNew("sink", lib.NodeTypes()[1], g)
+	out, in := src.OutPorts()[0], sink.InPorts()[0]
+
+	check := func(what string, err error, kind freesp.ErrorKind) {
+		k, ok := freesp.ErrorKindOf(err)
+		if !ok || k != kind {
+			t.Errorf("%s: expected %s error, got %v\n", what, kind, err)
+		}
+	}
+	_, err = out.Connection(in)
+	check("Connection", err, freesp.ErrNotFound)
+	check("RemoveConnection", out.RemoveConnection(in), freesp.ErrNotFound)
+	check("SetName", out.(interface {
+		SetName(string) error
+	}).SetName("x"), freesp.ErrInvalidOperation)
+	check("SetDirection", out.SetDirection(gr.InPort), freesp.ErrInvalidOperation)
+	check("AddConnection", out.AddConnection(behaviour.ConnectionNew(out, in)), freesp.ErrTypeMismatch)
+	check("AddConnection", out.AddConnection(behaviour.ConnectionNew(out, out)), freesp.ErrInvalidOperation)
+	_, err = lib.SignalTypes()[0].AddNewObject(nil, tr.Cursor{}, lib.NodeTypes()[0])
+	check("AddNewObject", err, freesp.ErrInvalidOperation)
+}
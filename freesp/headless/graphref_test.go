@@ -0,0 +1,118 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var graphRefFiles = map[string]string{
+	"lib.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Sub">
+      <intype port="a" type="s"></intype>
+      <intype port="b" type="s"></intype>
+      <outtype port="y" type="s"></outtype>
+      <implementation name="g" graph="sub/sub.sml"></implementation>
+   </node-type>
+</library>`,
+	"sub/sub.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input name="in-a">
+         <outtype port="" type="s"></outtype>
+      </input>
+      <input name="x">
+         <outtype port="" type="s"></outtype>
+      </input>
+      <output name="y">
+         <intype port="" type="s"></intype>
+      </output>
+   </nodes>
+   <connections>
+      <connect from="in-a" to="y" from-port="" to-port=""></connect>
+   </connections>
+</signal-graph>`,
+}
+
+func portNames(list []bh.PortTypeIf) (names []string) {
+	for _, p := range list {
+		names = append(names, p.Name())
+	}
+	return
+}
+
+func checkPorts(t *testing.T, what string, nt bh.NodeTypeIf, in, out string) {
+	var inNames, outNames string
+	for _, n := range portNames(nt.InPorts()) {
+		inNames += n + " "
+	}
+	for _, n := range portNames(nt.OutPorts()) {
+		outNames += n + " "
+	}
+	if inNames != in || outNames != out {
+		t.Errorf("%s: got ports in (%s), out (%s), expected in (%s), out (%s)\n", what, inNames, outNames, in, out)
+	}
+}
+
+func TestGraphRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graphref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	for name, text := range graphRefFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	c := ContextNew(dir)
+	doc, err := c.LibraryMgr().Access("lib.alml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := doc.(bh.LibraryIf)
+	nt := lib.NodeTypes()[0]
+	impl := nt.Implementation()[0]
+	if impl.GraphRef() != "sub/sub.sml" || impl.ImplementationType() != bh.NodeTypeGraph {
+		t.Fatalf("wrong implementation %v\n", impl)
+	}
+	if len(c.signalGraphMgr.Documents()) != 0 {
+		t.Errorf("graph loaded with the library\n")
+	}
+
+	// node x gets a port, port b an io-node
+	g := impl.Graph()
+	checkPorts(t, "bind", nt, "a b x ", "y ")
+	if len(g.InputNodes()) != 3 || len(g.OutputNodes()) != 1 {
+		t.Errorf("bind: wrong boundary %d -> %d\n", len(g.InputNodes()), len(g.OutputNodes()))
+	}
+	sg, err := c.SignalGraphMgr().Access("sub/sub.sml")
+	if err != nil || sg.(bh.SignalGraphIf).ItsType() != g {
+		t.Errorf("graph not shared with its document: %v\n", err)
+	}
+
+	n, err := behaviour.InputNodeNew("z", "s", g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.AddNode(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPorts(t, "add node", nt, "a b x z ", "y ")
+	g.RemoveNode(n)
+	checkPorts(t, "remove node", nt, "a b x ", "y ")
+
+	xmllib := behaviour.CreateXmlLibrary(lib)
+	xmlimpl := xmllib.NodeTypes[0].Implementation[0]
+	if xmlimpl.GraphRef != "sub/sub.sml" || len(xmlimpl.SignalGraph) != 0 {
+		t.Errorf("wrong XML implementation %v\n", xmlimpl)
+	}
+}
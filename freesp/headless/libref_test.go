@@ -0,0 +1,119 @@
+package headless
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const librefGraph = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="%s"></library>
+   <nodes>
+      <processing-node name="src" type="Src"></processing-node>
+   </nodes>
+</signal-graph>`
+
+// Directories a and b hold libraries of the same name, c refers to the
+// one of a.
+func TestLibraryRefDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "libref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := map[string]string{
+		"a/lib.alml": fmt.Sprintf(workspaceLibrary, "int"),
+		"a/g.sml":    fmt.Sprintf(librefGraph, "lib.alml"),
+		"b/lib.alml": fmt.Sprintf(workspaceLibrary, "float"),
+		"b/g.sml":    fmt.Sprintf(librefGraph, "lib.alml"),
+		"c/g.sml":    fmt.Sprintf(librefGraph, "../a/lib.alml"),
+	}
+	for name, text := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		err = ioutil.WriteFile(path, []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	cType := func(g bh.SignalGraphIf) string {
+		n, _ := g.ItsType().NodeByName("src")
+		return n.OutPorts()[0].SignalType().CType()
+	}
+
+	// each graph gets the library next to it
+	for dir, expected := range map[string]string{"a": "int", "b": "float"} {
+		doc, err := Load(filepath.Join(root, dir, "g.sml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := cType(doc.(bh.SignalGraphIf)); ct != expected {
+			t.Errorf("graph of %s uses library with %s, expected %s\n", dir, ct, expected)
+		}
+	}
+
+	// in one context, the library of b cannot shadow the one of a
+	c := ContextWithRegistryNew(root, freesp.RegistryNew())
+	a, err := c.SignalGraphMgr().Access("a/g.sml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.SignalGraphMgr().Access("b/g.sml")
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(root, "b", "lib.alml")) ||
+		!strings.Contains(err.Error(), filepath.Join(root, "a", "lib.alml")) {
+		t.Errorf("conflicting library not reported: %v\n", err)
+	}
+
+	// another ref to the same file shares the library, and is kept
+	g, err := c.SignalGraphMgr().Access("c/g.sml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	la, lc := a.(bh.SignalGraphIf).ItsType().Libraries(), g.(bh.SignalGraphIf).ItsType().Libraries()
+	if len(lc) != 1 || lc[0] != la[0] {
+		t.Errorf("library of a not shared: %v, %v\n", la, lc)
+	}
+	xmlg := behaviour.CreateXmlSignalGraph(g.(bh.SignalGraphIf))
+	if xmlg.Libraries[0].Name != "../a/lib.alml" {
+		t.Errorf("wrong library ref written: %s\n", xmlg.Libraries[0].Name)
+	}
+	if _, ok := c.Registry().GetLibraryByPath(backend.AbsPath(filepath.Join(root, "a", "lib.alml"))); !ok {
+		t.Errorf("library not registered by path\n")
+	}
+}
+
+// Libraries referring to each other are not loaded.
+func TestLibraryRefCycle(t *testing.T) {
+	root, err := ioutil.TempDir("", "libref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	lib := `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="%s"></library>
+   <signal-type name="%s" scope="" mode="" c-type="int" message-id=""></signal-type>
+</library>`
+	files := map[string]string{
+		"a.alml": fmt.Sprintf(lib, "b.alml", "sa"),
+		"b.alml": fmt.Sprintf(lib, "a.alml", "sb"),
+	}
+	for name, text := range files {
+		err = ioutil.WriteFile(filepath.Join(root, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	_, err = Load(filepath.Join(root, "a.alml"))
+	if err == nil || !strings.Contains(err.Error(), "reference cycle: a.alml, b.alml") {
+		t.Errorf("wrong error %v\n", err)
+	}
+}
@@ -0,0 +1,143 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var namespaceFiles = map[string]string{
+	"vendora.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="sample" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="sample"></outtype>
+   </node-type>
+</library>`,
+	"vendorb.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="sample" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <node-type name="Sink">
+      <intype port="i" type="sample"></intype>
+   </node-type>
+</library>`,
+	// refers to vendorb only: the field is of type vendorb::sample
+	"vendorc.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="vendorb.alml"></library>
+   <signal-type name="pair" scope="" mode="" c-type="" message-id="">
+      <field name="b" type="sample"></field>
+   </signal-type>
+</library>`,
+	// refers to vendora only: sample is vendora::sample
+	"one.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="vendora.alml"></library>
+   <nodes>
+      <output name="out">
+         <intype port="" type="sample"></intype>
+      </output>
+      <processing-node name="src" type="Src"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="src" to="out" from-port="o" to-port=""></connect>
+   </connections>
+</signal-graph>`,
+	"both.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="vendora.alml"></library>
+   <library ref="vendorb.alml"></library>
+   <nodes>
+      <output name="out">
+         <intype port="" type="sample"></intype>
+      </output>
+   </nodes>
+</signal-graph>`,
+	"qualified.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="vendora.alml"></library>
+   <library ref="vendorb.alml"></library>
+   <nodes>
+      <input name="in">
+         <outtype port="" type="vendorb::sample"></outtype>
+      </input>
+      <processing-node name="sink" type="Sink"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="in" to="sink" from-port="" to-port="i"></connect>
+   </connections>
+</signal-graph>`,
+}
+
+func TestNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, text := range namespaceFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	c := ContextNew(dir)
+	for _, name := range []string{"vendora.alml", "vendorb.alml"} {
+		_, err = c.LibraryMgr().Access(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, ok := freesp.GetSignalTypeByName("vendora::sample")
+	if !ok || a.CType() != "int" {
+		t.Fatalf("vendora::sample not resolved\n")
+	}
+	b, ok := freesp.GetSignalTypeByName("vendorb::sample")
+	if !ok || b.CType() != "float" {
+		t.Fatalf("vendorb::sample not resolved\n")
+	}
+	_, err = freesp.ResolveSignalType("sample", nil)
+	if _, ok := err.(*freesp.AmbiguousNameError); !ok {
+		t.Errorf("unqualified name not ambiguous: %v\n", err)
+	}
+	if freesp.SignalTypeRefName(a) != "vendora::sample" {
+		t.Errorf("wrong reference name %s\n", freesp.SignalTypeRefName(a))
+	}
+
+	_, err = c.SignalGraphMgr().Access("both.sml")
+	if err == nil || !strings.Contains(err.Error(), "vendora::sample, vendorb::sample") {
+		t.Errorf("wrong error for ambiguous name: %v\n", err)
+	}
+
+	doc, err := c.SignalGraphMgr().Access("one.sml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := doc.(bh.SignalGraphIf).ItsType()
+	if g.OutputNodes()[0].InPorts()[0].SignalType() != a {
+		t.Errorf("sample not resolved in scope of vendora\n")
+	}
+	xmlg := behaviour.CreateXmlSignalGraphType(g)
+	if xmlg.OutputNodes[0].InPort[0].PType != "vendora::sample" {
+		t.Errorf("ambiguous name written unqualified: %s\n", xmlg.OutputNodes[0].InPort[0].PType)
+	}
+
+	doc, err = c.SignalGraphMgr().Access("qualified.sml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = doc.(bh.SignalGraphIf).ItsType()
+	if g.InputNodes()[0].OutPorts()[0].SignalType() != b {
+		t.Errorf("qualified name not resolved\n")
+	}
+
+	_, err = c.LibraryMgr().Access("vendorc.alml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, _ := freesp.GetSignalTypeByName("pair")
+	a.SetTypeName("smp")
+	if pair.Fields()[0].FieldType() != "sample" {
+		t.Errorf("field of vendorb::sample renamed to %s\n", pair.Fields()[0].FieldType())
+	}
+}
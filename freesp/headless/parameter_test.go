@@ -0,0 +1,149 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"strings"
+	"testing"
+)
+
+const paramLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Fir">
+      <intype port="i" type="s"></intype>
+      <outtype port="o" type="s"></outtype>
+      <parameter name="taps" type="int" min="1" max="48"></parameter>
+      <parameter name="win" type="enum" default="hann" values="hann,flat"></parameter>
+   </node-type>
+   <node-type name="Filter">
+      <intype port="i" type="s"></intype>
+      <outtype port="o" type="s"></outtype>
+      <parameter name="order" type="int" default="8" max="32"></parameter>
+      <implementation name="g">
+         <signal-graph version="1.0">
+            <nodes>
+               <processing-node name="fir" type="Fir">
+                  <param name="taps" value="$order*2"></param>
+               </processing-node>
+            </nodes>
+         </signal-graph>
+      </implementation>
+   </node-type>
+</library>`
+
+func TestParamValues(t *testing.T) {
+	freesp.Init()
+	c := ContextNew("")
+	lib := behaviour.LibraryNew("test.alml", c)
+	_, err := lib.Read([]byte(paramLibrary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := lib.NodeTypes()[1]
+	g := behaviour.SignalGraphTypeNew(c)
+	n, err := behaviour.NodeNew("f", filter, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.AddNode(n)
+	values, err := behaviour.PathParamValues(g, "f/fir")
+	if err != nil || values["taps"] != "16" || values["win"] != "hann" {
+		t.Errorf("default propagation failed: %v %v\n", values, err)
+	}
+	if n.SetParamValue("order", "33") == nil {
+		t.Errorf("value above maximum accepted\n")
+	}
+	if n.SetParamValue("length", "3") == nil {
+		t.Errorf("unknown parameter accepted\n")
+	}
+	err = n.SetParamValue("order", "20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err = behaviour.PathParamValues(g, "f/fir")
+	if err != nil || values["taps"] != "40" {
+		t.Errorf("value propagation failed: %v %v\n", values, err)
+	}
+	n.SetParamValue("order", "32")
+	_, err = behaviour.PathParamValues(g, "f/fir")
+	if err == nil || !strings.Contains(err.Error(), "above maximum") {
+		t.Errorf("range of propagated value not checked: %v\n", err)
+	}
+	xmln := behaviour.CreateXmlProcessingNode(n)
+	if len(xmln.Param) != 1 || xmln.Param[0].Value != "32" {
+		t.Errorf("wrong XML parameter values %v\n", xmln.Param)
+	}
+
+	freesp.Init()
+	bad := strings.Replace(paramLibrary, `value="$order*2"`, `value="$order*9"`, 1)
+	_, err = behaviour.LibraryNew("bad.alml", c).Read([]byte(bad))
+	if err == nil || !strings.Contains(err.Error(), "above maximum") {
+		t.Errorf("invalid implementation value accepted: %v\n", err)
+	}
+}
+
+func TestEvalParam(t *testing.T) {
+	env := map[string]string{"taps": "16", "gain": "0.5", "win": "hann"}
+	case1 := []struct {
+		decl, expr, value, err string
+	}{
+		{"n: int [1..64]", "32", "32", ""},
+		{"n: int [1..64]", "$taps*2 + 1", "33", ""},
+		{"n: int [1..64]", "${taps}/(4-2)", "8", ""},
+		{"n: int [1..64]", "$taps*4 + 1", "", "above maximum"},
+		{"n: int", "$taps/3", "", "not integral"},
+		{"n: int", "$taps/0", "", "division by zero"},
+		{"n: int", "$size", "", "unknown parameter $size"},
+		{"n: int", "-(3 % 2)", "-1", ""},
+		{"x: float", "$gain*3", "1.5", ""},
+		{"x: float [0..1]", "-$gain", "", "below minimum"},
+		{"w: enum(hann,flat)", "$win", "hann", ""},
+		{"w: enum(hann,flat)", "rect", "", "none of"},
+		{"s: string", "fir_$taps", "fir_16", ""},
+	}
+	for i, c := range case1 {
+		p, err := behaviour.ParseParameter(c.decl)
+		if err != nil {
+			t.Errorf("testcase %d: %s\n", i, err)
+			continue
+		}
+		value, err := behaviour.EvalParam(p, c.expr, env)
+		if len(c.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("testcase %d: got error '%v', expected '%s'\n", i, err, c.err)
+			}
+			continue
+		}
+		if err != nil || value != c.value {
+			t.Errorf("testcase %d: got '%s' (%v), expected '%s'\n", i, value, err, c.value)
+		}
+	}
+}
+
+func TestParseParameter(t *testing.T) {
+	case1 := []struct {
+		text, result string
+	}{
+		{"taps: int = 16 [1..64]", "taps: int = 16 [1..64]"},
+		{" win :enum( hann , flat )= flat", "win: enum(hann,flat) = flat"},
+		{"gain: float [..2.5]", "gain: float [..2.5]"},
+		{"taps: int = 100 [1..64]", "above maximum"},
+		{"taps: int [64..1]", "empty range"},
+		{"name: string [1..2]", "ranges apply"},
+		{"mode: enum", "has no values"},
+		{"2x: int", "invalid parameter name"},
+		{"x: complex", "unknown parameter type"},
+	}
+	for i, c := range case1 {
+		p, err := behaviour.ParseParameter(c.text)
+		var result string
+		if err != nil {
+			result = err.Error()
+		} else {
+			result = p.String()
+		}
+		if !strings.Contains(result, c.result) {
+			t.Errorf("testcase %d: got '%s', expected '%s'\n", i, result, c.result)
+		}
+	}
+}
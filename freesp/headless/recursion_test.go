@@ -0,0 +1,49 @@
+package headless
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"strings"
+	"testing"
+)
+
+func TestNodeTypeCycle(t *testing.T) {
+	nodeType := func(name string, instances ...string) string {
+		var nodes string
+		for i, n := range instances {
+			nodes += fmt.Sprintf(`<processing-node name="n%d" type="%s"></processing-node>`, i, n)
+		}
+		return `<node-type name="` + name + `"><intype port="i" type="s"></intype>` +
+			`<implementation name="g"><signal-graph version="1.0"><nodes>` +
+			nodes + `</nodes></signal-graph></implementation></node-type>`
+	}
+	// node types are defined before they are instantiated, cycles are
+	// found before any node type is created
+	case1 := []struct {
+		nodeTypes []string
+		cycle     string
+	}{
+		{[]string{nodeType("B"), nodeType("A", "B")}, ""},
+		{[]string{nodeType("A", "A")}, "A -> A"},
+		{[]string{nodeType("A", "B"), nodeType("B", "C"), nodeType("C", "A")}, "A -> B -> C -> A"},
+		{[]string{nodeType("C"), nodeType("B", "C"), nodeType("A", "B", "C")}, ""},
+		{[]string{nodeType("A", "B"), nodeType("B", "C", "B"), nodeType("C")}, "B -> B"},
+	}
+	for i, c := range case1 {
+		freesp.Init()
+		text := `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">` +
+			`<signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>` +
+			strings.Join(c.nodeTypes, "") + `</library>`
+		_, err := behaviour.LibraryNew("test.alml", ContextNew("")).Read([]byte(text))
+		if len(c.cycle) == 0 {
+			if err != nil {
+				t.Errorf("testcase %d failed: %s\n", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasSuffix(err.Error(), "recursive node type: "+c.cycle) {
+			t.Errorf("testcase %d failed: got error '%v', expected cycle '%s'\n", i, err, c.cycle)
+		}
+	}
+}
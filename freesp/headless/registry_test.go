@@ -0,0 +1,82 @@
+package headless
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"sync"
+	"testing"
+)
+
+const workspaceLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="sample" scope="" mode="" c-type="%s" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="sample"></outtype>
+   </node-type>
+</library>`
+
+func TestWorkspaceRegistries(t *testing.T) {
+	freesp.Init()
+	var contexts []*Context
+	for _, cType := range []string{"int", "float"} {
+		c := ContextWithRegistryNew("", freesp.RegistryNew())
+		_, err := behaviour.LibraryNew("lib.alml", c).Read([]byte(fmt.Sprintf(workspaceLibrary, cType)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contexts = append(contexts, c)
+	}
+	if len(freesp.GetRegisteredSignalTypes()) != 0 {
+		t.Errorf("types registered in the default registry: %v\n", freesp.GetRegisteredSignalTypes())
+	}
+
+	var wg sync.WaitGroup
+	errors := make(chan string, 100)
+	for i, c := range contexts {
+		for k := 0; k < 10; k++ {
+			wg.Add(1)
+			go func(c *Context, cType string) {
+				defer wg.Done()
+				reg := c.Registry()
+				st, ok := reg.GetSignalTypeByName("sample")
+				if !ok || st.CType() != cType {
+					errors <- fmt.Sprintf("wrong signal type in workspace of %s", cType)
+					return
+				}
+				nt, ok := reg.GetNodeTypeByName("Src")
+				if !ok || nt.OutPorts()[0].SignalType() != st {
+					errors <- fmt.Sprintf("wrong node type in workspace of %s", cType)
+				}
+				if len(reg.GetRegisteredLibraries()) != 1 {
+					errors <- fmt.Sprintf("wrong libraries in workspace of %s", cType)
+				}
+			}(c, []string{"int", "float"}[i])
+		}
+	}
+	wg.Wait()
+	close(errors)
+	for e := range errors {
+		t.Error(e)
+	}
+
+	// renaming in one workspace leaves the other one alone
+	st, _ := contexts[0].Registry().GetSignalTypeByName("sample")
+	st.SetTypeName("value")
+	if _, ok := contexts[0].Registry().GetSignalTypeByName("value"); !ok {
+		t.Errorf("renamed type not registered\n")
+	}
+	if _, ok := contexts[1].Registry().GetSignalTypeByName("value"); ok {
+		t.Errorf("rename visible in other workspace\n")
+	}
+	doc, err := contexts[1].LibraryMgr().New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freesp.RegistryOf(contexts[1]) != contexts[1].Registry() {
+		t.Errorf("context registry not used\n")
+	}
+	if _, ok := contexts[1].Registry().GetLibraryByName(doc.(bh.LibraryIf).Filename()); !ok {
+		t.Errorf("new library not registered in its workspace\n")
+	}
+}
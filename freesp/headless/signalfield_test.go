@@ -0,0 +1,136 @@
+package headless
+
+import (
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var signalFieldFiles = map[string]string{
+	"base.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="vec3" scope="" mode="" c-type="" message-id="">
+      <field name="xyz" type="float32" array="3" unit="m" min="-10" max="10"></field>
+   </signal-type>
+   <signal-type name="opaque" scope="" mode="" c-type="struct foo" message-id=""></signal-type>
+</library>`,
+	"other.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="temp" scope="" mode="" c-type="float" message-id=""></signal-type>
+</library>`,
+	"pos.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="base.alml"></library>
+   <signal-type name="pos" scope="" mode="" c-type="" message-id="">
+      <field name="p" type="vec3"></field>
+      <field name="stamp" type="uint64"></field>
+      <field name="valid" type="bool"></field>
+      <field name="id" type="ident" array="2"></field>
+   </signal-type>
+   <signal-type name="ident" scope="" mode="" c-type="int" message-id=""></signal-type>
+</library>`,
+	"unref.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="t" scope="" mode="" c-type="" message-id="">
+      <field name="x" type="temp"></field>
+   </signal-type>
+</library>`,
+	"unknown.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="u" scope="" mode="" c-type="" message-id="">
+      <field name="x" type="nosuchtype"></field>
+   </signal-type>
+</library>`,
+	"loop.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="a" scope="" mode="" c-type="" message-id="">
+      <field name="b" type="b"></field>
+   </signal-type>
+   <signal-type name="b" scope="" mode="" c-type="" message-id="">
+      <field name="a" type="a" array="2"></field>
+   </signal-type>
+</library>`,
+	"range.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="r" scope="" mode="" c-type="" message-id="">
+      <field name="on" type="bool" min="0" max="1"></field>
+   </signal-type>
+</library>`,
+}
+
+func TestSignalFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signalfield")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, text := range signalFieldFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	c := ContextNew(dir)
+	doc, err := c.LibraryMgr().Access("pos.alml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := doc.(bh.LibraryIf)
+	pos := lib.SignalTypes()[0]
+	if len(pos.Fields()) != 4 || pos.Fields()[0].SignalType() != pos {
+		t.Fatalf("wrong fields %v\n", pos.Fields())
+	}
+	size, err := behaviour.SignalTypeSize(pos)
+	if err != nil || size != 12+8+1+2*4 {
+		t.Errorf("wrong size %d (%v)\n", size, err)
+	}
+	opaque, _ := freesp.GetSignalTypeByName("opaque")
+	_, err = behaviour.SignalTypeSize(opaque)
+	if err == nil {
+		t.Errorf("size of opaque type without primitive c-type\n")
+	}
+
+	f := pos.Fields()[1]
+	if f.SetRange("10", "1") == nil {
+		t.Errorf("empty range accepted\n")
+	}
+	if f.SetFieldType("pos") == nil {
+		t.Errorf("recursive field type accepted\n")
+	}
+	if f.SetName("valid") == nil {
+		t.Errorf("duplicate field name accepted\n")
+	}
+
+	xmllib := behaviour.CreateXmlLibrary(lib)
+	if len(xmllib.Libraries) != 1 || xmllib.Libraries[0].Name != "base.alml" {
+		t.Errorf("missing reference to field type library: %v\n", xmllib.Libraries)
+	}
+	xmlf := xmllib.SignalTypes[0].Field
+	if len(xmlf) != 4 || xmlf[3].Array != "2" || xmlf[0].FType != "vec3" {
+		t.Errorf("wrong XML fields %v\n", xmlf)
+	}
+
+	ident, _ := freesp.GetSignalTypeByName("ident")
+	ident.SetTypeName("key")
+	if pos.Fields()[3].FieldType() != "key" {
+		t.Errorf("field type not renamed\n")
+	}
+
+	_, err = c.LibraryMgr().Access("other.alml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	case1 := []struct {
+		filename, err string
+	}{
+		{"unref.alml", "defined in other.alml, which is not referenced"},
+		{"unknown.alml", "unknown field type nosuchtype"},
+		{"loop.alml", "field type a contains b"},
+		{"range.alml", "ranges apply to numeric fields only"},
+	}
+	for i, c1 := range case1 {
+		_, err = c.LibraryMgr().Access(c1.filename)
+		if err == nil || !strings.Contains(err.Error(), c1.err) {
+			t.Errorf("testcase %d: got error '%v', expected '%s'\n", i, err, c1.err)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/freesp/validate"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"regexp"
+	"strings"
+)
+
+// Lint rules check signal graphs for style and hygiene: the graphs are
+// valid, but likely not what was meant. Each rule can be switched off
+// or given another severity per project (see Config). Problems are
+// reported like those of package validate, with Rule set.
+
+type Rule struct {
+	Name        string
+	Description string
+	Severity    validate.Severity // unless configured otherwise
+	check       func(c *checker, g bh.SignalGraphTypeIf)
+}
+
+var rules = []*Rule{
+	{"unconnected-port", "ports without connection", validate.Warning, checkUnconnectedPorts},
+	{"multiple-drivers", "input ports connected to more than one output port", validate.Error, checkMultipleDrivers},
+	{"unreachable-node", "nodes not reachable from any input or source node", validate.Warning, checkUnreachableNodes},
+	{"dead-node", "nodes feeding no output or sink node", validate.Warning, checkDeadNodes},
+	{"unused-library", "referenced libraries which, with the libraries they reference, define no used type", validate.Warning, checkUnusedLibraries},
+	{"c-identifier", "node and port names which are no valid C identifiers", validate.Warning, checkIdentifiers},
+}
+
+// Rules returns all rules, in the order they are checked.
+func Rules() []*Rule {
+	return rules
+}
+
+func RuleByName(name string) (rule *Rule, ok bool) {
+	for _, rule = range rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+// Setting of a rule: disabled, or enabled reporting problems of
+// Severity.
+type Setting struct {
+	Enabled  bool
+	Severity validate.Severity
+}
+
+// Config holds the setting of each rule, by name.
+type Config map[string]Setting
+
+// DefaultConfig enables all rules with their default severity.
+func DefaultConfig() Config {
+	cfg := make(Config)
+	for _, r := range rules {
+		cfg[r.Name] = Setting{true, r.Severity}
+	}
+	return cfg
+}
+
+// Set configures rule by level "off", "warning" or "error".
+func (cfg Config) Set(rule, level string) error {
+	if _, ok := RuleByName(rule); !ok {
+		return fmt.Errorf("Config.Set error: unknown lint rule %s", rule)
+	}
+	if level == "off" {
+		cfg[rule] = Setting{false, cfg[rule].Severity}
+		return nil
+	}
+	severity, ok := validate.SeverityFromString(level)
+	if !ok {
+		return fmt.Errorf("Config.Set error: invalid level %s of lint rule %s", level, rule)
+	}
+	cfg[rule] = Setting{true, severity}
+	return nil
+}
+
+// ProjectConfig returns the default configuration, changed by the
+// lint settings of project p (nil for none).
+func ProjectConfig(p *backend.XmlProject) (cfg Config, err error) {
+	cfg = DefaultConfig()
+	if p == nil {
+		return
+	}
+	for _, l := range p.Lint {
+		err = cfg.Set(l.Rule, l.Level)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Documents returns the lint problems of docs, in the order of docs.
+func Documents(docs []tr.ToplevelTreeElementIf, cfg Config) (problems []validate.Problem) {
+	for _, doc := range docs {
+		problems = append(problems, Document(doc, cfg)...)
+	}
+	return
+}
+
+// Document checks the graph of a signal graph document, or the
+// implementation graphs of a library; other documents have no lint
+// problems.
+func Document(doc tr.ToplevelTreeElementIf, cfg Config) []validate.Problem {
+	c := &checker{filename: doc.Filename()}
+	switch d := doc.(type) {
+	case bh.SignalGraphIf:
+		c.graph(d.ItsType(), "", cfg)
+	case bh.LibraryIf:
+		for _, nt := range d.NodeTypes() {
+			for _, impl := range nt.Implementation() {
+				// referenced graphs are checked as documents of their own
+				if impl.ImplementationType() != bh.NodeTypeGraph || len(impl.GraphRef()) > 0 {
+					continue
+				}
+				loc := validate.At(validate.At("", "node type", nt.TypeName()), "implementation", impl.ElementName())
+				c.graph(impl.Graph(), loc, cfg)
+			}
+		}
+	}
+	return c.problems
+}
+
+// Graph returns the lint problems of g, a graph of document filename.
+func Graph(g bh.SignalGraphTypeIf, filename string, cfg Config) []validate.Problem {
+	c := &checker{filename: filename}
+	c.graph(g, "", cfg)
+	return c.problems
+}
+
+type checker struct {
+	filename string
+	location string // of the graph checked
+	rule     *Rule
+	severity validate.Severity
+	problems []validate.Problem
+}
+
+func (c *checker) graph(g bh.SignalGraphTypeIf, location string, cfg Config) {
+	if g == nil {
+		return
+	}
+	c.location = location
+	for _, r := range rules {
+		s, ok := cfg[r.Name]
+		if !ok {
+			s = Setting{true, r.Severity}
+		}
+		if !s.Enabled {
+			continue
+		}
+		c.rule, c.severity = r, s.Severity
+		r.check(c, g)
+	}
+}
+
+func (c *checker) report(location string, obj tr.TreeElementIf, format string, args ...interface{}) {
+	c.problems = append(c.problems, validate.Problem{c.severity, c.filename, location, obj,
+		fmt.Sprintf(format, args...), c.rule.Name})
+}
+
+func (c *checker) nodeLocation(n bh.NodeIf) string {
+	return validate.At(c.location, "node", n.Name())
+}
+
+func (c *checker) portLocation(p bh.PortIf) string {
+	return validate.At(c.nodeLocation(p.Node()), "port", p.Name())
+}
+
+func portName(p bh.PortIf) string {
+	return fmt.Sprintf("%s/%s", p.Node().Name(), p.Name())
+}
+
+func checkUnconnectedPorts(c *checker, g bh.SignalGraphTypeIf) {
+	for _, n := range g.Nodes() {
+		for _, p := range append(n.InPorts(), n.OutPorts()...) {
+			if len(p.Connections()) == 0 {
+				c.report(c.portLocation(p), p, "port %s not connected", portName(p))
+			}
+		}
+	}
+}
+
+func checkMultipleDrivers(c *checker, g bh.SignalGraphTypeIf) {
+	for _, n := range g.Nodes() {
+		for _, p := range n.InPorts() {
+			if len(p.Connections()) < 2 {
+				continue
+			}
+			var drivers []string
+			for _, q := range p.Connections() {
+				drivers = append(drivers, portName(q))
+			}
+			c.report(c.portLocation(p), p, "port %s driven by %s", portName(p), strings.Join(drivers, ", "))
+		}
+	}
+}
+
+// Nodes reached from roots, following connections from output to input
+// ports (forward) or back.
+func reached(roots []bh.NodeIf, forward bool) map[bh.NodeIf]bool {
+	visited := make(map[bh.NodeIf]bool)
+	todo := append([]bh.NodeIf(nil), roots...)
+	for len(todo) > 0 {
+		n := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if visited[n] {
+			continue
+		}
+		visited[n] = true
+		ports := n.OutPorts()
+		if !forward {
+			ports = n.InPorts()
+		}
+		for _, p := range ports {
+			for _, q := range p.Connections() {
+				todo = append(todo, q.Node())
+			}
+		}
+	}
+	return visited
+}
+
+// Processing nodes without input ports are sources like input nodes,
+// those without output ports sinks like output nodes.
+func roots(g bh.SignalGraphTypeIf, forward bool) []bh.NodeIf {
+	nodes := g.OutputNodes()
+	if forward {
+		nodes = g.InputNodes()
+	}
+	for _, n := range g.ProcessingNodes() {
+		if (forward && len(n.InPorts()) == 0) || (!forward && len(n.OutPorts()) == 0) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func checkUnreachableNodes(c *checker, g bh.SignalGraphTypeIf) {
+	visited := reached(roots(g, true), true)
+	for _, n := range g.Nodes() {
+		if !visited[n] {
+			c.report(c.nodeLocation(n), n, "node %s not reachable from any input or source node", n.Name())
+		}
+	}
+}
+
+func checkDeadNodes(c *checker, g bh.SignalGraphTypeIf) {
+	visited := reached(roots(g, false), false)
+	for _, n := range g.Nodes() {
+		if !visited[n] {
+			c.report(c.nodeLocation(n), n, "node %s feeds no output or sink node", n.Name())
+		}
+	}
+}
+
+// Libraries are unused if neither they nor the libraries they depend
+// on (see deps.Graph.Reaches) define a type used by g. Types are told
+// apart by their defining library, not by name. Open libraries are
+// used instead of their files.
+func checkUnusedLibraries(c *checker, g bh.SignalGraphTypeIf) {
+	if len(g.Libraries()) == 0 {
+		return
+	}
+	reg := freesp.RegistryOf(g.Context())
+	pathOf := func(l bh.LibraryIf) string {
+		path, ok := reg.LibraryPath(l)
+		if !ok {
+			path = l.Filename()
+		}
+		return path
+	}
+	// paths of the libraries defining the types used
+	var defining []string
+	seen := make(map[string]bool)
+	use := func(definedAt string) {
+		l, ok := reg.GetLibraryByName(definedAt)
+		if ok && !seen[definedAt] {
+			seen[definedAt] = true
+			defining = append(defining, pathOf(l))
+		}
+	}
+	for _, n := range g.Nodes() {
+		if n.ItsType() != nil {
+			use(n.ItsType().DefinedAt())
+		}
+		for _, p := range append(n.InPorts(), n.OutPorts()...) {
+			if p.SignalType() != nil {
+				use(p.SignalType().DefinedAt())
+			}
+		}
+	}
+	dg := deps.GraphNew()
+	for _, l := range g.Libraries() {
+		dg.Add(behaviour.CreateXmlLibrary(l), pathOf(l))
+	}
+	for _, l := range g.Libraries() {
+		used := false
+		for _, path := range defining {
+			if dg.Reaches(pathOf(l), path) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			c.report(validate.At(c.location, "library", l.Filename()), l, "none of the types of library %s used", l.Filename())
+		}
+	}
+}
+
+var identifier = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+var keywords = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true,
+	"long": true, "register": true, "restrict": true, "return": true, "short": true,
+	"signed": true, "sizeof": true, "static": true, "struct": true, "switch": true,
+	"typedef": true, "union": true, "unsigned": true, "void": true, "volatile": true,
+	"while": true, "_Bool": true, "_Complex": true, "_Imaginary": true,
+}
+
+func isIdentifier(name string) bool {
+	return identifier.MatchString(name) && !keywords[name]
+}
+
+// Port names are those of the node types, each node type is reported
+// once (at its first node).
+func checkIdentifiers(c *checker, g bh.SignalGraphTypeIf) {
+	seen := make(map[bh.NodeTypeIf]bool)
+	for _, n := range g.Nodes() {
+		if !isIdentifier(n.Name()) {
+			c.report(c.nodeLocation(n), n, "node name %q is no valid C identifier", n.Name())
+		}
+		if seen[n.ItsType()] {
+			continue
+		}
+		seen[n.ItsType()] = true
+		for _, p := range append(n.InPorts(), n.OutPorts()...) {
+			// ports of input and output nodes are unnamed
+			if len(p.Name()) > 0 && !isIdentifier(p.Name()) {
+				c.report(c.portLocation(p), p, "port name %q is no valid C identifier", p.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package lint
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/headless"
+	"github.com/axel-freesp/sge/freesp/validate"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var lintFiles = map[string]string{
+	"nodes.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="a" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="a"></outtype>
+   </node-type>
+   <node-type name="Mid">
+      <intype port="i" type="a"></intype>
+      <outtype port="o" type="a"></outtype>
+   </node-type>
+</library>`,
+	"other.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="b" scope="" mode="" c-type="int" message-id=""></signal-type>
+</library>`,
+	// defines a, but not the a used
+	"dup.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="a" scope="" mode="" c-type="float" message-id=""></signal-type>
+</library>`,
+	// used through nodes.alml, referenced by the implementation of Wrap
+	"all.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="nodes.alml"></library>
+   <node-type name="Wrap">
+      <intype port="i" type="a"></intype>
+      <implementation name="g">
+         <signal-graph version="1.0">
+            <library ref="nodes.alml"></library>
+            <nodes>
+               <input name="i">
+                  <outtype port="" type="a"></outtype>
+               </input>
+               <processing-node name="m" type="Mid"></processing-node>
+            </nodes>
+            <connections>
+               <connect from="i" to="m" from-port="" to-port="i"></connect>
+            </connections>
+         </signal-graph>
+      </implementation>
+   </node-type>
+</library>`,
+	"lint.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="nodes.alml"></library>
+   <library ref="other.alml"></library>
+   <library ref="all.alml"></library>
+   <library ref="dup.alml"></library>
+   <nodes>
+      <input name="in">
+         <outtype port="" type="nodes::a"></outtype>
+      </input>
+      <output name="out">
+         <intype port="" type="nodes::a"></intype>
+      </output>
+      <processing-node name="src" type="Src"></processing-node>
+      <processing-node name="mid" type="Mid"></processing-node>
+      <processing-node name="int" type="Mid"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="in" to="mid" from-port="" to-port="i"></connect>
+      <connect from="src" to="mid" from-port="o" to-port="i"></connect>
+      <connect from="mid" to="out" from-port="o" to-port=""></connect>
+   </connections>
+</signal-graph>`,
+}
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, text := range lintFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	freesp.Init()
+	doc, err := headless.ContextNew(dir).SignalGraphMgr().Access("lint.sml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"lint.sml: node int / port i: warning: port int/i not connected [unconnected-port]",
+		"lint.sml: node int / port o: warning: port int/o not connected [unconnected-port]",
+		"lint.sml: node mid / port i: error: port mid/i driven by in/, src/o [multiple-drivers]",
+		"lint.sml: node int: warning: node int not reachable from any input or source node [unreachable-node]",
+		"lint.sml: node int: warning: node int feeds no output or sink node [dead-node]",
+		"lint.sml: library other.alml: warning: none of the types of library other.alml used [unused-library]",
+		"lint.sml: library dup.alml: warning: none of the types of library dup.alml used [unused-library]",
+		"lint.sml: node int: warning: node name \"int\" is no valid C identifier [c-identifier]",
+	}
+	problems := Document(doc, DefaultConfig())
+	if len(problems) != len(expected) {
+		t.Fatalf("wrong problems %v\n", problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Errorf("problem %d is\n%s, expected\n%s\n", i, p, expected[i])
+		}
+	}
+	g := doc.(bh.SignalGraphIf).ItsType()
+	if n, _ := g.NodeByName("int"); problems[3].Object != n {
+		t.Errorf("wrong object of %s\n", problems[3])
+	}
+
+	p := backend.XmlProjectNew("")
+	p.Lint = append(p.Lint, *backend.XmlLintRuleNew("unconnected-port", "off"),
+		*backend.XmlLintRuleNew("unused-library", "error"))
+	cfg, err := ProjectConfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems = Graph(g, "lint.sml", cfg)
+	if len(problems) != len(expected)-2 || problems[3].Rule != "unused-library" || problems[3].Severity != validate.Error {
+		t.Errorf("wrong problems with project settings %v\n", problems)
+	}
+	if cfg.Set("unknown", "off") == nil || cfg.Set("dead-node", "fatal") == nil {
+		t.Errorf("invalid settings accepted\n")
+	}
+}
@@ -68,8 +68,8 @@ func (m mapelem) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEleme
 	return
 }
 
-func (m mapelem) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatalf("mapping.RemoveObject error: Nothing to remove\n")
+func (m mapelem) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("mapelem.RemoveObject", "nothing to remove")
 	return
 }
 
@@ -24,12 +24,13 @@ type mapping struct {
 	filename   string
 	pathPrefix string
 	maplist    behaviour.NodeIdList
+	freesp.Diagnostics
 }
 
 var _ mp.MappingIf = (*mapping)(nil)
 
 func MappingNew(filename string, context mod.ModelContextIf) *mapping {
-	return &mapping{nil, nil, context, make(map[string]*mapelem), filename, "", behaviour.NodeIdListInit()}
+	return &mapping{nil, nil, context, make(map[string]*mapelem), filename, "", behaviour.NodeIdListInit(), freesp.Diagnostics{}}
 }
 
 func findMapHint(xmlhints *backend.XmlMappingHint, nId string) (xmlh backend.XmlNodePosHint, ok bool) {
@@ -48,22 +49,27 @@ func MappingApplyHints(m mp.MappingIf, xmlhints *backend.XmlMappingHint) (err er
 		return
 	}
 	//log.Printf("MappingApplyHints: xmlhints = %v\n", xmlhints)
+	var d *freesp.Diagnostics
+	if mm, ok := m.(*mapping); ok {
+		d = &mm.Diagnostics
+	}
 	for _, nId := range m.MappedIds() {
 		melem, ok := m.MappedElement(nId)
 		if !ok {
-			log.Fatalf("MappingApplyHints internal error: %v has no mapping\n", nId)
+			d.Warnf("hints for node %v: node has no mapping", nId)
+			continue
 		}
 		log.Printf("MappingApplyHints: %v\n", nId)
 		xmlh, ok := findMapHint(xmlhints, nId.String())
 		if ok {
 			melem.SetExpanded(xmlh.Expanded)
 			log.Printf("MappingApplyHints: %v expanded: %v\n", nId, melem.Expanded())
-			freesp.ModePositionerApplyHints(melem, xmlh.XmlModeHint)
+			freesp.ModePositionerApplyHints(melem, xmlh.XmlModeHint, d)
 			for i, xmlp := range xmlh.InPorts {
-				freesp.ModePositionerApplyHints(&melem.(*mapelem).inports[i], xmlp.XmlModeHint)
+				freesp.ModePositionerApplyHints(&melem.(*mapelem).inports[i], xmlp.XmlModeHint, d)
 			}
 			for i, xmlp := range xmlh.OutPorts {
-				freesp.ModePositionerApplyHints(&melem.(*mapelem).outports[i], xmlp.XmlModeHint)
+				freesp.ModePositionerApplyHints(&melem.(*mapelem).outports[i], xmlp.XmlModeHint, d)
 			}
 		}
 	}
@@ -106,8 +112,8 @@ func (m mapping) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEleme
 	return
 }
 
-func (m mapping) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatalf("mapping.RemoveObject error: Nothing to remove\n")
+func (m mapping) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("mapping.RemoveObject", "nothing to remove")
 	return
 }
 
@@ -199,7 +205,7 @@ func (m *mapping) Read(data []byte) (cnt int, err error) {
 
 func (m *mapping) ReadFile(filepath string) error {
 	xmlm := backend.XmlMappingNew("", "")
-	err := xmlm.ReadFile(filepath)
+	err := freesp.PreserverOf(m.context).DocumentReadFile(xmlm, filepath)
 	if err != nil {
 		return fmt.Errorf("mapping.ReadFile: %v", err)
 	}
@@ -214,7 +220,7 @@ func (m mapping) Write() (data []byte, err error) {
 
 func (m mapping) WriteFile(filepath string) error {
 	xmlm := CreateXmlMapping(&m)
-	return xmlm.WriteFile(filepath)
+	return freesp.PreserverOf(m.context).DocumentWriteFile(xmlm, filepath)
 }
 
 func (m *mapping) RemoveFromTree(tree tr.TreeIf) {
@@ -251,7 +257,7 @@ func (m *mapping) createMappingFromXml(xmlm *backend.XmlMapping) (err error) {
 		if len(x.Process) > 0 {
 			p, ok = m.platform.ProcessByName(x.Process)
 			if !ok {
-				fmt.Printf("mapping.CreateMappingFromXml warning: process %s not in platform %s, assume unmapped\n", x.Process, m.platform.Filename())
+				m.Warnf("node %s: process %s not in platform %s, assume unmapped", x.Name, x.Process, m.platform.Filename())
 				//continue
 			}
 		}
@@ -268,7 +274,7 @@ func (m *mapping) createMappingFromXml(xmlm *backend.XmlMapping) (err error) {
 		if len(x.Process) > 0 {
 			p, ok = m.platform.ProcessByName(x.Process)
 			if !ok {
-				fmt.Printf("mapping.CreateMappingFromXml warning: process %s not in platform %s\n", x.Process, m.platform.Filename())
+				m.Warnf("node %s: process %s not in platform %s, assume unmapped", x.Name, x.Process, m.platform.Filename())
 				//continue
 			}
 		}
@@ -0,0 +1,658 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/tool"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// Three-way merge of freeSP documents. base, ours and theirs are read
+// into the Xml* structs of the backend, and the lists of a document
+// (nodes, connections, types, mappings, ...) are merged element by
+// element, identified by name: an element changed on one side takes
+// that change, an element changed differently on both sides is a
+// conflict and is written between conflict markers. Order is that of
+// ours, followed by the elements added by theirs. Archs and node types
+// changed on both sides are merged the same way, down to their
+// processes, channels, ports and implementations.
+//
+// Hint files are merged position-wise and never conflict: a position
+// changed differently on both sides is taken from ours.
+
+type Conflict struct {
+	What   string      // kind of element, e.g. "processing node"
+	Key    string      // name of the element, empty for attributes
+	Ours   interface{} // element (or attribute value) of ours, nil if removed
+	Theirs interface{} // element (or attribute value) of theirs, nil if removed
+}
+
+func (c Conflict) String() string {
+	var how string
+	switch {
+	case c.Ours == nil:
+		how = "only in theirs"
+	case c.Theirs == nil:
+		how = "only in ours"
+	default:
+		how = "changed in both"
+	}
+	if len(c.Key) == 0 {
+		return fmt.Sprintf("%s: %s", c.What, how)
+	}
+	return fmt.Sprintf("%s %s: %s", c.What, c.Key, how)
+}
+
+const hintSuffix = ".hints.xml"
+
+var documentMergers = map[string]func(m *merger, base, ours, theirs backend.Document){
+	"sml":               mergeSignalGraph,
+	"alml":              mergeLibrary,
+	"spml":              mergePlatform,
+	"mml":               mergeMapping,
+	"sml" + hintSuffix:  mergeGraphHint,
+	"spml" + hintSuffix: mergePlatformHint,
+	"mml" + hintSuffix:  mergeMappingHint,
+}
+
+// The document kind is the suffix, hint files are named after their
+// document (see backend.HintFilename).
+func documentKind(path string) string {
+	if strings.HasSuffix(path, hintSuffix) {
+		p := strings.TrimSuffix(path, hintSuffix)
+		return p[strings.LastIndex(p, "-")+1:] + hintSuffix
+	}
+	return tool.DocSuffix(path)
+}
+
+// Supported tells if the document stored as path can be merged.
+func Supported(path string) bool {
+	if backend.IsJsonFile(path) {
+		return false
+	}
+	_, ok := documentMergers[documentKind(path)]
+	return ok
+}
+
+// Merge merges ours and theirs, both derived from base. path is the
+// name of the document, its suffix selects the document kind. The
+// result contains conflict markers for all conflicts returned.
+// Unknown XML content is kept from ours. The result is formatted like
+// DocumentWriteFile writes documents.
+func Merge(base, ours, theirs []byte, path string) (data []byte, conflicts []Conflict, err error) {
+	if !Supported(path) {
+		err = fmt.Errorf("Merge error: %s: unsupported document", path)
+		return
+	}
+	b, _ := backend.DocumentNew(path)
+	o, _ := backend.DocumentNew(path)
+	t, _ := backend.DocumentNew(path)
+	pr := backend.PreserverNew()
+	// base is empty if the document was added on both sides
+	if len(bytes.TrimSpace(base)) > 0 {
+		err = read(pr, b, base, path, "base")
+		if err != nil {
+			return
+		}
+	}
+	err = read(pr, t, theirs, path, "theirs")
+	if err != nil {
+		return
+	}
+	err = read(pr, o, ours, path, "")
+	if err != nil {
+		return
+	}
+	m := mergerNew()
+	documentMergers[documentKind(path)](m, b, o, t)
+	if m.err != nil {
+		err = fmt.Errorf("Merge error: %s: %s", path, m.err)
+		return
+	}
+	data, err = pr.DocumentFormat(o, path)
+	if err != nil {
+		err = fmt.Errorf("Merge error: %s", err)
+		return
+	}
+	data, err = m.markConflicts(data)
+	conflicts = m.conflicts
+	return
+}
+
+// Only the unknown content of ours is kept (in pr), the result takes
+// it from there.
+func read(pr *backend.Preserver, doc backend.Document, data []byte, path, version string) (err error) {
+	if len(version) > 0 {
+		path = fmt.Sprintf("%s (%s)", path, version)
+		pr = nil
+	}
+	_, err = pr.DocumentRead(doc, data, path)
+	if err != nil {
+		err = fmt.Errorf("Merge error: %s: %s", path, err)
+	}
+	return
+}
+
+/*
+ *		Documents
+ */
+
+func mergeSignalGraph(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlSignalGraph), ours.(*backend.XmlSignalGraph), theirs.(*backend.XmlSignalGraph)
+	o.Libraries = m.list("library", b.Libraries, o.Libraries, t.Libraries, m.conflict).([]backend.XmlLibraryRef)
+	o.InputNodes = m.list("input node", b.InputNodes, o.InputNodes, t.InputNodes, m.conflict).([]backend.XmlInputNode)
+	o.OutputNodes = m.list("output node", b.OutputNodes, o.OutputNodes, t.OutputNodes, m.conflict).([]backend.XmlOutputNode)
+	o.ProcessingNodes = m.list("processing node", b.ProcessingNodes, o.ProcessingNodes, t.ProcessingNodes, m.conflict).([]backend.XmlProcessingNode)
+	ourConnections := o.Connections
+	o.Connections = m.list("connection", b.Connections, o.Connections, t.Connections, m.conflict).([]backend.XmlConnect)
+	// one side removed a node, the other one connected it
+	nodes := make(map[string]bool)
+	for _, l := range []interface{}{o.InputNodes, o.OutputNodes, o.ProcessingNodes} {
+		for _, k := range m.keys(l) {
+			nodes[k] = true
+		}
+	}
+	for i, c := range o.Connections {
+		if m.isPlaceholder(c.From) || (nodes[c.From] && nodes[c.To]) {
+			continue
+		}
+		var oc, tc interface{}
+		if m.contains(ourConnections, m.key(c)) {
+			oc = c
+		}
+		if m.contains(t.Connections, m.key(c)) {
+			tc = c
+		}
+		o.Connections[i] = m.conflict("connection", m.key(c), nil, oc, tc).(backend.XmlConnect)
+	}
+}
+
+func mergeLibrary(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlLibrary), ours.(*backend.XmlLibrary), theirs.(*backend.XmlLibrary)
+	o.Libraries = m.list("library", b.Libraries, o.Libraries, t.Libraries, m.conflict).([]backend.XmlLibraryRef)
+	o.SignalTypes = m.list("signal type", b.SignalTypes, o.SignalTypes, t.SignalTypes, m.conflict).([]backend.XmlSignalType)
+	o.NodeTypes = m.list("node type", b.NodeTypes, o.NodeTypes, t.NodeTypes, m.element).([]backend.XmlNodeType)
+	o.Compatible = m.list("compatibility", b.Compatible, o.Compatible, t.Compatible, m.conflict).([]backend.XmlCompatible)
+}
+
+func mergePlatform(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlPlatform), ours.(*backend.XmlPlatform), theirs.(*backend.XmlPlatform)
+	o.PlatformId = m.attr("platform-id", b.PlatformId, o.PlatformId, t.PlatformId)
+	o.Arch = m.list("arch", b.Arch, o.Arch, t.Arch, m.element).([]backend.XmlArch)
+}
+
+func mergeMapping(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlMapping), ours.(*backend.XmlMapping), theirs.(*backend.XmlMapping)
+	o.SignalGraph = m.attr("graph", b.SignalGraph, o.SignalGraph, t.SignalGraph)
+	o.Platform = m.attr("platform", b.Platform, o.Platform, t.Platform)
+	o.IOMappings = m.list("mapped io node", b.IOMappings, o.IOMappings, t.IOMappings, m.conflict).([]backend.XmlIOMap)
+	o.Mappings = m.list("mapped node", b.Mappings, o.Mappings, t.Mappings, m.conflict).([]backend.XmlNodeMap)
+}
+
+func mergeGraphHint(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlGraphHint), ours.(*backend.XmlGraphHint), theirs.(*backend.XmlGraphHint)
+	o.InputNode = m.list("input node", b.InputNode, o.InputNode, t.InputNode, m.hint).([]backend.XmlNodePosHint)
+	o.OutputNode = m.list("output node", b.OutputNode, o.OutputNode, t.OutputNode, m.hint).([]backend.XmlNodePosHint)
+	o.ProcessingNode = m.list("processing node", b.ProcessingNode, o.ProcessingNode, t.ProcessingNode, m.hint).([]backend.XmlNodePosHint)
+}
+
+func mergePlatformHint(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlPlatformHint), ours.(*backend.XmlPlatformHint), theirs.(*backend.XmlPlatformHint)
+	o.Arch = m.list("arch", b.Arch, o.Arch, t.Arch, m.hint).([]backend.XmlArchPosHint)
+}
+
+func mergeMappingHint(m *merger, base, ours, theirs backend.Document) {
+	b, o, t := base.(*backend.XmlMappingHint), ours.(*backend.XmlMappingHint), theirs.(*backend.XmlMappingHint)
+	o.MappedNodes = m.list("mapped node", b.MappedNodes, o.MappedNodes, t.MappedNodes, m.hint).([]backend.XmlNodePosHint)
+	o.Arch = m.list("arch", b.Arch, o.Arch, t.Arch, m.hint).([]backend.XmlArchPosHint)
+}
+
+/*
+ *		Elements
+ */
+
+// Elements are identified by key. The kinds of elements merged by
+// position (channel and arch port hints) have no key. Unknown kinds
+// of elements are recorded as error of the merge.
+func (m *merger) key(v interface{}) string {
+	switch e := v.(type) {
+	case backend.XmlLibraryRef:
+		return e.Name
+	case backend.XmlInputNode:
+		return e.NName
+	case backend.XmlOutputNode:
+		return e.NName
+	case backend.XmlProcessingNode:
+		return e.NName
+	case backend.XmlConnect:
+		return fmt.Sprintf("%s.%s -> %s.%s", e.From, e.FromPort, e.To, e.ToPort)
+	case backend.XmlSignalType:
+		return e.Name
+	case backend.XmlNodeType:
+		return e.TypeName
+	case backend.XmlInPort:
+		return e.PName
+	case backend.XmlOutPort:
+		return e.PName
+	case backend.XmlParameter:
+		return e.Name
+	case backend.XmlImplementation:
+		return e.Name
+	case backend.XmlCompatible:
+		return fmt.Sprintf("%s -> %s", e.From, e.To)
+	case backend.XmlArch:
+		return e.Name
+	case backend.XmlIOType:
+		return e.Name
+	case backend.XmlProcess:
+		return e.Name
+	case backend.XmlInChannel:
+		// a changed io-type is a removed and an added channel
+		return fmt.Sprintf("%s <- %s", e.IOType, e.Source)
+	case backend.XmlOutChannel:
+		return fmt.Sprintf("%s -> %s", e.IOType, e.Dest)
+	case backend.XmlIOMap:
+		return e.Name
+	case backend.XmlNodeMap:
+		return e.Name
+	case backend.XmlNodePosHint:
+		return e.Name
+	case backend.XmlPortPosHint:
+		return e.Name
+	case backend.XmlArchPosHint:
+		return e.Name
+	case backend.XmlProcessPosHint:
+		return e.Name
+	case backend.XmlModeHintEntry:
+		return e.Mode
+	}
+	m.unknown(v)
+	return ""
+}
+
+// An element of the same type as v, standing for a conflict.
+func (m *merger) placeholder(v interface{}, token string) interface{} {
+	switch v.(type) {
+	case backend.XmlLibraryRef:
+		return backend.XmlLibraryRef{Name: token}
+	case backend.XmlInputNode:
+		return backend.XmlInputNode{XmlNode: backend.XmlNode{NName: token}}
+	case backend.XmlOutputNode:
+		return backend.XmlOutputNode{XmlNode: backend.XmlNode{NName: token}}
+	case backend.XmlProcessingNode:
+		return backend.XmlProcessingNode{XmlNode: backend.XmlNode{NName: token}}
+	case backend.XmlConnect:
+		return backend.XmlConnect{From: token}
+	case backend.XmlSignalType:
+		return backend.XmlSignalType{Name: token}
+	case backend.XmlNodeType:
+		return backend.XmlNodeType{TypeName: token}
+	case backend.XmlInPort:
+		return backend.XmlInPort{XmlPort: backend.XmlPort{PName: token}}
+	case backend.XmlOutPort:
+		return backend.XmlOutPort{XmlPort: backend.XmlPort{PName: token}}
+	case backend.XmlParameter:
+		return backend.XmlParameter{Name: token}
+	case backend.XmlImplementation:
+		return backend.XmlImplementation{Name: token}
+	case backend.XmlCompatible:
+		return backend.XmlCompatible{From: token}
+	case backend.XmlArch:
+		return backend.XmlArch{Name: token}
+	case backend.XmlIOType:
+		return backend.XmlIOType{Name: token}
+	case backend.XmlProcess:
+		return backend.XmlProcess{Name: token}
+	case backend.XmlInChannel:
+		return backend.XmlInChannel{Source: token}
+	case backend.XmlOutChannel:
+		return backend.XmlOutChannel{Dest: token}
+	case backend.XmlIOMap:
+		return backend.XmlIOMap{XmlMap: backend.XmlMap{Name: token}}
+	case backend.XmlNodeMap:
+		return backend.XmlNodeMap{XmlMap: backend.XmlMap{Name: token}}
+	}
+	m.unknown(v)
+	return nil
+}
+
+// Only the first error is kept, the merge result is of no use anyway.
+func (m *merger) unknown(v interface{}) {
+	if m.err == nil {
+		m.err = fmt.Errorf("unknown element type %T", v)
+	}
+}
+
+func same(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	da, err1 := xml.Marshal(a)
+	db, err2 := xml.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(da, db)
+}
+
+// list is a slice of elements
+func (m *merger) contains(list interface{}, k string) bool {
+	l := reflect.ValueOf(list)
+	for i := 0; i < l.Len(); i++ {
+		if m.key(l.Index(i).Interface()) == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *merger) index(list interface{}) map[string]interface{} {
+	ret := make(map[string]interface{})
+	l := reflect.ValueOf(list)
+	for i := 0; i < l.Len(); i++ {
+		e := l.Index(i).Interface()
+		ret[m.key(e)] = e
+	}
+	return ret
+}
+
+/*
+ *		Merger
+ */
+
+const markerSize = 7
+
+// Called for elements changed differently on both sides, returns the
+// merged element (nil to remove it). base, ours or theirs are nil if
+// the element does not exist there.
+type resolver func(what, key string, base, ours, theirs interface{}) interface{}
+
+type merger struct {
+	conflicts []Conflict
+	err       error
+}
+
+func mergerNew() *merger {
+	return &merger{}
+}
+
+// Merge lists of elements (slices of the same type), the result is
+// a slice of that type.
+func (m *merger) list(what string, base, ours, theirs interface{}, resolve resolver) interface{} {
+	bm, om, tm := m.index(base), m.index(ours), m.index(theirs)
+	result := reflect.MakeSlice(reflect.TypeOf(ours), 0, 0)
+	var keys []string
+	o, t := reflect.ValueOf(ours), reflect.ValueOf(theirs)
+	for i := 0; i < o.Len(); i++ {
+		keys = append(keys, m.key(o.Index(i).Interface()))
+	}
+	for i := 0; i < t.Len(); i++ {
+		k := m.key(t.Index(i).Interface())
+		if _, ok := om[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		b, o, t := bm[k], om[k], tm[k]
+		var v interface{}
+		switch {
+		case same(o, t), same(t, b):
+			v = o
+		case same(o, b):
+			v = t
+		default:
+			v = resolve(what, k, b, o, t)
+		}
+		if v != nil {
+			result = reflect.Append(result, reflect.ValueOf(v))
+		}
+	}
+	return result.Interface()
+}
+
+// Names of the elements of list, placeholders are replaced by the
+// name of their conflict.
+func (m *merger) keys(list interface{}) (ret []string) {
+	l := reflect.ValueOf(list)
+	for i := 0; i < l.Len(); i++ {
+		k := m.key(l.Index(i).Interface())
+		if n, ok := m.conflictIndex(k); ok {
+			k = m.conflicts[n].Key
+		}
+		ret = append(ret, k)
+	}
+	return
+}
+
+// Resolver of document elements: a placeholder is inserted, it is
+// replaced by conflict markers after writing.
+func (m *merger) conflict(what, key string, base, ours, theirs interface{}) interface{} {
+	token := m.token()
+	m.conflicts = append(m.conflicts, Conflict{what, key, ours, theirs})
+	v := ours
+	if v == nil {
+		v = theirs
+	}
+	return m.placeholder(v, token)
+}
+
+// Resolver of archs and node types: an element existing on all sides
+// is merged by its attributes and lists, all other elements are
+// conflicts.
+func (m *merger) element(what, key string, base, ours, theirs interface{}) interface{} {
+	if base == nil || ours == nil || theirs == nil {
+		return m.conflict(what, key, base, ours, theirs)
+	}
+	sub := fmt.Sprintf("%s %s", what, key)
+	switch o := ours.(type) {
+	case backend.XmlArch:
+		b, t := base.(backend.XmlArch), theirs.(backend.XmlArch)
+		o.IOType = m.list(sub+" io-type", b.IOType, o.IOType, t.IOType, m.conflict).([]backend.XmlIOType)
+		o.Processes = m.list(sub+" process", b.Processes, o.Processes, t.Processes, m.element).([]backend.XmlProcess)
+		return o
+	case backend.XmlProcess:
+		b, t := base.(backend.XmlProcess), theirs.(backend.XmlProcess)
+		o.InputChannels = m.list(sub+" input channel", b.InputChannels, o.InputChannels, t.InputChannels, m.conflict).([]backend.XmlInChannel)
+		o.OutputChannels = m.list(sub+" output channel", b.OutputChannels, o.OutputChannels, t.OutputChannels, m.conflict).([]backend.XmlOutChannel)
+		return o
+	case backend.XmlNodeType:
+		b, t := base.(backend.XmlNodeType), theirs.(backend.XmlNodeType)
+		o.InPort = m.list(sub+" in-port", b.InPort, o.InPort, t.InPort, m.conflict).([]backend.XmlInPort)
+		o.OutPort = m.list(sub+" out-port", b.OutPort, o.OutPort, t.OutPort, m.conflict).([]backend.XmlOutPort)
+		o.Parameter = m.list(sub+" parameter", b.Parameter, o.Parameter, t.Parameter, m.conflict).([]backend.XmlParameter)
+		o.Implementation = m.list(sub+" implementation", b.Implementation, o.Implementation, t.Implementation, m.element).([]backend.XmlImplementation)
+		return o
+	case backend.XmlImplementation:
+		b, t := base.(backend.XmlImplementation), theirs.(backend.XmlImplementation)
+		if !same(o.SignalGraph, t.SignalGraph) {
+			if !same(o.SignalGraph, b.SignalGraph) {
+				return m.conflict(what, key, base, ours, theirs)
+			}
+			o.SignalGraph = t.SignalGraph
+		}
+		o.GraphRef = m.attr(sub+" graph", b.GraphRef, o.GraphRef, t.GraphRef)
+		return o
+	}
+	return m.conflict(what, key, base, ours, theirs)
+}
+
+// Merge an attribute, conflicts are marked by the token.
+func (m *merger) attr(what string, base, ours, theirs string) string {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours
+	case ours == base:
+		return theirs
+	}
+	token := m.token()
+	m.conflicts = append(m.conflicts, Conflict{what, "", ours, theirs})
+	return token
+}
+
+func (m *merger) token() string {
+	return fmt.Sprintf("@@conflict-%d@@", len(m.conflicts))
+}
+
+func (m *merger) isPlaceholder(s string) bool {
+	_, ok := m.conflictIndex(s)
+	return ok
+}
+
+func (m *merger) conflictIndex(s string) (n int, ok bool) {
+	_, err := fmt.Sscanf(s, "@@conflict-%d@@", &n)
+	ok = (err == nil && n < len(m.conflicts) && s == fmt.Sprintf("@@conflict-%d@@", n))
+	return
+}
+
+// Resolver of hints: position lists are merged by mode, otherwise
+// ours wins.
+func (m *merger) hint(what, key string, base, ours, theirs interface{}) interface{} {
+	if ours == nil || theirs == nil {
+		return m.oursWins(what, key, ours)
+	}
+	sub := fmt.Sprintf("%s %s", what, key)
+	switch o := ours.(type) {
+	case backend.XmlNodePosHint:
+		b, _ := base.(backend.XmlNodePosHint)
+		t := theirs.(backend.XmlNodePosHint)
+		if o.Expanded == b.Expanded {
+			o.Expanded = t.Expanded
+		}
+		o.XmlModeHint = m.modeHint(sub, b.XmlModeHint, o.XmlModeHint, t.XmlModeHint)
+		o.InPorts = m.list(sub+" in-port", b.InPorts, o.InPorts, t.InPorts, m.hint).([]backend.XmlPortPosHint)
+		o.OutPorts = m.list(sub+" out-port", b.OutPorts, o.OutPorts, t.OutPorts, m.hint).([]backend.XmlPortPosHint)
+		return o
+	case backend.XmlPortPosHint:
+		b, _ := base.(backend.XmlPortPosHint)
+		t := theirs.(backend.XmlPortPosHint)
+		o.XmlModeHint = m.modeHint(sub, b.XmlModeHint, o.XmlModeHint, t.XmlModeHint)
+		return o
+	case backend.XmlArchPosHint:
+		b, _ := base.(backend.XmlArchPosHint)
+		t := theirs.(backend.XmlArchPosHint)
+		o.XmlModeHint = m.modeHint(sub, b.XmlModeHint, o.XmlModeHint, t.XmlModeHint)
+		o.Processes = m.list(sub+" process", b.Processes, o.Processes, t.Processes, m.hint).([]backend.XmlProcessPosHint)
+		if !same(o.ArchPorts, t.ArchPorts) {
+			if same(o.ArchPorts, b.ArchPorts) {
+				o.ArchPorts = t.ArchPorts
+			} else {
+				m.oursWins(sub, "arch ports", o.ArchPorts)
+			}
+		}
+		return o
+	case backend.XmlProcessPosHint:
+		b, _ := base.(backend.XmlProcessPosHint)
+		t := theirs.(backend.XmlProcessPosHint)
+		o.XmlModeHint = m.modeHint(sub, b.XmlModeHint, o.XmlModeHint, t.XmlModeHint)
+		if !same(o.InChannels, t.InChannels) || !same(o.OutChannels, t.OutChannels) {
+			if same(o.InChannels, b.InChannels) && same(o.OutChannels, b.OutChannels) {
+				o.InChannels, o.OutChannels = t.InChannels, t.OutChannels
+			} else {
+				m.oursWins(sub, "channels", o.InChannels)
+			}
+		}
+		return o
+	}
+	return m.oursWins(what, key, ours)
+}
+
+func (m *merger) modeHint(what string, base, ours, theirs backend.XmlModeHint) backend.XmlModeHint {
+	entries := m.list(what+" mode", base.Entry, ours.Entry, theirs.Entry, func(what, key string, base, ours, theirs interface{}) interface{} {
+		return m.oursWins(what, key, ours)
+	})
+	return backend.XmlModeHint{entries.([]backend.XmlModeHintEntry)}
+}
+
+func (m *merger) oursWins(what, key string, ours interface{}) interface{} {
+	log.Printf("merge: %s %s changed in both, taking ours\n", what, key)
+	return ours
+}
+
+/*
+ *		Conflict markers
+ */
+
+// Replace lines with placeholders by conflict markers. Placeholder
+// elements have no children, and are written in one line.
+func (m *merger) markConflicts(data []byte) (out []byte, err error) {
+	if len(m.conflicts) == 0 {
+		out = data
+		return
+	}
+	var buf bytes.Buffer
+	marker := func(c byte, label string) {
+		buf.WriteString(strings.Repeat(string(c), markerSize))
+		if len(label) > 0 {
+			buf.WriteString(" " + label)
+		}
+		buf.WriteByte('\n')
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, line := range lines {
+		n, ok := m.lineConflict(line)
+		if !ok {
+			buf.WriteString(line)
+			continue
+		}
+		c := m.conflicts[n]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		var ours, theirs string
+		if _, isAttr := c.Ours.(string); isAttr {
+			ours, theirs = m.replaceTokens(line, true), m.replaceTokens(line, false)
+		} else {
+			ours, err = fragment(c.Ours, indent)
+			if err != nil {
+				return
+			}
+			theirs, err = fragment(c.Theirs, indent)
+			if err != nil {
+				return
+			}
+		}
+		marker('<', "ours")
+		buf.WriteString(ours)
+		marker('=', "")
+		buf.WriteString(theirs)
+		marker('>', "theirs")
+	}
+	out = buf.Bytes()
+	return
+}
+
+func (m *merger) lineConflict(line string) (n int, ok bool) {
+	i := strings.Index(line, "@@conflict-")
+	if i < 0 {
+		return
+	}
+	_, err := fmt.Sscanf(line[i:], "@@conflict-%d@@", &n)
+	ok = (err == nil && n < len(m.conflicts))
+	return
+}
+
+// All attribute tokens of line replaced by the values of one side.
+func (m *merger) replaceTokens(line string, ours bool) string {
+	for n, c := range m.conflicts {
+		v := c.Theirs
+		if ours {
+			v = c.Ours
+		}
+		if s, ok := v.(string); ok {
+			line = strings.Replace(line, fmt.Sprintf("@@conflict-%d@@", n), s, -1)
+		}
+	}
+	return line
+}
+
+func fragment(v interface{}, indent string) (s string, err error) {
+	if v == nil {
+		return
+	}
+	data, err := xml.MarshalIndent(v, indent, "   ")
+	if err != nil {
+		err = fmt.Errorf("Merge error: %s", err)
+		return
+	}
+	s = string(data) + "\n"
+	return
+}
@@ -0,0 +1,280 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+)
+
+const mappingBase = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p.spml">
+   <map-node name="a" process="a1/p1"></map-node>
+   <map-node name="b" process="a1/p1"></map-node>
+   <map-node name="c" process="a1/p1"></map-node>
+</mapping>`
+
+const mappingOurs = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p.spml">
+   <map-node name="a" process="a1/p2"></map-node>
+   <map-node name="b" process="a1/p2"></map-node>
+   <map-node name="c" process="a1/p1"></map-node>
+</mapping>`
+
+const mappingTheirs = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p2.spml">
+   <map-node name="a" process="a1/p3"></map-node>
+   <map-node name="b" process="a1/p1"></map-node>
+   <map-node name="d" process="a1/p1"></map-node>
+</mapping>`
+
+const mappingMerged = `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="g.sml" platform="p2.spml">
+<<<<<<< ours
+   <map-node name="a" process="a1/p2"></map-node>
+=======
+   <map-node name="a" process="a1/p3"></map-node>
+>>>>>>> theirs
+   <map-node name="b" process="a1/p2"></map-node>
+   <map-node name="d" process="a1/p1"></map-node>
+</mapping>`
+
+const graphBase = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+      <processing-node name="f" type="Filter"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>`
+
+const graphOurs = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+      <processing-node name="f" type="FirFilter"></processing-node>
+      <output port="o" name="out" type="sink"></output>
+   </nodes>
+   <connections>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+      <connect from="f" to="out" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>`
+
+const graphTheirs = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+      <processing-node name="f" type="Filter"></processing-node>
+      <processing-node name="g" type="Gain"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+      <connect from="f" to="g" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>`
+
+const graphMerged = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+      <output port="o" name="out" type="sink"></output>
+      <processing-node name="f" type="FirFilter"></processing-node>
+      <processing-node name="g" type="Gain"></processing-node>
+   </nodes>
+   <connections>
+      <connect from="in" to="f" from-port="o" to-port="i"></connect>
+      <connect from="f" to="out" from-port="o" to-port="i"></connect>
+      <connect from="f" to="g" from-port="o" to-port="i"></connect>
+   </connections>
+</signal-graph>`
+
+// Theirs connects node f, which ours removed.
+const graphRemoved = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+   </nodes>
+   <connections></connections>
+</signal-graph>`
+
+const graphRemovedMerged = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="i" name="in" type="src"></input>
+      <processing-node name="g" type="Gain"></processing-node>
+   </nodes>
+   <connections>
+<<<<<<< ours
+=======
+      <connect from="f" to="g" from-port="o" to-port="i"></connect>
+>>>>>>> theirs
+   </connections>
+</signal-graph>`
+
+const hintBase = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="f" expanded="false">
+      <hint mode="normal" x="10" y="10"></hint>
+      <hint mode="expanded" x="10" y="10"></hint>
+   </processing-node>
+</hints>`
+
+const hintOurs = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="f" expanded="false">
+      <hint mode="normal" x="50" y="10"></hint>
+      <hint mode="expanded" x="10" y="10"></hint>
+   </processing-node>
+</hints>`
+
+const hintTheirs = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="f" expanded="true">
+      <hint mode="normal" x="90" y="10"></hint>
+      <hint mode="expanded" x="10" y="70"></hint>
+   </processing-node>
+</hints>`
+
+const hintMerged = `<hints xmlns="http://www.freesp.de/xml/freeSP" ref="g.sml">
+   <processing-node name="f" expanded="true">
+      <hint mode="normal" x="50" y="10"></hint>
+      <hint mode="expanded" x="10" y="70"></hint>
+   </processing-node>
+</hints>`
+
+const platformBase = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="p2"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t1" source="p1"></input-channel>
+      </process>
+   </arch>
+</platform>`
+
+// Ours adds a channel to p1 and changes the one of p2, theirs adds an
+// io-type and a process.
+const platformOurs = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="p2"></output-channel>
+         <output-channel io-type="t1" dest="p3"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t2" source="p1"></input-channel>
+      </process>
+   </arch>
+</platform>`
+
+const platformTheirs = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <io-type name="t2" mode="async"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="p2"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t1" source="p1"></input-channel>
+      </process>
+      <process name="p3"></process>
+   </arch>
+</platform>`
+
+const platformMerged = `<platform xmlns="http://www.freesp.de/xml/freeSP" version="1.0" platform-id="p">
+   <arch name="a1">
+      <io-type name="t1" mode="sync"></io-type>
+      <io-type name="t2" mode="async"></io-type>
+      <process name="p1">
+         <output-channel io-type="t1" dest="p2"></output-channel>
+         <output-channel io-type="t1" dest="p3"></output-channel>
+      </process>
+      <process name="p2">
+         <input-channel io-type="t2" source="p1"></input-channel>
+      </process>
+      <process name="p3"></process>
+   </arch>
+</platform>`
+
+const libraryBase = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <node-type name="Filter">
+      <intype port="i" type="s1"></intype>
+      <outtype port="o" type="s1"></outtype>
+      <implementation name="c" graph="a.sml"></implementation>
+   </node-type>
+</library>`
+
+// Ours changes a port, theirs adds one, both change the implementation.
+const libraryOurs = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <node-type name="Filter">
+      <intype port="i" type="s2"></intype>
+      <outtype port="o" type="s1"></outtype>
+      <implementation name="c" graph="b.sml"></implementation>
+   </node-type>
+</library>`
+
+const libraryTheirs = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <node-type name="Filter">
+      <intype port="i" type="s1"></intype>
+      <intype port="j" type="s1"></intype>
+      <outtype port="o" type="s1"></outtype>
+      <implementation name="c" graph="c.sml"></implementation>
+   </node-type>
+</library>`
+
+const libraryMerged = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <node-type name="Filter">
+      <intype port="i" type="s2"></intype>
+      <intype port="j" type="s1"></intype>
+      <outtype port="o" type="s1"></outtype>
+<<<<<<< ours
+      <implementation name="c" graph="b.sml"></implementation>
+=======
+      <implementation name="c" graph="c.sml"></implementation>
+>>>>>>> theirs
+   </node-type>
+</library>`
+
+func TestMerge(t *testing.T) {
+	case1 := []struct {
+		path               string
+		base, ours, theirs string
+		merged             string
+		conflicts          []string
+	}{
+		{"m.mml", mappingBase, mappingOurs, mappingTheirs, mappingMerged, []string{
+			"mapped node a: changed in both",
+		}},
+		{"g.sml", graphBase, graphOurs, graphTheirs, graphMerged, nil},
+		{"g.sml", graphBase, graphRemoved, graphTheirs, graphRemovedMerged, []string{
+			"connection f.o -> g.i: only in theirs",
+		}},
+		{"g-sml.hints.xml", hintBase, hintOurs, hintTheirs, hintMerged, nil},
+		{"p.spml", platformBase, platformOurs, platformTheirs, platformMerged, nil},
+		{"l.alml", libraryBase, libraryOurs, libraryTheirs, libraryMerged, []string{
+			"node type Filter implementation c graph: changed in both",
+		}},
+	}
+	for i, c := range case1 {
+		data, conflicts, err := Merge([]byte(c.base), []byte(c.ours), []byte(c.theirs), c.path)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		// written like SGE saves documents
+		const header = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+		if !strings.HasPrefix(string(data), header) {
+			t.Errorf("testcase %d failed: no XML declaration in\n%s\n", i, data)
+		}
+		if strings.TrimSpace(strings.TrimPrefix(string(data), header)) != c.merged {
+			t.Errorf("testcase %d failed: merged\n%s\nexpected\n%s\n", i, data, c.merged)
+		}
+		if len(conflicts) != len(c.conflicts) {
+			t.Errorf("testcase %d failed: %d conflicts %v, expected %d\n", i, len(conflicts), conflicts, len(c.conflicts))
+			continue
+		}
+		for j, cf := range conflicts {
+			if cf.String() != c.conflicts[j] {
+				t.Errorf("testcase %d failed: conflict %d is %q, expected %q\n", i, j, cf, c.conflicts[j])
+			}
+		}
+	}
+}
+
+func TestUnknownElement(t *testing.T) {
+	m := mergerNew()
+	m.list("thing", []int{1}, []int{2}, []int{3}, m.conflict)
+	if m.err == nil {
+		t.Errorf("TestUnknownElement failed: no error\n")
+	}
+}
@@ -45,16 +45,6 @@ func createArchFromXml(xmla backend.XmlArch, platform pf.PlatformIf) (a *arch, e
 		}
 		a.processes.Append(pr)
 	}
-	/*
-		for _, xmlh := range xmla.Entry {
-			mode, ok := freesp.ModeFromString[xmlh.Mode]
-			if !ok {
-				log.Printf("createArchFromXml Warning: hint mode %s not defined\n", xmlh.Mode)
-				continue
-			}
-			a.position[mode] = image.Point{xmlh.X, xmlh.Y}
-		}
-	*/
 	return
 }
 
@@ -160,15 +150,15 @@ func (a *arch) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElement
 		//log.Printf("arch.AddNewObject: successfully added process %v\n", p)
 
 	default:
-		log.Fatalf("arch.AddNewObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("arch.RemoveObject", "cannot remove %T from an arch", obj)
 	}
 	return
 }
 
-func (a *arch) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (a *arch) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if a != tree.Object(parent) {
-		log.Printf("arch.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("arch.RemoveObject", "not removing child of mine")
 		return
 	}
 	obj := tree.Object(cursor)
@@ -226,7 +216,7 @@ func (a *arch) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWi
 		//log.Printf("arch.RemoveObject: successfully removed process %v\n", p)
 
 	default:
-		log.Fatalf("arch.AddNewObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("arch.RemoveObject", "cannot remove %T from an arch", obj)
 	}
 	return
 }
@@ -29,7 +29,7 @@ func CreateXmlArchHint(a pf.ArchIf) (xmla *backend.XmlArchPosHint) {
 }
 
 func CreateXmlArchPortHint(p pf.ArchPortIf) (xmlp *backend.XmlArchPortPosHint) {
-	xmlp = backend.XmlArchPortPosHintNew()
+	xmlp = backend.XmlArchPortPosHintNew(ArchPortHintKey(p))
 	xmlp.Entry = freesp.CreateXmlModePosition(p).Entry
 	return
 }
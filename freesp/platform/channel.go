@@ -34,9 +34,7 @@ func createInChannelFromXml(xmlc backend.XmlInChannel, p pf.ProcessIf) (ch *chan
 		return
 	}
 	ch = ChannelNew(gr.InPort, iot, p, xmlc.Source)
-	//ch.channelPositionsFromXml(xmlc.XmlChannel)
 	ap := p.Arch().(*arch).AddArchPort(ch)
-	//ap.archPortPositionsFromXml(xmlc.XmlChannel)
 	ch.archport = ap
 	return
 }
@@ -48,41 +46,11 @@ func createOutChannelFromXml(xmlc backend.XmlOutChannel, p pf.ProcessIf) (ch *ch
 		return
 	}
 	ch = ChannelNew(gr.OutPort, iot, p, xmlc.Dest)
-	//ch.channelPositionsFromXml(xmlc.XmlChannel)
 	ap := p.Arch().(*arch).AddArchPort(ch)
-	//ap.archPortPositionsFromXml(xmlc.XmlChannel)
 	ch.archport = ap
 	return
 }
 
-/*
-func (ch *channel) channelPositionsFromXml(xmlc backend.XmlChannel) {
-	for _, xmlh := range xmlc.Entry {
-		mode, ok := freesp.ModeFromString[xmlh.Mode]
-		if !ok {
-			log.Printf("channelPositionsFromXml warning: hint mode %s not defined\n",
-				xmlh.Mode)
-			continue
-		}
-		ch.SetModePosition(mode, image.Point{xmlh.X, xmlh.Y})
-	}
-	return
-}
-
-func (ap *archPort) archPortPositionsFromXml(xmlc backend.XmlChannel) {
-	for _, xmlh := range xmlc.ArchPortHints.Entry {
-		mode, ok := freesp.ModeFromString[xmlh.Mode]
-		if !ok {
-			log.Printf("archPortPositionsFromXml warning: hint mode %s not defined\n",
-				xmlh.Mode)
-			continue
-		}
-		ap.SetModePosition(mode, image.Point{xmlh.X, xmlh.Y})
-	}
-	return
-}
-*/
-
 func channelGetIOTypeFromArch(a pf.ArchIf, iotype string) (iot pf.IOTypeIf, err error) {
 	var ok bool
 	for _, iot = range a.IOTypes() {
@@ -141,8 +109,9 @@ func (c *channel) Direction() gr.PortDirection {
 	return c.direction
 }
 
-func (c *channel) SetDirection(newDir gr.PortDirection) {
+func (c *channel) SetDirection(newDir gr.PortDirection) error {
 	c.direction = newDir
+	return nil
 }
 
 //
@@ -203,8 +172,8 @@ func (c *channel) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElem
 	return
 }
 
-func (c *channel) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatalf("channel.RemoveObject error: nothing to remove\n")
+func (c *channel) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("channel.RemoveObject", "nothing to remove")
 	return
 }
 
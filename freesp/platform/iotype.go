@@ -32,7 +32,8 @@ var _ pf.IOTypeIf = (*iotype)(nil)
 
 func IOTypeNew(name string, mode gr.IOMode, platform pf.PlatformIf) (t *iotype, err error) {
 	newT := &iotype{name, mode, platform}
-	ioType, ok := freesp.GetIOTypeByName(name)
+	reg := platformRegistry(platform)
+	ioType, ok := reg.GetIOTypeByName(name)
 	if ok {
 		if (*newT) != (*(ioType.(*iotype))) {
 			err = fmt.Errorf("IOTypeNew error: adding existing io type %s, which is incompatible.", name)
@@ -42,7 +43,7 @@ func IOTypeNew(name string, mode gr.IOMode, platform pf.PlatformIf) (t *iotype,
 		t = ioType.(*iotype)
 	} else {
 		t = newT
-		freesp.RegisterIOType(t)
+		reg.RegisterIOType(t)
 	}
 	return
 }
@@ -74,14 +75,15 @@ func (t *iotype) Name() string {
 }
 
 func (t *iotype) SetName(newName string) {
-	_, ok := freesp.GetIOTypeByName(newName)
+	reg := platformRegistry(t.platform)
+	_, ok := reg.GetIOTypeByName(newName)
 	if ok {
 		log.Printf("iotype.SetName error: cannot rename to existing iotype.\n")
 		return
 	}
-	freesp.RemoveRegisteredIOType(t)
+	reg.RemoveRegisteredIOType(t)
 	t.name = newName
-	freesp.RegisterIOType(t)
+	reg.RegisterIOType(t)
 }
 
 //
@@ -101,8 +103,8 @@ func (t *iotype) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEleme
 	return
 }
 
-func (t *iotype) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
-	log.Fatalf("iotype.RemoveObject error: nothing to remove\n")
+func (t *iotype) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
+	err = freesp.InvalidOperationError("iotype.RemoveObject", "nothing to remove")
 	return
 }
 
@@ -5,6 +5,7 @@ import (
 	"github.com/axel-freesp/sge/backend"
 	"github.com/axel-freesp/sge/freesp"
 	gr "github.com/axel-freesp/sge/interface/graph"
+	mod "github.com/axel-freesp/sge/interface/model"
 	pf "github.com/axel-freesp/sge/interface/platform"
 	tr "github.com/axel-freesp/sge/interface/tree"
 	"log"
@@ -16,12 +17,33 @@ type platform struct {
 	pathPrefix string
 	platformId string
 	archlist   archList
+	context    mod.ModelContextIf
+	freesp.Diagnostics
 }
 
 var _ pf.PlatformIf = (*platform)(nil)
 
-func PlatformNew(filename string) *platform {
-	return &platform{filename, "", "", archListInit()}
+// IO types of the platform are registered in the registry of context.
+func PlatformNew(filename string, context mod.ModelContextIf) *platform {
+	return &platform{filename, "", "", archListInit(), context, freesp.Diagnostics{}}
+}
+
+func platformRegistry(p pf.PlatformIf) *freesp.Registry {
+	pl, ok := p.(*platform)
+	if ok {
+		return freesp.RegistryOf(pl.context)
+	}
+	return freesp.DefaultRegistry()
+}
+
+// Collects the load warnings of p (nil for foreign platforms, whose
+// warnings are logged).
+func platformDiagnostics(p pf.PlatformIf) *freesp.Diagnostics {
+	pl, ok := p.(*platform)
+	if ok {
+		return &pl.Diagnostics
+	}
+	return nil
 }
 
 func PlatformApplyHints(p pf.PlatformIf, xmlhints *backend.XmlPlatformHint) (err error) {
@@ -29,38 +51,103 @@ func PlatformApplyHints(p pf.PlatformIf, xmlhints *backend.XmlPlatformHint) (err
 		err = fmt.Errorf("PlatformApplyHints error: filename mismatch\n")
 		return
 	}
-	for i, xmla := range xmlhints.Arch {
-		a := p.Arch()[i]
-		if a.Name() != xmla.Name {
-			log.Printf("PlatformApplyHints error: arch name mismatch\n")
+	d := platformDiagnostics(p)
+	for _, xmla := range xmlhints.Arch {
+		a, ok := archByName(p, xmla.Name)
+		if !ok {
+			d.Warnf("hints for arch %s: arch not found", xmla.Name)
 			continue
 		}
-		ArchApplyHints(a, &xmla)
+		ArchApplyHints(a, &xmla, d)
 	}
 	return
 }
 
-func ArchApplyHints(a pf.ArchIf, xmla *backend.XmlArchPosHint) {
-	freesp.ModePositionerApplyHints(a, xmla.XmlModeHint)
+// Processes are found by name, arch ports by their channel (or by
+// position for hint files without channels).
+func ArchApplyHints(a pf.ArchIf, xmla *backend.XmlArchPosHint, d *freesp.Diagnostics) {
+	freesp.ModePositionerApplyHints(a, xmla.XmlModeHint, d)
 	for i, xmlp := range xmla.ArchPorts {
-		p := a.ArchPorts()[i]
-		freesp.ModePositionerApplyHints(p, xmlp.XmlModeHint)
+		p, ok := archPortByHint(a, i, xmlp.Channel)
+		if !ok {
+			d.Warnf("hints for arch port %d (%s) of arch %s: arch port not found", i, xmlp.Channel, a.Name())
+			continue
+		}
+		freesp.ModePositionerApplyHints(p, xmlp.XmlModeHint, d)
 	}
-	for i, xmlp := range xmla.Processes {
-		p := a.Processes()[i]
-		ProcessApplyHints(p, &xmlp)
+	for _, xmlp := range xmla.Processes {
+		p, ok := processByName(a, xmlp.Name)
+		if !ok {
+			d.Warnf("hints for process %s/%s: process not found", a.Name(), xmlp.Name)
+			continue
+		}
+		ProcessApplyHints(p, &xmlp, d)
 	}
 }
 
-func ProcessApplyHints(p pf.ProcessIf, xmlp *backend.XmlProcessPosHint) {
-	freesp.ModePositionerApplyHints(p, xmlp.XmlModeHint)
+func archByName(p pf.PlatformIf, name string) (a pf.ArchIf, ok bool) {
+	for _, a = range p.Arch() {
+		if a.Name() == name {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+func processByName(a pf.ArchIf, name string) (p pf.ProcessIf, ok bool) {
+	for _, p = range a.Processes() {
+		if p.Name() == name {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+func archPortByHint(a pf.ArchIf, i int, channel string) (p pf.ArchPortIf, ok bool) {
+	if len(channel) == 0 {
+		ok = i < len(a.ArchPorts())
+		if ok {
+			p = a.ArchPorts()[i]
+		}
+		return
+	}
+	for _, p = range a.ArchPorts() {
+		if ArchPortHintKey(p) == channel {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+// Identifies an arch port in hint files, e.g. "p1 -> t1-a2/p3" for
+// the output channel of process p1 to process a2/p3.
+func ArchPortHintKey(p pf.ArchPortIf) string {
+	c := p.Channel()
+	dir := "->"
+	if c.Direction() == gr.InPort {
+		dir = "<-"
+	}
+	return fmt.Sprintf("%s %s %s", c.Process().Name(), dir, c.Name())
+}
+
+func ProcessApplyHints(p pf.ProcessIf, xmlp *backend.XmlProcessPosHint, d *freesp.Diagnostics) {
+	freesp.ModePositionerApplyHints(p, xmlp.XmlModeHint, d)
 	for i, xmlc := range xmlp.InChannels {
-		c := p.InChannels()[i]
-		freesp.ModePositionerApplyHints(c, xmlc.XmlModeHint)
+		if i >= len(p.InChannels()) {
+			d.Warnf("hints for process %s: more input channels than the process has", p.Name())
+			break
+		}
+		freesp.ModePositionerApplyHints(p.InChannels()[i], xmlc.XmlModeHint, d)
 	}
 	for i, xmlc := range xmlp.OutChannels {
-		c := p.OutChannels()[i]
-		freesp.ModePositionerApplyHints(c, xmlc.XmlModeHint)
+		if i >= len(p.OutChannels()) {
+			d.Warnf("hints for process %s: more output channels than the process has", p.Name())
+			break
+		}
+		freesp.ModePositionerApplyHints(p.OutChannels()[i], xmlc.XmlModeHint, d)
 	}
 }
 
@@ -139,7 +226,7 @@ func (p *platform) Read(data []byte) (cnt int, err error) {
 
 func (p *platform) ReadFile(filepath string) (err error) {
 	xmlp := backend.XmlPlatformNew()
-	err = xmlp.ReadFile(filepath)
+	err = freesp.PreserverOf(p.context).DocumentReadFile(xmlp, filepath)
 	if err != nil {
 		err = fmt.Errorf("platform.Read: %v", err)
 		return
@@ -224,7 +311,7 @@ func (p *platform) Write() (data []byte, err error) {
 
 func (p *platform) WriteFile(filepath string) (err error) {
 	xmlp := CreateXmlPlatform(p)
-	err = xmlp.WriteFile(filepath)
+	err = freesp.PreserverOf(p.context).DocumentWriteFile(xmlp, filepath)
 	return
 }
 
@@ -271,10 +358,11 @@ func (p *platform) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeEle
 	return
 }
 
-func (p *platform) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (p *platform) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if p != tree.Object(parent) {
-		log.Fatal("platform.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("platform.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
@@ -288,16 +376,20 @@ func (p *platform) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.
 		}
 		for _, pr := range a.Processes() {
 			prCursor := tree.CursorAt(cursor, pr)
-			del := a.RemoveObject(tree, prCursor)
+			var del []tr.IdWithObject
+			del, err = a.RemoveObject(tree, prCursor)
 			for _, d := range del {
 				removed = append(removed, d)
 			}
+			if err != nil {
+				return
+			}
 		}
 		prefix, index := tree.Remove(cursor)
 		removed = append(removed, tr.IdWithObject{prefix, index, a})
 
 	default:
-		log.Fatalf("platform.RemoveObject error: invalid type %T\n", obj)
+		err = freesp.InvalidOperationError("platform.RemoveObject", "cannot remove %T from a platform", obj)
 	}
 	return
 }
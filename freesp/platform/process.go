@@ -44,17 +44,6 @@ func createProcessFromXml(xmlp backend.XmlProcess, a pf.ArchIf) (pr *process, er
 		}
 		pr.outChannels.Append(ch)
 	}
-	/*
-		for _, xmlh := range xmlp.Entry {
-			mode, ok := freesp.ModeFromString[xmlh.Mode]
-			if !ok {
-				log.Printf("createProcessFromXml Warning: hint mode %s not defined\n",
-					xmlh.Mode)
-				continue
-			}
-			pr.SetModePosition(mode, image.Point{xmlh.X, xmlh.Y})
-		}
-	*/
 	return
 }
 
@@ -204,10 +193,11 @@ func (p *process) AddNewObject(tree tr.TreeIf, cursor tr.Cursor, obj tr.TreeElem
 	return
 }
 
-func (p *process) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject) {
+func (p *process) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.IdWithObject, err error) {
 	parent := tree.Parent(cursor)
 	if p != tree.Object(parent) {
-		log.Fatal("process.RemoveObject error: not removing child of mine.")
+		err = freesp.InconsistentModelError("process.RemoveObject", "not removing child of mine")
+		return
 	}
 	obj := tree.Object(cursor)
 	switch obj.(type) {
@@ -233,7 +223,7 @@ func (p *process) RemoveObject(tree tr.TreeIf, cursor tr.Cursor) (removed []tr.I
 		removed = append(removed, tr.IdWithObject{prefix, index, c})
 
 	default:
-		log.Fatalf("Port.RemoveObject error: invalid type %T: %v\n", obj, obj)
+		err = freesp.InvalidOperationError("process.RemoveObject", "cannot remove %T from a process", obj)
 	}
 	return
 }
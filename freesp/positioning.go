@@ -4,15 +4,14 @@ import (
 	"github.com/axel-freesp/sge/backend"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	"image"
-	"log"
 )
 
-func PathModePositionerApplyHints(pmp gr.PathModePositioner, xmln backend.XmlModeHint) {
+func PathModePositionerApplyHints(pmp gr.PathModePositioner, xmln backend.XmlModeHint, d *Diagnostics) {
 	for _, m := range xmln.Entry {
 		path, modestring := gr.SeparatePathMode(m.Mode)
 		mode, ok := gr.ModeFromString[string(modestring)]
 		if !ok {
-			log.Printf("PathModePositionerApplyHints Warning: hint mode %s not defined\n", m.Mode)
+			d.Warnf("hint mode %s not defined", m.Mode)
 			continue
 		}
 		pos := image.Point{m.X, m.Y}
@@ -20,11 +19,11 @@ func PathModePositionerApplyHints(pmp gr.PathModePositioner, xmln backend.XmlMod
 	}
 }
 
-func ModePositionerApplyHints(mp gr.ModePositioner, xmln backend.XmlModeHint) {
+func ModePositionerApplyHints(mp gr.ModePositioner, xmln backend.XmlModeHint, d *Diagnostics) {
 	for _, m := range xmln.Entry {
 		mode, ok := gr.ModeFromString[m.Mode]
 		if !ok {
-			log.Printf("ModePositionerApplyHints Warning: hint mode %s not defined\n", m.Mode)
+			d.Warnf("hint mode %s not defined", m.Mode)
 			continue
 		}
 		pos := image.Point{m.X, m.Y}
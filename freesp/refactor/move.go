@@ -0,0 +1,140 @@
+package refactor
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	"path/filepath"
+	"strings"
+)
+
+type TypeRef struct {
+	Kind TypeKind
+	Name string
+}
+
+// Move describes types moved from library From to library To, Remaining
+// are the types left in From. Documents using a moved type need a
+// reference to To; their reference to From is obsolete unless they use
+// one of the remaining types. FromPath and ToPath are the files of the
+// libraries: references are compared by the file they resolve to, same
+// named libraries in other directories are left alone. Without them,
+// references are compared by text.
+type Move struct {
+	From, To         string
+	Types            []TypeRef
+	Remaining        []TypeRef
+	FromPath, ToPath string
+}
+
+// Apply updates the library references of doc, stored as filename,
+// count tells how many were added or removed.
+func (m Move) Apply(doc backend.Document, filename string) (count int) {
+	var refs *[]backend.XmlLibraryRef
+	switch d := doc.(type) {
+	case *backend.XmlSignalGraph:
+		refs = &d.Libraries
+	case *backend.XmlLibrary:
+		refs = &d.Libraries
+	default:
+		return
+	}
+	if !m.uses(doc, m.Types) {
+		return
+	}
+	dir := filepath.Dir(filename)
+	if !hasRef(*refs, dir, m.To, m.ToPath) && !isLibrary(filename, m.To, m.ToPath) {
+		*refs = append(*refs, *backend.XmlLibraryRefNew(refTo(dir, m.To, m.ToPath)))
+		count++
+	}
+	if m.uses(doc, m.Remaining) {
+		return
+	}
+	for i, r := range *refs {
+		if sameRef(r.Name, dir, m.From, m.FromPath) {
+			*refs = append((*refs)[:i], (*refs)[i+1:]...)
+			count++
+			break
+		}
+	}
+	return
+}
+
+// Definitions in a library do not count as use.
+func (m Move) uses(doc backend.Document, types []TypeRef) bool {
+	for _, t := range types {
+		n := nameReferences(doc, t.Kind, t.Name)
+		if lib, ok := doc.(*backend.XmlLibrary); ok {
+			n -= definitions(lib, t)
+		}
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func definitions(lib *backend.XmlLibrary, t TypeRef) (count int) {
+	if t.Kind == SignalType {
+		for _, st := range lib.SignalTypes {
+			if st.Name == t.Name {
+				count++
+			}
+		}
+	} else {
+		for _, nt := range lib.NodeTypes {
+			if nt.TypeName == t.Name {
+				count++
+			}
+		}
+	}
+	return
+}
+
+func hasRef(refs []backend.XmlLibraryRef, dir, name, path string) bool {
+	for _, r := range refs {
+		if sameRef(r.Name, dir, name, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ref, used in directory dir, refers to the library name stored as
+// path. Unresolved references are compared by text.
+func sameRef(ref, dir, name, path string) bool {
+	if len(path) > 0 {
+		if r, err := backend.ResolveRef(ref, dir); err == nil {
+			return r.AbsPath() == backend.AbsPath(path)
+		}
+	}
+	return ref == name
+}
+
+// The document stored as filename is the library itself.
+func isLibrary(filename, name, path string) bool {
+	if len(path) > 0 {
+		return backend.AbsPath(filename) == backend.AbsPath(path)
+	}
+	return filepath.Base(filename) == name
+}
+
+// The reference to add in directory dir: name if it resolves to path,
+// the path relative to dir otherwise.
+func refTo(dir, name, path string) string {
+	if len(path) == 0 || sameRef(name, dir, name, path) {
+		return name
+	}
+	rel, err := filepath.Rel(backend.AbsPath(dir), backend.AbsPath(path))
+	if err != nil {
+		return backend.AbsPath(path)
+	}
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return filepath.ToSlash(rel)
+}
+
+// MoveInFiles computes the changes of all files using the moved types.
+// Files are not written, see FileChange.Apply.
+func MoveInFiles(files []string, m Move) ([]FileChange, error) {
+	return changeFiles(files, "refactor.MoveInFiles", m.Apply)
+}
@@ -0,0 +1,240 @@
+package refactor
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/tool"
+)
+
+// Renaming a signal or node type has to update every document that
+// refers to it by name: node types of nodes, signal types of ports
+// (also in nested implementation graphs) and the definitions in
+// libraries. Open documents refer to types by pointer and are written
+// with the new name anyway, the functions here update the files.
+//
+// Several libraries may define types of the same name. A type is given
+// by its name and the library defining it (see DefinedAt); names of
+// other libraries' types are kept. Unqualified names are resolved in
+// the scope of the document, by the dependency graph of the documents.
+
+type TypeKind int
+
+const (
+	SignalType TypeKind = iota
+	NodeType
+)
+
+func (k TypeKind) String() string {
+	switch k {
+	case SignalType:
+		return "signal type"
+	case NodeType:
+		return "node type"
+	}
+	return fmt.Sprintf("TypeKind(%d)", int(k))
+}
+
+// RenameType replaces all references in doc, stored as filename, to
+// the type oldName defined at definedAt by newName, count tells how many
+// were replaced. Qualified references keep their namespace. g resolves
+// unqualified names, doc is added to it unless added before.
+func RenameType(g *deps.Graph, doc backend.Document, filename string, kind TypeKind, definedAt, oldName, newName string) (count int) {
+	refersTo := referencesOf(g, doc, filename, kind, definedAt)
+	walk(doc, kind, func(name *string, definition bool) {
+		if !refersTo(*name, definition) {
+			return
+		}
+		renamed, ok := freesp.RenameTypeName(*name, definedAt, oldName, newName)
+		if ok {
+			*name = renamed
+			count++
+		}
+	})
+	return
+}
+
+// References counts the references in doc, stored as filename, to the
+// type name defined at definedAt, see RenameType.
+func References(g *deps.Graph, doc backend.Document, filename string, kind TypeKind, definedAt, name string) (count int) {
+	refersTo := referencesOf(g, doc, filename, kind, definedAt)
+	walk(doc, kind, func(n *string, definition bool) {
+		_, bare := freesp.SplitTypeName(*n)
+		if bare == name && refersTo(*n, definition) {
+			count++
+		}
+	})
+	return
+}
+
+// Counts the references in doc to types called name, of any library.
+func nameReferences(doc backend.Document, kind TypeKind, name string) (count int) {
+	walk(doc, kind, func(n *string, definition bool) {
+		_, bare := freesp.SplitTypeName(*n)
+		if bare == name {
+			count++
+		}
+	})
+	return
+}
+
+// Tells if a type name in doc refers to the type defined at definedAt.
+// Definitions do so only in the defining library itself.
+func referencesOf(g *deps.Graph, doc backend.Document, filename string, kind TypeKind, definedAt string) func(string, bool) bool {
+	g.Add(doc, filename)
+	namespace := freesp.LibraryNamespace(definedAt)
+	return func(typeName string, definition bool) bool {
+		if definition {
+			return freesp.LibraryNamespace(filename) == namespace
+		}
+		var lib string
+		var err error
+		if kind == SignalType {
+			lib, err = g.ResolveSignalType(filename, typeName)
+		} else {
+			lib, err = g.ResolveNodeType(filename, typeName)
+		}
+		return err == nil && freesp.LibraryNamespace(lib) == namespace
+	}
+}
+
+func walk(doc backend.Document, kind TypeKind, visit func(name *string, definition bool)) {
+	switch d := doc.(type) {
+	case *backend.XmlSignalGraph:
+		walkGraph(d, kind, visit)
+	case *backend.XmlLibrary:
+		if kind == SignalType {
+			for i := range d.SignalTypes {
+				st := &d.SignalTypes[i]
+				visit(&st.Name, true)
+				for k := range st.Field {
+					visit(&st.Field[k].FType, false)
+				}
+			}
+		}
+		for i := range d.NodeTypes {
+			nt := &d.NodeTypes[i]
+			if kind == NodeType {
+				visit(&nt.TypeName, true)
+			} else {
+				walkPorts(nt.InPort, nt.OutPort, visit)
+			}
+			for _, impl := range nt.Implementation {
+				for k := range impl.SignalGraph {
+					walkGraph(&impl.SignalGraph[k], kind, visit)
+				}
+			}
+		}
+		for i := range d.Compatible {
+			c := &d.Compatible[i]
+			if kind == SignalType {
+				visit(&c.From, false)
+				visit(&c.To, false)
+			} else if len(c.Converter) > 0 {
+				visit(&c.Converter, false)
+			}
+		}
+	}
+}
+
+func walkGraph(g *backend.XmlSignalGraph, kind TypeKind, visit func(*string, bool)) {
+	for _, n := range g.Nodes() {
+		if kind == NodeType {
+			visit(&n.NType, false)
+		} else {
+			walkPorts(n.InPort, n.OutPort, visit)
+		}
+	}
+}
+
+func walkPorts(in []backend.XmlInPort, out []backend.XmlOutPort, visit func(*string, bool)) {
+	for i := range in {
+		visit(&in[i].PType, false)
+	}
+	for i := range out {
+		visit(&out[i].PType, false)
+	}
+}
+
+// FileChange is the refactoring of one file: References tells how many
+// references were changed, Old and New are the complete contents before
+// and after.
+type FileChange struct {
+	Filename   string
+	References int
+	Old, New   []byte
+}
+
+func (c FileChange) Apply() error {
+	return tool.WriteFile(c.Filename, c.New)
+}
+
+func (c FileChange) Revert() error {
+	return tool.WriteFile(c.Filename, c.Old)
+}
+
+// RenameInFiles computes the changes of all files referring to the
+// type oldName defined at definedAt, g resolves the names (add open
+// documents to it first). Files are not written, see FileChange.Apply.
+func RenameInFiles(g *deps.Graph, files []string, kind TypeKind, definedAt, oldName, newName string) ([]FileChange, error) {
+	return changeFiles(files, "refactor.RenameInFiles", func(doc backend.Document, filename string) int {
+		return RenameType(g, doc, filename, kind, definedAt, oldName, newName)
+	})
+}
+
+// Applies change to each document in files, change returns the number
+// of changes made.
+func changeFiles(files []string, caller string, change func(backend.Document, string) int) (changes []FileChange, err error) {
+	// the files are not open in the editor, keep their unknown content
+	// apart from the editor's
+	pr := backend.PreserverNew()
+	for _, f := range files {
+		doc, ok := backend.DocumentNew(f)
+		if !ok {
+			continue
+		}
+		var data []byte
+		data, err = tool.ReadFile(f)
+		if err != nil {
+			err = fmt.Errorf("%s error: %v", caller, err)
+			return
+		}
+		_, err = pr.DocumentRead(doc, data, f)
+		if err != nil {
+			err = fmt.Errorf("%s error: %s: %v", caller, f, err)
+			return
+		}
+		count := change(doc, f)
+		if count == 0 {
+			continue
+		}
+		var out []byte
+		out, err = pr.DocumentFormat(doc, f)
+		if err != nil {
+			err = fmt.Errorf("%s error: %s: %v", caller, f, err)
+			return
+		}
+		if !bytes.Equal(data, out) {
+			changes = append(changes, FileChange{f, count, data, out})
+		}
+	}
+	return
+}
+
+// ProjectFiles lists the graphs and libraries of the current project,
+// the only documents which refer to types.
+func ProjectFiles() (files []string) {
+	p := backend.Project()
+	if p == nil {
+		return
+	}
+	for _, m := range p.Graphs {
+		files = append(files, backend.ProjectPath(m.Ref))
+	}
+	for _, m := range p.Libraries {
+		files = append(files, backend.ProjectPath(m.Ref))
+	}
+	return
+}
@@ -0,0 +1,267 @@
+package refactor
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const renameLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Filter">
+      <intype port="i" type="s"></intype>
+      <outtype port="o" type="s"></outtype>
+      <implementation name="g">
+         <signal-graph version="1.0">
+            <nodes>
+               <processing-node name="f" type="Filter2">
+                  <intype port="i" type="s"></intype>
+               </processing-node>
+            </nodes>
+         </signal-graph>
+      </implementation>
+   </node-type>
+   <node-type name="Filter2">
+      <intype port="i" type="t"></intype>
+   </node-type>
+</library>`
+
+const renameGraph = `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <nodes>
+      <input port="o" name="in" type="autoInputNodeType-s">
+         <outtype port="o" type="s"></outtype>
+      </input>
+      <processing-node name="f" type="Filter"></processing-node>
+      <processing-node name="f2" type="Filter2"></processing-node>
+   </nodes>
+</signal-graph>`
+
+func TestRenameType(t *testing.T) {
+	case1 := []struct {
+		filename, text   string
+		kind             TypeKind
+		oldName, newName string
+		count            int
+	}{
+		{"l.alml", renameLibrary, SignalType, "s", "sample", 4},
+		{"l.alml", renameLibrary, SignalType, "t", "sample", 0}, // not defined
+		{"l.alml", renameLibrary, NodeType, "Filter2", "Fir", 2},
+		{"l.alml", renameLibrary, NodeType, "s", "Fir", 0},
+		{"g.sml", renameGraph, SignalType, "s", "sample", 1},
+		{"g.sml", renameGraph, NodeType, "Filter", "Fir", 1},
+	}
+	for i, c := range case1 {
+		doc, ok := backend.DocumentNew(c.filename)
+		if !ok {
+			t.Fatalf("testcase %d failed: no document for %s\n", i, c.filename)
+		}
+		_, err := backend.DocumentRead(doc, []byte(c.text), c.filename)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		g := deps.GraphNew()
+		lib := &backend.XmlLibrary{}
+		_, err = backend.DocumentRead(lib, []byte(renameLibrary), "l.alml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Add(lib, "l.alml")
+		count := RenameType(g, doc, c.filename, c.kind, "l.alml", c.oldName, c.newName)
+		if count != c.count {
+			t.Errorf("testcase %d failed: %d references renamed, expected %d\n", i, count, c.count)
+		}
+		if References(g, doc, c.filename, c.kind, "l.alml", c.oldName) != 0 {
+			t.Errorf("testcase %d failed: references to %s left\n", i, c.oldName)
+		}
+		data, err := backend.DocumentWrite(doc, c.filename)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		if strings.Count(string(data), `"`+c.newName+`"`) != c.count {
+			t.Errorf("testcase %d failed: got\n%s\n", i, data)
+		}
+	}
+}
+
+// Both libraries define the signal type sample, only the one of vendora
+// is renamed.
+func TestRenameLibraries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	library := func(refs, types string) string {
+		return `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">` + refs + types + `
+</library>`
+	}
+	graph := func(ref, ports string) string {
+		return `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="` + ref + `"></library>
+   <nodes>
+      <input port="o" name="in" type="autoInputNodeType">` + ports + `</input>
+   </nodes>
+</signal-graph>`
+	}
+	files := map[string]string{
+		"vendora.alml": library(``, `
+   <signal-type name="sample" scope="" mode="" c-type="int" message-id=""></signal-type>`),
+		"vendorb.alml": library(`
+   <library ref="vendora.alml"></library>`, `
+   <signal-type name="sample" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <signal-type name="pair" scope="" mode="" c-type="" message-id="">
+      <field name="a" type="vendora::sample"></field>
+      <field name="b" type="vendorb::sample"></field>
+   </signal-type>`),
+		"ga.sml": graph("vendora.alml", `
+         <outtype port="o" type="sample"></outtype>
+         <outtype port="p" type="vendorb::sample"></outtype>`),
+		"gb.sml": graph("vendorb.alml", `
+         <outtype port="o" type="sample"></outtype>`),
+	}
+	var paths []string
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		err = ioutil.WriteFile(path, []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	changes, err := RenameInFiles(deps.GraphNew(), paths, SignalType, "vendora.alml", "sample", "smp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"vendora.alml": 1, "vendorb.alml": 1, "ga.sml": 1}
+	if len(changes) != len(expected) {
+		t.Errorf("%d files changed, expected %d\n", len(changes), len(expected))
+	}
+	for _, c := range changes {
+		name := filepath.Base(c.Filename)
+		if c.References != expected[name] {
+			t.Errorf("%s: %d references renamed, expected %d\n", name, c.References, expected[name])
+		}
+		for _, text := range map[string][]string{
+			"vendora.alml": {`name="smp"`},
+			"vendorb.alml": {`name="sample"`, `type="vendora::smp"`, `type="vendorb::sample"`},
+			"ga.sml":       {`type="smp"`, `type="vendorb::sample"`},
+		}[name] {
+			if !strings.Contains(string(c.New), text) {
+				t.Errorf("%s: %s missing, got\n%s\n", name, text, c.New)
+			}
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	m := Move{From: "a.alml", To: "b.alml",
+		Types:     []TypeRef{{NodeType, "Filter"}},
+		Remaining: []TypeRef{{NodeType, "Filter2"}, {SignalType, "s"}}}
+	graph := func(nodes string) string {
+		return `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="a.alml"></library>
+   <nodes>` + nodes + `</nodes>
+</signal-graph>`
+	}
+	case1 := []struct {
+		filename, text string
+		count          int
+		refs           []string
+	}{
+		{"g.sml", graph(`<processing-node name="f" type="Filter"></processing-node>`), 2, []string{"b.alml"}},
+		{"g.sml", graph(`<processing-node name="f" type="Filter"></processing-node>
+			<processing-node name="f2" type="Filter2"></processing-node>`), 1, []string{"a.alml", "b.alml"}},
+		{"g.sml", graph(`<processing-node name="f2" type="Filter2"></processing-node>`), 0, []string{"a.alml"}},
+		{"b.alml", renameLibrary, 0, nil},
+	}
+	for i, c := range case1 {
+		doc, ok := backend.DocumentNew(c.filename)
+		if !ok {
+			t.Fatalf("testcase %d failed: no document for %s\n", i, c.filename)
+		}
+		_, err := backend.DocumentRead(doc, []byte(c.text), c.filename)
+		if err != nil {
+			t.Errorf("testcase %d failed: %s\n", i, err)
+			continue
+		}
+		count := m.Apply(doc, c.filename)
+		if count != c.count {
+			t.Errorf("testcase %d failed: %d references changed, expected %d\n", i, count, c.count)
+		}
+		var refs []string
+		switch d := doc.(type) {
+		case *backend.XmlSignalGraph:
+			for _, r := range d.Libraries {
+				refs = append(refs, r.Name)
+			}
+		case *backend.XmlLibrary:
+			for _, r := range d.Libraries {
+				refs = append(refs, r.Name)
+			}
+		}
+		if strings.Join(refs, " ") != strings.Join(c.refs, " ") {
+			t.Errorf("testcase %d failed: references %v, expected %v\n", i, refs, c.refs)
+		}
+	}
+}
+
+// Directories a and b hold libraries of the same name, types move from
+// the one of a to the one of b.
+func TestMoveDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "move")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	graph := `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="lib.alml"></library>
+   <nodes>
+      <processing-node name="f" type="Filter"></processing-node>
+   </nodes>
+</signal-graph>`
+	for _, name := range []string{"a/lib.alml", "a/g.sml", "b/lib.alml", "b/g.sml"} {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		err = ioutil.WriteFile(path, []byte(graph), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := Move{From: "lib.alml", To: "lib.alml",
+		Types:    []TypeRef{{NodeType, "Filter"}},
+		FromPath: filepath.Join(root, "a/lib.alml"),
+		ToPath:   filepath.Join(root, "b/lib.alml")}
+	case1 := []struct {
+		filename string
+		count    int
+		refs     []string
+	}{
+		{"a/g.sml", 2, []string{"../b/lib.alml"}},
+		{"b/g.sml", 0, []string{"lib.alml"}},
+	}
+	for i, c := range case1 {
+		filename := filepath.Join(root, c.filename)
+		doc := backend.XmlSignalGraphNew()
+		_, err := backend.DocumentRead(doc, []byte(graph), filename)
+		if err != nil {
+			t.Fatalf("testcase %d failed: %s\n", i, err)
+		}
+		count := m.Apply(doc, filename)
+		if count != c.count {
+			t.Errorf("testcase %d failed: %d references changed, expected %d\n", i, count, c.count)
+		}
+		var refs []string
+		for _, r := range doc.Libraries {
+			refs = append(refs, r.Name)
+		}
+		if strings.Join(refs, " ") != strings.Join(c.refs, " ") {
+			t.Errorf("testcase %d failed: references %v, expected %v\n", i, refs, c.refs)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package freesp
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mod "github.com/axel-freesp/sge/interface/model"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	"github.com/axel-freesp/sge/tool"
+	"sort"
+	"sync"
+)
+
+// Registry keeps the signal, node and IO types and the libraries known
+// in a model context. Each context owns a registry, such that several
+// workspaces can be loaded in one process. Registries may be read from
+// concurrent goroutines; changes have to be serialized with all other
+// accesses by the caller (as done by the job list of the editor).
+type Registry struct {
+	mutex sync.RWMutex
+	// Types are registered by name, with one entry per defining library
+	// (see ResolveSignalType for the resolution of names).
+	signalTypes           map[string][]bh.SignalTypeIf
+	nodeTypes             map[string][]bh.NodeTypeIf
+	libraries             map[string]bh.LibraryIf
+	libraryPaths          map[string]bh.LibraryIf // loaded libraries by absolute path
+	ioTypes               map[string]pf.IOTypeIf
+	registeredNodeTypes   tool.StringList
+	registeredSignalTypes tool.StringList
+	registeredIOTypes     tool.StringList
+	// Former names of renamed types, such that documents and snippets
+	// still using them can be read.
+	signalTypeAliases map[string]string
+	nodeTypeAliases   map[string]string
+}
+
+func RegistryNew() *Registry {
+	return &Registry{
+		signalTypes:           make(map[string][]bh.SignalTypeIf),
+		nodeTypes:             make(map[string][]bh.NodeTypeIf),
+		libraries:             make(map[string]bh.LibraryIf),
+		libraryPaths:          make(map[string]bh.LibraryIf),
+		ioTypes:               make(map[string]pf.IOTypeIf),
+		registeredNodeTypes:   tool.StringListInit(),
+		registeredSignalTypes: tool.StringListInit(),
+		registeredIOTypes:     tool.StringListInit(),
+		signalTypeAliases:     make(map[string]string),
+		nodeTypeAliases:       make(map[string]string),
+	}
+}
+
+// RegistryOf returns the registry owned by context, or the default
+// registry for contexts without one (and nil contexts).
+func RegistryOf(context mod.ModelContextIf) *Registry {
+	owner, ok := context.(interface {
+		Registry() *Registry
+	})
+	if ok && owner.Registry() != nil {
+		return owner.Registry()
+	}
+	return DefaultRegistry()
+}
+
+// PreserverOf returns the preserver of unknown XML content owned by
+// context, or the default preserver of the editor.
+func PreserverOf(context mod.ModelContextIf) *backend.Preserver {
+	owner, ok := context.(interface {
+		Preserver() *backend.Preserver
+	})
+	if ok {
+		return owner.Preserver()
+	}
+	return backend.DefaultPreserver()
+}
+
+// Registered types, by name. Names of types defined by several
+// libraries are qualified.
+func (r *Registry) GetRegisteredNodeTypes() (ret []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, name := range r.registeredNodeTypes.Strings() {
+		list := r.nodeTypes[name]
+		if len(list) == 1 {
+			ret = append(ret, name)
+			continue
+		}
+		for _, nt := range list {
+			ret = append(ret, QualifiedTypeName(nt.DefinedAt(), name))
+		}
+	}
+	return
+}
+
+func (r *Registry) GetRegisteredSignalTypes() (ret []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, name := range r.registeredSignalTypes.Strings() {
+		list := r.signalTypes[name]
+		if len(list) == 1 {
+			ret = append(ret, name)
+			continue
+		}
+		for _, st := range list {
+			ret = append(ret, QualifiedTypeName(st.DefinedAt(), name))
+		}
+	}
+	return
+}
+
+func (r *Registry) GetNodeTypeByName(typeName string) (nType bh.NodeTypeIf, ok bool) {
+	nType, err := r.ResolveNodeType(typeName, nil)
+	ok = (err == nil)
+	return
+}
+
+func (r *Registry) GetSignalTypeByName(typeName string) (sType bh.SignalTypeIf, ok bool) {
+	sType, err := r.ResolveSignalType(typeName, nil)
+	ok = (err == nil)
+	return
+}
+
+// ResolveSignalType returns the signal type typeName refers to. Scope
+// are the filenames of the libraries visible where typeName is used
+// (nil for all libraries).
+func (r *Registry) ResolveSignalType(typeName string, scope map[string]bool) (sType bh.SignalTypeIf, err error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	namespace, name := SplitTypeName(typeName)
+	list, ok := r.signalTypes[name]
+	if !ok {
+		name = r.signalTypeAliases[name]
+		list = r.signalTypes[name]
+	}
+	var definedAt []string
+	for _, st := range list {
+		definedAt = append(definedAt, st.DefinedAt())
+	}
+	var i int
+	i, err = resolve("signal type", typeName, namespace, name, definedAt, scope)
+	if err == nil {
+		sType = list[i]
+	}
+	return
+}
+
+// ResolveNodeType returns the node type typeName refers to, see
+// ResolveSignalType.
+func (r *Registry) ResolveNodeType(typeName string, scope map[string]bool) (nType bh.NodeTypeIf, err error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	namespace, name := SplitTypeName(typeName)
+	list, ok := r.nodeTypes[name]
+	if !ok {
+		name = r.nodeTypeAliases[name]
+		list = r.nodeTypes[name]
+	}
+	var definedAt []string
+	for _, nt := range list {
+		definedAt = append(definedAt, nt.DefinedAt())
+	}
+	var i int
+	i, err = resolve("node type", typeName, namespace, name, definedAt, scope)
+	if err == nil {
+		nType = list[i]
+	}
+	return
+}
+
+// Name to refer to st by: qualified if several libraries define a
+// signal type of that name.
+func (r *Registry) SignalTypeRefName(st bh.SignalTypeIf) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if len(r.signalTypes[st.TypeName()]) > 1 {
+		return QualifiedTypeName(st.DefinedAt(), st.TypeName())
+	}
+	return st.TypeName()
+}
+
+func (r *Registry) NodeTypeRefName(nt bh.NodeTypeIf) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if len(r.nodeTypes[nt.TypeName()]) > 1 {
+		return QualifiedTypeName(nt.DefinedAt(), nt.TypeName())
+	}
+	return nt.TypeName()
+}
+
+func (r *Registry) GetLibraryByName(filename string) (lib bh.LibraryIf, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	lib, ok = r.libraries[filename]
+	return
+}
+
+// GetLibraryByPath returns the library loaded from path (absolute, see
+// backend.RefResolution.AbsPath).
+func (r *Registry) GetLibraryByPath(path string) (lib bh.LibraryIf, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	lib, ok = r.libraryPaths[path]
+	return
+}
+
+// Registered libraries, ordered by filename.
+func (r *Registry) GetRegisteredLibraries() (ret []bh.LibraryIf) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var names []string
+	for name := range r.libraries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ret = append(ret, r.libraries[name])
+	}
+	return
+}
+
+func (r *Registry) GetRegisteredIOTypes() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]string(nil), r.registeredIOTypes.Strings()...)
+}
+
+func (r *Registry) GetIOTypeByName(name string) (ioType pf.IOTypeIf, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ioType, ok = r.ioTypes[name]
+	return
+}
+
+func (r *Registry) RegisterSignalType(st bh.SignalTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.registerSignalType(st)
+}
+
+func (r *Registry) registerSignalType(st bh.SignalTypeIf) {
+	name := st.TypeName()
+	for i, t := range r.signalTypes[name] {
+		if t == st {
+			return
+		}
+		// replaces a former definition by the same library
+		if t.DefinedAt() == st.DefinedAt() {
+			r.signalTypes[name][i] = st
+			return
+		}
+	}
+	r.signalTypes[name] = append(r.signalTypes[name], st)
+	if _, ok := r.registeredSignalTypes.Find(name); !ok {
+		r.registeredSignalTypes.Append(name)
+	}
+}
+
+func (r *Registry) RegisterNodeType(nt bh.NodeTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.registerNodeType(nt)
+}
+
+func (r *Registry) registerNodeType(nt bh.NodeTypeIf) {
+	name := nt.TypeName()
+	for i, t := range r.nodeTypes[name] {
+		if t == nt {
+			return
+		}
+		// replaces a former definition by the same library
+		if t.DefinedAt() == nt.DefinedAt() {
+			r.nodeTypes[name][i] = nt
+			return
+		}
+	}
+	r.nodeTypes[name] = append(r.nodeTypes[name], nt)
+	if _, ok := r.registeredNodeTypes.Find(name); !ok {
+		r.registeredNodeTypes.Append(name)
+	}
+}
+
+// Register st, formerly registered as oldName, by its current name.
+// oldName stays an alias of st. Unregistered types are ignored.
+func (r *Registry) RenameRegisteredSignalType(st bh.SignalTypeIf, oldName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.unregisterSignalType(st, oldName) {
+		return
+	}
+	r.registerSignalType(st)
+	renameAlias(r.signalTypeAliases, oldName, st.TypeName())
+}
+
+// Register nt, formerly registered as oldName, by its current name.
+// oldName stays an alias of nt. Unregistered types are ignored.
+func (r *Registry) RenameRegisteredNodeType(nt bh.NodeTypeIf, oldName string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.unregisterNodeType(nt, oldName) {
+		return
+	}
+	r.registerNodeType(nt)
+	renameAlias(r.nodeTypeAliases, oldName, nt.TypeName())
+}
+
+func (r *Registry) unregisterSignalType(st bh.SignalTypeIf, name string) bool {
+	list := r.signalTypes[name]
+	for i, t := range list {
+		if t != st {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		if len(list) > 0 {
+			r.signalTypes[name] = list
+		} else {
+			delete(r.signalTypes, name)
+			r.registeredSignalTypes.Remove(name)
+		}
+		return true
+	}
+	return false
+}
+
+func (r *Registry) unregisterNodeType(nt bh.NodeTypeIf, name string) bool {
+	list := r.nodeTypes[name]
+	for i, t := range list {
+		if t != nt {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		if len(list) > 0 {
+			r.nodeTypes[name] = list
+		} else {
+			delete(r.nodeTypes, name)
+			r.registeredNodeTypes.Remove(name)
+		}
+		return true
+	}
+	return false
+}
+
+func renameAlias(aliases map[string]string, oldName, newName string) {
+	for a, n := range aliases {
+		if n == oldName {
+			aliases[a] = newName
+		}
+	}
+	aliases[oldName] = newName
+	// renamed back
+	delete(aliases, newName)
+}
+
+func (r *Registry) RegisterLibrary(lib bh.LibraryIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.libraries[lib.Filename()] = lib
+}
+
+// RegisterLibraryPath records that lib was loaded from path
+// (absolute). Libraries are identified by filename otherwise, two
+// libraries of the same filename cannot be registered.
+func (r *Registry) RegisterLibraryPath(lib bh.LibraryIf, path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.libraryPaths[path] = lib
+}
+
+// LibraryPath returns the path lib was loaded from, if any.
+func (r *Registry) LibraryPath(lib bh.LibraryIf) (path string, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for p, l := range r.libraryPaths {
+		if l == lib {
+			return p, true
+		}
+	}
+	return
+}
+
+func (r *Registry) RegisterIOType(iot pf.IOTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ioTypes[iot.Name()] = iot
+	r.registeredIOTypes.Append(iot.Name())
+}
+
+func (r *Registry) RemoveRegisteredLibrary(lib bh.LibraryIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.libraries, lib.Filename())
+	for p, l := range r.libraryPaths {
+		if l == lib {
+			delete(r.libraryPaths, p)
+		}
+	}
+}
+
+func (r *Registry) RemoveRegisteredNodeType(nt bh.NodeTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.unregisterNodeType(nt, nt.TypeName())
+}
+
+func (r *Registry) RemoveRegisteredSignalType(st bh.SignalTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.unregisterSignalType(st, st.TypeName())
+}
+
+func (r *Registry) RemoveRegisteredIOType(iot pf.IOTypeIf) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := iot.Name()
+	delete(r.ioTypes, name)
+	_, ok := r.registeredIOTypes.Find(name)
+	if ok {
+		r.registeredIOTypes.Remove(name)
+	}
+}
@@ -0,0 +1,114 @@
+package freesp
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/tool"
+	"strings"
+)
+
+// Type names may be qualified by the namespace of the defining
+// library, its filename without directory and suffix: "vendor::sample"
+// is the type sample of library vendor.alml.
+//
+// Qualified names refer to the type of that library. An unqualified
+// name refers to the only type of that name; if several libraries
+// define it, only those in scope (the libraries a document refers to,
+// directly or indirectly) are considered, and more than one of them is
+// an ambiguity error.
+
+const NamespaceSeparator = "::"
+
+func LibraryNamespace(filename string) string {
+	return tool.DocPrefix(tool.Basename(filename))
+}
+
+// Qualified name of the type name defined at definedAt. Types not
+// defined by a library have no namespace.
+func QualifiedTypeName(definedAt, name string) string {
+	if len(definedAt) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s%s%s", LibraryNamespace(definedAt), NamespaceSeparator, name)
+}
+
+func SplitTypeName(typeName string) (namespace, name string) {
+	idx := strings.Index(typeName, NamespaceSeparator)
+	if idx < 0 {
+		return "", typeName
+	}
+	return typeName[:idx], typeName[idx+len(NamespaceSeparator):]
+}
+
+// AmbiguousNameError is returned for unqualified names matching the
+// types of several libraries.
+type AmbiguousNameError struct {
+	Kind       string // "signal type" or "node type"
+	Name       string
+	Candidates []string // qualified names
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%s %s is ambiguous: %s", e.Kind, e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// Chooses among the definitions of name (given by the libraries
+// defining them) the one typeName refers to.
+func resolve(kind, typeName, namespace, name string, definedAt []string, scope map[string]bool) (index int, err error) {
+	var candidates []int
+	for i, d := range definedAt {
+		if len(namespace) == 0 || LibraryNamespace(d) == namespace {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(namespace) == 0 && len(candidates) > 1 && scope != nil {
+		var inScope []int
+		for _, i := range candidates {
+			if scope[definedAt[i]] {
+				inScope = append(inScope, i)
+			}
+		}
+		if len(inScope) > 0 {
+			candidates = inScope
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		err = fmt.Errorf("unknown %s %s", kind, typeName)
+	case 1:
+		index = candidates[0]
+	default:
+		e := &AmbiguousNameError{Kind: kind, Name: typeName}
+		for _, i := range candidates {
+			e.Candidates = append(e.Candidates, QualifiedTypeName(definedAt[i], name))
+		}
+		err = e
+	}
+	return
+}
+
+// ResolveTypeName chooses among the libraries definedAt defining a
+// type of the name of typeName the one typeName refers to, for
+// documents outside the registry (see Registry.ResolveSignalType).
+func ResolveTypeName(kind, typeName string, definedAt []string, scope map[string]bool) (index int, err error) {
+	namespace, name := SplitTypeName(typeName)
+	return resolve(kind, typeName, namespace, name, definedAt, scope)
+}
+
+// RenameTypeName replaces the name part oldName of typeName by newName,
+// keeping the namespace. ok tells if typeName may refer to oldName as
+// defined at definedAt: names qualified by the namespace of another
+// library do not. Callers resolve unqualified names themselves, they
+// may refer to the type of another library.
+func RenameTypeName(typeName, definedAt, oldName, newName string) (renamed string, ok bool) {
+	namespace, name := SplitTypeName(typeName)
+	if name != oldName {
+		return typeName, false
+	}
+	if len(namespace) == 0 {
+		return newName, true
+	}
+	if namespace != LibraryNamespace(definedAt) {
+		return typeName, false
+	}
+	return namespace + NamespaceSeparator + newName, true
+}
@@ -0,0 +1,255 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"strings"
+)
+
+// Validation checks documents as they are in memory, such that the
+// editor can list the problems of all open documents after each change.
+// Errors make a document unusable (e.g. connections of mismatching
+// signal types), warnings point to likely mistakes.
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Problem found in document Filename. Location names the offending
+// element like xref.Usage.Location does, Object is the element itself
+// (nil if there is none to select). Rule is the lint rule reporting
+// the problem (see package lint), empty for validation problems.
+type Problem struct {
+	Severity Severity
+	Filename string
+	Location string
+	Object   tr.TreeElementIf
+	Message  string
+	Rule     string
+}
+
+func (p Problem) String() string {
+	if len(p.Rule) > 0 {
+		return fmt.Sprintf("%s: %s: %s: %s [%s]", p.Filename, p.Location, p.Severity, p.Message, p.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", p.Filename, p.Location, p.Severity, p.Message)
+}
+
+// SeverityFromString is the inverse of Severity.String.
+func SeverityFromString(s string) (severity Severity, ok bool) {
+	switch s {
+	case "error":
+		return Error, true
+	case "warning":
+		return Warning, true
+	}
+	return
+}
+
+// Documents returns the problems of docs, in the order of docs.
+func Documents(docs []tr.ToplevelTreeElementIf) (problems []Problem) {
+	for _, doc := range docs {
+		problems = append(problems, Document(doc)...)
+	}
+	return
+}
+
+// Document returns the problems of a signal graph, library, platform
+// or mapping document, starting with the warnings found while loading
+// it (see freesp.Diagnostics).
+func Document(doc tr.ToplevelTreeElementIf) []Problem {
+	c := &checker{filename: doc.Filename()}
+	for _, w := range freesp.LoadWarnings(doc) {
+		c.report(Warning, "", doc, "%s", w)
+	}
+	switch d := doc.(type) {
+	case bh.SignalGraphIf:
+		c.graph(d.ItsType(), "", nil)
+	case bh.LibraryIf:
+		c.library(d)
+	case pf.PlatformIf:
+		c.platform(d)
+	case mp.MappingIf:
+		c.mapping(d)
+	}
+	return c.problems
+}
+
+type checker struct {
+	filename string
+	problems []Problem
+}
+
+func (c *checker) report(severity Severity, location string, obj tr.TreeElementIf, format string, args ...interface{}) {
+	if len(location) == 0 {
+		location = "document"
+	}
+	c.problems = append(c.problems, Problem{severity, c.filename, location, obj, fmt.Sprintf(format, args...), ""})
+}
+
+// At appends the element what name to location, in the notation of
+// Problem.Location.
+func At(location, what, name string) string {
+	if len(location) == 0 {
+		return fmt.Sprintf("%s %s", what, name)
+	}
+	return fmt.Sprintf("%s / %s %s", location, what, name)
+}
+
+// Checks g, which is the graph of a signal graph document (outer nil)
+// or an implementation of node type outer.
+func (c *checker) graph(g bh.SignalGraphTypeIf, location string, outer bh.NodeTypeIf) {
+	isIO := make(map[bh.NodeIf]bool)
+	for _, n := range append(g.InputNodes(), g.OutputNodes()...) {
+		isIO[n] = true
+	}
+	for _, n := range g.Nodes() {
+		nloc := At(location, "node", n.Name())
+		if n.ItsType() == nil {
+			c.report(Error, nloc, n, "node type undefined")
+			continue
+		}
+		if isIO[n] {
+			if _, linked := n.PortLink(); outer != nil && !linked {
+				c.report(Warning, nloc, n, "not linked to a port of node type %s", outer.TypeName())
+			}
+		} else if err := behaviour.CheckNodeParamValues(n, outer); err != nil {
+			c.report(Error, nloc, n, "%s", err)
+		}
+		for _, p := range append(n.InPorts(), n.OutPorts()...) {
+			c.port(p, At(nloc, "port", p.Name()))
+		}
+	}
+}
+
+func (c *checker) port(p bh.PortIf, location string) {
+	if p.SignalType() == nil {
+		c.report(Error, location, p, "signal type undefined")
+	}
+	for _, q := range p.Connections() {
+		var obj tr.TreeElementIf = p
+		conn, err := p.Connection(q)
+		if err == nil {
+			obj = conn
+		}
+		if !isConnected(q, p) {
+			c.report(Error, location, obj, "connection to %s/%s missing at the other end", q.Node().Name(), q.Name())
+			continue
+		}
+		// each connection is checked once, at its output port
+		if q.Direction() == p.Direction() {
+			c.report(Error, location, obj, "connected to %s/%s of the same direction", q.Node().Name(), q.Name())
+			continue
+		}
+		if p.Direction() != gr.OutPort || p.SignalType() == nil || q.SignalType() == nil {
+			continue
+		}
+		if !behaviour.SignalTypesCompatible(p.SignalType(), q.SignalType()) {
+			c.report(Error, location, obj, "signal type %s does not match %s of %s/%s",
+				p.SignalType().TypeName(), q.SignalType().TypeName(), q.Node().Name(), q.Name())
+		}
+	}
+}
+
+func isConnected(p, q bh.PortIf) bool {
+	for _, r := range p.Connections() {
+		if r == q {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) library(l bh.LibraryIf) {
+	for _, nt := range l.NodeTypes() {
+		ntloc := At("", "node type", nt.TypeName())
+		for _, p := range append(nt.InPorts(), nt.OutPorts()...) {
+			if p.SignalType() == nil {
+				c.report(Error, At(ntloc, "port", p.Name()), p, "signal type undefined")
+			}
+		}
+		if cycle := behaviour.NodeTypeCycle(nt); cycle != nil {
+			c.report(Error, ntloc, nt, "instantiates itself: %s", strings.Join(cycle, " -> "))
+			// the implementation graphs would be expanded without end
+			continue
+		}
+		for _, impl := range nt.Implementation() {
+			if impl.ImplementationType() != bh.NodeTypeGraph {
+				continue
+			}
+			iloc := At(ntloc, "implementation", impl.ElementName())
+			g := impl.Graph()
+			if g == nil {
+				c.report(Error, iloc, impl, "graph %s not available", impl.GraphRef())
+				continue
+			}
+			// referenced graphs are checked as documents of their own
+			if len(impl.GraphRef()) == 0 {
+				c.graph(g, iloc, nt)
+			}
+		}
+	}
+}
+
+func (c *checker) platform(p pf.PlatformIf) {
+	for _, a := range p.Arch() {
+		aloc := At("", "arch", a.Name())
+		for _, pr := range a.Processes() {
+			prloc := At(aloc, "process", pr.Name())
+			for _, ch := range append(pr.InChannels(), pr.OutChannels()...) {
+				chloc := At(prloc, "channel", ch.Name())
+				if ch.IOType() == nil {
+					c.report(Error, chloc, ch, "io type undefined")
+				}
+				if ch.Link() == nil {
+					c.report(Warning, chloc, ch, "not linked to a channel of another process")
+				}
+			}
+		}
+	}
+}
+
+func (c *checker) mapping(m mp.MappingIf) {
+	if m.Graph() == nil {
+		c.report(Error, "", m, "signal graph undefined")
+		return
+	}
+	if m.Platform() == nil {
+		c.report(Error, "", m, "platform undefined")
+		return
+	}
+	for _, id := range m.MappedIds() {
+		melem, ok := m.MappedElement(id)
+		if !ok {
+			continue
+		}
+		loc := At("", "node", id.String())
+		p, ok := melem.Process()
+		if !ok {
+			c.report(Warning, loc, melem, "not mapped to a process")
+			continue
+		}
+		if p.Arch() == nil || p.Arch().Platform() != m.Platform() {
+			c.report(Error, loc, melem, "process %s not in platform %s", p.Name(), m.Platform().Filename())
+		}
+	}
+}
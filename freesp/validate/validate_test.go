@@ -0,0 +1,84 @@
+package validate
+
+import (
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/headless"
+	"strings"
+	"testing"
+)
+
+const validateLibrary = `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="a" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <signal-type name="b" scope="" mode="" c-type="float" message-id=""></signal-type>
+   <node-type name="Src">
+      <outtype port="o" type="a"></outtype>
+   </node-type>
+   <node-type name="Fir">
+      <intype port="i" type="a"></intype>
+      <outtype port="o" type="a"></outtype>
+      <parameter name="taps" type="int" min="1" max="48"></parameter>
+   </node-type>
+</library>`
+
+func TestValidate(t *testing.T) {
+	freesp.Init()
+	c := headless.ContextNew("")
+	lib := behaviour.LibraryNew("validate.alml", c)
+	_, err := lib.Read([]byte(validateLibrary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := Document(lib); len(problems) != 0 {
+		t.Errorf("problems in valid library: %v\n", problems)
+	}
+
+	sg := behaviour.SignalGraphNew("validate.sml", c)
+	g := sg.ItsType()
+	src, _ := behaviour.NodeNew("src", lib.NodeTypes()[0], g)
+	fir, _ := behaviour.NodeNew("fir", lib.NodeTypes()[1], g)
+	g.AddNode(src)
+	g.AddNode(fir)
+	err = src.OutPorts()[0].AddConnection(behaviour.ConnectionNew(src.OutPorts()[0], fir.InPorts()[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := Document(sg)
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, "parameter taps not set") {
+		t.Fatalf("wrong problems %v\n", problems)
+	}
+	if problems[0].Severity != Error || problems[0].Object != fir || problems[0].Location != "node fir" {
+		t.Errorf("wrong problem %v\n", problems[0])
+	}
+	fir.SetParamValue("taps", "8")
+	if problems := Document(sg); len(problems) != 0 {
+		t.Errorf("problems in valid graph: %v\n", problems)
+	}
+
+	// changing the port type leaves an invalid connection
+	b, _ := freesp.GetSignalTypeByName("b")
+	lib.NodeTypes()[1].InPorts()[0].SetSignalType(b)
+	problems = Document(sg)
+	if len(problems) != 1 || problems[0].Location != "node src / port o" ||
+		problems[0].Message != "signal type a does not match b of fir/i" {
+		t.Errorf("wrong problems %v\n", problems)
+	}
+
+	// warnings found while loading hints are problems of the document
+	hint := backend.XmlGraphHintNew(sg.Filename())
+	xmln := backend.XmlNodePosHintNew("fir")
+	xmln.Entry = append(xmln.Entry, *backend.XmlModeHintEntryNew("bogus", 1, 2))
+	xmln.InPorts = append(xmln.InPorts, *backend.XmlPortPosHintNew("i"))
+	hint.ProcessingNode = append(hint.ProcessingNode, *xmln)
+	err = behaviour.SignalGraphApplyHints(sg, hint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems = Document(sg)
+	if len(problems) != 3 || problems[0].Severity != Warning || problems[0].Location != "document" ||
+		problems[0].Message != "hint mode bogus not defined" ||
+		problems[1].Message != "hints for node fir: no hint for output port o" {
+		t.Fatalf("wrong problems %v\n", problems)
+	}
+}
@@ -0,0 +1,300 @@
+package xref
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/tool"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The cross-reference index lists where node types, signal types and
+// documents are referred to: node types by nodes, signal types by ports
+// of nodes and node types and by fields of signal types, graphs by mappings and graph implementations,
+// platforms by mappings, libraries by graphs and libraries. It is built from the documents themselves,
+// their files need not be open.
+
+type Kind int
+
+const (
+	NodeTypeRef Kind = iota
+	SignalTypeRef
+	GraphRef
+	PlatformRef
+	LibraryRef
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NodeTypeRef:
+		return "node type"
+	case SignalTypeRef:
+		return "signal type"
+	case GraphRef:
+		return "signal graph"
+	case PlatformRef:
+		return "platform"
+	case LibraryRef:
+		return "library"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Usage is a single reference. Name is the referenced type, or the
+// absolute path of the referenced document if it could be resolved.
+// The remaining fields locate the reference in Filename, empty ones do
+// not apply: a node of the graph or of implementation Impl of node type
+// NodeType, a port of that node or node type, a field of signal type
+// SignalType, or a compatibility declaration ("from -> to").
+type Usage struct {
+	Kind          Kind
+	Name          string
+	Filename      string
+	NodeType      string
+	Impl          string
+	Node          string
+	Port          string
+	SignalType    string
+	Field         string
+	Compatibility string
+}
+
+func (u Usage) Location() string {
+	var parts []string
+	add := func(what, name string) {
+		if len(name) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", what, name))
+		}
+	}
+	add("node type", u.NodeType)
+	add("implementation", u.Impl)
+	add("node", u.Node)
+	add("port", u.Port)
+	add("signal type", u.SignalType)
+	add("field", u.Field)
+	add("compatibility", u.Compatibility)
+	if len(parts) == 0 {
+		return "document"
+	}
+	return strings.Join(parts, " / ")
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%s: %s", u.Filename, u.Location())
+}
+
+type key struct {
+	kind Kind
+	name string
+}
+
+type Index struct {
+	usages map[key][]Usage
+	files  map[string]bool
+}
+
+func IndexNew() *Index {
+	return &Index{make(map[key][]Usage), make(map[string]bool)}
+}
+
+// Contains tells if the document stored as filename has been indexed.
+func (x *Index) Contains(filename string) bool {
+	return x.files[backend.AbsPath(filename)]
+}
+
+// Usages lists the references to name, ordered by file and location.
+// Documents are named by their path, or by the text of unresolved
+// references.
+func (x *Index) Usages(kind Kind, name string) (list []Usage) {
+	list = append(list, x.usages[key{kind, name}]...)
+	if kind == GraphRef || kind == PlatformRef || kind == LibraryRef {
+		if abs := backend.AbsPath(name); abs != name {
+			list = append(list, x.usages[key{kind, abs}]...)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Filename != list[j].Filename {
+			return list[i].Filename < list[j].Filename
+		}
+		return list[i].Location() < list[j].Location()
+	})
+	return
+}
+
+// Add indexes doc, stored as filename. Documents are indexed once,
+// later versions are ignored: add open documents first to index them
+// instead of their files.
+func (x *Index) Add(doc backend.Document, filename string) {
+	filename = backend.AbsPath(filename)
+	if x.files[filename] {
+		return
+	}
+	x.files[filename] = true
+	dir := filepath.Dir(filename)
+	switch d := doc.(type) {
+	case *backend.XmlSignalGraph:
+		x.addLibraryRefs(d.Libraries, filename, dir)
+		x.addGraph(d, Usage{Filename: filename})
+	case *backend.XmlLibrary:
+		x.addLibraryRefs(d.Libraries, filename, dir)
+		for _, st := range d.SignalTypes {
+			for _, f := range st.Field {
+				x.add(Usage{Kind: SignalTypeRef, Name: f.FType, Filename: filename,
+					SignalType: st.Name, Field: f.Name})
+			}
+		}
+		for _, nt := range d.NodeTypes {
+			at := Usage{Filename: filename, NodeType: nt.TypeName}
+			x.addPorts(nt.InPort, nt.OutPort, at)
+			for _, impl := range nt.Implementation {
+				at.Impl = impl.Name
+				if len(impl.GraphRef) > 0 {
+					u := at
+					u.Kind, u.Name = GraphRef, resolve(impl.GraphRef, dir)
+					x.add(u)
+				}
+				for k := range impl.SignalGraph {
+					x.addGraph(&impl.SignalGraph[k], at)
+				}
+			}
+		}
+		for _, c := range d.Compatible {
+			at := Usage{Filename: filename, Compatibility: fmt.Sprintf("%s -> %s", c.From, c.To)}
+			for _, name := range []string{c.From, c.To} {
+				u := at
+				u.Kind, u.Name = SignalTypeRef, name
+				x.add(u)
+			}
+			if len(c.Converter) > 0 {
+				u := at
+				u.Kind, u.Name = NodeTypeRef, c.Converter
+				x.add(u)
+			}
+		}
+	case *backend.XmlMapping:
+		x.add(Usage{Kind: GraphRef, Name: resolve(d.SignalGraph, dir), Filename: filename})
+		x.add(Usage{Kind: PlatformRef, Name: resolve(d.Platform, dir), Filename: filename})
+	}
+}
+
+// AddFile reads and indexes a document, hint and project files are
+// ignored.
+func (x *Index) AddFile(filename string) (err error) {
+	if x.Contains(filename) {
+		return
+	}
+	doc, ok := backend.DocumentNew(filename)
+	if !ok {
+		return
+	}
+	switch doc.(type) {
+	case *backend.XmlSignalGraph, *backend.XmlLibrary, *backend.XmlMapping:
+	default:
+		return
+	}
+	data, err := tool.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("Index.AddFile error: %v", err)
+		return
+	}
+	// documents are only read, their unknown content is not kept
+	var keepNothing *backend.Preserver
+	_, err = keepNothing.DocumentRead(doc, data, filename)
+	if err != nil {
+		err = fmt.Errorf("Index.AddFile error: %s: %v", filename, err)
+		return
+	}
+	x.Add(doc, filename)
+	return
+}
+
+// AddDir indexes all documents below dir, except in hidden directories.
+// Unreadable documents are skipped with a warning.
+func (x *Index) AddDir(dir string) (err error) {
+	return tool.WalkFiles(dir, func(p string) error {
+		e := x.AddFile(p)
+		if e != nil {
+			log.Printf("Index.AddDir warning: %s\n", e)
+		}
+		return nil
+	})
+}
+
+// AddSearchPath indexes the members of the current project and all
+// documents along the search path.
+func (x *Index) AddSearchPath() {
+	if p := backend.Project(); p != nil {
+		for _, list := range [][]backend.XmlProjectMember{p.Graphs, p.Libraries, p.Mappings} {
+			for _, m := range list {
+				err := x.AddFile(backend.ProjectPath(m.Ref))
+				if err != nil {
+					log.Printf("Index.AddSearchPath warning: %s\n", err)
+				}
+			}
+		}
+	}
+	for _, dir := range backend.XmlSearchPaths() {
+		if len(dir) == 0 {
+			continue
+		}
+		err := x.AddDir(dir)
+		if err != nil {
+			log.Printf("Index.AddSearchPath warning: %s\n", err)
+		}
+	}
+}
+
+// Type references are indexed by name, without library namespace.
+func (x *Index) add(u Usage) {
+	if u.Kind == SignalTypeRef || u.Kind == NodeTypeRef {
+		_, u.Name = freesp.SplitTypeName(u.Name)
+	}
+	k := key{u.Kind, u.Name}
+	x.usages[k] = append(x.usages[k], u)
+}
+
+func (x *Index) addLibraryRefs(refs []backend.XmlLibraryRef, filename, dir string) {
+	for _, r := range refs {
+		x.add(Usage{Kind: LibraryRef, Name: resolve(r.Name, dir), Filename: filename})
+	}
+}
+
+func (x *Index) addGraph(g *backend.XmlSignalGraph, at Usage) {
+	for _, n := range g.Nodes() {
+		u := at
+		u.Node = n.NName
+		u.Kind, u.Name = NodeTypeRef, n.NType
+		x.add(u)
+		x.addPorts(n.InPort, n.OutPort, u)
+	}
+}
+
+func (x *Index) addPorts(in []backend.XmlInPort, out []backend.XmlOutPort, at Usage) {
+	at.Kind = SignalTypeRef
+	for _, p := range in {
+		u := at
+		u.Name, u.Port = p.PType, p.PName
+		x.add(u)
+	}
+	for _, p := range out {
+		u := at
+		u.Name, u.Port = p.PType, p.PName
+		x.add(u)
+	}
+}
+
+// Documents are referred to by the file the reference resolves to,
+// unresolved references keep their text.
+func resolve(ref, dir string) string {
+	if len(ref) == 0 {
+		return ref
+	}
+	r, err := backend.ResolveRef(ref, dir)
+	if err != nil {
+		return ref
+	}
+	return r.AbsPath()
+}
@@ -0,0 +1,91 @@
+package xref
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var xrefFiles = map[string]string{
+	"lib/filters.alml": `<library xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <signal-type name="s" scope="" mode="" c-type="int" message-id=""></signal-type>
+   <node-type name="Filter">
+      <intype port="i" type="s"></intype>
+      <outtype port="o" type="s"></outtype>
+   </node-type>
+   <node-type name="Chain">
+      <implementation name="g">
+         <signal-graph version="1.0">
+            <nodes>
+               <processing-node name="f1" type="Filter"></processing-node>
+            </nodes>
+         </signal-graph>
+      </implementation>
+      <implementation name="ext" graph="g.sml"></implementation>
+   </node-type>
+</library>`,
+	"lib/g.sml": `<signal-graph xmlns="http://www.freesp.de/xml/freeSP" version="1.0">
+   <library ref="filters.alml"></library>
+   <nodes>
+      <processing-node name="f" type="Filter">
+         <intype port="i" type="s"></intype>
+      </processing-node>
+   </nodes>
+</signal-graph>`,
+	"m.mml":      `<mapping xmlns="http://www.freesp.de/xml/freeSP" graph="lib/g.sml" platform="p.spml"></mapping>`,
+	".git/x.sml": `not a graph`,
+}
+
+func TestIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, text := range xrefFiles {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		err = ioutil.WriteFile(path, []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	x := IndexNew()
+	err = x.AddDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	case1 := []struct {
+		kind      Kind
+		name      string
+		locations []string
+	}{
+		{NodeTypeRef, "Filter", []string{
+			"lib/filters.alml: node type Chain / implementation g / node f1",
+			"lib/g.sml: node f"}},
+		{NodeTypeRef, "Chain", nil},
+		{SignalTypeRef, "s", []string{
+			"lib/filters.alml: node type Filter / port i",
+			"lib/filters.alml: node type Filter / port o",
+			"lib/g.sml: node f / port i"}},
+		{LibraryRef, filepath.Join(dir, "lib/filters.alml"), []string{"lib/g.sml: document"}},
+		{GraphRef, filepath.Join(dir, "lib/g.sml"), []string{
+			"lib/filters.alml: node type Chain / implementation ext",
+			"m.mml: document"}},
+		{PlatformRef, "p.spml", []string{"m.mml: document"}},
+	}
+	for i, c := range case1 {
+		list := x.Usages(c.kind, c.name)
+		if len(list) != len(c.locations) {
+			t.Errorf("testcase %d failed: got %v\n", i, list)
+			continue
+		}
+		for j, u := range list {
+			rel, _ := filepath.Rel(dir, u.Filename)
+			if rel+": "+u.Location() != c.locations[j] {
+				t.Errorf("testcase %d failed: got %s: %s, expected %s\n", i, rel, u.Location(), c.locations[j])
+			}
+		}
+	}
+}
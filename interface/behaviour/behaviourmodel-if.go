@@ -20,6 +20,7 @@ type SignalGraphIf interface {
 type SignalGraphTypeIf interface {
 	tree.TreeElementIf
 	Libraries() []LibraryIf
+	LibraryRef(LibraryIf) string
 	Nodes() []NodeIf
 	NodeByName(string) (NodeIf, bool)
 	NodeByPath(string) (NodeIf, bool)
@@ -39,6 +40,9 @@ type LibraryIf interface {
 	RemoveNodeType(t NodeTypeIf)
 	AddSignalType(SignalTypeIf) bool
 	RemoveSignalType(SignalTypeIf)
+	Compatibilities() []CompatibilityIf
+	AddCompatibility(CompatibilityIf) error
+	RemoveCompatibility(CompatibilityIf)
 }
 
 type NodeTypeIf interface {
@@ -49,11 +53,14 @@ type NodeTypeIf interface {
 	InPorts() []PortTypeIf
 	OutPorts() []PortTypeIf
 	Implementation() []ImplementationIf
+	Parameters() []ParameterIf
 	Instances() []NodeIf
 	AddNamedPortType(PortTypeIf)
 	RemoveNamedPortType(PortTypeIf)
 	AddImplementation(ImplementationIf)
 	RemoveImplementation(ImplementationIf)
+	AddParameter(ParameterIf) error
+	RemoveParameter(ParameterIf)
 }
 
 type ImplementationIf interface {
@@ -62,8 +69,11 @@ type ImplementationIf interface {
 	ElementName() string
 	SetElemName(string)
 	Graph() SignalGraphTypeIf
+	GraphRef() string
 }
 
+// Graph implementations with a non-empty GraphRef are read from the
+// referenced signal graph file when their graph is first accessed.
 type ImplementationType int
 
 const (
@@ -71,6 +81,46 @@ const (
 	NodeTypeGraph   ImplementationType = 1
 )
 
+type ParameterIf interface {
+	Name() string
+	ParamType() ParamType
+	Default() string
+	Min() string
+	Max() string
+	Values() []string
+	CheckValue(string) error
+}
+
+// Values of int and float parameters may be restricted to the range
+// Min..Max (empty if unrestricted), enum parameters take one of Values.
+type ParamType int
+
+const (
+	ParamInt ParamType = iota
+	ParamFloat
+	ParamString
+	ParamEnum
+)
+
+// Compatibility of signal types, declared by a library: outputs of
+// type From may be connected to inputs of type To. Subtype relations
+// are transitive, convertible connections need a node of node type
+// Converter in between.
+type CompatibilityIf interface {
+	From() string
+	To() string
+	Kind() CompatibilityKind
+	Converter() string
+}
+
+type CompatibilityKind int
+
+const (
+	CompatIdentity CompatibilityKind = iota
+	CompatSubtype
+	CompatConvertible
+)
+
 type NodeIf interface {
 	tree.NamedTreeElementIf
 	graph.PathModePositioner
@@ -82,6 +132,8 @@ type NodeIf interface {
 	OutPortIndex(portname string) int
 	Context() SignalGraphTypeIf
 	PortLink() (string, bool)
+	ParamValue(name string) (string, bool)
+	SetParamValue(name, value string) error
 	SubNode(ownId, childId NodeIdIf) (NodeIf, bool)
 	Children(ownId NodeIdIf) []NodeIdIf
 }
@@ -109,6 +161,30 @@ type SignalTypeIf interface {
 	SetScope(Scope)
 	Mode() Mode
 	SetMode(Mode)
+	Fields() []SignalFieldIf
+	AddField(SignalFieldIf) error
+	RemoveField(SignalFieldIf)
+}
+
+// Field of a structured signal type. FieldType is a primitive type
+// (int8..int64, uint8..uint64, float32, float64, bool, char) or the name
+// of another signal type, ArraySize is 0 for scalar fields. Numeric
+// fields may carry a physical Unit and the value range Min..Max (empty
+// if unrestricted).
+type SignalFieldIf interface {
+	tree.TreeElementIf
+	Name() string
+	SetName(string) error
+	FieldType() string
+	SetFieldType(string) error
+	ArraySize() int
+	SetArraySize(int) error
+	Unit() string
+	SetUnit(string)
+	Min() string
+	Max() string
+	SetRange(min, max string) error
+	SignalType() SignalTypeIf
 }
 
 type Scope int
@@ -139,9 +215,9 @@ type PortIf interface {
 	SignalType() SignalTypeIf
 	Connections() []PortIf
 	Node() NodeIf
-	Connection(PortIf) ConnectionIf
+	Connection(PortIf) (ConnectionIf, error)
 	AddConnection(ConnectionIf) error
-	RemoveConnection(c PortIf)
+	RemoveConnection(c PortIf) error
 }
 
 type ConnectionIf interface {
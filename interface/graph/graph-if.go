@@ -55,7 +55,7 @@ type Shaper interface {
 
 type Directioner interface {
 	Direction() PortDirection
-	SetDirection(PortDirection)
+	SetDirection(PortDirection) error
 }
 
 type PortDirection bool
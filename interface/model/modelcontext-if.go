@@ -5,8 +5,8 @@ import (
 )
 
 type ModelContextIf interface {
-	SignalGraphMgr() FileManagerIf
-	LibraryMgr() FileManagerIf
+	SignalGraphMgr() FileManagerSignalGraphIf
+	LibraryMgr() FileManagerLibraryIf
 	PlatformMgr() FileManagerIf
 	MappingMgr() FileManagerMappingIf
 }
@@ -19,6 +19,20 @@ type FileManagerIf interface {
 	Store(name string) error
 }
 
+type FileManagerLibraryIf interface {
+	FileManagerIf
+	// AccessRef loads a library referenced by a document in directory dir.
+	// Libraries are identified by the absolute path ref resolves to.
+	AccessRef(ref, dir string) (tree.ToplevelTreeElementIf, error)
+}
+
+type FileManagerSignalGraphIf interface {
+	FileManagerIf
+	// AccessRef loads a signal graph referenced by a document in directory dir.
+	// Graphs are identified by the absolute path ref resolves to.
+	AccessRef(ref, dir string) (tree.ToplevelTreeElementIf, error)
+}
+
 type FileManagerMappingIf interface {
 	FileManagerIf
 	SetGraphForNew(g interface{})
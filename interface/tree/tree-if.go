@@ -38,7 +38,9 @@ type TreeElementIf interface {
 	graph.XmlCreator
 	AddToTree(tree TreeIf, cursor Cursor)
 	AddNewObject(tree TreeIf, cursor Cursor, obj TreeElementIf) (newCursor Cursor, err error)
-	RemoveObject(tree TreeIf, cursor Cursor) (removed []IdWithObject)
+	// RemoveObject removes the child at cursor. removed lists the tree
+	// entries removed, for undo, also those removed before an error.
+	RemoveObject(tree TreeIf, cursor Cursor) (removed []IdWithObject, err error)
 }
 
 type ToplevelTreeElementIf interface {
@@ -86,6 +88,7 @@ const (
 	SymbolMappings
 	SymbolMapped
 	SymbolUnmapped
+	SymbolSignalField
 )
 
 type Property interface {
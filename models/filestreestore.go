@@ -112,6 +112,21 @@ func (s *FilesTreeStore) SetValueById(id, value string) (err error) {
 	return
 }
 
+// Sets the text of all entries showing obj, e.g. a type shown in its
+// library and below every node or port using it.
+func (s *FilesTreeStore) SetValueByObject(obj tr.TreeElementIf, value string) (err error) {
+	for id, e := range s.lookup {
+		if e.elem != obj {
+			continue
+		}
+		err = s.SetValueById(id, value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // Returns the string shown in textCol
 func (s *FilesTreeStore) GetValue(iter *gtk.TreeIter) (ret string, err error) {
 	v, err := s.treestore.GetValue(iter, textCol)
@@ -176,7 +191,7 @@ func (tree *FilesTreeStore) DeleteObject(id string) (deleted []tr.IdWithObject,
 	fmt.Sscanf(id[li+1:], "%d", &position)
 
 	cursor := tr.Cursor{id, tr.AppendCursor}
-	deleted = parent.RemoveObject(tree, cursor)
+	deleted, err = parent.RemoveObject(tree, cursor)
 	return
 }
 
@@ -363,6 +378,15 @@ func (s *FilesTreeStore) Cursor(obj tr.TreeElementIf) (cursor tr.Cursor) {
 	return tr.Cursor{path, tr.AppendCursor}
 }
 
+// Like Cursor, ok is false if obj is not in the tree.
+func (s *FilesTreeStore) FindCursor(obj tr.TreeElementIf) (cursor tr.Cursor, ok bool) {
+	_, path, err := s.getIterAndPathFromObject(obj)
+	if err != nil {
+		return
+	}
+	return tr.Cursor{path, tr.AppendCursor}, true
+}
+
 // Subtree search
 func (s *FilesTreeStore) CursorAt(start tr.Cursor, obj tr.TreeElementIf) (cursor tr.Cursor) {
 	path, ok := s.getIdFromObjectRecursive(start.Path, obj)
@@ -63,6 +63,7 @@ var sConfig = []symbolConfig{
 	{tr.SymbolMappings, makeFilename("mappingsPic")},
 	{tr.SymbolMapped, makeFilename("mappedPic")},
 	{tr.SymbolUnmapped, makeFilename("unmappedPic")},
+	{tr.SymbolSignalField, makeFilename("signal-type")},
 }
 
 var normalTable, readonlyTable map[tr.Symbol]*gdk.Pixbuf
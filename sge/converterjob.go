@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"log"
+)
+
+// InsertConverterJob connects two ports of convertible signal types:
+// it inserts a node of the converter node type and connects it to both
+// ports. Reverting removes the node together with its connections.
+type InsertConverterJob struct {
+	portId    string // port the dialog was opened on
+	other     bh.PortIf
+	converter bh.NodeTypeIf
+	nodeId    string
+}
+
+func InsertConverterJobNew(portId string, other bh.PortIf, converter bh.NodeTypeIf) *InsertConverterJob {
+	return &InsertConverterJob{portId, other, converter, ""}
+}
+
+func (j *InsertConverterJob) String() string {
+	return fmt.Sprintf("InsertConverterJob(%s at %s -> %s/%s, node=%s)", j.converter.TypeName(),
+		j.portId, j.other.Node().Name(), j.other.Name(), j.nodeId)
+}
+
+// Choice of the port selector for ports which need a converter.
+func convertiblePortChoice(p bh.PortIf, converter bh.NodeTypeIf) string {
+	return fmt.Sprintf("%s/%s (via %s)", p.Node().Name(), p.Name(), converter.TypeName())
+}
+
+// Node names of converters are derived from the converter type.
+func converterNodeName(g bh.SignalGraphTypeIf, converter bh.NodeTypeIf) string {
+	used := make(map[string]bool)
+	for _, n := range g.Nodes() {
+		used[n.Name()] = true
+	}
+	name := converter.TypeName()
+	for i := 1; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", converter.TypeName(), i)
+	}
+	return name
+}
+
+func (j *InsertConverterJob) Insert(fts *models.FilesTreeStore) (state string, err error) {
+	var obj tr.TreeElementIf
+	obj, err = fts.GetObjectById(j.portId)
+	if err != nil {
+		return
+	}
+	this, ok := obj.(bh.PortIf)
+	if !ok {
+		err = fmt.Errorf("InsertConverterJob.Insert error: %s is no port", j.portId)
+		return
+	}
+	from, to := this, j.other
+	if this.Direction() == gr.InPort {
+		from, to = j.other, this
+	}
+	graphId := getParentId(getParentId(j.portId))
+	graph := this.Node().Context()
+	var n bh.NodeIf
+	n, err = behaviour.NodeNew(converterNodeName(graph, j.converter), j.converter, graph)
+	if err != nil {
+		return
+	}
+	j.nodeId, err = fts.AddNewObject(graphId, -1, n)
+	if err != nil {
+		return
+	}
+	nodeCursor := tr.Cursor{j.nodeId, tr.AppendCursor}
+	in, out := n.InPorts()[0], n.OutPorts()[0]
+	_, err = fts.AddNewObject(fts.CursorAt(nodeCursor, in).Path, -1, behaviour.ConnectionNew(from, in))
+	if err == nil {
+		_, err = fts.AddNewObject(fts.CursorAt(nodeCursor, out).Path, -1, behaviour.ConnectionNew(out, to))
+	}
+	if err != nil {
+		_, derr := fts.DeleteObject(j.nodeId)
+		if derr != nil {
+			log.Printf("InsertConverterJob.Insert error: %s\n", derr)
+		}
+		return
+	}
+	state = j.nodeId
+	return
+}
+
+func (j *InsertConverterJob) Remove(fts *models.FilesTreeStore) (state string, err error) {
+	var del []tr.IdWithObject
+	del, err = fts.DeleteObject(j.nodeId)
+	if err != nil {
+		return
+	}
+	state = del[len(del)-1].ParentId
+	return
+}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"github.com/axel-freesp/sge/freesp"
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/behaviour"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	mp "github.com/axel-freesp/sge/interface/mapping"
@@ -50,18 +51,19 @@ func (dialog *EditDialog) setCurrentValues(context string) {
 		if len(obj.(bh.NodeIf).InPorts()) > 0 {
 			if len(obj.(bh.NodeIf).OutPorts()) > 0 {
 				dialog.nodeNameEntry.SetText(obj.(bh.NodeIf).Name())
-				for i, t = range freesp.GetRegisteredNodeTypes() {
-					if obj.(bh.NodeIf).ItsType().TypeName() == t {
+				for i, t = range global.Registry().GetRegisteredNodeTypes() {
+					if global.Registry().NodeTypeRefName(obj.(bh.NodeIf).ItsType()) == t {
 						break
 					}
 				}
 				dialog.nodeTypeSelector.SetActive(i)
 				dialog.nodeTypeSelector.SetSensitive(false)
+				dialog.nodeParamsEntry.SetText(behaviour.ParamValuesString(obj.(bh.NodeIf)))
 			} else {
 				// assume one input port
 				dialog.outputNodeNameEntry.SetText(obj.(bh.NodeIf).Name())
-				for i, t = range freesp.GetRegisteredSignalTypes() {
-					if obj.(bh.NodeIf).InPorts()[0].SignalType().TypeName() == t {
+				for i, t = range global.Registry().GetRegisteredSignalTypes() {
+					if global.Registry().SignalTypeRefName(obj.(bh.NodeIf).InPorts()[0].SignalType()) == t {
 						break
 					}
 				}
@@ -71,8 +73,8 @@ func (dialog *EditDialog) setCurrentValues(context string) {
 		} else {
 			// assume one output port
 			dialog.inputNodeNameEntry.SetText(obj.(bh.NodeIf).Name())
-			for i, t = range freesp.GetRegisteredSignalTypes() {
-				if obj.(bh.NodeIf).OutPorts()[0].SignalType().TypeName() == t {
+			for i, t = range global.Registry().GetRegisteredSignalTypes() {
+				if global.Registry().SignalTypeRefName(obj.(bh.NodeIf).OutPorts()[0].SignalType()) == t {
 					break
 				}
 			}
@@ -81,13 +83,14 @@ func (dialog *EditDialog) setCurrentValues(context string) {
 		}
 	case bh.NodeTypeIf:
 		dialog.typeNameEntry.SetText(obj.(bh.NodeTypeIf).TypeName())
+		dialog.typeParamsEntry.SetText(behaviour.ParametersString(obj.(bh.NodeTypeIf).Parameters()))
 	case bh.PortTypeIf:
 		dialog.portNameEntry.SetText(obj.(bh.PortTypeIf).Name())
 		if obj.(bh.PortTypeIf).Direction() == gr.OutPort {
 			dialog.directionSelector.SetActive(1)
 		}
-		for i, t = range freesp.GetRegisteredSignalTypes() {
-			if obj.(bh.PortTypeIf).SignalType().TypeName() == t {
+		for i, t = range global.Registry().GetRegisteredSignalTypes() {
+			if global.Registry().SignalTypeRefName(obj.(bh.PortTypeIf).SignalType()) == t {
 				break
 			}
 		}
@@ -100,6 +103,19 @@ func (dialog *EditDialog) setCurrentValues(context string) {
 		dialog.channelIdEntry.SetText(st.ChannelId())
 		dialog.scopeSelector.SetActive(int(st.Scope()))
 		dialog.modeSelector.SetActive(int(st.Mode()))
+	case bh.SignalFieldIf:
+		f := obj.(bh.SignalFieldIf)
+		dialog.fieldNameEntry.SetText(f.Name())
+		for i, t = range fieldTypes() {
+			if f.FieldType() == t {
+				break
+			}
+		}
+		dialog.fieldTypeSelector.SetActive(i)
+		dialog.arraySizeEntry.SetText(fmt.Sprintf("%d", f.ArraySize()))
+		dialog.unitEntry.SetText(f.Unit())
+		dialog.fieldMinEntry.SetText(f.Min())
+		dialog.fieldMaxEntry.SetText(f.Max())
 	case bh.ImplementationIf:
 		dialog.implNameEntry.SetText(obj.(bh.ImplementationIf).ElementName())
 	case pf.ArchIf:
@@ -119,7 +135,7 @@ func (dialog *EditDialog) setCurrentValues(context string) {
 			dialog.channelDirectionSelector.SetActive(1)
 		}
 		dialog.channelDirectionSelector.SetSensitive(false)
-		for i, t = range freesp.GetRegisteredIOTypes() {
+		for i, t = range global.Registry().GetRegisteredIOTypes() {
 			if obj.(pf.ChannelIf).IOType().Name() == t {
 				break
 			}
@@ -187,6 +203,8 @@ func (dialog *EditDialog) getActiveElementType() (context string, e elementType)
 		log.Fatalf("editdialog.go: getActiveElementType error: Connection is read-only\n")
 	case bh.SignalTypeIf:
 		e = eSignalType
+	case bh.SignalFieldIf:
+		e = eSignalField
 	case bh.LibraryIf:
 		e = eLibrary
 		log.Fatalf("editdialog.go: getActiveElementType error: LibraryIf is read-only\n")
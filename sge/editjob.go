@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"github.com/axel-freesp/sge/freesp"
 	"github.com/axel-freesp/sge/freesp/behaviour"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
@@ -55,6 +54,12 @@ func (j *EditJob) EditObject(fts *models.FilesTreeStore, direction EditJobDirect
 	switch j.elemType {
 	case eNode:
 		n := obj.(bh.NodeIf)
+		params := behaviour.ParamValuesString(n)
+		err = behaviour.SetParamValues(n, (*detail)[iNodeParams])
+		if err != nil {
+			return
+		}
+		(*old)[iNodeParams] = params
 		(*old)[iNodeName] = n.Name()
 		n.SetName((*detail)[iNodeName])
 		fts.SetValueById(j.objId, n.Name())
@@ -82,6 +87,15 @@ func (j *EditJob) EditObject(fts *models.FilesTreeStore, direction EditJobDirect
 		}
 	case eNodeType:
 		nt := obj.(bh.NodeTypeIf)
+		// Parameters can be changed with instances, their values are
+		// kept where valid.
+		var params []bh.ParameterIf
+		params, err = behaviour.ParseParameters((*detail)[iTypeParams])
+		if err != nil {
+			return
+		}
+		(*old)[iTypeParams] = behaviour.ParametersString(nt.Parameters())
+		behaviour.SetParameters(nt, params)
 		if len(nt.Instances()) > 0 {
 			log.Printf("jobApplier.Apply(JobEdit): WARNING: NodeTypeIf %s has instances.\n", nt.TypeName())
 			log.Printf("jobApplier.Apply(JobEdit): Editing is not implemented in this case.\n")
@@ -103,16 +117,19 @@ func (j *EditJob) EditObject(fts *models.FilesTreeStore, direction EditJobDirect
 		(*old)[iPortName] = pt.Name()
 		(*old)[iSignalTypeSelect] = pt.SignalType().TypeName()
 		(*old)[iDirection] = direction2string[pt.Direction()]
-		fts.DeleteObject(ptCursor.Path)
+		_, err = fts.DeleteObject(ptCursor.Path)
+		if err != nil {
+			return
+		}
 		fts.AddNewObject(ntCursor.Path, ntCursor.Position,
 			behaviour.PortTypeNew((*detail)[iPortName],
 				(*detail)[iSignalTypeSelect],
-				string2direction[(*detail)[iDirection]]))
+				string2direction[(*detail)[iDirection]], &global))
 		state = ptCursor.Path
 	case eSignalType:
 		st := obj.(bh.SignalTypeIf)
 		if (*detail)[iSignalTypeName] != st.TypeName() {
-			log.Printf("jobApplier.Apply(JobEdit): Renaming SignalType is not implemented.\n")
+			log.Printf("jobApplier.Apply(JobEdit): use Edit > Rename Type to rename SignalType %s.\n", st.TypeName())
 		}
 		(*old)[iCType] = st.CType()
 		st.SetCType((*detail)[iCType])
@@ -122,6 +139,23 @@ func (j *EditJob) EditObject(fts *models.FilesTreeStore, direction EditJobDirect
 		st.SetScope(string2scope[(*detail)[iScope]])
 		(*old)[iSignalMode] = mode2string[st.Mode()]
 		st.SetMode(string2mode[(*detail)[iSignalMode]])
+	case eSignalField:
+		f := obj.(bh.SignalFieldIf)
+		var n int
+		n, err = arraySize((*detail)[iArraySize])
+		if err != nil {
+			return
+		}
+		name, fType, size := f.Name(), f.FieldType(), fmt.Sprintf("%d", f.ArraySize())
+		unit, min, max := f.Unit(), f.Min(), f.Max()
+		err = behaviour.SetSignalField(f, (*detail)[iFieldName], (*detail)[iFieldTypeSelect], n,
+			(*detail)[iUnit], (*detail)[iFieldMin], (*detail)[iFieldMax])
+		if err != nil {
+			return
+		}
+		(*old)[iFieldName], (*old)[iFieldTypeSelect], (*old)[iArraySize] = name, fType, size
+		(*old)[iUnit], (*old)[iFieldMin], (*old)[iFieldMax] = unit, min, max
+		fts.SetValueById(j.objId, fmt.Sprint(f))
 	case eImplementation:
 		impl := obj.(bh.ImplementationIf)
 		(*old)[iImplName] = impl.ElementName()
@@ -189,7 +223,7 @@ func (j *EditJob) EditObject(fts *models.FilesTreeStore, direction EditJobDirect
 		//(*old)[iChannelDirection] = direction2string[c.Direction()]
 		//c.SetDirection(string2direction[(*detail)[iChannelDirection]])
 		(*old)[iIOTypeSelect] = c.IOType().Name()
-		iot, ok := freesp.GetIOTypeByName((*detail)[iIOTypeSelect])
+		iot, ok := global.Registry().GetIOTypeByName((*detail)[iIOTypeSelect])
 		if ok {
 			c.SetIOType(iot)
 		} else {
@@ -228,7 +262,11 @@ func updateConnections(p bh.PortIf, fts *models.FilesTreeStore) {
 	nodeCursor := fts.Cursor(p.Node())
 	portCursor := fts.CursorAt(nodeCursor, p)
 	for _, c := range p.Connections() {
-		conn := p.Connection(c)
+		conn, err := p.Connection(c)
+		if err != nil {
+			log.Printf("updateConnections: %s\n", err)
+			continue
+		}
 		connCursor := fts.CursorAt(portCursor, conn)
 		otherNode := c.Node()
 		otherNodeCursor := fts.Cursor(otherNode)
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	mp "github.com/axel-freesp/sge/interface/mapping"
@@ -33,6 +33,9 @@ const (
 	iInputTypeSelect                 = "InputTypeSelect"
 	iOutputTypeSelect                = "OutputTypeSelect"
 	iImplementationType              = "ImplementationType"
+	iGraphFile                       = "GraphFile"
+	iNodeParams                      = "NodeParams"
+	iTypeParams                      = "TypeParams"
 	iPortSelect                      = "PortSelect"
 	iCType                           = "CType"
 	iChannelId                       = "ChannelId"
@@ -47,6 +50,12 @@ const (
 	iProcessName                     = "ProcessName"
 	iArchName                        = "ArchName"
 	iProcessSelect                   = "ProcessSelect"
+	iFieldName                       = "FieldName"
+	iFieldTypeSelect                 = "FieldTypeSelect"
+	iArraySize                       = "ArraySize"
+	iUnit                            = "Unit"
+	iFieldMin                        = "FieldMin"
+	iFieldMax                        = "FieldMax"
 )
 
 type elementType string
@@ -61,6 +70,7 @@ const (
 	ePortType                   = "PortType"
 	eConnection                 = "Connection"
 	eSignalType                 = "SignalType"
+	eSignalField                = "SignalField"
 	eLibrary                    = "Library"
 	eImplementation             = "Implementation"
 	ePlatform                   = "Platform"
@@ -72,14 +82,15 @@ const (
 )
 
 var inputElementMap = map[elementType][]inputElement{
-	eNode:           {iNodeName, iNodeTypeSelect},
+	eNode:           {iNodeName, iNodeTypeSelect, iNodeParams},
 	eInputNode:      {iInputNodeName, iInputTypeSelect},
 	eOutputNode:     {iOutputNodeName, iOutputTypeSelect},
-	eNodeType:       {iTypeName},
+	eNodeType:       {iTypeName, iTypeParams},
 	ePortType:       {iPortName, iSignalTypeSelect, iDirection},
 	eConnection:     {iPortSelect},
 	eSignalType:     {iSignalTypeName, iCType, iChannelId, iScope, iSignalMode},
-	eImplementation: {iImplName, iImplementationType},
+	eSignalField:    {iFieldName, iFieldTypeSelect, iArraySize, iUnit, iFieldMin, iFieldMax},
+	eImplementation: {iImplName, iImplementationType, iGraphFile},
 	eChannel:        {iChannelDirection, iIOTypeSelect, iChannelLinkSelect},
 	eIOType:         {iIOTypeName, iIOModeSelect},
 	eProcess:        {iProcessName},
@@ -156,6 +167,7 @@ type EditMenuDialog struct {
 	processSelector          *gtk.ComboBoxText
 	ioModeSelector           *gtk.ComboBoxText
 	processMapSelector       *gtk.ComboBoxText
+	fieldTypeSelector        *gtk.ComboBoxText
 
 	nodeNameEntry       *gtk.Entry
 	inputNodeNameEntry  *gtk.Entry
@@ -163,12 +175,20 @@ type EditMenuDialog struct {
 	typeNameEntry       *gtk.Entry
 	portNameEntry       *gtk.Entry
 	implNameEntry       *gtk.Entry
+	graphFileEntry      *gtk.Entry
+	nodeParamsEntry     *gtk.Entry
+	typeParamsEntry     *gtk.Entry
 	signalTypeNameEntry *gtk.Entry
 	cTypeEntry          *gtk.Entry
 	channelIdEntry      *gtk.Entry
 	ioTypeNameEntry     *gtk.Entry
 	processNameEntry    *gtk.Entry
 	archNameEntry       *gtk.Entry
+	fieldNameEntry      *gtk.Entry
+	arraySizeEntry      *gtk.Entry
+	unitEntry           *gtk.Entry
+	fieldMinEntry       *gtk.Entry
+	fieldMaxEntry       *gtk.Entry
 }
 
 func EditMenuDialogInit(d *gtk.Dialog, fts *models.FilesTreeStore) (ret EditMenuDialog) {
@@ -231,28 +251,60 @@ func (dialog *EditMenuDialog) CreateDialog(extra *gtk.Widget) (err error) {
 
 // For connection only: lookup matching ports to connect.
 func getMatchingPorts(fts *models.FilesTreeStore, object tr.TreeElementIf) (ret []bh.PortIf) {
-	var thisPort bh.PortIf
+	thisPort := connectingPort(object)
+	for _, p := range otherPorts(thisPort) {
+		out, in := p.SignalType(), thisPort.SignalType()
+		if thisPort.Direction() == gr.OutPort {
+			out, in = in, out
+		}
+		if behaviour.SignalTypesCompatible(out, in) {
+			ret = append(ret, p)
+		}
+	}
+	return
+}
+
+// For connection only: lookup ports which can be connected through a
+// converter node, and the node type of the converter.
+func getConvertiblePorts(fts *models.FilesTreeStore, object tr.TreeElementIf) (ret []bh.PortIf, conv []bh.NodeTypeIf) {
+	thisPort := connectingPort(object)
+	for _, p := range otherPorts(thisPort) {
+		out, in := p.SignalType(), thisPort.SignalType()
+		if thisPort.Direction() == gr.OutPort {
+			out, in = in, out
+		}
+		if behaviour.SignalTypesCompatible(out, in) {
+			continue
+		}
+		nt, ok := behaviour.Converter(out, in)
+		if ok {
+			ret = append(ret, p)
+			conv = append(conv, nt)
+		}
+	}
+	return
+}
+
+func connectingPort(object tr.TreeElementIf) (thisPort bh.PortIf) {
 	switch object.(type) {
 	case bh.PortIf:
 		thisPort = object.(bh.PortIf)
 	case bh.ConnectionIf:
-		log.Fatal("getMatchingPorts error: expecting Port, not Connection")
+		log.Fatal("connectingPort error: expecting Port, not Connection")
 	default:
-		log.Fatal("getMatchingPorts error: expecting Port")
+		log.Fatal("connectingPort error: expecting Port")
 	}
-	thisNode := thisPort.Node()
-	graph := thisNode.Context()
+	return
+}
+
+// Ports of the opposite direction in the graph of thisPort.
+func otherPorts(thisPort bh.PortIf) (ret []bh.PortIf) {
+	graph := thisPort.Node().Context()
 	for _, n := range graph.Nodes() {
-		var ports []bh.PortIf
 		if thisPort.Direction() == gr.InPort {
-			ports = n.OutPorts()
+			ret = append(ret, n.OutPorts()...)
 		} else {
-			ports = n.InPorts()
-		}
-		for _, p := range ports {
-			if p.SignalType().TypeName() == thisPort.SignalType().TypeName() {
-				ret = append(ret, p)
-			}
+			ret = append(ret, n.InPorts()...)
 		}
 	}
 	return
@@ -334,6 +386,30 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return newEntry(&dialog.implNameEntry)
 		},
 	},
+	iGraphFile: {"Graph file (optional):",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.graphFileEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.graphFileEntry)
+		},
+	},
+	iNodeParams: {"Parameters:",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.nodeParamsEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.nodeParamsEntry)
+		},
+	},
+	iTypeParams: {"Parameters:",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.typeParamsEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.typeParamsEntry)
+		},
+	},
 	iSignalTypeName: {"Name:",
 		func(dialog *EditMenuDialog) string {
 			return getText(dialog.signalTypeNameEntry)
@@ -342,6 +418,46 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return newEntry(&dialog.signalTypeNameEntry)
 		},
 	},
+	iFieldName: {"Name:",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.fieldNameEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.fieldNameEntry)
+		},
+	},
+	iArraySize: {"Array size (0 for scalars):",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.arraySizeEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.arraySizeEntry)
+		},
+	},
+	iUnit: {"Unit (optional):",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.unitEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.unitEntry)
+		},
+	},
+	iFieldMin: {"Minimum (optional):",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.fieldMinEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.fieldMinEntry)
+		},
+	},
+	iFieldMax: {"Maximum (optional):",
+		func(dialog *EditMenuDialog) string {
+			return getText(dialog.fieldMaxEntry)
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newEntry(&dialog.fieldMaxEntry)
+		},
+	},
 	iIOTypeName: {"Name:",
 		func(dialog *EditMenuDialog) string {
 			return getText(dialog.ioTypeNameEntry)
@@ -371,7 +487,7 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return dialog.nodeTypeSelector.GetActiveText()
 		},
 		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
-			return newComboBox(&dialog.nodeTypeSelector, freesp.GetRegisteredNodeTypes())
+			return newComboBox(&dialog.nodeTypeSelector, global.Registry().GetRegisteredNodeTypes())
 		},
 	},
 	iSignalTypeSelect: {"Select signal type:",
@@ -379,7 +495,15 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return dialog.signalTypeSelector.GetActiveText()
 		},
 		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
-			return newComboBox(&dialog.signalTypeSelector, freesp.GetRegisteredSignalTypes())
+			return newComboBox(&dialog.signalTypeSelector, global.Registry().GetRegisteredSignalTypes())
+		},
+	},
+	iFieldTypeSelect: {"Select field type:",
+		func(dialog *EditMenuDialog) string {
+			return dialog.fieldTypeSelector.GetActiveText()
+		},
+		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
+			return newComboBox(&dialog.fieldTypeSelector, fieldTypes())
 		},
 	},
 	iInputTypeSelect: {"Select signal type:",
@@ -387,7 +511,7 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return dialog.inputTypeSelector.GetActiveText()
 		},
 		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
-			return newComboBox(&dialog.inputTypeSelector, freesp.GetRegisteredSignalTypes())
+			return newComboBox(&dialog.inputTypeSelector, global.Registry().GetRegisteredSignalTypes())
 		},
 	},
 	iOutputTypeSelect: {"Select signal type:",
@@ -395,7 +519,7 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return dialog.outputTypeSelector.GetActiveText()
 		},
 		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
-			return newComboBox(&dialog.outputTypeSelector, freesp.GetRegisteredSignalTypes())
+			return newComboBox(&dialog.outputTypeSelector, global.Registry().GetRegisteredSignalTypes())
 		},
 	},
 	iIOTypeSelect: {"Select IO type:",
@@ -403,7 +527,7 @@ var inputHandling = map[inputElement]inputElementHandling{
 			return dialog.ioTypeSelector.GetActiveText()
 		},
 		func(dialog *EditMenuDialog) (obj *gtk.Widget, err error) {
-			return newComboBox(&dialog.ioTypeSelector, freesp.GetRegisteredIOTypes())
+			return newComboBox(&dialog.ioTypeSelector, global.Registry().GetRegisteredIOTypes())
 		},
 	},
 	iImplementationType: {"Implementation type:",
@@ -440,6 +564,10 @@ var inputHandling = map[inputElement]inputElementHandling{
 			for _, p := range getMatchingPorts(dialog.fts, object) {
 				choices = append(choices, fmt.Sprintf("%s/%s", p.Node().Name(), p.Name()))
 			}
+			ports, conv := getConvertiblePorts(dialog.fts, object)
+			for i, p := range ports {
+				choices = append(choices, convertiblePortChoice(p, conv[i]))
+			}
 			return newComboBox(&dialog.portSelector, choices)
 		},
 	},
@@ -539,6 +667,11 @@ var inputHandling = map[inputElement]inputElementHandling{
 	},
 }
 
+// Field types: the primitive types, followed by all signal types.
+func fieldTypes() []string {
+	return append(behaviour.PrimitiveTypes(), global.Registry().GetRegisteredSignalTypes()...)
+}
+
 func getText(entry *gtk.Entry) string {
 	text, _ := entry.GetText()
 	return text
@@ -603,6 +736,7 @@ var stackAlternatives = []elementType{
 	ePortType,
 	eConnection,
 	eSignalType,
+	eSignalField,
 	eImplementation,
 	eArch,
 	eIOType,
@@ -14,6 +14,9 @@ const (
 	JobDeleteObject
 	JobEdit
 	JobPaste
+	JobRename
+	JobMove
+	JobInsertConverter
 )
 
 type EditorJob struct {
@@ -23,10 +26,13 @@ type EditorJob struct {
 	deleteObject *DeleteObjectJob
 	edit         *EditJob
 	paste        *PasteJob
+	rename       *RenameJob
+	move         *MoveJob
+	converter    *InsertConverterJob
 }
 
 func EditorJobNew(jobType JobType, jobDetail fmt.Stringer) *EditorJob {
-	ret := &EditorJob{jobType, jobDetail, nil, nil, nil, nil}
+	ret := &EditorJob{jobType, jobDetail, nil, nil, nil, nil, nil, nil, nil}
 	switch jobType {
 	case JobNewElement:
 		ret.newElement = jobDetail.(*NewElementJob)
@@ -36,6 +42,12 @@ func EditorJobNew(jobType JobType, jobDetail fmt.Stringer) *EditorJob {
 		ret.edit = jobDetail.(*EditJob)
 	case JobPaste:
 		ret.paste = jobDetail.(*PasteJob)
+	case JobRename:
+		ret.rename = jobDetail.(*RenameJob)
+	case JobMove:
+		ret.move = jobDetail.(*MoveJob)
+	case JobInsertConverter:
+		ret.converter = jobDetail.(*InsertConverterJob)
 	}
 	return ret
 }
@@ -51,6 +63,12 @@ func (e *EditorJob) String() string {
 		kind = "Edit"
 	case JobPaste:
 		kind = "Paste"
+	case JobRename:
+		kind = "Rename"
+	case JobMove:
+		kind = "Move"
+	case JobInsertConverter:
+		kind = "InsertConverter"
 	}
 	return fmt.Sprintf("EditorJob( %s( %v ) )", kind, e.jobDetail)
 }
@@ -120,6 +138,22 @@ func (a *jobApplier) Apply(jobI interface{}) (state interface{}, err error) {
 			}
 		}
 		level--
+	case JobRename:
+		state, err = job.rename.Rename(a.fts, EditJobForward)
+		if err != nil {
+			log.Printf("jobApplier.Apply (JobRename): error: %s\n", err)
+		}
+	case JobMove:
+		state, err = job.move.Move(a.fts, EditJobForward)
+		if err != nil {
+			log.Printf("jobApplier.Apply (JobMove): error: %s\n", err)
+		}
+	case JobInsertConverter:
+		state, err = job.converter.Insert(a.fts)
+		if err != nil {
+			state = a.fts.GetCurrentId()
+			log.Printf("jobApplier.Apply (JobInsertConverter): error: %s\n", err)
+		}
 	}
 	return
 }
@@ -159,6 +193,22 @@ func (a *jobApplier) Revert(jobI interface{}) (state interface{}, err error) {
 			log.Printf("jobApplier.Apply (JobPaste): error: %s\n", err)
 			return
 		}
+	case JobRename:
+		state, err = job.rename.Rename(a.fts, EditJobRevert)
+		if err != nil {
+			log.Printf("jobApplier.Revert (JobRename): error: %s\n", err)
+		}
+	case JobMove:
+		state, err = job.move.Move(a.fts, EditJobRevert)
+		if err != nil {
+			log.Printf("jobApplier.Revert (JobMove): error: %s\n", err)
+		}
+	case JobInsertConverter:
+		state, err = job.converter.Remove(a.fts)
+		if err != nil {
+			state = a.fts.GetCurrentId()
+			log.Printf("jobApplier.Revert (JobInsertConverter): error: %s\n", err)
+		}
 	}
 	return
 }
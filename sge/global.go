@@ -23,14 +23,20 @@ const (
 )
 
 type Global struct {
-	win                                     *GoAppWindow
-	jl                                      *jobList
-	fts                                     *models.FilesTreeStore
-	ftv                                     *views.FilesTreeView
-	graphviewMap                            map[bh.ImplementationIf]views.GraphViewIf
-	clp                                     *gtk.Clipboard
-	signalGraphMgr, libraryMgr, platformMgr mod.FileManagerIf
-	mappingMgr                              mod.FileManagerMappingIf
+	win            *GoAppWindow
+	jl             *jobList
+	fts            *models.FilesTreeStore
+	ftv            *views.FilesTreeView
+	usv            *views.UsagesView
+	prv            *views.ProblemsView
+	depsView       views.GraphViewIf
+	graphviewMap   map[bh.ImplementationIf]views.GraphViewIf
+	clp            *gtk.Clipboard
+	platformMgr    mod.FileManagerIf
+	signalGraphMgr mod.FileManagerSignalGraphIf
+	libraryMgr     mod.FileManagerLibraryIf
+	mappingMgr     mod.FileManagerMappingIf
+	registry       *freesp.Registry
 }
 
 var _ views.ContextIf = (*Global)(nil)
@@ -39,6 +45,7 @@ var _ filemanager.FilemanagerContextIf = (*Global)(nil)
 
 func GlobalInit(g *Global) {
 	g.graphviewMap = make(map[bh.ImplementationIf]views.GraphViewIf)
+	g.registry = freesp.RegistryNew()
 	g.signalGraphMgr = filemanager.FileManagerSGNew(g)
 	g.libraryMgr = filemanager.FileManagerLibNew(g)
 	g.platformMgr = filemanager.FileManagerPFNew(g)
@@ -49,11 +56,16 @@ func GlobalInit(g *Global) {
 //		freesp.Context interface
 //
 
-func (g *Global) SignalGraphMgr() mod.FileManagerIf {
+// Registry keeps the types of all documents open in the editor.
+func (g *Global) Registry() *freesp.Registry {
+	return g.registry
+}
+
+func (g *Global) SignalGraphMgr() mod.FileManagerSignalGraphIf {
 	return g.signalGraphMgr
 }
 
-func (g *Global) LibraryMgr() mod.FileManagerIf {
+func (g *Global) LibraryMgr() mod.FileManagerLibraryIf {
 	return g.libraryMgr
 }
 
@@ -159,7 +171,7 @@ func (g *Global) CleanupNodeType(nt bh.NodeTypeIf) {
 			g.CleanupNodeTypesFromNodes(impl.Graph().Nodes())
 		}
 	}
-	freesp.RemoveRegisteredNodeType(nt)
+	g.Registry().RemoveRegisteredNodeType(nt)
 }
 
 func (g *Global) SignalTypeIsInUse(st bh.SignalTypeIf) bool {
@@ -183,7 +195,7 @@ func (g *Global) SignalTypeIsInUse(st bh.SignalTypeIf) bool {
 }
 
 func (g *Global) CleanupSignalType(st bh.SignalTypeIf) {
-	freesp.RemoveRegisteredSignalType(st)
+	g.Registry().RemoveRegisteredSignalType(st)
 }
 
 //
@@ -15,6 +15,9 @@ type GoAppMenu struct {
 	fileNewPlat  *gtk.MenuItem
 	fileNewMap   *gtk.MenuItem
 	fileOpen     *gtk.MenuItem
+	fileOpenProj *gtk.MenuItem
+	fileRecent   *gtk.MenuItem
+	menuRecent   *gtk.Menu
 	fileSave     *gtk.MenuItem
 	fileSaveAs   *gtk.MenuItem
 	fileClose    *gtk.MenuItem
@@ -25,6 +28,9 @@ type GoAppMenu struct {
 	editRedo     *gtk.MenuItem
 	editNew      *gtk.MenuItem
 	editEdit     *gtk.MenuItem
+	editRename   *gtk.MenuItem
+	editUsages   *gtk.MenuItem
+	editMove     *gtk.MenuItem
 	editDelete   *gtk.MenuItem
 	editCopy     *gtk.MenuItem
 	editPaste    *gtk.MenuItem
@@ -32,6 +38,9 @@ type GoAppMenu struct {
 	viewmenu     *gtk.MenuItem
 	viewExpand   *gtk.MenuItem
 	viewCollapse *gtk.MenuItem
+	viewCompare  *gtk.MenuItem
+	viewEndComp  *gtk.MenuItem
+	viewDeps     *gtk.MenuItem
 	menuAbout    *gtk.Menu
 	aboutmenu    *gtk.MenuItem
 	aboutAbout   *gtk.MenuItem
@@ -80,6 +89,19 @@ func (m *GoAppMenu) Init() {
 	if err != nil {
 		log.Fatal("Unable to create fileOpen:", err)
 	}
+	m.fileOpenProj, err = gtk.MenuItemNewWithLabel("Open Project")
+	if err != nil {
+		log.Fatal("Unable to create fileOpenProj:", err)
+	}
+	m.fileRecent, err = gtk.MenuItemNewWithLabel("Recent Projects")
+	if err != nil {
+		log.Fatal("Unable to create fileRecent:", err)
+	}
+	m.menuRecent, err = gtk.MenuNew()
+	if err != nil {
+		log.Fatal("Unable to create menuRecent:", err)
+	}
+	m.fileRecent.SetSubmenu(m.menuRecent)
 	m.fileSave, err = gtk.MenuItemNewWithLabel("Save")
 	if err != nil {
 		log.Fatal("Unable to create fileSave:", err)
@@ -103,6 +125,8 @@ func (m *GoAppMenu) Init() {
 	x, _ := gtk.SeparatorMenuItemNew()
 	m.menuFile.Append(x)
 	m.menuFile.Append(m.fileOpen)
+	m.menuFile.Append(m.fileOpenProj)
+	m.menuFile.Append(m.fileRecent)
 	m.menuFile.Append(m.fileSave)
 	m.menuFile.Append(m.fileSaveAs)
 	x, _ = gtk.SeparatorMenuItemNew()
@@ -142,6 +166,18 @@ func (m *GoAppMenu) Init() {
 	if err != nil {
 		log.Fatal("Unable to create editEdit:", err)
 	}
+	m.editRename, err = gtk.MenuItemNewWithMnemonic("Rename _Type...")
+	if err != nil {
+		log.Fatal("Unable to create editRename:", err)
+	}
+	m.editMove, err = gtk.MenuItemNewWithMnemonic("_Move Types...")
+	if err != nil {
+		log.Fatal("Unable to create editMove:", err)
+	}
+	m.editUsages, err = gtk.MenuItemNewWithMnemonic("Find _Usages")
+	if err != nil {
+		log.Fatal("Unable to create editUsages:", err)
+	}
 	m.editCopy, err = gtk.MenuItemNewWithMnemonic("_Copy")
 	if err != nil {
 		log.Fatal("Unable to create editCopy:", err)
@@ -160,6 +196,9 @@ func (m *GoAppMenu) Init() {
 	m.menuEdit.Append(x)
 	m.menuEdit.Append(m.editNew)
 	m.menuEdit.Append(m.editEdit)
+	m.menuEdit.Append(m.editRename)
+	m.menuEdit.Append(m.editMove)
+	m.menuEdit.Append(m.editUsages)
 	m.menuEdit.Append(m.editDelete)
 	x, _ = gtk.SeparatorMenuItemNew()
 	m.menuEdit.Append(x)
@@ -184,8 +223,27 @@ func (m *GoAppMenu) Init() {
 	if err != nil {
 		log.Fatal("Unable to create viewCollapse:", err)
 	}
+	m.viewCompare, err = gtk.MenuItemNewWithLabel("Compare With...")
+	if err != nil {
+		log.Fatal("Unable to create viewCompare:", err)
+	}
+	m.viewEndComp, err = gtk.MenuItemNewWithLabel("End Compare")
+	if err != nil {
+		log.Fatal("Unable to create viewEndComp:", err)
+	}
+	m.viewDeps, err = gtk.MenuItemNewWithMnemonic("_Dependencies")
+	if err != nil {
+		log.Fatal("Unable to create viewDeps:", err)
+	}
 	m.menuView.Append(m.viewExpand)
 	m.menuView.Append(m.viewCollapse)
+	x, _ = gtk.SeparatorMenuItemNew()
+	m.menuView.Append(x)
+	m.menuView.Append(m.viewCompare)
+	m.menuView.Append(m.viewEndComp)
+	x, _ = gtk.SeparatorMenuItemNew()
+	m.menuView.Append(x)
+	m.menuView.Append(m.viewDeps)
 	m.viewmenu.SetSubmenu(m.menuView)
 	m.menubar.Append(m.viewmenu)
 
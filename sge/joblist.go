@@ -24,15 +24,32 @@ type IJobList interface {
 type jobList struct {
 	undoStack, redoStack *tool.Stack
 	system               IJobApplier
+	reportError          func(err error)
+	changed              func()
 }
 
 var _ IJobList = (*jobList)(nil)
 
-func jobListNew(system IJobApplier) *jobList {
-	j := &jobList{tool.StackNew(), tool.StackNew(), system}
+// Errors of jobs are passed to reportError (nil to only log them),
+// changed is called after each job, undo and redo (may be nil).
+func jobListNew(system IJobApplier, reportError func(err error), changed func()) *jobList {
+	j := &jobList{tool.StackNew(), tool.StackNew(), system, reportError, changed}
 	return j
 }
 
+func (j *jobList) done() {
+	if j.changed != nil {
+		j.changed()
+	}
+}
+
+func (j *jobList) failed(op string, err error) {
+	log.Println(op, "error: ", err)
+	if j.reportError != nil {
+		j.reportError(err)
+	}
+}
+
 func (j *jobList) Reset() {
 	j.undoStack.Reset()
 	j.redoStack.Reset()
@@ -46,9 +63,10 @@ func (j *jobList) Undo() (state interface{}, ok bool) {
 		var err error
 		state, err = j.system.Revert(job)
 		if err != nil {
-			log.Println("jobList.Undo error: ", err)
+			j.failed("jobList.Undo", err)
 			j.Reset()
 		}
+		j.done()
 		ok = true
 	}
 	return
@@ -62,9 +80,10 @@ func (j *jobList) Redo() (state interface{}, ok bool) {
 		var err error
 		state, err = j.system.Apply(job)
 		if err != nil {
-			log.Println("jobList.Redo error: ", err)
+			j.failed("jobList.Redo", err)
 			j.Reset()
 		}
+		j.done()
 		ok = true
 	}
 	return
@@ -78,8 +97,9 @@ func (j *jobList) Apply(job interface{}) (state interface{}, ok bool) {
 		j.undoStack.Push(job)
 		ok = true
 	} else {
-		log.Println("jobList.Apply error: ", err)
+		j.failed("jobList.Apply", err)
 	}
+	j.done()
 	return
 }
 
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/axel-freesp/sge/backend"
-	"github.com/axel-freesp/sge/freesp"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	mp "github.com/axel-freesp/sge/interface/mapping"
 	pf "github.com/axel-freesp/sge/interface/platform"
@@ -111,7 +110,6 @@ func main() {
 	unhandledArgs := os.Args
 	gtk.Init(&unhandledArgs)
 	backend.Init()
-	freesp.Init()
 	GlobalInit(&global)
 
 	var err error
@@ -155,6 +153,16 @@ func main() {
 		log.Fatal("Unable to create FilesTreeView:", err)
 	}
 	global.win.navigation_box.Add(global.ftv.Widget())
+	global.usv, err = views.UsagesViewNew(width/2, height/4, navigateToUsage)
+	if err != nil {
+		log.Fatal("Unable to create UsagesView:", err)
+	}
+	global.win.navigation_box.Add(global.usv.Widget())
+	global.prv, err = views.ProblemsViewNew(width/2, height/4, navigateToProblem)
+	if err != nil {
+		log.Fatal("Unable to create ProblemsView:", err)
+	}
+	global.win.navigation_box.Add(global.prv.Widget())
 
 	selection, err := global.ftv.TreeView().GetSelection()
 	if err != nil {
@@ -164,34 +172,54 @@ func main() {
 	selection.Connect("changed", treeSelectionChangedCB, menu)
 
 	japp := jobApplierNew(global.fts)
-	global.jl = jobListNew(japp)
+	global.jl = jobListNew(japp, showError, updateProblems)
 
 	MenuFileInit(menu)
+	MenuProjectInit(menu)
 	MenuEditInit(menu)
 	MenuViewInit(menu, &global)
+	MenuCompareInit(menu)
+	MenuDependenciesInit(menu)
 	MenuAboutInit(menu)
 
-	// Handle command line arguments: treat each as a filename:
-	for i, p := range unhandledArgs {
-		if i > 0 {
-			switch tool.Suffix(p) {
-			case "sml":
-				_, err = global.SignalGraphMgr().Access(p)
-			case "alml":
-				_, err = global.LibraryMgr().Access(p)
-			case "spml":
-				_, err = global.PlatformMgr().Access(p)
-			case "mml":
-				_, err = global.MappingMgr().Access(p)
-			default:
-				log.Println("Warning: unknown suffix", tool.Suffix(p))
-			}
+	// Handle command line arguments: treat each as a filename,
+	// "-compare old" compares the following document with old ("-": stdin):
+	var compareFile string
+	for i := 1; i < len(unhandledArgs); i++ {
+		p := unhandledArgs[i]
+		if p == "-compare" && i+1 < len(unhandledArgs) {
+			i++
+			compareFile = unhandledArgs[i]
+			continue
+		}
+		var doc tr.ToplevelTreeElementIf
+		switch tool.DocSuffix(p) {
+		case "sml":
+			doc, err = global.SignalGraphMgr().Access(p)
+		case "alml":
+			_, err = global.LibraryMgr().Access(p)
+		case "spml":
+			_, err = global.PlatformMgr().Access(p)
+		case "mml":
+			doc, err = global.MappingMgr().Access(p)
+		case backend.ProjectSuffix:
+			err = openProject(menu, p)
+		default:
+			log.Println("Warning: unknown suffix", tool.Suffix(p))
+		}
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if len(compareFile) > 0 && doc != nil {
+			err = compareWith(doc, compareFile)
 			if err != nil {
 				log.Println(err)
-				continue
 			}
+			compareFile = ""
 		}
 	}
+	updateProblems()
 
 	global.win.Window().ShowAll()
 	gtk.Main()
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/headless"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/tool"
+	"github.com/gotk3/gotk3/gtk"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+func MenuCompareInit(menu *GoAppMenu) {
+	menu.viewCompare.Connect("activate", func() { viewCompare() })
+	menu.viewEndComp.Connect("activate", func() { viewEndCompare() })
+}
+
+/*
+ *		Callbacks
+ */
+
+func viewCompare() {
+	doc, ok := currentComparable()
+	if !ok {
+		return
+	}
+	filename, ok := runCompareDialog(doc)
+	if !ok {
+		return
+	}
+	err := compareWith(doc, filename)
+	if err != nil {
+		log.Printf("viewCompare: %s\n", err)
+	}
+}
+
+func viewEndCompare() {
+	doc, ok := currentComparable()
+	if !ok {
+		return
+	}
+	err := global.GVC().Compare(doc, nil)
+	if err != nil {
+		log.Printf("viewEndCompare: %s\n", err)
+	}
+}
+
+/*
+ *		Local functions
+ */
+
+// Signal graphs and mappings can be compared.
+func currentComparable() (doc tr.ToplevelTreeElementIf, ok bool) {
+	if len(global.fts.GetCurrentId()) == 0 {
+		return
+	}
+	doc = getCurrentTopObject(global.fts)
+	switch doc.(type) {
+	case bh.SignalGraphIf, mp.MappingIf:
+		ok = true
+	default:
+		log.Printf("compare: %s is neither a signal graph nor a mapping\n", doc.Filename())
+	}
+	return
+}
+
+// Show the differences of doc to another version of it, read from
+// oldFile or from stdin if oldFile is "-" (e.g. git show HEAD:graph.sml).
+// The other version is read apart from all open documents, references
+// are resolved relative to the directory of doc.
+func compareWith(doc tr.ToplevelTreeElementIf, oldFile string) (err error) {
+	if oldFile == "-" {
+		oldFile, err = stdinToFile(doc.Filename())
+		if err != nil {
+			return
+		}
+		defer os.Remove(oldFile)
+	}
+	dir := doc.PathPrefix()
+	if len(dir) == 0 {
+		dir = backend.XmlRoot()
+	}
+	old, err := headless.LoadAs(oldFile, doc.Filename(), dir)
+	if err != nil {
+		return
+	}
+	err = global.GVC().Compare(doc, old)
+	if err == nil {
+		log.Printf("compare: %s with %s\n", doc.Filename(), oldFile)
+	}
+	return
+}
+
+func stdinToFile(name string) (filename string, err error) {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		err = fmt.Errorf("stdinToFile error: %s", err)
+		return
+	}
+	f, err := ioutil.TempFile("", "sge-compare-")
+	if err != nil {
+		err = fmt.Errorf("stdinToFile error: %s", err)
+		return
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	if err != nil {
+		err = fmt.Errorf("stdinToFile error: %s", err)
+		os.Remove(f.Name())
+		return
+	}
+	filename = f.Name()
+	return
+}
+
+func runCompareDialog(doc tr.ToplevelTreeElementIf) (filename string, ok bool) {
+	dialog, err := gtk.FileChooserDialogNewWith2Buttons(
+		fmt.Sprintf("Choose version to compare %s with", doc.Filename()),
+		nil,
+		gtk.FILE_CHOOSER_ACTION_OPEN,
+		"Cancel",
+		gtk.RESPONSE_CANCEL,
+		"Compare",
+		gtk.RESPONSE_OK)
+	if err != nil {
+		log.Printf("runCompareDialog error: %s\n", err)
+		return
+	}
+	dir := doc.PathPrefix()
+	if len(dir) == 0 {
+		dir = backend.XmlRoot()
+	}
+	dialog.SetCurrentFolder(dir)
+	suffix := tool.DocSuffix(doc.Filename())
+	ff, _ := gtk.FileFilterNew()
+	ff.SetName(fmt.Sprintf("%s (*.%s)", Description(doc), suffix))
+	ff.AddPattern(fmt.Sprintf("*.%s", suffix))
+	dialog.AddFilter(ff)
+	response := dialog.Run()
+	ok = (gtk.ResponseType(response) == gtk.RESPONSE_OK)
+	filename = dialog.GetFilename()
+	dialog.Destroy()
+	return
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/views"
+	"log"
+)
+
+func MenuDependenciesInit(menu *GoAppMenu) {
+	menu.viewDeps.Connect("activate", func() { viewDependencies() })
+}
+
+// The dependency view is created once, later it is synced with the
+// other views.
+func viewDependencies() {
+	if global.depsView != nil {
+		global.depsView.Sync()
+		return
+	}
+	gv, err := views.DependencyViewNew(dependencySource, navigateToDocument)
+	if err != nil {
+		log.Printf("viewDependencies: %s\n", err)
+		return
+	}
+	global.depsView = gv
+	global.win.graphViews.Add(gv, "Dependencies")
+	global.ShowAll()
+}
+
+// Open documents as they are in memory, the other project members from
+// their files.
+func dependencySource() *deps.Graph {
+	g := deps.GraphNew()
+	addOpenDocuments(g.Add, global.fts)
+	g.AddProject()
+	return g
+}
+
+func navigateToDocument(filename string) {
+	doc, err := accessDocument(filename)
+	if err != nil {
+		log.Printf("navigateToDocument: %s\n", err)
+		return
+	}
+	selectInTree(doc)
+}
@@ -19,6 +19,9 @@ func MenuEditInit(menu *GoAppMenu) {
 	menu.editRedo.Connect("activate", func() { editRedo(menu, fts, jl, ftv) })
 	menu.editNew.Connect("activate", func() { editNew(menu, fts, jl, ftv) })
 	menu.editEdit.Connect("activate", func() { editEdit(menu, fts, jl, ftv) })
+	menu.editRename.Connect("activate", func() { editRename(menu, fts, jl, ftv) })
+	menu.editUsages.Connect("activate", func() { editFindUsages(fts) })
+	menu.editMove.Connect("activate", func() { editMove(menu, fts, jl, ftv) })
 	menu.editDelete.Connect("activate", func() { editDelete(menu, fts, jl, ftv) })
 	menu.editCopy.Connect("activate", func() { editCopy(fts, clp) })
 	menu.editPaste.Connect("activate", func() { editPaste(menu, fts, jl, ftv, clp) })
@@ -27,6 +30,9 @@ func MenuEditInit(menu *GoAppMenu) {
 	menu.editNew.SetSensitive(false)
 	menu.editDelete.SetSensitive(false)
 	menu.editEdit.SetSensitive(false)
+	menu.editRename.SetSensitive(false)
+	menu.editUsages.SetSensitive(false)
+	menu.editMove.SetSensitive(false)
 }
 
 func MenuEditPost(menu *GoAppMenu, fts *models.FilesTreeStore, jl IJobList) {
@@ -50,10 +56,19 @@ func MenuEditCurrent(menu *GoAppMenu, fts *models.FilesTreeStore, jl IJobList) {
 		menu.editNew.SetSensitive(prop.MayAddObject())
 		menu.editDelete.SetSensitive(prop.MayRemove())
 		menu.editEdit.SetSensitive(prop.MayEdit())
+		_, ok := renameKind(fts.Object(cursor))
+		menu.editRename.SetSensitive(ok)
+		_, _, ok = usageKey(fts.Object(cursor))
+		menu.editUsages.SetSensitive(ok)
+		_, ok = movableFrom(fts, cursor)
+		menu.editMove.SetSensitive(ok)
 	} else {
 		menu.editNew.SetSensitive(false)
 		menu.editDelete.SetSensitive(false)
 		menu.editEdit.SetSensitive(false)
+		menu.editRename.SetSensitive(false)
+		menu.editUsages.SetSensitive(false)
+		menu.editMove.SetSensitive(false)
 	}
 }
 
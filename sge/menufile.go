@@ -45,10 +45,10 @@ var allFileTypes = [4]FileType{
 }
 
 var descriptionFileTypes = map[FileType]string{
-	FileTypeGraph: "Graph File (*.sml)",
-	FileTypeLib:   "Library File (*.alml)",
-	FileTypePlat:  "Platform File (*.spml)",
-	FileTypeMap:   "Mapping File (*.mml)",
+	FileTypeGraph: "Graph File (*.sml, *.sml.json)",
+	FileTypeLib:   "Library File (*.alml, *.alml.json)",
+	FileTypePlat:  "Platform File (*.spml, *.spml.json)",
+	FileTypeMap:   "Mapping File (*.mml, *.mml.json)",
 }
 
 func Suffix(obj tr.ToplevelTreeElementIf) string {
@@ -86,6 +86,10 @@ var _ DirectoryMgrIf = (*DirectoryMgr)(nil)
 func DirectoryMgrNew(xmlRoot string) (d *DirectoryMgr) {
 	d = &DirectoryMgr{xmlRoot, nil, [4]string{xmlRoot, xmlRoot, xmlRoot, xmlRoot}}
 	d.dirList = append(d.dirList, xmlRoot)
+	for _, ft := range allFileTypes {
+		// project output directories, if any
+		d.current[d.objTypeIndex(ft)] = backend.OutputDir(string(ft))
+	}
 	return d
 }
 
@@ -181,6 +185,7 @@ func fileNewSg(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	if err != nil {
 		log.Printf("fileNewSg: %s\n", err)
 	}
+	updateProblems()
 }
 
 func fileNewLib(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
@@ -188,6 +193,7 @@ func fileNewLib(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	if err != nil {
 		log.Printf("fileNewLib: %s\n", err)
 	}
+	updateProblems()
 }
 
 func fileNewPlat(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
@@ -195,6 +201,7 @@ func fileNewPlat(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	if err != nil {
 		log.Printf("fileNewPlat: %s\n", err)
 	}
+	updateProblems()
 }
 
 func fileNewMap(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
@@ -229,8 +236,8 @@ func fileNewMap(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	_, err = global.MappingMgr().New()
 	if err != nil {
 		log.Printf("fileNewMap: %s\n", err)
-		return
 	}
+	updateProblems()
 }
 
 func getFileMgr(ft FileType) (fileMgr mod.FileManagerIf, err error) {
@@ -259,7 +266,7 @@ func fileOpen(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	var err error
 	var obj tr.ToplevelTreeElementIf
 	var fileMgr mod.FileManagerIf
-	fileMgr, err = getFileMgr(FileType(tool.Suffix(fname)))
+	fileMgr, err = getFileMgr(FileType(tool.DocSuffix(fname)))
 	obj, err = fileMgr.Access(fname)
 	if err != nil {
 		log.Printf("fileOpen error: %s\n", err)
@@ -267,6 +274,7 @@ func fileOpen(fts *models.FilesTreeStore, ftv *views.FilesTreeView) {
 	}
 	obj.SetPathPrefix(prefix)
 	dirMgr.SetCurrent(fileType(obj), tool.Dirname(fname))
+	updateProblems()
 }
 
 func fileSaveAs(fts *models.FilesTreeStore) {
@@ -336,6 +344,7 @@ func fileClose(menu *GoAppMenu, fts *models.FilesTreeStore, ftv *views.FilesTree
 	global.FileMgr(obj).Remove(obj.(fd.Filenamer).Filename())
 	jl.Reset()
 	MenuEditPost(menu, fts, jl)
+	updateProblems()
 }
 
 /*
@@ -381,6 +390,7 @@ func runFileDialog(fTypes []FileType, toSave bool, announce string) (filename st
 		ff, _ := gtk.FileFilterNew()
 		ff.SetName(descriptionFileTypes[ft])
 		ff.AddPattern(fmt.Sprintf("*.%s", string(ft)))
+		ff.AddPattern(fmt.Sprintf("*.%s.%s", string(ft), tool.JsonSuffix))
 		dialog.AddFilter(ff)
 	}
 	response := dialog.Run()
@@ -397,6 +407,10 @@ func getFilenameToSave(fts *models.FilesTreeStore) (filename string, ok bool) {
 	dirname := tool.Dirname(filename)
 	basename := tool.Basename(filename)
 	suffix := tool.Suffix(basename)
+	if tool.IsJson(basename) && tool.DocSuffix(basename) == string(fileType(obj)) {
+		// JSON encoding requested
+		return
+	}
 	if suffix == basename { // no suffix given
 		filename = fmt.Sprintf("%s.%s", filename, string(fileType(obj)))
 	} else if suffix != string(fileType(obj)) { // wrong suffix
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/refactor"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"github.com/axel-freesp/sge/views"
+	"github.com/gotk3/gotk3/gtk"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+func editMove(menu *GoAppMenu, fts *models.FilesTreeStore, jl IJobList, ftv *views.FilesTreeView) {
+	defer MenuEditPost(menu, fts, jl)
+	from, ok := movableFrom(fts, fts.Current())
+	if !ok {
+		return
+	}
+	var targets []bh.LibraryIf
+	for _, l := range openLibraries(fts) {
+		if l != from {
+			targets = append(targets, l)
+		}
+	}
+	if len(targets) == 0 {
+		log.Println("editMove: no other library open to move types to")
+		return
+	}
+	types, to, ok := runMoveDialog(from, targets, fts.Object(fts.Current()))
+	if !ok || len(types) == 0 {
+		return
+	}
+	m := refactor.Move{From: from.Filename(), To: to.Filename(),
+		FromPath: libraryPath(from), ToPath: libraryPath(to)}
+	for _, t := range typesOf(from) {
+		ref := typeRef(t)
+		moved := false
+		for _, mt := range types {
+			moved = moved || mt == t
+		}
+		if moved {
+			m.Types = append(m.Types, ref)
+		} else {
+			m.Remaining = append(m.Remaining, ref)
+		}
+	}
+	changes, err := refactor.MoveInFiles(closedProjectFiles(fts), m)
+	if err != nil {
+		log.Println("editMove error: ", err)
+		return
+	}
+	if !runMovePreview(m, changes) {
+		return
+	}
+	job := MoveJobNew(from, to, types, changes)
+	state, ok := jl.Apply(EditorJobNew(JobMove, job))
+	if ok {
+		global.win.graphViews.Sync()
+		path, err := gtk.TreePathNewFromString(state.(string))
+		if err != nil {
+			log.Println("editMove error: TreePathNewFromString failed:", err)
+			return
+		}
+		ftv.TreeView().ExpandToPath(path)
+		ftv.TreeView().SetCursor(path, ftv.TreeView().GetExpanderColumn(), false)
+	}
+}
+
+func libraryPath(l bh.LibraryIf) string {
+	return absPath(filepath.Join(l.PathPrefix(), l.Filename()))
+}
+
+// Types can be moved if they are selected in their library.
+func movableFrom(fts *models.FilesTreeStore, cursor tr.Cursor) (from bh.LibraryIf, ok bool) {
+	if !strings.Contains(cursor.Path, ":") {
+		return
+	}
+	if _, ok = renameKind(fts.Object(cursor)); !ok {
+		return
+	}
+	from, ok = fts.Object(fts.Parent(cursor)).(bh.LibraryIf)
+	return
+}
+
+func openLibraries(fts *models.FilesTreeStore) (libs []bh.LibraryIf) {
+	var te tr.TreeElementIf
+	var err error
+	for i := 0; err == nil; i++ {
+		te, err = fts.GetObjectById(fmt.Sprintf("%d", i))
+		if l, ok := te.(bh.LibraryIf); err == nil && ok {
+			libs = append(libs, l)
+		}
+	}
+	return
+}
+
+func typesOf(l bh.LibraryIf) (types []tr.TreeElementIf) {
+	for _, t := range l.SignalTypes() {
+		types = append(types, t)
+	}
+	for _, t := range l.NodeTypes() {
+		types = append(types, t)
+	}
+	return
+}
+
+func typeRef(t tr.TreeElementIf) refactor.TypeRef {
+	kind, _ := renameKind(t)
+	return refactor.TypeRef{kind, typeName(t)}
+}
+
+// Lets the user choose the types of from to move (current is checked)
+// and the target library.
+func runMoveDialog(from bh.LibraryIf, targets []bh.LibraryIf, current tr.TreeElementIf) (types []tr.TreeElementIf, to bh.LibraryIf, ok bool) {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		log.Println("runMoveDialog error: ", err)
+		return
+	}
+	d.SetTitle(fmt.Sprintf("Move types from %s", from.Filename()))
+	box, err := d.GetContentArea()
+	if err != nil {
+		log.Println("runMoveDialog error: ", err)
+		return
+	}
+	var names []string
+	for _, l := range targets {
+		names = append(names, l.Filename())
+	}
+	var combo *gtk.ComboBoxText
+	w, err := newComboBox(&combo, names)
+	if err != nil {
+		log.Println("runMoveDialog error: ", err)
+		return
+	}
+	row, err := createLabeledRow("Target library:", w)
+	if err != nil {
+		log.Println("runMoveDialog error: ", err)
+		return
+	}
+	box.PackStart(row, false, false, 6)
+	all := typesOf(from)
+	checks := make([]*gtk.CheckButton, len(all))
+	for i, t := range all {
+		ref := typeRef(t)
+		checks[i], err = gtk.CheckButtonNewWithLabel(fmt.Sprintf("%s %s", ref.Kind, ref.Name))
+		if err != nil {
+			log.Println("runMoveDialog error: ", err)
+			return
+		}
+		checks[i].SetActive(t == current)
+		box.PackStart(checks[i], false, false, 0)
+	}
+	d.AddButton("Cancel", gtk.RESPONSE_CANCEL)
+	d.AddButton("OK", gtk.RESPONSE_OK)
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	d.ShowAll()
+	ok = (gtk.ResponseType(d.Run()) == gtk.RESPONSE_OK)
+	if ok {
+		for i, t := range all {
+			if checks[i].GetActive() {
+				types = append(types, t)
+			}
+		}
+		to = targets[combo.GetActive()]
+	}
+	d.Destroy()
+	return
+}
+
+func runMovePreview(m refactor.Move, changes []refactor.FileChange) bool {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		log.Println("runMovePreview error: ", err)
+		return false
+	}
+	d.SetTitle(fmt.Sprintf("Move types from %s to %s", m.From, m.To))
+	box, err := d.GetContentArea()
+	if err != nil {
+		log.Println("runMovePreview error: ", err)
+		return false
+	}
+	addRow := func(text string) {
+		row, err := createLabeledRow(text, nil)
+		if err != nil {
+			log.Println("runMovePreview error: ", err)
+			return
+		}
+		box.PackStart(row, false, false, 0)
+	}
+	for _, t := range m.Types {
+		addRow(fmt.Sprintf("%s %s", t.Kind, t.Name))
+	}
+	addRow(fmt.Sprintf("Open documents: library references updated, save %s and %s.", m.From, m.To))
+	if len(changes) > 0 {
+		addRow("Project files (library references rewritten now):")
+		for _, c := range changes {
+			addRow(fmt.Sprintf("    %s: %d references", c.Filename, c.References))
+		}
+	}
+	d.AddButton("Cancel", gtk.RESPONSE_CANCEL)
+	d.AddButton("Move", gtk.RESPONSE_OK)
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	d.ShowAll()
+	ok := (gtk.ResponseType(d.Run()) == gtk.RESPONSE_OK)
+	d.Destroy()
+	return ok
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	mod "github.com/axel-freesp/sge/interface/model"
+	"github.com/axel-freesp/sge/tool"
+	"github.com/gotk3/gotk3/gtk"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxRecentProjects = 8
+
+var recentItems []*gtk.MenuItem
+
+func MenuProjectInit(menu *GoAppMenu) {
+	menu.fileOpenProj.Connect("activate", func() { fileOpenProject(menu) })
+	updateRecentMenu(menu)
+}
+
+/*
+ *		Callbacks
+ */
+
+func fileOpenProject(menu *GoAppMenu) {
+	filename, ok := runProjectDialog()
+	if !ok {
+		return
+	}
+	err := openProject(menu, filename)
+	if err != nil {
+		log.Printf("fileOpenProject: %s\n", err)
+	}
+}
+
+/*
+ *		Local functions
+ */
+
+// Make the project the current one and open all its documents.
+// Libraries are opened first, such that graphs find their node types.
+func openProject(menu *GoAppMenu, filename string) (err error) {
+	filename, err = filepath.Abs(filename)
+	if err != nil {
+		return
+	}
+	p := backend.XmlProjectNew("")
+	err = p.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	backend.SetProject(p, filename)
+	dirMgr = DirectoryMgrNew(backend.XmlRoot())
+	members := []struct {
+		ft   FileType
+		refs []backend.XmlProjectMember
+	}{
+		{FileTypeLib, p.Libraries},
+		{FileTypeGraph, p.Graphs},
+		{FileTypePlat, p.Platforms},
+		{FileTypeMap, p.Mappings},
+	}
+	for _, m := range members {
+		var fileMgr mod.FileManagerIf
+		fileMgr, err = getFileMgr(m.ft)
+		if err != nil {
+			return
+		}
+		for _, ref := range m.refs {
+			_, err = fileMgr.Access(ref.Ref)
+			if err != nil {
+				log.Printf("openProject: %s\n", err)
+			}
+		}
+	}
+	err = nil
+	updateProblems()
+	addRecentProject(filename)
+	updateRecentMenu(menu)
+	log.Printf("openProject: project %s opened\n", filename)
+	return
+}
+
+func runProjectDialog() (filename string, ok bool) {
+	dialog, err := gtk.FileChooserDialogNewWith2Buttons(
+		"Choose project to open",
+		nil,
+		gtk.FILE_CHOOSER_ACTION_OPEN,
+		"Cancel",
+		gtk.RESPONSE_CANCEL,
+		"Open",
+		gtk.RESPONSE_OK)
+	if err != nil {
+		log.Printf("runProjectDialog error: %s\n", err)
+		return
+	}
+	dialog.SetCurrentFolder(backend.XmlRoot())
+	ff, _ := gtk.FileFilterNew()
+	ff.SetName(fmt.Sprintf("Project File (*.%s)", backend.ProjectSuffix))
+	ff.AddPattern(fmt.Sprintf("*.%s", backend.ProjectSuffix))
+	dialog.AddFilter(ff)
+	response := dialog.Run()
+	ok = (gtk.ResponseType(response) == gtk.RESPONSE_OK)
+	filename = dialog.GetFilename()
+	dialog.Destroy()
+	return
+}
+
+func updateRecentMenu(menu *GoAppMenu) {
+	for _, item := range recentItems {
+		menu.menuRecent.Remove(item)
+	}
+	recentItems = nil
+	for _, filename := range recentProjects() {
+		item, err := gtk.MenuItemNewWithLabel(filename)
+		if err != nil {
+			log.Printf("updateRecentMenu error: %s\n", err)
+			return
+		}
+		f := filename
+		item.Connect("activate", func() {
+			err := openProject(menu, f)
+			if err != nil {
+				log.Printf("fileRecentProject: %s\n", err)
+			}
+		})
+		menu.menuRecent.Append(item)
+		recentItems = append(recentItems, item)
+	}
+	menu.fileRecent.SetSensitive(len(recentItems) > 0)
+	menu.menuRecent.ShowAll()
+}
+
+// The list of recently opened projects is kept in the home directory,
+// most recent first.
+func recentProjectsFilename() string {
+	return fmt.Sprintf("%s/.sge-recent-projects", os.Getenv("HOME"))
+}
+
+func recentProjects() (list []string) {
+	data, err := tool.ReadFile(recentProjectsFilename())
+	if err != nil {
+		return
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		if len(strings.TrimSpace(l)) > 0 {
+			list = append(list, strings.TrimSpace(l))
+		}
+	}
+	return
+}
+
+func addRecentProject(filename string) {
+	list := []string{filename}
+	for _, f := range recentProjects() {
+		if f != filename && len(list) < maxRecentProjects {
+			list = append(list, f)
+		}
+	}
+	err := tool.WriteFile(recentProjectsFilename(), []byte(strings.Join(list, "\n")+"\n"))
+	if err != nil {
+		log.Printf("addRecentProject: failed to write %s: %s\n", recentProjectsFilename(), err)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/freesp/refactor"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"github.com/axel-freesp/sge/views"
+	"github.com/gotk3/gotk3/gtk"
+	"log"
+	"path/filepath"
+	"sort"
+)
+
+func editRename(menu *GoAppMenu, fts *models.FilesTreeStore, jl IJobList, ftv *views.FilesTreeView) {
+	defer MenuEditPost(menu, fts, jl)
+	obj, err := fts.GetObjectById(fts.GetCurrentId())
+	if err != nil {
+		log.Println("editRename error: ", err)
+		return
+	}
+	kind, ok := renameKind(obj)
+	if !ok {
+		return
+	}
+	oldName := typeName(obj)
+	newName, ok := runRenameDialog(obj, kind, oldName)
+	if !ok {
+		return
+	}
+	definedAt := typeDefinedAt(obj)
+	g := deps.GraphNew()
+	open := openReferences(fts, g, kind, definedAt, oldName)
+	changes, err := refactor.RenameInFiles(g, closedProjectFiles(fts), kind, definedAt, oldName, newName)
+	if err != nil {
+		log.Println("editRename error: ", err)
+		return
+	}
+	if !runRenamePreview(kind, oldName, newName, open, changes) {
+		return
+	}
+	job := RenameJobNew(obj, kind, oldName, newName, changes)
+	state, ok := jl.Apply(EditorJobNew(JobRename, job))
+	if ok {
+		global.win.graphViews.Sync()
+		path, err := gtk.TreePathNewFromString(state.(string))
+		if err != nil {
+			log.Println("editRename error: TreePathNewFromString failed:", err)
+			return
+		}
+		ftv.TreeView().ExpandToPath(path)
+		ftv.TreeView().SetCursor(path, ftv.TreeView().GetExpanderColumn(), false)
+	}
+}
+
+// Signal and node types can be renamed.
+func renameKind(obj tr.TreeElementIf) (kind refactor.TypeKind, ok bool) {
+	switch obj.(type) {
+	case bh.SignalTypeIf:
+		kind, ok = refactor.SignalType, true
+	case bh.NodeTypeIf:
+		kind, ok = refactor.NodeType, true
+	}
+	return
+}
+
+func typeName(obj tr.TreeElementIf) string {
+	switch t := obj.(type) {
+	case bh.SignalTypeIf:
+		return t.TypeName()
+	case bh.NodeTypeIf:
+		return t.TypeName()
+	}
+	return ""
+}
+
+func typeDefinedAt(obj tr.TreeElementIf) string {
+	switch t := obj.(type) {
+	case bh.SignalTypeIf:
+		return t.DefinedAt()
+	case bh.NodeTypeIf:
+		return t.DefinedAt()
+	}
+	return ""
+}
+
+// A new name is valid if no other type is registered with it.
+func validTypeName(obj tr.TreeElementIf, kind refactor.TypeKind, oldName, newName string) bool {
+	if len(newName) == 0 || newName == oldName {
+		return false
+	}
+	var other tr.TreeElementIf
+	var ok bool
+	if kind == refactor.SignalType {
+		other, ok = global.Registry().GetSignalTypeByName(newName)
+	} else {
+		other, ok = global.Registry().GetNodeTypeByName(newName)
+	}
+	return !ok || other == obj
+}
+
+// Counts the references to the type in the open documents, by filename.
+// The open documents are added to g before the project files, names are
+// resolved with the editor's versions.
+func openReferences(fts *models.FilesTreeStore, g *deps.Graph, kind refactor.TypeKind, definedAt, name string) (refs map[string]int) {
+	refs = make(map[string]int)
+	docs := make(map[string]backend.Document)
+	names := make(map[string]string)
+	var te tr.TreeElementIf
+	var err error
+	for i := 0; err == nil; i++ {
+		te, err = fts.GetObjectById(fmt.Sprintf("%d", i))
+		if err != nil {
+			break
+		}
+		top := te.(tr.ToplevelTreeElementIf)
+		var doc backend.Document
+		switch d := te.(type) {
+		case bh.SignalGraphIf:
+			doc = behaviour.CreateXmlSignalGraph(d)
+		case bh.LibraryIf:
+			doc = behaviour.CreateXmlLibrary(d)
+		default:
+			continue
+		}
+		path := absPath(filepath.Join(top.PathPrefix(), top.Filename()))
+		g.Add(doc, path)
+		docs[path] = doc
+		names[path] = top.Filename()
+	}
+	g.AddProject()
+	for path, doc := range docs {
+		n := refactor.References(g, doc, path, kind, definedAt, name)
+		if n > 0 {
+			refs[names[path]] = n
+		}
+	}
+	return
+}
+
+// Project files which are not open, refactorings rewrite them.
+func closedProjectFiles(fts *models.FilesTreeStore) (files []string) {
+	open := make(map[string]bool)
+	var te tr.TreeElementIf
+	var err error
+	for i := 0; err == nil; i++ {
+		te, err = fts.GetObjectById(fmt.Sprintf("%d", i))
+		if err == nil {
+			top := te.(tr.ToplevelTreeElementIf)
+			open[absPath(filepath.Join(top.PathPrefix(), top.Filename()))] = true
+		}
+	}
+	for _, f := range refactor.ProjectFiles() {
+		if !open[absPath(f)] {
+			files = append(files, f)
+		}
+	}
+	return
+}
+
+// Paths relative to the project, as absolute path.
+func absPath(path string) string {
+	return backend.AbsPath(backend.ProjectPath(path))
+}
+
+func runRenameDialog(obj tr.TreeElementIf, kind refactor.TypeKind, oldName string) (newName string, ok bool) {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		log.Println("runRenameDialog error: ", err)
+		return
+	}
+	d.SetTitle(fmt.Sprintf("Rename %s", kind))
+	box, err := d.GetContentArea()
+	if err != nil {
+		log.Println("runRenameDialog error: ", err)
+		return
+	}
+	var entry *gtk.Entry
+	w, err := newEntry(&entry)
+	if err != nil {
+		log.Println("runRenameDialog error: ", err)
+		return
+	}
+	entry.SetText(oldName)
+	entry.SetActivatesDefault(true)
+	row, err := createLabeledRow(fmt.Sprintf("New name of %s:", oldName), w)
+	if err != nil {
+		log.Println("runRenameDialog error: ", err)
+		return
+	}
+	box.PackStart(row, false, false, 6)
+	d.AddButton("Cancel", gtk.RESPONSE_CANCEL)
+	okButton, _ := d.AddButton("OK", gtk.RESPONSE_OK)
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	okButton.SetSensitive(false)
+	entry.Connect("changed", func() {
+		okButton.SetSensitive(validTypeName(obj, kind, oldName, getText(entry)))
+	})
+	d.ShowAll()
+	ok = (gtk.ResponseType(d.Run()) == gtk.RESPONSE_OK)
+	newName = getText(entry)
+	d.Destroy()
+	ok = ok && validTypeName(obj, kind, oldName, newName)
+	return
+}
+
+// Lists the affected documents, returns true if the renaming shall be
+// applied.
+func runRenamePreview(kind refactor.TypeKind, oldName, newName string, open map[string]int, changes []refactor.FileChange) bool {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		log.Println("runRenamePreview error: ", err)
+		return false
+	}
+	d.SetTitle(fmt.Sprintf("Rename %s %s to %s", kind, oldName, newName))
+	box, err := d.GetContentArea()
+	if err != nil {
+		log.Println("runRenamePreview error: ", err)
+		return false
+	}
+	addRow := func(text string) {
+		row, err := createLabeledRow(text, nil)
+		if err != nil {
+			log.Println("runRenamePreview error: ", err)
+			return
+		}
+		box.PackStart(row, false, false, 0)
+	}
+	if len(open) == 0 && len(changes) == 0 {
+		addRow("No document refers to the type.")
+	}
+	if len(open) > 0 {
+		addRow("Open documents (saved with the new name):")
+		var names []string
+		for f := range open {
+			names = append(names, f)
+		}
+		sort.Strings(names)
+		for _, f := range names {
+			addRow(fmt.Sprintf("    %s: %d references", f, open[f]))
+		}
+	}
+	if len(changes) > 0 {
+		addRow("Project files (rewritten now):")
+		for _, c := range changes {
+			addRow(fmt.Sprintf("    %s: %d references", c.Filename, c.References))
+		}
+	}
+	d.AddButton("Cancel", gtk.RESPONSE_CANCEL)
+	d.AddButton("Rename", gtk.RESPONSE_OK)
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	d.ShowAll()
+	ok := (gtk.ResponseType(d.Run()) == gtk.RESPONSE_OK)
+	d.Destroy()
+	return ok
+}
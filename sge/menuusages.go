@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/mapping"
+	"github.com/axel-freesp/sge/freesp/xref"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	mod "github.com/axel-freesp/sge/interface/model"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"github.com/axel-freesp/sge/tool"
+	"github.com/gotk3/gotk3/gtk"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+func editFindUsages(fts *models.FilesTreeStore) {
+	obj, err := fts.GetObjectById(fts.GetCurrentId())
+	if err != nil {
+		log.Println("editFindUsages error: ", err)
+		return
+	}
+	kind, name, ok := usageKey(obj)
+	if !ok {
+		return
+	}
+	x := xref.IndexNew()
+	addOpenDocuments(x.Add, fts)
+	x.AddSearchPath()
+	what := fmt.Sprintf("%s %s", kind, name)
+	if kind == xref.GraphRef || kind == xref.PlatformRef || kind == xref.LibraryRef {
+		what = fmt.Sprintf("%s %s", kind, filepath.Base(name))
+	}
+	global.usv.Set(what, x.Usages(kind, name))
+}
+
+// What obj is referred to by: types by name, documents by path.
+func usageKey(obj tr.TreeElementIf) (kind xref.Kind, name string, ok bool) {
+	ok = true
+	switch t := obj.(type) {
+	case bh.NodeTypeIf:
+		kind, name = xref.NodeTypeRef, t.TypeName()
+	case bh.SignalTypeIf:
+		kind, name = xref.SignalTypeRef, t.TypeName()
+	case bh.SignalGraphIf:
+		kind, name = xref.GraphRef, documentPath(t)
+	case pf.PlatformIf:
+		kind, name = xref.PlatformRef, documentPath(t)
+	case bh.LibraryIf:
+		kind, name = xref.LibraryRef, documentPath(t)
+	default:
+		ok = false
+	}
+	return
+}
+
+func documentPath(doc tr.ToplevelTreeElementIf) string {
+	return absPath(filepath.Join(doc.PathPrefix(), doc.Filename()))
+}
+
+// Open documents are passed to add as they are in memory.
+func addOpenDocuments(add func(backend.Document, string), fts *models.FilesTreeStore) {
+	var te tr.TreeElementIf
+	var err error
+	for i := 0; err == nil; i++ {
+		te, err = fts.GetObjectById(fmt.Sprintf("%d", i))
+		if err != nil {
+			break
+		}
+		var doc backend.Document
+		switch d := te.(type) {
+		case bh.SignalGraphIf:
+			doc = behaviour.CreateXmlSignalGraph(d)
+		case bh.LibraryIf:
+			doc = behaviour.CreateXmlLibrary(d)
+		case mp.MappingIf:
+			doc = mapping.CreateXmlMapping(d)
+		default:
+			continue
+		}
+		add(doc, documentPath(te.(tr.ToplevelTreeElementIf)))
+	}
+}
+
+// Select the element a usage refers to, open its document if needed.
+func navigateToUsage(u xref.Usage) {
+	doc, err := accessDocument(u.Filename)
+	if err != nil {
+		log.Printf("navigateToUsage: %s\n", err)
+		return
+	}
+	var target tr.TreeElementIf = doc
+	switch d := doc.(type) {
+	case bh.SignalGraphIf:
+		target = usageInGraph(d.ItsType(), u, target)
+	case bh.LibraryIf:
+		target = usageInLibrary(d, u, target)
+	}
+	selectInTree(target)
+}
+
+func selectInTree(target tr.TreeElementIf) {
+	selectCursor(global.fts.Cursor(target))
+}
+
+func selectCursor(cursor tr.Cursor) {
+	path, err := gtk.TreePathNewFromString(cursor.Path)
+	if err != nil {
+		log.Println("selectCursor error: TreePathNewFromString failed:", err)
+		return
+	}
+	tv := global.ftv.TreeView()
+	tv.ExpandToPath(path)
+	tv.SetCursor(path, tv.GetExpanderColumn(), false)
+}
+
+func usageInGraph(g bh.SignalGraphTypeIf, u xref.Usage, target tr.TreeElementIf) tr.TreeElementIf {
+	n, ok := g.NodeByName(u.Node)
+	if !ok {
+		return target
+	}
+	target = n
+	for _, p := range append(n.InPorts(), n.OutPorts()...) {
+		if p.Name() == u.Port {
+			target = p
+		}
+	}
+	return target
+}
+
+func usageInLibrary(l bh.LibraryIf, u xref.Usage, target tr.TreeElementIf) tr.TreeElementIf {
+	for _, nt := range l.NodeTypes() {
+		if nt.TypeName() != u.NodeType {
+			continue
+		}
+		target = nt
+		if len(u.Impl) > 0 {
+			for _, impl := range nt.Implementation() {
+				if impl.ElementName() == u.Impl && impl.ImplementationType() == bh.NodeTypeGraph {
+					target = usageInGraph(impl.Graph(), u, impl)
+				}
+			}
+			return target
+		}
+		for _, p := range append(nt.InPorts(), nt.OutPorts()...) {
+			if p.Name() == u.Port {
+				target = p
+			}
+		}
+	}
+	return target
+}
+
+// The open document stored as filename, read if necessary.
+func accessDocument(filename string) (doc tr.ToplevelTreeElementIf, err error) {
+	var te tr.TreeElementIf
+	for i := 0; err == nil; i++ {
+		te, err = global.fts.GetObjectById(fmt.Sprintf("%d", i))
+		if err == nil && documentPath(te.(tr.ToplevelTreeElementIf)) == filename {
+			doc = te.(tr.ToplevelTreeElementIf)
+			return
+		}
+	}
+	err = nil
+	var mgr mod.FileManagerIf
+	switch tool.DocSuffix(filename) {
+	case "sml":
+		mgr = global.SignalGraphMgr()
+	case "alml":
+		mgr = global.LibraryMgr()
+	case "mml":
+		mgr = global.MappingMgr()
+	default:
+		err = fmt.Errorf("accessDocument error: unknown document type %s", filename)
+		return
+	}
+	// file managers read documents by their name along the search path
+	for _, sp := range backend.XmlSearchPaths() {
+		rel, e := filepath.Rel(absPath(sp), filename)
+		if e == nil && !strings.HasPrefix(rel, "..") {
+			return mgr.Access(rel)
+		}
+	}
+	err = fmt.Errorf("accessDocument error: %s is not on the search path", filename)
+	return
+}
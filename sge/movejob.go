@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/refactor"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"log"
+)
+
+// MoveJob moves node and signal types from one open library to another
+// and updates the library references of the project files that are not
+// open.
+type MoveJob struct {
+	from, to bh.LibraryIf
+	types    []tr.TreeElementIf
+	files    []refactor.FileChange
+}
+
+func MoveJobNew(from, to bh.LibraryIf, types []tr.TreeElementIf, files []refactor.FileChange) *MoveJob {
+	return &MoveJob{from, to, types, files}
+}
+
+func (j *MoveJob) String() string {
+	return fmt.Sprintf("MoveJob(%d types %s -> %s, %d files)", len(j.types), j.from.Filename(), j.to.Filename(), len(j.files))
+}
+
+func (j *MoveJob) Move(fts *models.FilesTreeStore, direction EditJobDirection) (state string, err error) {
+	src, dst := j.from, j.to
+	if direction == EditJobRevert {
+		src, dst = j.to, j.from
+	}
+	// all types are checked first, the move is done completely or not
+	// at all
+	for _, t := range j.types {
+		switch obj := t.(type) {
+		case bh.SignalTypeIf:
+			err = behaviour.CheckMoveSignalType(obj, src, dst)
+		case bh.NodeTypeIf:
+			err = behaviour.CheckMoveNodeType(obj, src, dst)
+		default:
+			err = fmt.Errorf("MoveJob.Move error: invalid object type %T", t)
+		}
+		if err != nil {
+			return
+		}
+	}
+	for _, t := range j.types {
+		switch obj := t.(type) {
+		case bh.SignalTypeIf:
+			err = behaviour.MoveSignalType(obj, src, dst)
+		case bh.NodeTypeIf:
+			err = behaviour.MoveNodeType(obj, src, dst)
+		}
+		if err != nil {
+			return
+		}
+		fts.Remove(fts.CursorAt(fts.Cursor(src), t))
+		dstCursor := fts.Cursor(dst)
+		var c tr.Cursor
+		if _, ok := t.(bh.SignalTypeIf); ok {
+			// signal types are listed before node types
+			dstCursor.Position = len(dst.SignalTypes()) - 1
+			c = fts.Insert(dstCursor)
+		} else {
+			c = fts.Append(dstCursor)
+		}
+		t.AddToTree(fts, c)
+	}
+	if len(j.types) > 0 {
+		state = fts.CursorAt(fts.Cursor(dst), j.types[0]).Path
+	}
+	for _, f := range j.files {
+		var e error
+		if direction == EditJobForward {
+			e = f.Apply()
+		} else {
+			e = f.Revert()
+		}
+		if e != nil {
+			log.Printf("MoveJob.Move: failed to write %s: %s\n", f.Filename, e)
+			err = e
+		}
+	}
+	return
+}
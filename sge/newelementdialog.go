@@ -1,7 +1,7 @@
 package main
 
 import (
-	//"fmt"
+	"fmt"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	pf "github.com/axel-freesp/sge/interface/platform"
 	"github.com/axel-freesp/sge/models"
@@ -45,7 +45,8 @@ var choiceMap = map[elementType][]elementType{
 	ePort:           {eConnection},
 	ePortType:       {ePortType},
 	eConnection:     {eConnection},
-	eSignalType:     {eSignalType, eNodeType},
+	eSignalType:     {eSignalType, eSignalField, eNodeType},
+	eSignalField:    {eSignalField},
 	eLibrary:        {eSignalType, eNodeType},
 	eImplementation: {eImplementation, eNode},
 	ePlatform:       {eArch},
@@ -77,6 +78,8 @@ func getSelectorChoices(fts *models.FilesTreeStore) []elementType {
 		activeElem = eConnection
 	case bh.SignalTypeIf:
 		activeElem = eSignalType
+	case bh.SignalFieldIf:
+		activeElem = eSignalField
 	case bh.LibraryIf:
 		activeElem = eLibrary
 	case bh.ImplementationIf:
@@ -127,9 +130,42 @@ func (dialog *NewElementDialog) CollectJob(context string) *EditorJob {
 	for _, i := range inputElementMap[job.elemType] {
 		job.input[i] = dialog.readOut(i)
 	}
+	if job.elemType == eConnection {
+		conv := dialog.converterJob(context, job.input[iPortSelect])
+		if conv != nil {
+			return EditorJobNew(JobInsertConverter, conv)
+		}
+	}
 	return EditorJobNew(JobNewElement, job)
 }
 
+// Ports of convertible signal types are connected through a converter
+// node, returns nil for other choices.
+func (dialog *NewElementDialog) converterJob(context, choice string) *InsertConverterJob {
+	fts := dialog.fts
+	object, err := fts.GetObjectById(context)
+	if err != nil {
+		return nil
+	}
+	if _, ok := object.(bh.ConnectionIf); ok {
+		context = getParentId(context)
+		object, err = fts.GetObjectById(context)
+		if err != nil {
+			return nil
+		}
+	}
+	if _, ok := object.(bh.PortIf); !ok {
+		return nil
+	}
+	ports, conv := getConvertiblePorts(fts, object)
+	for i, p := range ports {
+		if convertiblePortChoice(p, conv[i]) == choice {
+			return InsertConverterJobNew(context, p, conv[i])
+		}
+	}
+	return nil
+}
+
 func (dialog *NewElementDialog) Run(fts *models.FilesTreeStore) (job *EditorJob, ok bool) {
 	context := fts.GetCurrentId()
 	if context == "" {
@@ -142,5 +178,39 @@ func (dialog *NewElementDialog) Run(fts *models.FilesTreeStore) (job *EditorJob,
 		//log.Println("editNew terminated: OK", job)
 	}
 	dialog.Dialog().Destroy()
+	if ok && job.jobType == JobInsertConverter {
+		ok = runInsertConverterDialog(job.converter)
+	}
 	return
 }
+
+// Connecting ports of convertible signal types needs a converter node,
+// the user confirms to insert it.
+func runInsertConverterDialog(job *InsertConverterJob) bool {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		log.Println("runInsertConverterDialog error: ", err)
+		return false
+	}
+	d.SetTitle("Insert converter")
+	box, err := d.GetContentArea()
+	if err != nil {
+		log.Println("runInsertConverterDialog error: ", err)
+		return false
+	}
+	text := fmt.Sprintf("The signal types differ. Connect %s/%s through a new %s node?",
+		job.other.Node().Name(), job.other.Name(), job.converter.TypeName())
+	row, err := createLabeledRow(text, nil)
+	if err != nil {
+		log.Println("runInsertConverterDialog error: ", err)
+		return false
+	}
+	box.PackStart(row, false, false, 6)
+	d.AddButton("Cancel", gtk.RESPONSE_CANCEL)
+	d.AddButton("Insert", gtk.RESPONSE_OK)
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	d.ShowAll()
+	ok := (gtk.ResponseType(d.Run()) == gtk.RESPONSE_OK)
+	d.Destroy()
+	return ok
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/axel-freesp/sge/models"
 	//"image"
 	"log"
+	"strconv"
 	"strings"
 )
 
@@ -34,6 +35,18 @@ func (j *NewElementJob) String() string {
 	return ret
 }
 
+// Empty array sizes denote scalar fields.
+func arraySize(text string) (n int, err error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return
+	}
+	n, err = strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		err = fmt.Errorf("invalid array size '%s'", text)
+	}
+	return
+}
+
 func getParentId(id string) string {
 	split := strings.Split(id, ":")
 	return strings.Join(split[:len(split)-1], ":")
@@ -43,7 +56,8 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 	var parentObject tr.TreeElementIf
 	parentObject, err = fts.GetObjectById(j.parentId)
 	if err != nil {
-		log.Fatal("NewElementJob.CreateObject error: referenced parentObject run away...")
+		err = freesp.NotFoundError("NewElementJob.CreateObject", "parent %s not found", j.parentId)
+		return
 	}
 	switch j.elemType {
 	case eNode, eInputNode, eOutputNode:
@@ -60,17 +74,26 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			if parentObject.(bh.ImplementationIf).ImplementationType() == bh.NodeTypeGraph {
 				context = parentObject.(bh.ImplementationIf).Graph()
 			} else {
-				log.Fatal("NewElementJob.CreateObject(eNode) error: parent implementation is no graph...")
+				err = freesp.InvalidOperationError("NewElementJob.CreateObject", "implementation %s is no graph", parentObject.(bh.ImplementationIf).ElementName())
+				return
 			}
 		default:
-			log.Fatal("NewElementJob.CreateObject(eNode) error: referenced parentObject wrong type...")
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add nodes to %T", parentObject)
+			return
 		}
 		if j.elemType == eNode {
-			ntype, ok := freesp.GetNodeTypeByName(j.input[iNodeTypeSelect])
+			ntype, ok := global.Registry().GetNodeTypeByName(j.input[iNodeTypeSelect])
 			if !ok {
-				log.Fatal("NewElementJob.CreateObject(eNode) error: referenced parentObject type wrong...")
+				err = freesp.NotFoundError("NewElementJob.CreateObject", "node type %s not found", j.input[iNodeTypeSelect])
+				return
 			}
 			ret, err = behaviour.NodeNew(j.input[iNodeName], ntype, context)
+			if err == nil {
+				perr := behaviour.SetParamValues(ret.(bh.NodeIf), j.input[iNodeParams])
+				if perr != nil {
+					log.Printf("NewElementJob.CreateObject(eNode) warning: %s (using defaults)\n", perr)
+				}
+			}
 		} else if j.elemType == eInputNode {
 			ret, err = behaviour.InputNodeNew(j.input[iInputNodeName], j.input[iInputTypeSelect], context)
 		} else {
@@ -100,9 +123,17 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 		case bh.LibraryIf:
 			context = parentObject.(bh.LibraryIf).Filename()
 		default:
-			log.Fatal("NewElementJob.CreateObject(eNodeType) error: referenced parentObject wrong type...")
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add node types to %T", parentObject)
+			return
 		}
-		ret = behaviour.NodeTypeNew(j.input[iTypeName], context)
+		var params []bh.ParameterIf
+		params, err = behaviour.ParseParameters(j.input[iTypeParams])
+		if err != nil {
+			return
+		}
+		nt := behaviour.NodeTypeNew(j.input[iTypeName], context, &global)
+		behaviour.SetParameters(nt, params)
+		ret = nt
 
 	case eConnection:
 		switch parentObject.(type) {
@@ -121,7 +152,8 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 				}
 			}
 			if parentObject == nil {
-				log.Fatalf("NewElementJob.CreateObject(eNodeType) error: no valid FROM port for edge job %v\n", j)
+				err = freesp.NotFoundError("NewElementJob.CreateObject", "no port %s for the connection", j.extra)
+				return
 			}
 			_ = parentObject.(bh.PortIf)
 		case bh.ImplementationIf:
@@ -138,11 +170,13 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 				}
 			}
 			if parentObject == nil {
-				log.Fatalf("NewElementJob.CreateObject(eNodeType) error: no valid FROM port for edge job %v\n", j)
+				err = freesp.NotFoundError("NewElementJob.CreateObject", "no port %s for the connection", j.extra)
+				return
 			}
 			_ = parentObject.(bh.PortIf)
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eConnection) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add connections to %T", parentObject)
+			return
 		}
 		ports := getMatchingPorts(fts, parentObject)
 		for _, p := range ports {
@@ -160,6 +194,9 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 				break
 			}
 		}
+		if ret == nil {
+			err = freesp.NotFoundError("NewElementJob.CreateObject", "port %s not found", j.input[iPortSelect])
+		}
 
 	case ePortType:
 		switch parentObject.(type) {
@@ -167,14 +204,15 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			j.parentId = getParentId(j.parentId)
 		case bh.NodeTypeIf:
 		default:
-			log.Fatal("NewElementJob.CreateObject(ePortType) error: referenced parentObject wrong type...")
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add port types to %T", parentObject)
+			return
 		}
-		_, ok := freesp.GetSignalTypeByName(j.input[iSignalTypeSelect])
+		_, ok := global.Registry().GetSignalTypeByName(j.input[iSignalTypeSelect])
 		if !ok {
-			err = fmt.Errorf("NewElementJob.CreateObject(ePortType) error: referenced signal type wrong...")
+			err = freesp.NotFoundError("NewElementJob.CreateObject", "signal type %s not found", j.input[iSignalTypeSelect])
 			return
 		}
-		ret = behaviour.PortTypeNew(j.input[iPortName], j.input[iSignalTypeSelect], string2direction[j.input[iDirection]])
+		ret = behaviour.PortTypeNew(j.input[iPortName], j.input[iSignalTypeSelect], string2direction[j.input[iDirection]], &global)
 
 	case eSignalType:
 		switch parentObject.(type) {
@@ -184,33 +222,71 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			j.parentId = getParentId(j.parentId)
 		case bh.LibraryIf:
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eSignalType) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add signal types to %T", parentObject)
+			return
 		}
 		parentObject, err = fts.GetObjectById(j.parentId)
 		if err != nil {
-			log.Fatalf("NewElementJob.CreateObject(eSignalType) error: parent library object not found\n")
+			err = freesp.NotFoundError("NewElementJob.CreateObject", "library %s not found", j.parentId)
+			return
 		}
 		name := j.input[iSignalTypeName]
 		cType := j.input[iCType]
 		channelId := j.input[iChannelId]
 		scope := string2scope[j.input[iScope]]
 		mode := string2mode[j.input[iSignalMode]]
-		ret, err = behaviour.SignalTypeNew(name, cType, channelId, scope, mode, parentObject.(bh.LibraryIf).Filename())
+		ret, err = behaviour.SignalTypeNew(name, cType, channelId, scope, mode, parentObject.(bh.LibraryIf).Filename(), &global)
 		if err != nil {
 			log.Printf("NewElementJob.CreateObject(eSignalType) error: SignalTypeNew failed: %s\n", err)
 			return
 		}
 
+	case eSignalField:
+		switch parentObject.(type) {
+		case bh.SignalFieldIf:
+			j.parentId = getParentId(j.parentId)
+		case bh.SignalTypeIf:
+		default:
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add fields to %T", parentObject)
+			return
+		}
+		var n int
+		n, err = arraySize(j.input[iArraySize])
+		if err != nil {
+			return
+		}
+		ret, err = behaviour.SignalFieldNew(j.input[iFieldName], j.input[iFieldTypeSelect], n,
+			j.input[iUnit], j.input[iFieldMin], j.input[iFieldMax])
+		if err != nil {
+			log.Printf("NewElementJob.CreateObject(eSignalField) error: SignalFieldNew failed: %s\n", err)
+			return
+		}
+
 	case eImplementation:
 		switch parentObject.(type) {
 		case bh.ImplementationIf:
 			j.parentId = getParentId(j.parentId)
 		case bh.NodeTypeIf:
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eSignalType) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add implementations to %T", parentObject)
+			return
 		}
 		implType := string2implType[j.input[iImplementationType]]
-		ret = behaviour.ImplementationNew(j.input[iImplName], implType, &global)
+		graphFile := j.input[iGraphFile]
+		if implType == bh.NodeTypeGraph && len(graphFile) > 0 {
+			// resolve the graph file relative to the library
+			var refDir string
+			nt, ok := parentObject.(bh.NodeTypeIf)
+			if ok {
+				lib, ok := global.Registry().GetLibraryByName(nt.DefinedAt())
+				if ok {
+					refDir = lib.PathPrefix()
+				}
+			}
+			ret = behaviour.GraphRefImplementationNew(j.input[iImplName], graphFile, refDir, &global)
+		} else {
+			ret = behaviour.ImplementationNew(j.input[iImplName], implType, &global)
+		}
 
 	case eArch:
 		switch parentObject.(type) {
@@ -219,7 +295,8 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			parentObject = parentObject.(pf.ArchIf).Platform()
 		case pf.PlatformIf:
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eArch) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add architectures to %T", parentObject)
+			return
 		}
 		ret = platform.ArchNew(j.input[iArchName], parentObject.(pf.PlatformIf))
 
@@ -232,7 +309,8 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 		case pf.ArchIf:
 			p = parentObject.(pf.ArchIf).Platform()
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eIOType) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add io types to %T", parentObject)
+			return
 		}
 		ret, err = platform.IOTypeNew(j.input[iIOTypeName], gr.IOMode(j.input[iIOModeSelect]), p)
 
@@ -243,7 +321,8 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			parentObject = parentObject.(pf.ProcessIf).Arch()
 		case pf.ArchIf:
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eProcess) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add processes to %T", parentObject)
+			return
 		}
 		ret = platform.ProcessNew(j.input[iProcessName], parentObject.(pf.ArchIf))
 
@@ -254,27 +333,31 @@ func (j *NewElementJob) CreateObject(fts *models.FilesTreeStore) (ret tr.TreeEle
 			parentObject = parentObject.(pf.ChannelIf).Process()
 		case pf.ProcessIf:
 		default:
-			log.Fatalf("NewElementJob.CreateObject(eChannel) error: referenced parentObject wrong type %T\n", parentObject)
+			err = freesp.InvalidOperationError("NewElementJob.CreateObject", "cannot add channels to %T", parentObject)
+			return
 		}
 		processes := getOtherProcesses(fts, parentObject)
 		var p pf.ProcessIf
-		for _, p = range processes {
-			s := fmt.Sprintf("%s/%s", p.Arch().Name(), p.Name())
+		for _, q := range processes {
+			s := fmt.Sprintf("%s/%s", q.Arch().Name(), q.Name())
 			if s == j.input[iChannelLinkSelect] {
+				p = q
 				break
 			}
 		}
 		if p == nil {
-			log.Fatalf("NewElementJob.CreateObject(eChannel) error: can't find chosen process\n", j.input[iChannelLinkSelect])
+			err = freesp.NotFoundError("NewElementJob.CreateObject", "process %s not found", j.input[iChannelLinkSelect])
+			return
 		}
-		ioType, ok := freesp.GetIOTypeByName(j.input[iIOTypeSelect])
+		ioType, ok := global.Registry().GetIOTypeByName(j.input[iIOTypeSelect])
 		if !ok {
-			log.Fatalf("NewElementJob.CreateObject(eChannel) error: can't find chosen ioType\n", j.input[iIOTypeSelect])
+			err = freesp.NotFoundError("NewElementJob.CreateObject", "io type %s not found", j.input[iIOTypeSelect])
+			return
 		}
 		ret = platform.ChannelNew(string2direction[j.input[iChannelDirection]], ioType, parentObject.(pf.ProcessIf), j.input[iChannelLinkSelect])
 
 	default:
-		log.Fatal("NewElementJob.CreateObject error: invalid elemType ", j.elemType)
+		err = freesp.InvalidOperationError("NewElementJob.CreateObject", "invalid element type %v", j.elemType)
 	}
 	return
 }
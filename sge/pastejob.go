@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 	"github.com/axel-freesp/sge/backend"
-	"github.com/axel-freesp/sge/freesp"
+	"github.com/axel-freesp/sge/freesp/behaviour"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	"github.com/axel-freesp/sge/models"
 	"log"
@@ -85,6 +85,11 @@ func ParseText(text string, fts *models.FilesTreeStore) (job *EditorJob, err err
 	case bh.ConnectionIf:
 
 	case bh.SignalTypeIf:
+		j, ok = parseSignalField(text, context)
+		if ok {
+			job = EditorJobNew(JobPaste, j)
+			return
+		}
 		j, ok = parseSignalType(text, getParentId(context))
 		if ok {
 			job = EditorJobNew(JobPaste, j)
@@ -106,6 +111,13 @@ func ParseText(text string, fts *models.FilesTreeStore) (job *EditorJob, err err
 			return
 		}
 
+	case bh.SignalFieldIf:
+		j, ok = parseSignalField(text, getParentId(context))
+		if ok {
+			job = EditorJobNew(JobPaste, j)
+			return
+		}
+
 	case bh.ImplementationIf:
 
 	default:
@@ -136,13 +148,14 @@ func parseNode(text, context string, graph bh.SignalGraphTypeIf) (job *PasteJob,
 		}
 		xmln.NName = createNextNameCandidate(xmln.NName)
 	}
-	_, validType := freesp.GetNodeTypeByName(xmln.NType)
+	_, validType := global.Registry().GetNodeTypeByName(xmln.NType)
 	var njob *NewElementJob
 	switch {
 	case validType:
 		njob = NewElementJobNew(context, eNode)
 		njob.input[iNodeName] = xmln.NName
 		njob.input[iNodeTypeSelect] = xmln.NType
+		njob.input[iNodeParams] = paramValuesText(xmln.Param)
 	case len(xmln.InPort) == 0:
 		if len(xmln.OutPort) == 0 {
 			fmt.Printf("parseNode error: no ports.\n")
@@ -179,7 +192,7 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 		return
 	}
 	for true {
-		_, exist := freesp.GetNodeTypeByName(xmlnt.TypeName)
+		_, exist := global.Registry().GetNodeTypeByName(xmlnt.TypeName)
 		if !exist {
 			break
 		}
@@ -189,6 +202,7 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 	job.context = context
 	ntjob := NewElementJobNew(context, eNodeType)
 	ntjob.input[iTypeName] = xmlnt.TypeName
+	ntjob.input[iTypeParams] = parametersText(xmlnt.Parameter)
 	job.newElements = append(job.newElements, ntjob)
 	for _, p := range xmlnt.InPort {
 		pj := PasteJobNew()
@@ -212,7 +226,10 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 		pj := PasteJobNew()
 		j := NewElementJobNew("", eImplementation)
 		j.input[iImplName] = impl.Name
-		if impl.SignalGraph == nil {
+		if len(impl.GraphRef) > 0 {
+			j.input[iImplementationType] = implType2string[bh.NodeTypeGraph]
+			j.input[iGraphFile] = impl.GraphRef
+		} else if impl.SignalGraph == nil {
 			j.input[iImplementationType] = implType2string[bh.NodeTypeElement]
 		} else {
 			j.input[iImplementationType] = implType2string[bh.NodeTypeGraph]
@@ -223,12 +240,13 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 			for _, n := range impl.SignalGraph[0].ProcessingNodes {
 				nj := NewElementJobNew("", eNode)
 				nj.input[iNodeName] = n.NName
-				_, validNodeType := freesp.GetNodeTypeByName(n.NType)
+				_, validNodeType := global.Registry().GetNodeTypeByName(n.NType)
 				if !validNodeType {
 					fmt.Printf("parseNodeType error: referenced node type %s not registered.\n", n.NType)
 					return
 				}
 				nj.input[iNodeTypeSelect] = n.NType
+				nj.input[iNodeParams] = paramValuesText(n.Param)
 				//nj.extra = fmt.Sprintf("%d|%d", n.Hint.X, n.Hint.Y)
 				// TODO: SetModePosition ...
 				//fmt.Printf("parseNodeType: fill hint of implementation graph node: %s\n", nj.extra)
@@ -254,7 +272,7 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 				}
 				for _, n := range impl.SignalGraph[0].ProcessingNodes {
 					if n.NName == e.From {
-						nType, good := freesp.GetNodeTypeByName(n.NType)
+						nType, good := global.Registry().GetNodeTypeByName(n.NType)
 						if !good {
 							fmt.Printf("parseNodeType error: node type %s not registered.\n", n.NType)
 							return
@@ -270,7 +288,7 @@ func parseNodeType(text, context string) (job *PasteJob, ok bool) {
 				}
 				for _, n := range impl.SignalGraph[0].ProcessingNodes {
 					if n.NName == e.To {
-						nType, good := freesp.GetNodeTypeByName(n.NType)
+						nType, good := global.Registry().GetNodeTypeByName(n.NType)
 						if !good {
 							fmt.Printf("parseNodeType error: node type %s not registered.\n", n.NType)
 							return
@@ -333,7 +351,7 @@ func parseSignalGraph(text, context string) (job *PasteJob, ok bool) {
 	job.context = context
 	job.newElements = append(job.newElements, njob)
 	for _, xmln := range xmlsg.ProcessingNodes {
-		_, validNodeType := freesp.GetNodeTypeByName(xmln.NType)
+		_, validNodeType := global.Registry().GetNodeTypeByName(xmln.NType)
 		if !validNodeType {
 			fmt.Printf("parseSignalGraph error: node type %s not registered.\n", xmln.NType)
 			return
@@ -389,7 +407,7 @@ func parseSignalType(text, context string) (job *PasteJob, ok bool) {
 	if xmlerr != nil {
 		return
 	}
-	signalTypes := freesp.GetRegisteredSignalTypes()
+	signalTypes := global.Registry().GetRegisteredSignalTypes()
 	for true {
 		valid := true
 		for _, reg := range signalTypes {
@@ -411,10 +429,39 @@ func parseSignalType(text, context string) (job *PasteJob, ok bool) {
 	stjob.input[iScope] = scopeMap[xmlst.Scope]
 	stjob.input[iSignalMode] = modeMap[xmlst.Mode]
 	job.newElements = append(job.newElements, stjob)
+	for _, f := range xmlst.Field {
+		fj := PasteJobNew()
+		fj.newElements = append(fj.newElements, signalFieldJob("", f))
+		job.children = append(job.children, fj)
+	}
+	ok = true
+	return
+}
+
+func parseSignalField(text, context string) (job *PasteJob, ok bool) {
+	xmlf := backend.XmlSignalField{}
+	_, xmlerr := xmlf.Read([]byte(text))
+	if xmlerr != nil {
+		return
+	}
+	job = PasteJobNew()
+	job.context = context
+	job.newElements = append(job.newElements, signalFieldJob(context, xmlf))
 	ok = true
 	return
 }
 
+func signalFieldJob(context string, f backend.XmlSignalField) *NewElementJob {
+	j := NewElementJobNew(context, eSignalField)
+	j.input[iFieldName] = f.Name
+	j.input[iFieldTypeSelect] = f.FType
+	j.input[iArraySize] = f.Array
+	j.input[iUnit] = f.Unit
+	j.input[iFieldMin] = f.Min
+	j.input[iFieldMax] = f.Max
+	return j
+}
+
 func createNextNameCandidate(text string) string {
 	basename := baseName(text)
 	suffix := text[len(basename):]
@@ -432,3 +479,34 @@ func baseName(text string) string {
 	}
 	return text
 }
+
+// Parameter values and declarations in the notation of the edit dialog.
+func paramValuesText(values []backend.XmlParamValue) string {
+	var list []string
+	for _, v := range values {
+		list = append(list, fmt.Sprintf("%s=%s", v.Name, v.Value))
+	}
+	return strings.Join(list, "; ")
+}
+
+func parametersText(decls []backend.XmlParameter) string {
+	var list []bh.ParameterIf
+	for _, d := range decls {
+		pType, ok := behaviour.ParamTypeFromString(d.PType)
+		if !ok {
+			log.Printf("parametersText warning: parameter %s: unknown type %s\n", d.Name, d.PType)
+			continue
+		}
+		var values []string
+		if len(d.Values) > 0 {
+			values = strings.Split(d.Values, ",")
+		}
+		p, err := behaviour.ParameterNew(d.Name, pType, d.Default, d.Min, d.Max, values)
+		if err != nil {
+			log.Printf("parametersText warning: %s\n", err)
+			continue
+		}
+		list = append(list, p)
+	}
+	return behaviour.ParametersString(list)
+}
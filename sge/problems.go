@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/lint"
+	"github.com/axel-freesp/sge/freesp/validate"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"log"
+)
+
+// The open documents are validated and linted after each job and after
+// opening or closing documents, the problems panel lists the result and
+// the graph views mark the nodes concerned.
+func updateProblems() {
+	if global.prv == nil {
+		return
+	}
+	docs := openDocuments(global.fts)
+	problems := validate.Documents(docs)
+	cfg, err := lint.ProjectConfig(backend.Project())
+	if err != nil {
+		log.Printf("updateProblems: %s, using default lint rules\n", err)
+		cfg = lint.DefaultConfig()
+	}
+	problems = append(problems, lint.Documents(docs, cfg)...)
+	global.prv.Set(problems)
+	global.win.graphViews.SetProblems(problems)
+}
+
+func openDocuments(fts *models.FilesTreeStore) (docs []tr.ToplevelTreeElementIf) {
+	for i := 0; ; i++ {
+		te, err := fts.GetObjectById(fmt.Sprintf("%d", i))
+		if err != nil {
+			return
+		}
+		docs = append(docs, te.(tr.ToplevelTreeElementIf))
+	}
+}
+
+// Select the element a problem refers to, or its document if the
+// element is not shown in the tree (any more).
+func navigateToProblem(p validate.Problem) {
+	if p.Object != nil {
+		cursor, ok := global.fts.FindCursor(p.Object)
+		if ok {
+			selectCursor(cursor)
+			return
+		}
+	}
+	for _, doc := range openDocuments(global.fts) {
+		if doc.Filename() == p.Filename {
+			selectInTree(doc)
+			return
+		}
+	}
+}
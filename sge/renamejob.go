@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/refactor"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/models"
+	"log"
+)
+
+// RenameJob renames a signal or node type in all open documents (by
+// renaming the type object) and in the project files that are not
+// open (by rewriting them).
+type RenameJob struct {
+	object           tr.TreeElementIf
+	kind             refactor.TypeKind
+	oldName, newName string
+	files            []refactor.FileChange
+}
+
+func RenameJobNew(object tr.TreeElementIf, kind refactor.TypeKind, oldName, newName string, files []refactor.FileChange) *RenameJob {
+	return &RenameJob{object, kind, oldName, newName, files}
+}
+
+func (j *RenameJob) String() string {
+	return fmt.Sprintf("RenameJob(%s %s -> %s, %d files)", j.kind, j.oldName, j.newName, len(j.files))
+}
+
+func (j *RenameJob) Rename(fts *models.FilesTreeStore, direction EditJobDirection) (state string, err error) {
+	name := j.newName
+	if direction == EditJobRevert {
+		name = j.oldName
+	}
+	switch t := j.object.(type) {
+	case bh.SignalTypeIf:
+		t.SetTypeName(name)
+	case bh.NodeTypeIf:
+		t.SetTypeName(name)
+	default:
+		err = fmt.Errorf("RenameJob.Rename error: invalid object type %T", j.object)
+		return
+	}
+	fts.SetValueByObject(j.object, name)
+	if _, ok := j.object.(bh.SignalTypeIf); ok {
+		// fields show their type
+		for _, n := range global.Registry().GetRegisteredSignalTypes() {
+			st, _ := global.Registry().GetSignalTypeByName(n)
+			for _, f := range st.Fields() {
+				if f.FieldType() == name {
+					fts.SetValueByObject(f, fmt.Sprint(f))
+				}
+			}
+		}
+	}
+	state = fts.Cursor(j.object).Path
+	for _, f := range j.files {
+		var e error
+		if direction == EditJobForward {
+			e = f.Apply()
+		} else {
+			e = f.Revert()
+		}
+		if e != nil {
+			log.Printf("RenameJob.Rename: failed to write %s: %s\n", f.Filename, e)
+			err = e
+		}
+	}
+	return
+}
@@ -19,3 +19,11 @@ func createLabeledRow(labelText string, widget *gtk.Widget) (box *gtk.Box, err e
 	}
 	return
 }
+
+// showError tells the user about a failed operation; the model stays
+// as it was before.
+func showError(err error) {
+	d := gtk.MessageDialogNew(global.win.Window(), gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_CLOSE, "%s", err)
+	d.Run()
+	d.Destroy()
+}
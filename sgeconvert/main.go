@@ -0,0 +1,227 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/dsl"
+	"github.com/axel-freesp/sge/tool"
+	"log"
+	"os"
+	"strings"
+)
+
+// sgeconvert translates freeSP documents and hint files between
+// their XML, JSON and textual encoding. The encoding of a file is taken
+// from its name: "graph.sml" is XML, "graph.sml.json" is JSON and
+// "graph.sml.sgt" is text (signal graphs and libraries only).
+// Hint files are named "graph-sml.hints.xml" or "graph-sml.hints.json".
+// Unknown XML content is lost in JSON and text, such documents are only
+// converted with -force.
+
+const hintsSuffix = "hints"
+
+const (
+	encXml  = "xml"
+	encJson = "json"
+	encText = "text"
+)
+
+var output = flag.String("o", "", "output filename (only with a single input file)")
+var target = flag.String("to", "", "target encoding: xml, json or text (default: json for XML input, xml otherwise)")
+var force = flag.Bool("force", false, "convert XML documents with unknown content, which is lost in JSON and text")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-to encoding] [-o output] [-force] file...\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+	switch *target {
+	case "", encXml, encJson, encText:
+	default:
+		fmt.Fprintf(os.Stderr, "%s: invalid target encoding %s\n", os.Args[0], *target)
+		usage()
+	}
+	if len(*output) > 0 && flag.NArg() > 1 {
+		log.Fatal("sgeconvert error: -o requires a single input file")
+	}
+	var failed bool
+	for _, in := range flag.Args() {
+		out := *output
+		if len(out) == 0 {
+			out = convertedFilename(in, targetEncoding(in))
+		}
+		err := convert(in, out)
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+		log.Printf("sgeconvert: %s -> %s\n", in, out)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func encoding(filename string) string {
+	switch tool.Suffix(filename) {
+	case tool.JsonSuffix:
+		return encJson
+	case dsl.Suffix:
+		return encText
+	}
+	return encXml
+}
+
+func targetEncoding(in string) string {
+	if len(*target) > 0 {
+		return *target
+	}
+	if encoding(in) == encXml {
+		return encJson
+	}
+	return encXml
+}
+
+func stripEncoding(filename string) string {
+	switch tool.Suffix(filename) {
+	case "xml", tool.JsonSuffix, dsl.Suffix:
+		return tool.Prefix(filename)
+	}
+	return filename
+}
+
+func isHintFile(filename string) bool {
+	return tool.Suffix(stripEncoding(filename)) == hintsSuffix
+}
+
+func convertedFilename(filename, enc string) string {
+	base := stripEncoding(filename)
+	switch enc {
+	case encJson:
+		return fmt.Sprintf("%s.%s", base, tool.JsonSuffix)
+	case encText:
+		return fmt.Sprintf("%s.%s", base, dsl.Suffix)
+	}
+	if isHintFile(filename) {
+		return fmt.Sprintf("%s.xml", base)
+	}
+	return base
+}
+
+func documentNew(filename string) (doc backend.Document, err error) {
+	if isHintFile(filename) {
+		// hint files are named <prefix>-<suffix>.hints.xml
+		base := tool.Prefix(stripEncoding(filename))
+		idx := strings.LastIndex(base, "-")
+		switch base[idx+1:] {
+		case "sml":
+			doc = backend.XmlGraphHintNew("")
+		case "spml":
+			doc = backend.XmlPlatformHintNew("")
+		case "mml":
+			doc = backend.XmlMappingHintNew("")
+		default:
+			err = fmt.Errorf("documentNew error: unknown hint file type %s", filename)
+		}
+		return
+	}
+	switch tool.Suffix(stripEncoding(filename)) {
+	case "sml":
+		doc = backend.XmlSignalGraphNew()
+	case "alml":
+		doc = backend.XmlLibraryNew()
+	case "spml":
+		doc = backend.XmlPlatformNew()
+	case "mml":
+		doc = backend.XmlMappingNew("", "")
+	case backend.ProjectSuffix:
+		doc = backend.XmlProjectNew("")
+	default:
+		err = fmt.Errorf("documentNew error: unknown file type %s", filename)
+	}
+	return
+}
+
+func readText(doc backend.Document, data []byte, filename string) (ret backend.Document, err error) {
+	switch doc.(type) {
+	case *backend.XmlSignalGraph:
+		ret, err = dsl.ParseSignalGraph(filename, data)
+	case *backend.XmlLibrary:
+		ret, err = dsl.ParseLibrary(filename, data)
+	default:
+		err = fmt.Errorf("readText error: no textual encoding for %s", filename)
+	}
+	return
+}
+
+func writeText(doc backend.Document, filename string) (data []byte, err error) {
+	switch doc.(type) {
+	case *backend.XmlSignalGraph:
+		data = dsl.PrintSignalGraph(doc.(*backend.XmlSignalGraph))
+	case *backend.XmlLibrary:
+		data = dsl.PrintLibrary(doc.(*backend.XmlLibrary))
+	default:
+		err = fmt.Errorf("writeText error: no textual encoding for %s", filename)
+	}
+	return
+}
+
+func convert(in, out string) (err error) {
+	if encoding(in) == encoding(out) {
+		err = fmt.Errorf("convert error: %s and %s have the same encoding", in, out)
+		return
+	}
+	var doc backend.Document
+	doc, err = documentNew(in)
+	if err != nil {
+		return
+	}
+	var data []byte
+	data, err = tool.ReadFile(in)
+	if err != nil {
+		err = fmt.Errorf("convert error: failed to read file %s", in)
+		return
+	}
+	if encoding(in) == encText {
+		doc, err = readText(doc, data, in)
+	} else {
+		_, err = backend.DocumentRead(doc, data, in)
+	}
+	if err != nil {
+		err = fmt.Errorf("convert error: %s: %v", in, err)
+		return
+	}
+	if encoding(out) != encXml && backend.DefaultPreserver().Preserved(in) {
+		if !*force {
+			err = fmt.Errorf("convert error: %s has unknown elements, attributes or comments, which %s cannot keep (use -force to drop them)", in, out)
+			return
+		}
+		log.Printf("sgeconvert warning: %s: unknown elements, attributes and comments are dropped\n", in)
+	}
+	switch {
+	case encoding(out) == encText:
+		data, err = writeText(doc, out)
+		if err != nil {
+			return
+		}
+		err = tool.WriteFile(out, data)
+	case isHintFile(in):
+		data, err = backend.DocumentWrite(doc, out)
+		if err != nil {
+			return
+		}
+		err = tool.WriteFile(out, data)
+	default:
+		err = backend.DocumentWriteFile(doc, out)
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/deps"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// sgedeps analyzes the library references of signal graphs and
+// libraries (files, or all of them below directories, and the members
+// of a project given with -project). It reports cycles, references to
+// missing libraries and references to libraries none of whose types are
+// used. Referenced libraries are read along the search path.
+//
+// With -dot the dependency graph is printed in graphviz format instead.
+// Exit status 1 reports cycles or missing libraries, 2 errors.
+
+var dot = flag.Bool("dot", false, "print the dependency graph in graphviz format")
+var projectFile = flag.String("project", "", "analyze the members of this project, using its search path")
+var verbose = flag.Bool("v", false, "show log messages of the backend")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-dot] [-v] [-project file] path...\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 && len(*projectFile) == 0 {
+		usage()
+	}
+	log.SetFlags(0)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		tool.VerboseErr = false
+	}
+	backend.Init()
+	g := deps.GraphNew()
+	if len(*projectFile) > 0 {
+		p := backend.XmlProjectNew("")
+		err := p.ReadFile(*projectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgedeps: %s: %s\n", *projectFile, err)
+			os.Exit(2)
+		}
+		backend.SetProject(p, *projectFile)
+		g.AddProject()
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.IsDir() {
+				err = g.AddDir(path)
+			} else {
+				err = g.AddFile(path)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgedeps: %s\n", err)
+			failed = true
+		}
+	}
+	r := g.Analyze()
+	dir, _ := os.Getwd()
+	if *dot {
+		r.WriteDot(os.Stdout, dir)
+	} else {
+		r.WriteText(os.Stdout, dir)
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case r.Failed():
+		os.Exit(1)
+	}
+}
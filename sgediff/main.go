@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/diff"
+	"github.com/axel-freesp/sge/freesp/headless"
+	tr "github.com/axel-freesp/sge/interface/tree"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// sgediff reports the semantic differences between two versions of a
+// freeSP document (*.sml, *.alml, *.spml, *.mml). It can be used as git
+// difftool or as GIT_EXTERNAL_DIFF (see README.md).
+//
+// Exit status is 0 if the documents are equivalent, 1 if they differ
+// (always 0 when run as GIT_EXTERNAL_DIFF) and 2 on errors.
+
+var layout = flag.Bool("layout", false, "also report layout changes")
+var verbose = flag.Bool("v", false, "show log messages of the model")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-layout] [-v] old new\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	var oldFile, newFile, oldName, newName string
+	var external bool
+	switch flag.NArg() {
+	case 2:
+		oldFile, newFile = flag.Arg(0), flag.Arg(1)
+		oldName, newName = oldFile, newFile
+	case 7:
+		// GIT_EXTERNAL_DIFF: path old-file old-hex old-mode new-file new-hex new-mode
+		oldFile, newFile = flag.Arg(1), flag.Arg(4)
+		oldName, newName = flag.Arg(0), flag.Arg(0)
+		external = true
+	default:
+		usage()
+	}
+	log.SetFlags(0)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		tool.VerboseErr = false
+	}
+	// git passes /dev/null for added or deleted files
+	switch {
+	case oldFile == os.DevNull && newFile == os.DevNull:
+		return
+	case oldFile == os.DevNull:
+		fmt.Printf("%s: new document\n", newName)
+		exit(1, external)
+	case newFile == os.DevNull:
+		fmt.Printf("%s: deleted\n", oldName)
+		exit(1, external)
+	}
+	backend.Init()
+	oldDoc, err := load(oldFile, oldName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sgediff: %s\n", err)
+		os.Exit(2)
+	}
+	changes, err := compare(oldDoc, newFile, newName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sgediff: %s\n", err)
+		os.Exit(2)
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", newName)
+	for _, c := range changes {
+		fmt.Printf("  %s\n", c)
+	}
+	exit(1, external)
+}
+
+// git aborts if an external diff program reports differences by
+// its exit status.
+func exit(code int, external bool) {
+	if external {
+		code = 0
+	}
+	os.Exit(code)
+}
+
+func compare(oldDoc tr.ToplevelTreeElementIf, newFile, name string) (changes []diff.Change, err error) {
+	// documents are loaded one after the other with fresh registries,
+	// such that both versions of a type can coexist
+	newDoc, err := load(newFile, name)
+	if err != nil {
+		return
+	}
+	return diff.Documents(oldDoc, newDoc, diff.Options{Layout: *layout})
+}
+
+// git hands over temporary copies, the document kind is then taken
+// from the original name, and references are resolved relative to
+// the directory of the original.
+func load(filename, name string) (doc tr.ToplevelTreeElementIf, err error) {
+	return headless.LoadAs(filename, filepath.Base(name), filepath.Dir(name))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// sgefmt writes freeSP documents, hint and project files canonically
+// (see backend.Canonicalize), such that saving the same model always
+// yields the same file. Directories are searched for such files.
+//
+// Without flags the canonical form is printed, -w rewrites the files,
+// -check lists the files that are not canonical and exits with status 1
+// if there are any (for CI). Exit status 2 reports errors.
+
+var write = flag.Bool("w", false, "rewrite files which are not canonical")
+var check = flag.Bool("check", false, "list files which are not canonical, exit status 1 if any")
+var verbose = flag.Bool("v", false, "show log messages of the backend")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-w | -check] [-v] path...\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 || (*write && *check) {
+		usage()
+	}
+	log.SetFlags(0)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		tool.VerboseErr = false
+	}
+	backend.Init()
+	backend.SetCanonical(true)
+	var failed, differs bool
+	for _, path := range flag.Args() {
+		files, err := documents(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgefmt: %s\n", err)
+			failed = true
+			continue
+		}
+		for _, f := range files {
+			d, err := format(f)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "sgefmt: %s\n", err)
+				failed = true
+			}
+			differs = differs || d
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case *check && differs:
+		os.Exit(1)
+	}
+}
+
+// All files below path which can be formatted, path itself if it is
+// a file.
+func documents(path string) (files []string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		files = append(files, path)
+		return
+	}
+	err = tool.WalkFiles(path, func(p string) error {
+		if _, ok := backend.DocumentNew(p); ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return
+}
+
+// Format file as requested by the flags, differs tells if it was not
+// canonical.
+func format(filename string) (differs bool, err error) {
+	doc, ok := backend.DocumentNew(filename)
+	if !ok {
+		err = fmt.Errorf("%s: unknown file type", filename)
+		return
+	}
+	data, err := tool.ReadFile(filename)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+		return
+	}
+	_, err = backend.DocumentRead(doc, data, filename)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+		return
+	}
+	var out []byte
+	if isHint(doc) {
+		// hint files are stored without XML header
+		out, err = backend.DocumentWrite(doc, filename)
+	} else {
+		out, err = backend.DocumentFormat(doc, filename)
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+		return
+	}
+	differs = !bytes.Equal(data, out)
+	switch {
+	case *check:
+		if differs {
+			fmt.Println(filename)
+		}
+	case *write:
+		if differs {
+			err = tool.WriteFile(filename, out)
+		}
+	default:
+		_, err = os.Stdout.Write(out)
+	}
+	return
+}
+
+func isHint(doc backend.Document) bool {
+	switch doc.(type) {
+	case *backend.XmlGraphHint, *backend.XmlPlatformHint, *backend.XmlMappingHint:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/headless"
+	"github.com/axel-freesp/sge/freesp/lint"
+	"github.com/axel-freesp/sge/freesp/validate"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// sgelint checks signal graphs (*.sml) and the implementation graphs of
+// libraries (*.alml) by the lint rules, configured by the project given
+// with -project (whose graphs and libraries are checked as well) and
+// changed by -rule options.
+//
+// Exit status is 1 if problems of severity error were found, 2 on
+// errors.
+
+var projectFile = flag.String("project", "", "use the lint settings of this project and check its members")
+var listRules = flag.Bool("rules", false, "list the lint rules and their default severity")
+var verbose = flag.Bool("v", false, "show log messages of the model")
+
+// -rule name=level, may be repeated
+type ruleFlags []string
+
+func (r *ruleFlags) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *ruleFlags) Set(s string) error {
+	*r = append(*r, s)
+	return nil
+}
+
+var ruleSettings ruleFlags
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-rules] [-v] [-project file] [-rule name=off|warning|error]... file...\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Var(&ruleSettings, "rule", "set the level of a rule: name=off|warning|error")
+	flag.Usage = usage
+	flag.Parse()
+	if *listRules {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-18s %-8s %s\n", r.Name, r.Severity, r.Description)
+		}
+		return
+	}
+	if flag.NArg() == 0 && len(*projectFile) == 0 {
+		usage()
+	}
+	log.SetFlags(0)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		tool.VerboseErr = false
+	}
+	backend.Init()
+	files := flag.Args()
+	if len(*projectFile) > 0 {
+		p := backend.XmlProjectNew("")
+		err := p.ReadFile(*projectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgelint: %s: %s\n", *projectFile, err)
+			os.Exit(2)
+		}
+		backend.SetProject(p, *projectFile)
+		for _, list := range [][]backend.XmlProjectMember{p.Graphs, p.Libraries} {
+			for _, m := range list {
+				files = append(files, backend.ProjectPath(m.Ref))
+			}
+		}
+	}
+	cfg, err := lint.ProjectConfig(backend.Project())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sgelint: %s: %s\n", *projectFile, err)
+		os.Exit(2)
+	}
+	for _, s := range ruleSettings {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			fmt.Fprintf(os.Stderr, "sgelint: invalid rule setting %s\n", s)
+			os.Exit(2)
+		}
+		err = cfg.Set(kv[0], kv[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgelint: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	failed, errors := false, false
+	for _, path := range files {
+		doc, err := headless.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgelint: %s\n", err)
+			failed = true
+			continue
+		}
+		for _, p := range lint.Document(doc, cfg) {
+			p.Filename = path
+			fmt.Println(p)
+			errors = errors || p.Severity == validate.Error
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case errors:
+		os.Exit(1)
+	}
+}
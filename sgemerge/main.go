@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/freesp/merge"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// sgemerge merges two versions of a freeSP document (*.sml, *.alml,
+// *.spml, *.mml and their hint files) derived from a common base. It
+// is meant as git merge driver (see README.md):
+//
+//	sgemerge base ours theirs [path]
+//
+// The result is written to ours. Exit status is 0 for a clean merge,
+// 1 if conflicts are left and 2 on errors. Documents which cannot be
+// merged structurally are merged textually by git merge-file.
+
+var verbose = flag.Bool("v", false, "show log messages of the model")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-v] base ours theirs [path]\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	var baseFile, oursFile, theirsFile, path string
+	switch flag.NArg() {
+	case 3:
+		baseFile, oursFile, theirsFile = flag.Arg(0), flag.Arg(1), flag.Arg(2)
+		path = oursFile
+	case 4:
+		// git merge driver: %O %A %B %P, the files are temporary
+		// copies, the document kind is taken from the path
+		baseFile, oursFile, theirsFile, path = flag.Arg(0), flag.Arg(1), flag.Arg(2), flag.Arg(3)
+	default:
+		usage()
+	}
+	log.SetFlags(0)
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+		tool.VerboseErr = false
+	}
+	if !merge.Supported(path) {
+		os.Exit(mergeFile(baseFile, oursFile, theirsFile))
+	}
+	backend.Init()
+	var data [3][]byte
+	for i, f := range []string{baseFile, oursFile, theirsFile} {
+		var err error
+		data[i], err = ioutil.ReadFile(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sgemerge: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	result, conflicts, err := merge.Merge(data[0], data[1], data[2], path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sgemerge: %s, merging textually\n", err)
+		os.Exit(mergeFile(baseFile, oursFile, theirsFile))
+	}
+	err = ioutil.WriteFile(oursFile, result, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sgemerge: %s\n", err)
+		os.Exit(2)
+	}
+	if len(conflicts) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "sgemerge: %s: %d conflicts\n", path, len(conflicts))
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "  %s\n", c)
+	}
+	os.Exit(1)
+}
+
+// Line based merge into oursFile, returns the exit status.
+func mergeFile(baseFile, oursFile, theirsFile string) int {
+	cmd := exec.Command("git", "merge-file", "-L", "ours", "-L", "base", "-L", "theirs",
+		oursFile, baseFile, theirsFile)
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err == nil {
+		return 0
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		// conflicts left
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "sgemerge: git merge-file: %s\n", err)
+	return 2
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/axel-freesp/sge/backend"
+	"github.com/axel-freesp/sge/tool"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// sgemigrate upgrades all freeSP XML documents (*.sml, *.alml, *.spml,
+// *.mml, *.sgeproj) below the given directories to the current format version.
+// Files are rewritten in place, the original is kept as a backup.
+
+var dryRun = flag.Bool("n", false, "only report which files would be migrated")
+var backupSuffix = flag.String("backup", "bak", "suffix of backup files")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-n] [-backup suffix] dir|file...\n", os.Args[0])
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+	if len(*backupSuffix) == 0 {
+		log.Fatal("sgemigrate error: empty backup suffix")
+	}
+	var failed bool
+	for _, root := range flag.Args() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !isDocument(path) {
+				return nil
+			}
+			err = migrate(path, info.Mode())
+			if err != nil {
+				log.Println(err)
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func isDocument(path string) bool {
+	switch tool.Suffix(path) {
+	case "sml", "alml", "spml", "mml", backend.ProjectSuffix:
+		return true
+	}
+	return false
+}
+
+func migrate(path string, mode os.FileMode) error {
+	data, err := tool.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("sgemigrate error: %v", err)
+	}
+	out, result, err := backend.Migrate(data)
+	if err != nil {
+		return fmt.Errorf("sgemigrate error: %s: %v", path, err)
+	}
+	if result.Newer {
+		log.Printf("sgemigrate: %s: version %s is newer than %s, skipped\n", path, result.From, backend.FormatVersion)
+		return nil
+	}
+	if !result.Migrated() {
+		return nil
+	}
+	log.Printf("sgemigrate: %s: %s -> %s\n", path, result.From, result.To)
+	for _, s := range result.Steps {
+		log.Printf("   %s\n", s)
+	}
+	if *dryRun {
+		return nil
+	}
+	backup := fmt.Sprintf("%s.%s", path, *backupSuffix)
+	err = ioutil.WriteFile(backup, data, mode)
+	if err != nil {
+		return fmt.Errorf("sgemigrate error: failed to write backup: %v", err)
+	}
+	err = ioutil.WriteFile(path, out, mode)
+	if err != nil {
+		return fmt.Errorf("sgemigrate error: %v", err)
+	}
+	return nil
+}
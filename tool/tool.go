@@ -3,6 +3,7 @@ package tool
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -124,6 +125,23 @@ func RelPath(short, long string) string {
 	return long[len(short)+1:]
 }
 
+// WalkFiles calls visit for all files below dir, hidden directories
+// (starting with ".") are skipped. An error of visit ends the walk.
+func WalkFiles(dir string, visit func(path string) error) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return visit(p)
+	})
+}
+
 func Suffix(path string) string {
 	idx := strings.LastIndex(path, ".")
 	if idx < 0 {
@@ -139,3 +157,25 @@ func Prefix(path string) string {
 	}
 	return path[:idx]
 }
+
+const JsonSuffix = "json"
+
+func IsJson(path string) bool {
+	return Suffix(path) == JsonSuffix
+}
+
+// DocSuffix and DocPrefix ignore a trailing .json, such that
+// "graph.sml.json" is recognized as a signal graph file.
+func DocSuffix(path string) string {
+	if IsJson(path) {
+		return Suffix(Prefix(path))
+	}
+	return Suffix(path)
+}
+
+func DocPrefix(path string) string {
+	if IsJson(path) {
+		return Prefix(Prefix(path))
+	}
+	return Prefix(path)
+}
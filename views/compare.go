@@ -0,0 +1,228 @@
+package views
+
+import (
+	"fmt"
+	freesp "github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/diff"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	gr "github.com/axel-freesp/sge/interface/graph"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	"github.com/axel-freesp/sge/views/graph"
+	"github.com/gotk3/gotk3/cairo"
+	"image"
+	"log"
+	"strings"
+)
+
+// compareOverlay shows the differences of the document of a view to
+// another version of it (see ComparerIf). Nodes and connections are
+// identified by name, ghosts are built from the old version.
+//
+// An old version without layout (e.g. read from stdin, without hint
+// file) has all nodes at the origin. Moves are not shown then, and
+// ghosts of removed nodes are lined up at the top.
+type compareOverlay struct {
+	nodes            map[string]graph.CompareState // current nodes by path
+	connections      map[string]graph.CompareState // current connections, see connectionKey
+	oldNodes         map[string]graph.NodeIf
+	layout           bool
+	ghosts           []compareGhost
+	ghostConnections []graph.ConnectIf
+}
+
+type compareGhost struct {
+	node  graph.NodeIf
+	state graph.CompareState
+}
+
+// The nodes of the old version, placed by getPositioner.
+func compareOverlayNew(old bh.SignalGraphTypeIf, graphId string, getPositioner graph.GetPositioner) *compareOverlay {
+	o := &compareOverlay{make(map[string]graph.CompareState), make(map[string]graph.CompareState),
+		make(map[string]graph.NodeIf), false, nil, nil}
+	for _, n := range old.Nodes() {
+		n.SetActiveMode(gr.PositionModeNormal)
+		gn := graph.NodeNew(getPositioner, n, freesp.NodeIdFromString(n.Name(), graphId))
+		o.oldNodes[n.Name()] = gn
+		if gn.Position() != (image.Point{}) {
+			o.layout = true
+		}
+	}
+	return o
+}
+
+// Overlay of the changes from graph old to cur.
+func signalGraphOverlayNew(old, cur bh.SignalGraphTypeIf, graphId string) *compareOverlay {
+	getPositioner := func(nId bh.NodeIdIf) gr.ModePositioner {
+		n, ok := old.NodeByPath(nId.String())
+		if !ok {
+			log.Printf("signalGraphOverlayNew: could not find node %v\n", nId)
+			return gr.ModePositionerObjectNew()
+		}
+		proxy := gr.PathModePositionerProxyNew(n)
+		proxy.SetActivePath(nId.Parent().String())
+		return proxy
+	}
+	o := compareOverlayNew(old, graphId, getPositioner)
+	o.addGraphChanges(diff.SignalGraphTypes(old, cur, diff.Options{Layout: o.layout}))
+	return o
+}
+
+// Overlay of the changes from mapping old to cur: changes of the graph,
+// and nodes mapped to another process.
+func mappingOverlayNew(old, cur mp.MappingIf) *compareOverlay {
+	getPositioner := func(nId bh.NodeIdIf) gr.ModePositioner {
+		melem, ok := old.MappedElement(nId)
+		if !ok {
+			return gr.ModePositionerObjectNew()
+		}
+		return melem
+	}
+	o := compareOverlayNew(old.Graph().ItsType(), old.Graph().Filename(), getPositioner)
+	o.addGraphChanges(diff.SignalGraphs(old.Graph(), cur.Graph(), diff.Options{}))
+	for _, c := range diff.Mappings(old, cur, diff.Options{Layout: o.layout}) {
+		if c.What != "mapped node" {
+			continue
+		}
+		switch c.Kind {
+		case diff.Added:
+			o.setState(c.Path, graph.CompareStateAdded)
+		case diff.Changed:
+			o.setState(c.Path, graph.CompareStateModified)
+			if o.layout {
+				o.addGhost(c.Path, graph.CompareStateMoved)
+			}
+		case diff.Moved:
+			o.addGhost(c.Path, graph.CompareStateMoved)
+		}
+	}
+	return o
+}
+
+func (o *compareOverlay) addGraphChanges(changes []diff.Change) {
+	renamed := make(map[string]string)
+	for _, c := range changes {
+		if c.What == "node" && c.Kind == diff.Renamed {
+			renamed[c.Old] = c.New
+		}
+	}
+	curName := func(name string) string {
+		if n, ok := renamed[name]; ok {
+			return n
+		}
+		return name
+	}
+	for _, c := range changes {
+		switch c.What {
+		case "node":
+			switch c.Kind {
+			case diff.Added:
+				o.setState(c.Path, graph.CompareStateAdded)
+			case diff.Removed:
+				o.addGhost(c.Path, graph.CompareStateRemoved)
+			case diff.Renamed, diff.Changed:
+				o.setState(curName(c.Path), graph.CompareStateModified)
+			case diff.Moved:
+				o.addGhost(c.Path, graph.CompareStateMoved)
+			}
+		case "input port", "output port":
+			o.setState(curName(c.Path[:strings.LastIndex(c.Path, ".")]), graph.CompareStateModified)
+		case "connection":
+			switch c.Kind {
+			case diff.Added:
+				o.connections[c.Path] = graph.CompareStateAdded
+			case diff.Removed:
+				o.addGhostConnection(c.Path)
+			}
+		}
+	}
+}
+
+// Added nodes stay added, whatever else changed.
+func (o *compareOverlay) setState(path string, state graph.CompareState) {
+	if s, ok := o.nodes[path]; ok && s == graph.CompareStateAdded {
+		return
+	}
+	o.nodes[path] = state
+}
+
+func (o *compareOverlay) addGhost(path string, state graph.CompareState) {
+	n, ok := o.oldNodes[strings.Split(path, "/")[0]]
+	if !ok {
+		return
+	}
+	if !o.layout {
+		w := graph.NumericOption(graph.NodeWidth) + 20
+		n.SetPosition(image.Point{20 + len(o.ghosts)*w, 20})
+	}
+	o.ghosts = append(o.ghosts, compareGhost{n, state})
+}
+
+func (o *compareOverlay) addGhostConnection(path string) {
+	ends := strings.Split(path, " -> ")
+	if len(ends) != 2 {
+		return
+	}
+	split := func(end string) (node, port string) {
+		i := strings.LastIndex(end, ".")
+		return end[:i], end[i+1:]
+	}
+	fromName, fromPort := split(ends[0])
+	toName, toPort := split(ends[1])
+	from, ok1 := o.oldNodes[fromName]
+	to, ok2 := o.oldNodes[toName]
+	if !ok1 || !ok2 {
+		return
+	}
+	o.ghostConnections = append(o.ghostConnections,
+		graph.ConnectionNew(from, to, from.OutPortIndex(fromPort), to.InPortIndex(toPort)))
+}
+
+func (o *compareOverlay) bBox() (box image.Rectangle) {
+	for _, g := range o.ghosts {
+		box = box.Union(g.node.BBox())
+	}
+	return
+}
+
+func (o *compareOverlay) draw(context *cairo.Context, r image.Rectangle, nodes []graph.NodeIf, connections []graph.ConnectIf) {
+	for _, g := range o.ghosts {
+		if g.node.BBox().Overlaps(r) {
+			graph.DrawCompareGhost(context, g.node.BBox(), g.node.Name(), g.state)
+		}
+	}
+	for _, c := range o.ghostConnections {
+		if c.BBox().Overlaps(r) {
+			graph.DrawCompareConnection(context, c, graph.CompareStateRemoved)
+		}
+	}
+	o.drawNodes(context, r, nodes, "")
+	for _, c := range connections {
+		if c == nil {
+			continue
+		}
+		state, ok := o.connections[connectionKey(c)]
+		if ok && c.BBox().Overlaps(r) {
+			graph.DrawCompareConnection(context, c, state)
+		}
+	}
+}
+
+func (o *compareOverlay) drawNodes(context *cairo.Context, r image.Rectangle, nodes []graph.NodeIf, prefix string) {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		path := prefix + n.Name()
+		state, ok := o.nodes[path]
+		if ok && n.BBox().Overlaps(r) {
+			graph.DrawCompareFrame(context, n.BBox(), state)
+		}
+		o.drawNodes(context, r, n.ChildNodes(), path+"/")
+	}
+}
+
+// Same format as connections in package diff.
+func connectionKey(c graph.ConnectIf) string {
+	return fmt.Sprintf("%s.%s -> %s.%s", c.From().Name(), c.From().OutPorts()[c.FromId()].Name(),
+		c.To().Name(), c.To().InPorts()[c.ToId()].Name())
+}
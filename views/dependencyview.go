@@ -0,0 +1,274 @@
+package views
+
+import (
+	"github.com/axel-freesp/sge/freesp/deps"
+	bh "github.com/axel-freesp/sge/interface/behaviour"
+	mp "github.com/axel-freesp/sge/interface/mapping"
+	pf "github.com/axel-freesp/sge/interface/platform"
+	"github.com/axel-freesp/sge/views/graph"
+	"github.com/gotk3/gotk3/cairo"
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"image"
+	"path/filepath"
+	"sort"
+)
+
+// The dependency view shows signal graphs and libraries as boxes with
+// arrows to the libraries they reference. Documents referencing nothing
+// are drawn at the bottom, each other one above the documents it
+// references. References closing a cycle are red, unused references
+// grey, missing libraries dashed. Double click a document to select
+// it in the tree.
+
+const (
+	depBoxWidth  = 160
+	depBoxHeight = 32
+	depColDist   = 200
+	depRowDist   = 90
+	depMargin    = 20
+)
+
+type dependencyBox struct {
+	filename         string
+	library, missing bool
+	box              image.Rectangle
+}
+
+type dependencyView struct {
+	parent   *ScaledView
+	area     DrawArea
+	source   func() *deps.Graph
+	navigate func(filename string)
+	report   *deps.Report
+	boxes    map[string]*dependencyBox
+}
+
+var _ ScaledScene = (*dependencyView)(nil)
+var _ GraphViewIf = (*dependencyView)(nil)
+
+// DependencyViewNew creates the view, source provides the documents to
+// analyze on each Sync.
+func DependencyViewNew(source func() *deps.Graph, navigate func(filename string)) (viewer *dependencyView, err error) {
+	viewer = &dependencyView{source: source, navigate: navigate}
+	err = viewer.init()
+	if err != nil {
+		return
+	}
+	viewer.Sync()
+	return
+}
+
+func (v *dependencyView) Widget() *gtk.Widget {
+	return v.parent.Widget()
+}
+
+func (v *dependencyView) init() (err error) {
+	v.parent, err = ScaledViewNew(v)
+	if err != nil {
+		return
+	}
+	v.area, err = DrawAreaInit(v)
+	if err != nil {
+		return
+	}
+	v.parent.Container().Add(v.area)
+	return
+}
+
+func (v *dependencyView) Sync() {
+	g := v.source()
+	v.report = g.Analyze()
+	v.boxes = make(map[string]*dependencyBox)
+	for _, d := range g.Documents() {
+		v.boxes[d.Filename] = &dependencyBox{filename: d.Filename, library: d.Library}
+	}
+	for _, e := range v.report.Missing {
+		v.boxes[e.To] = &dependencyBox{filename: e.To, library: true, missing: true}
+	}
+	v.layout()
+	v.Update()
+}
+
+func (v dependencyView) IdentifyGraph(g bh.SignalGraphIf) bool {
+	return false
+}
+
+func (v dependencyView) IdentifyPlatform(p pf.PlatformIf) bool {
+	return false
+}
+
+func (v dependencyView) IdentifyMapping(g mp.MappingIf) bool {
+	return false
+}
+
+func (v *dependencyView) Select(obj interface{}) {
+}
+
+func (v *dependencyView) Select2(obj interface{}, id string) {
+}
+
+func (v *dependencyView) Expand(obj interface{}) {
+}
+
+func (v *dependencyView) Collapse(obj interface{}) {
+}
+
+//
+//		ScaledScene interface
+//
+
+func (v *dependencyView) Update() (width, height int) {
+	box := v.bBox()
+	width = int(float64(box.Max.X+depMargin) * v.parent.Scale())
+	height = int(float64(box.Max.Y+depMargin) * v.parent.Scale())
+	v.area.SetSizeRequest(width, height)
+	v.area.QueueDrawArea(0, 0, width, height)
+	return
+}
+
+//
+//		DrawAreaClient interface
+//
+
+func (v *dependencyView) ButtonCallback(area DrawArea, evType gdk.EventType, position image.Point) {
+	if evType != gdk.EVENT_2BUTTON_PRESS {
+		return
+	}
+	pos := v.parent.Position(position)
+	for _, b := range v.boxes {
+		if !b.missing && pos.In(b.box) {
+			v.navigate(b.filename)
+			return
+		}
+	}
+}
+
+func (v *dependencyView) MotionCallback(area DrawArea, position image.Point) {
+}
+
+func (v *dependencyView) DrawCallback(area DrawArea, context *cairo.Context) {
+	context.Scale(v.parent.Scale(), v.parent.Scale())
+	unused := make(map[deps.Edge]bool)
+	for _, e := range v.report.Unused {
+		unused[e] = true
+	}
+	for _, e := range v.report.Edges {
+		p1, p2 := v.edgePoints(e)
+		var r, g, b float64
+		switch {
+		case v.report.InCycle(e):
+			r, g, b, _ = graph.ColorOption(graph.DependencyCycle)
+		case unused[e]:
+			r, g, b, _ = graph.ColorOption(graph.DependencyUnused)
+		default:
+			r, g, b, _ = graph.ColorOption(graph.NormalLine)
+		}
+		context.SetLineWidth(2)
+		context.SetSourceRGB(r, g, b)
+		graph.DrawArrow(context, p1, p2)
+	}
+	for _, e := range v.report.Missing {
+		p1, p2 := v.edgePoints(e)
+		r, g, b, _ := graph.ColorOption(graph.DependencyCycle)
+		context.SetLineWidth(2)
+		context.SetSourceRGB(r, g, b)
+		graph.DrawDashedLine(context, p1, p2)
+	}
+	for _, b := range v.boxes {
+		v.drawBox(context, b)
+	}
+}
+
+//
+//		Private methods
+//
+
+func (v *dependencyView) drawBox(context *cairo.Context, b *dependencyBox) {
+	x, y := float64(b.box.Min.X), float64(b.box.Min.Y)
+	w, h := float64(b.box.Dx()), float64(b.box.Dy())
+	var r, g, bl float64
+	if b.missing {
+		r, g, bl, _ = graph.ColorOption(graph.DependencyCycle)
+		context.SetLineWidth(1)
+		context.SetSourceRGB(r, g, bl)
+		p0, p2 := b.box.Min, b.box.Max
+		p1, p3 := image.Point{p2.X, p0.Y}, image.Point{p0.X, p2.Y}
+		graph.DrawDashedLine(context, p0, p1)
+		graph.DrawDashedLine(context, p1, p2)
+		graph.DrawDashedLine(context, p2, p3)
+		graph.DrawDashedLine(context, p3, p0)
+	} else {
+		if b.library {
+			r, g, bl, _ = graph.ColorOption(graph.DependencyLibrary)
+		} else {
+			r, g, bl, _ = graph.ColorOption(graph.DependencyGraph)
+		}
+		context.SetSourceRGB(r, g, bl)
+		context.Rectangle(x, y, w, h)
+		context.Fill()
+		r, g, bl, _ = graph.ColorOption(graph.BoxFrame)
+		context.SetLineWidth(1)
+		context.SetSourceRGB(r, g, bl)
+		context.Rectangle(x, y, w, h)
+		context.Stroke()
+	}
+	r, g, bl, _ = graph.ColorOption(graph.Text)
+	context.SetSourceRGB(r, g, bl)
+	context.SetFontSize(float64(graph.NumericOption(graph.FontSize)))
+	context.MoveTo(x+float64(graph.NumericOption(graph.NodeTextX)), y+float64(graph.NumericOption(graph.NodeTextY)))
+	context.ShowText(filepath.Base(b.filename))
+}
+
+// Arrows lead from the bottom of the referencing document to the top of
+// the referenced one.
+func (v *dependencyView) edgePoints(e deps.Edge) (p1, p2 image.Point) {
+	from, to := v.boxes[e.From].box, v.boxes[e.To].box
+	p1 = image.Point{(from.Min.X + from.Max.X) / 2, from.Max.Y}
+	p2 = image.Point{(to.Min.X + to.Max.X) / 2, to.Min.Y}
+	return
+}
+
+// Each document is placed one row above the highest document it
+// references, ignoring references which close a cycle.
+func (v *dependencyView) layout() {
+	level := make(map[string]int)
+	edges := append([]deps.Edge{}, v.report.Missing...)
+	for _, e := range v.report.Edges {
+		if !v.report.InCycle(e) {
+			edges = append(edges, e)
+		}
+	}
+	for changed, i := true, 0; changed && i <= len(v.boxes); i++ {
+		changed = false
+		for _, e := range edges {
+			if level[e.From] <= level[e.To] {
+				level[e.From] = level[e.To] + 1
+				changed = true
+			}
+		}
+	}
+	var names []string
+	maxLevel := 0
+	for name := range v.boxes {
+		names = append(names, name)
+		if level[name] > maxLevel {
+			maxLevel = level[name]
+		}
+	}
+	sort.Strings(names)
+	column := make(map[int]int)
+	for _, name := range names {
+		l := level[name]
+		pos := image.Point{depMargin + column[l]*depColDist, depMargin + (maxLevel-l)*depRowDist}
+		v.boxes[name].box = image.Rectangle{pos, pos.Add(image.Point{depBoxWidth, depBoxHeight})}
+		column[l]++
+	}
+}
+
+func (v *dependencyView) bBox() (box image.Rectangle) {
+	for _, b := range v.boxes {
+		box = box.Union(b.box)
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"github.com/gotk3/gotk3/cairo"
+	"image"
+	"math"
+)
+
+// Drawing of the compare overlay: items of the current version are
+// framed by the color of their state, items only present in the old
+// version (and old positions of moved nodes) are drawn as dashed ghosts.
+
+type CompareState int
+
+const (
+	CompareStateAdded CompareState = iota
+	CompareStateRemoved
+	CompareStateModified
+	CompareStateMoved
+)
+
+const dashLength = 6.0
+
+func compareColor(state CompareState) (r, g, b float64) {
+	switch state {
+	case CompareStateAdded:
+		r, g, b, _ = ColorOption(CompareAdded)
+	case CompareStateRemoved:
+		r, g, b, _ = ColorOption(CompareRemoved)
+	case CompareStateModified:
+		r, g, b, _ = ColorOption(CompareModified)
+	default:
+		r, g, b, _ = ColorOption(CompareMoved)
+	}
+	return
+}
+
+// Frame a node of the current version.
+func DrawCompareFrame(ctxt interface{}, box image.Rectangle, state CompareState) {
+	switch ctxt.(type) {
+	case *cairo.Context:
+		context := ctxt.(*cairo.Context)
+		r, g, b := compareColor(state)
+		context.SetLineWidth(3)
+		context.SetSourceRGB(r, g, b)
+		x, y := float64(box.Min.X+global.padX), float64(box.Min.Y+global.padY)
+		w, h := float64(box.Dx()-2*global.padX), float64(box.Dy()-2*global.padY)
+		context.Rectangle(x-2, y-2, w+4, h+4)
+		context.Stroke()
+	}
+}
+
+// Draw a node of the old version as dashed outline with its name.
+func DrawCompareGhost(ctxt interface{}, box image.Rectangle, name string, state CompareState) {
+	switch ctxt.(type) {
+	case *cairo.Context:
+		context := ctxt.(*cairo.Context)
+		r, g, b := compareColor(state)
+		context.SetLineWidth(1)
+		context.SetSourceRGB(r, g, b)
+		p0 := box.Min.Add(image.Point{global.padX, global.padY})
+		p2 := box.Max.Sub(image.Point{global.padX, global.padY})
+		p1, p3 := image.Point{p2.X, p0.Y}, image.Point{p0.X, p2.Y}
+		DrawDashedLine(context, p0, p1)
+		DrawDashedLine(context, p1, p2)
+		DrawDashedLine(context, p2, p3)
+		DrawDashedLine(context, p3, p0)
+		context.SetFontSize(float64(global.fontSize))
+		context.MoveTo(float64(p0.X+global.textX), float64(p0.Y+global.textY))
+		context.ShowText(name)
+	}
+}
+
+// Draw a connection of the current version in the color of its state,
+// or a removed connection (between nodes of the old version) dashed.
+func DrawCompareConnection(ctxt interface{}, c ConnectIf, state CompareState) {
+	switch ctxt.(type) {
+	case *cairo.Context:
+		context := ctxt.(*cairo.Context)
+		r, g, b := compareColor(state)
+		context.SetSourceRGB(r, g, b)
+		p1, p2 := connectionPoints(c.From(), c.To(), c.FromId(), c.ToId())
+		if state == CompareStateRemoved {
+			context.SetLineWidth(2)
+			DrawDashedLine(context, p1, p2)
+		} else {
+			context.SetLineWidth(3)
+			DrawArrow(context, p1, p2)
+		}
+	}
+}
+
+func DrawDashedLine(gc *cairo.Context, p1, p2 image.Point) {
+	dx, dy := float64(p2.X-p1.X), float64(p2.Y-p1.Y)
+	l := math.Sqrt(dx*dx + dy*dy)
+	if l == 0 {
+		return
+	}
+	ex, ey := dx/l, dy/l
+	for s := 0.0; s < l; s += 2 * dashLength {
+		e := math.Min(s+dashLength, l)
+		gc.MoveTo(float64(p1.X)+s*ex, float64(p1.Y)+s*ey)
+		gc.LineTo(float64(p1.X)+e*ex, float64(p1.Y)+e*ey)
+	}
+	gc.Stroke()
+}
@@ -85,6 +85,16 @@ const (
 	SelectExpandedNode
 	HighlightExpandedNode
 	NormalExpandedNode
+	CompareAdded
+	CompareRemoved
+	CompareModified
+	CompareMoved
+	DependencyGraph
+	DependencyLibrary
+	DependencyCycle
+	DependencyUnused
+	ProblemError
+	ProblemWarning
 )
 
 func ColorOption(index int) (r, g, b, a float64) {
@@ -179,6 +189,16 @@ var defaultOptions = gOptions{
 		{"SelectExpandedNode", color.RGBA{220, 255, 255, 0x40}},
 		{"HighlightExpandedNode", color.RGBA{255, 255, 220, 0x40}},
 		{"NormalExpandedNode", color.RGBA{255, 255, 255, 0x40}},
+		{"CompareAdded", color.RGBA{0, 170, 0, 0xff}},
+		{"CompareRemoved", color.RGBA{220, 0, 0, 0xff}},
+		{"CompareModified", color.RGBA{230, 140, 0, 0xff}},
+		{"CompareMoved", color.RGBA{150, 150, 150, 0xff}},
+		{"DependencyGraph", color.RGBA{255, 204, 146, 0xff}},
+		{"DependencyLibrary", color.RGBA{204, 255, 153, 0xff}},
+		{"DependencyCycle", color.RGBA{220, 0, 0, 0xff}},
+		{"DependencyUnused", color.RGBA{190, 190, 190, 0xff}},
+		{"ProblemError", color.RGBA{220, 0, 0, 0xff}},
+		{"ProblemWarning", color.RGBA{240, 180, 0, 0xff}},
 	},
 	[]optionString{ // actually not needed anymore:
 		{"FontPath", "/usr/share/fonts/truetype"},
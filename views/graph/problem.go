@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"github.com/gotk3/gotk3/cairo"
+	"image"
+	"math"
+)
+
+// Drawing of problem marks: a node with lint or validation problems is
+// framed and gets a filled circle with "!" at its upper right corner,
+// in the color of its worst problem.
+
+const problemMarkRadius = 7.0
+
+// Mark a node with problems, isError selects the error color.
+func DrawProblemMark(ctxt interface{}, box image.Rectangle, isError bool) {
+	switch ctxt.(type) {
+	case *cairo.Context:
+		context := ctxt.(*cairo.Context)
+		r, g, b, _ := ColorOption(ProblemWarning)
+		if isError {
+			r, g, b, _ = ColorOption(ProblemError)
+		}
+		context.SetSourceRGB(r, g, b)
+		context.SetLineWidth(2)
+		x, y := float64(box.Min.X+global.padX), float64(box.Min.Y+global.padY)
+		w, h := float64(box.Dx()-2*global.padX), float64(box.Dy()-2*global.padY)
+		context.Rectangle(x-1, y-1, w+2, h+2)
+		context.Stroke()
+		cx, cy := x+w, y
+		context.Arc(cx, cy, problemMarkRadius, 0, 2*math.Pi)
+		context.Fill()
+		r, g, b, _ = ColorOption(Background)
+		context.SetSourceRGB(r, g, b)
+		context.SetFontSize(float64(global.fontSize))
+		context.MoveTo(cx-2, cy+float64(global.fontSize)/2-1)
+		context.ShowText("!")
+	}
+}
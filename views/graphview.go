@@ -2,9 +2,11 @@ package views
 
 import (
 	"fmt"
+	"github.com/axel-freesp/sge/freesp/validate"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	mp "github.com/axel-freesp/sge/interface/mapping"
 	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
 	"github.com/gotk3/gotk3/gtk"
 	"log"
 )
@@ -167,3 +169,36 @@ func (gvc *graphViewCollection) CurrentView() (v GraphViewIf) {
 	}
 	return
 }
+
+// Compare shows the differences of doc to its version old in the
+// view of doc, old == nil ends compare mode.
+func (gvc *graphViewCollection) Compare(doc, old tr.ToplevelTreeElementIf) error {
+	for _, v := range gvc.graphview {
+		var found bool
+		switch doc.(type) {
+		case bh.SignalGraphIf:
+			found = v.IdentifyGraph(doc.(bh.SignalGraphIf))
+		case mp.MappingIf:
+			found = v.IdentifyMapping(doc.(mp.MappingIf))
+		}
+		if !found {
+			continue
+		}
+		c, ok := v.(ComparerIf)
+		if !ok {
+			break
+		}
+		return c.Compare(old)
+	}
+	return fmt.Errorf("graphViewCollection.Compare error: no compare mode for %s", doc.Filename())
+}
+
+// SetProblems marks the elements problems refer to in all views which
+// can show them.
+func (gvc *graphViewCollection) SetProblems(problems []validate.Problem) {
+	for _, v := range gvc.graphview {
+		if m, ok := v.(ProblemMarkerIf); ok {
+			m.SetProblems(problems)
+		}
+	}
+}
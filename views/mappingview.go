@@ -1,6 +1,7 @@
 package views
 
 import (
+	"fmt"
 	freesp "github.com/axel-freesp/sge/freesp/behaviour"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
@@ -29,13 +30,17 @@ type mappingView struct {
 
 	dragOffs       image.Point
 	button1Pressed bool
+
+	compareWith mp.MappingIf
+	compare     *compareOverlay
 }
 
 var _ ScaledScene = (*mappingView)(nil)
 var _ GraphViewIf = (*mappingView)(nil)
+var _ ComparerIf = (*mappingView)(nil)
 
 func MappingViewNew(m mp.MappingIf, context ContextIf) (viewer *mappingView, err error) {
-	viewer = &mappingView{nil, DrawArea{}, m, nil, nil, nil, context, nil, unmappedProcessNew(), image.Point{}, false}
+	viewer = &mappingView{nil, DrawArea{}, m, nil, nil, nil, context, nil, unmappedProcessNew(), image.Point{}, false, nil, nil}
 	err = viewer.init()
 	if err != nil {
 		return
@@ -166,10 +171,28 @@ func (v *mappingView) Sync() {
 		}
 	}
 	v.unmapped = graph.ProcessMappingNew(unmappedNodes, unmappedIds, v.unmappedObj)
+	v.compare = nil
+	if v.compareWith != nil {
+		v.compare = mappingOverlayNew(v.compareWith, v.mapping)
+	}
 	v.area.SetSizeRequest(v.calcSceneWidth(), v.calcSceneHeight())
 	v.drawAll()
 }
 
+func (v *mappingView) Compare(old tr.ToplevelTreeElementIf) error {
+	v.drawAll()
+	switch old.(type) {
+	case nil:
+		v.compareWith = nil
+	case mp.MappingIf:
+		v.compareWith = old.(mp.MappingIf)
+	default:
+		return fmt.Errorf("mappingView.Compare error: %s is not a mapping", old.Filename())
+	}
+	v.Sync()
+	return nil
+}
+
 func (v mappingView) IdentifyGraph(g bh.SignalGraphIf) bool {
 	return false
 }
@@ -572,6 +595,9 @@ func (v *mappingView) DrawCallback(area DrawArea, context *cairo.Context) {
 	v.drawNodes(context, r)
 	v.drawChannels(context, r)
 	v.drawConnections(context, r)
+	if v.compare != nil {
+		v.compare.draw(context, r, v.nodes, v.connections)
+	}
 }
 
 func (v *mappingView) drawConnections(context *cairo.Context, r image.Rectangle) {
@@ -689,6 +715,9 @@ func (v *mappingView) bBox() (box image.Rectangle) {
 		}
 	}
 	box = box.Union(v.unmapped.BBox())
+	if v.compare != nil {
+		box = box.Union(v.compare.bBox())
+	}
 	return
 }
 
@@ -734,7 +763,7 @@ func (p *unmappedProcess) AddToTree(tr.TreeIf, tr.Cursor) {
 func (p *unmappedProcess) AddNewObject(tr.TreeIf, tr.Cursor, tr.TreeElementIf) (c tr.Cursor, e error) {
 	return
 }
-func (p *unmappedProcess) RemoveObject(tr.TreeIf, tr.Cursor) (r []tr.IdWithObject) {
+func (p *unmappedProcess) RemoveObject(tr.TreeIf, tr.Cursor) (r []tr.IdWithObject, err error) {
 	return
 }
 
@@ -0,0 +1,113 @@
+package views
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/validate"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"path/filepath"
+	"strconv"
+)
+
+// ProblemsView lists the problems of the open documents, activating a
+// row calls the navigation callback with its problem.
+type ProblemsView struct {
+	ScrolledView
+	box      *gtk.Box
+	title    *gtk.Label
+	view     *gtk.TreeView
+	store    *gtk.ListStore
+	problems []validate.Problem
+	navigate func(validate.Problem)
+}
+
+const (
+	problemSeverityCol = 0
+	problemFileCol     = 1
+	problemLocationCol = 2
+	problemMessageCol  = 3
+)
+
+func ProblemsViewNew(width, height int, navigate func(validate.Problem)) (viewer *ProblemsView, err error) {
+	v, err := ScrolledViewNew(width, height)
+	if err != nil {
+		viewer = nil
+		return
+	}
+	viewer = &ProblemsView{ScrolledView: *v, navigate: navigate}
+	err = viewer.init()
+	return
+}
+
+func (v *ProblemsView) Widget() *gtk.Widget {
+	return &v.box.Widget
+}
+
+func (v *ProblemsView) init() (err error) {
+	v.box, err = gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+	if err != nil {
+		return fmt.Errorf("ProblemsView.init error: BoxNew: %v", err)
+	}
+	v.title, err = gtk.LabelNew("No problems")
+	if err != nil {
+		return fmt.Errorf("ProblemsView.init error: LabelNew: %v", err)
+	}
+	v.store, err = gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING)
+	if err != nil {
+		return fmt.Errorf("ProblemsView.init error: ListStoreNew: %v", err)
+	}
+	v.view, err = gtk.TreeViewNewWithModel(v.store)
+	if err != nil {
+		return fmt.Errorf("ProblemsView.init error: TreeViewNewWithModel: %v", err)
+	}
+	for i, title := range []string{"Severity", "File", "Location", "Problem"} {
+		var renderer *gtk.CellRendererText
+		renderer, err = gtk.CellRendererTextNew()
+		if err != nil {
+			return fmt.Errorf("ProblemsView.init error: CellRendererTextNew: %v", err)
+		}
+		var col *gtk.TreeViewColumn
+		col, err = gtk.TreeViewColumnNewWithAttribute(title, renderer, "text", i)
+		if err != nil {
+			return fmt.Errorf("ProblemsView.init error: TreeViewColumnNewWithAttribute: %v", err)
+		}
+		v.view.AppendColumn(col)
+	}
+	v.view.Connect("row-activated", func(tv *gtk.TreeView, path *gtk.TreePath) {
+		i, err := strconv.Atoi(path.String())
+		if err != nil || i >= len(v.problems) || v.navigate == nil {
+			return
+		}
+		v.navigate(v.problems[i])
+	})
+	v.scrolled.Add(v.view)
+	v.box.PackStart(v.title, false, false, 3)
+	v.box.PackStart(v.ScrolledView.Widget(), true, true, 0)
+	return
+}
+
+// Set replaces the listed problems.
+func (v *ProblemsView) Set(problems []validate.Problem) {
+	v.problems = problems
+	var errors, warnings int
+	for _, p := range problems {
+		if p.Severity == validate.Error {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	if len(problems) == 0 {
+		v.title.SetText("No problems")
+	} else {
+		v.title.SetText(fmt.Sprintf("%d errors, %d warnings", errors, warnings))
+	}
+	v.store.Clear()
+	for _, p := range problems {
+		iter := v.store.Append()
+		v.store.SetValue(iter, problemSeverityCol, p.Severity.String())
+		v.store.SetValue(iter, problemFileCol, filepath.Base(p.Filename))
+		v.store.SetValue(iter, problemLocationCol, p.Location)
+		v.store.SetValue(iter, problemMessageCol, p.Message)
+	}
+}
@@ -1,11 +1,14 @@
 package views
 
 import (
+	"fmt"
 	freesp "github.com/axel-freesp/sge/freesp/behaviour"
+	"github.com/axel-freesp/sge/freesp/validate"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	mp "github.com/axel-freesp/sge/interface/mapping"
 	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
 	"github.com/axel-freesp/sge/views/graph"
 	"github.com/gotk3/gotk3/cairo"
 	"github.com/gotk3/gotk3/gdk"
@@ -25,13 +28,20 @@ type signalGraphView struct {
 
 	dragOffs       image.Point
 	button1Pressed bool
+
+	compareWith bh.SignalGraphTypeIf
+	compare     *compareOverlay
+
+	problems map[string]validate.Severity // worst severity by node name
 }
 
 var _ ScaledScene = (*signalGraphView)(nil)
 var _ GraphViewIf = (*signalGraphView)(nil)
+var _ ComparerIf = (*signalGraphView)(nil)
+var _ ProblemMarkerIf = (*signalGraphView)(nil)
 
 func SignalGraphViewNew(g bh.SignalGraphIf, context ContextIf) (viewer *signalGraphView, err error) {
-	viewer = &signalGraphView{nil, DrawArea{}, nil, nil, g.ItsType(), g.Filename(), context, image.Point{}, false}
+	viewer = &signalGraphView{nil, DrawArea{}, nil, nil, g.ItsType(), g.Filename(), context, image.Point{}, false, nil, nil, nil}
 	err = viewer.init()
 	if err != nil {
 		return
@@ -41,7 +51,7 @@ func SignalGraphViewNew(g bh.SignalGraphIf, context ContextIf) (viewer *signalGr
 }
 
 func SignalGraphViewNewFromType(g bh.SignalGraphTypeIf, context ContextIf) (viewer *signalGraphView, err error) {
-	viewer = &signalGraphView{nil, DrawArea{}, nil, nil, g, "", context, image.Point{}, false}
+	viewer = &signalGraphView{nil, DrawArea{}, nil, nil, g, "", context, image.Point{}, false, nil, nil, nil}
 	err = viewer.init()
 	if err != nil {
 		return
@@ -121,6 +131,10 @@ func (v *signalGraphView) Sync() {
 			}
 		}
 	}
+	v.compare = nil
+	if v.compareWith != nil {
+		v.compare = signalGraphOverlayNew(v.compareWith, g, v.graphId)
+	}
 	v.area.SetSizeRequest(v.calcSceneWidth(), v.calcSceneHeight())
 	v.drawAll()
 	if wasSelected {
@@ -129,6 +143,44 @@ func (v *signalGraphView) Sync() {
 	}
 }
 
+func (v *signalGraphView) Compare(old tr.ToplevelTreeElementIf) error {
+	v.drawAll()
+	switch old.(type) {
+	case nil:
+		v.compareWith = nil
+	case bh.SignalGraphIf:
+		v.compareWith = old.(bh.SignalGraphIf).ItsType()
+	default:
+		return fmt.Errorf("signalGraphView.Compare error: %s is not a signal graph", old.Filename())
+	}
+	v.Sync()
+	return nil
+}
+
+// SetProblems marks the nodes of the graph problems refer to, by
+// themselves, their ports or connections.
+func (v *signalGraphView) SetProblems(problems []validate.Problem) {
+	v.problems = make(map[string]validate.Severity)
+	for _, p := range problems {
+		var n bh.NodeIf
+		switch obj := p.Object.(type) {
+		case bh.NodeIf:
+			n = obj
+		case bh.PortIf:
+			n = obj.Node()
+		case bh.ConnectionIf:
+			n = obj.From().Node()
+		}
+		if n == nil || n.Context() != v.sgType {
+			continue
+		}
+		if s, ok := v.problems[n.Name()]; !ok || s == validate.Warning {
+			v.problems[n.Name()] = p.Severity
+		}
+	}
+	v.drawAll()
+}
+
 func (v signalGraphView) IdentifyGraph(g bh.SignalGraphIf) bool {
 	return g.ItsType() == v.sgType
 }
@@ -334,7 +386,12 @@ func (v *signalGraphView) handleConnectSelect(pos image.Point) {
 			p1 := n1.OutPorts()[c.FromId()]
 			n2 := c.To()
 			p2 := n2.InPorts()[c.ToId()]
-			v.context.SelectConnect(p1.Connection(p2))
+			conn, err := p1.Connection(p2)
+			if err != nil {
+				log.Printf("signalGraphView.handleConnectSelect: %s\n", err)
+			} else {
+				v.context.SelectConnect(conn)
+			}
 			v.repaintConnection(c)
 		} else {
 			if c.Deselect() {
@@ -397,6 +454,15 @@ func (v *signalGraphView) DrawCallback(area DrawArea, context *cairo.Context) {
 	r := image.Rect(int(x1), int(y1), int(x2), int(y2))
 	v.drawNodes(context, r)
 	v.drawConnections(context, r)
+	for _, n := range v.nodes {
+		s, ok := v.problems[n.Name()]
+		if ok && n.BBox().Overlaps(r) {
+			graph.DrawProblemMark(context, n.BBox(), s == validate.Error)
+		}
+	}
+	if v.compare != nil {
+		v.compare.draw(context, r, v.nodes, v.connections)
+	}
 }
 
 func (v *signalGraphView) drawConnections(context *cairo.Context, r image.Rectangle) {
@@ -447,6 +513,9 @@ func (v *signalGraphView) bBox() (box image.Rectangle) {
 			box = box.Union(o.BBox())
 		}
 	}
+	if v.compare != nil {
+		box = box.Union(v.compare.bBox())
+	}
 	return
 }
 
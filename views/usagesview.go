@@ -0,0 +1,102 @@
+package views
+
+import (
+	"fmt"
+	"github.com/axel-freesp/sge/freesp/xref"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"path/filepath"
+	"strconv"
+)
+
+// UsagesView lists the result of a "Find Usages" search, activating
+// a row calls the navigation callback with its usage.
+type UsagesView struct {
+	ScrolledView
+	box      *gtk.Box
+	title    *gtk.Label
+	view     *gtk.TreeView
+	store    *gtk.ListStore
+	usages   []xref.Usage
+	navigate func(xref.Usage)
+}
+
+const (
+	usageFileCol     = 0
+	usageLocationCol = 1
+)
+
+func UsagesViewNew(width, height int, navigate func(xref.Usage)) (viewer *UsagesView, err error) {
+	v, err := ScrolledViewNew(width, height)
+	if err != nil {
+		viewer = nil
+		return
+	}
+	viewer = &UsagesView{ScrolledView: *v, navigate: navigate}
+	err = viewer.init()
+	return
+}
+
+func (v *UsagesView) Widget() *gtk.Widget {
+	return &v.box.Widget
+}
+
+func (v *UsagesView) init() (err error) {
+	v.box, err = gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+	if err != nil {
+		return fmt.Errorf("UsagesView.init error: BoxNew: %v", err)
+	}
+	v.title, err = gtk.LabelNew("")
+	if err != nil {
+		return fmt.Errorf("UsagesView.init error: LabelNew: %v", err)
+	}
+	v.store, err = gtk.ListStoreNew(glib.TYPE_STRING, glib.TYPE_STRING)
+	if err != nil {
+		return fmt.Errorf("UsagesView.init error: ListStoreNew: %v", err)
+	}
+	v.view, err = gtk.TreeViewNewWithModel(v.store)
+	if err != nil {
+		return fmt.Errorf("UsagesView.init error: TreeViewNewWithModel: %v", err)
+	}
+	for i, title := range []string{"File", "Location"} {
+		var renderer *gtk.CellRendererText
+		renderer, err = gtk.CellRendererTextNew()
+		if err != nil {
+			return fmt.Errorf("UsagesView.init error: CellRendererTextNew: %v", err)
+		}
+		var col *gtk.TreeViewColumn
+		col, err = gtk.TreeViewColumnNewWithAttribute(title, renderer, "text", i)
+		if err != nil {
+			return fmt.Errorf("UsagesView.init error: TreeViewColumnNewWithAttribute: %v", err)
+		}
+		v.view.AppendColumn(col)
+	}
+	v.view.Connect("row-activated", func(tv *gtk.TreeView, path *gtk.TreePath) {
+		i, err := strconv.Atoi(path.String())
+		if err != nil || i >= len(v.usages) || v.navigate == nil {
+			return
+		}
+		v.navigate(v.usages[i])
+	})
+	v.scrolled.Add(v.view)
+	v.box.PackStart(v.title, false, false, 3)
+	v.box.PackStart(v.ScrolledView.Widget(), true, true, 0)
+	// hidden until the first search
+	v.title.Show()
+	v.scrolled.ShowAll()
+	v.box.SetNoShowAll(true)
+	return
+}
+
+// Set shows the usages of what.
+func (v *UsagesView) Set(what string, usages []xref.Usage) {
+	v.usages = usages
+	v.title.SetText(fmt.Sprintf("%d usages of %s", len(usages), what))
+	v.store.Clear()
+	for _, u := range usages {
+		iter := v.store.Append()
+		v.store.SetValue(iter, usageFileCol, filepath.Base(u.Filename))
+		v.store.SetValue(iter, usageLocationCol, u.Location())
+	}
+	v.box.Show()
+}
@@ -1,10 +1,12 @@
 package views
 
 import (
+	"github.com/axel-freesp/sge/freesp/validate"
 	bh "github.com/axel-freesp/sge/interface/behaviour"
 	gr "github.com/axel-freesp/sge/interface/graph"
 	mp "github.com/axel-freesp/sge/interface/mapping"
 	pf "github.com/axel-freesp/sge/interface/platform"
+	tr "github.com/axel-freesp/sge/interface/tree"
 	"github.com/gotk3/gotk3/gtk"
 )
 
@@ -32,6 +34,8 @@ type GraphViewCollectionIf interface {
 	Select(obj interface{})
 	Select2(obj interface{}, id string)
 	CurrentView() GraphViewIf
+	Compare(doc, old tr.ToplevelTreeElementIf) error
+	SetProblems([]validate.Problem)
 }
 
 type GraphViewIf interface {
@@ -46,6 +50,18 @@ type GraphViewIf interface {
 	IdentifyMapping(mp.MappingIf) bool
 }
 
+// Views which can show the differences of their document to another
+// version of it. Compare(nil) ends compare mode.
+type ComparerIf interface {
+	Compare(old tr.ToplevelTreeElementIf) error
+}
+
+// Views which mark the elements problems refer to. Problems of other
+// documents are ignored.
+type ProblemMarkerIf interface {
+	SetProblems([]validate.Problem)
+}
+
 type XmlTextViewIf interface {
 	Set(gr.XmlCreator) error
 }